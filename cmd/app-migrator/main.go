@@ -25,7 +25,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cmd"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
-	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/datamover"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
 )
@@ -61,7 +61,14 @@ func newContext() *context.Context {
 		DropletCountToKeep: 2,
 		Metadata:           metadata.NewMetadata(),
 		Summary:            report.NewSummary(os.Stdout),
-		DirWriter:          io.NewDirWriter(),
+		Resources:          report.NewResources(os.Stdout),
+		Preflight:          report.NewPreflight(os.Stdout),
+		ServicePlanChecks:  report.NewServicePlanChecks(os.Stdout),
+		MissingUsers:       report.NewMissingUsers(os.Stdout),
+		SecurityGroupDiffs: report.NewSecurityGroupDiffs(os.Stdout),
+		ServiceKeyChanges:  report.NewServiceKeyChanges(os.Stdout),
+		DataMoves:          report.NewDataMoves(os.Stdout),
+		MovedInstances:     datamover.NewInstances(),
 		DropletExporter:    export.NewDropletExporter(),
 		ManifestExporter:   export.NewManifestExporter(),
 		AutoScalerExporter: export.NewAutoScalerExporter(),
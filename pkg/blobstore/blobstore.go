@@ -0,0 +1,212 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package blobstore keeps the droplets and packages of an export once, named by the SHA-256 of their content.
+//
+// Apps reference their blobs with a small file next to their manifest, named after the droplet or package it replaces,
+// e.g. my_org/my_space/my_app.tgz.sha256 holds the digest of the droplet stored in .app-migrator/blobs/sha256/<digest>.
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+)
+
+const (
+	// Root is the directory of the export holding the blob stores
+	Root = aio.ReservedDir + "/blobs"
+	// Dir is the directory of the export holding the blobs
+	Dir = Root + "/sha256"
+	// RefSuffix is appended to the name of a droplet or package to name the file referencing its blob
+	RefSuffix = ".sha256"
+)
+
+// Writer stores the files of an export, as context.DirWriter does
+type Writer interface {
+	aio.Putter
+	Stat(name string) (fs.FileInfo, error)
+	Delete(name string) error
+}
+
+// ChecksumMismatchError is returned when the content of a blob does not match the digest it was stored under
+type ChecksumMismatchError struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum of %s is %s, expected %s", e.Name, e.Actual, e.Expected)
+}
+
+// locks serializes the writes of a blob shared by apps exported concurrently
+var locks sync.Map
+
+// Name returns the name of the blob with the given digest
+func Name(digest string) string {
+	return path.Join(Dir, digest)
+}
+
+// Store writes blobs to an export, encrypting them when sealer is not nil
+type Store struct {
+	w      Writer
+	sealer crypt.Sealer
+}
+
+func New(w Writer, sealer crypt.Sealer) *Store {
+	return &Store{w: w, sealer: sealer}
+}
+
+// Put references the blob with the given digest from name, downloading it with open unless it is already stored.
+// When the digest is not known in advance it is computed from the content, which is spooled to a temporary file
+// first. Put reports whether an already stored blob was reused.
+func (s *Store) Put(name, digest string, open func() (io.ReadCloser, error)) (bool, error) {
+	digest = strings.ToLower(digest)
+	if digest != "" {
+		reused, err := s.putKnown(digest, open)
+		if err != nil {
+			return false, err
+		}
+		return reused, s.reference(name, digest)
+	}
+
+	body, err := open()
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	tmp, err := aio.CopyToTempFile(io.TeeReader(body, h))
+	if tmp != nil {
+		defer os.Remove(tmp.Name())
+	}
+	if err != nil {
+		return false, err
+	}
+	digest = hex.EncodeToString(h.Sum(nil))
+
+	reused, err := s.putKnown(digest, func() (io.ReadCloser, error) {
+		return os.Open(tmp.Name())
+	})
+	if err != nil {
+		return false, err
+	}
+	return reused, s.reference(name, digest)
+}
+
+func (s *Store) putKnown(digest string, open func() (io.ReadCloser, error)) (bool, error) {
+	lock, _ := locks.LoadOrStore(digest, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	name := Name(digest)
+	if _, err := s.w.Stat(name); err == nil {
+		return true, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	body, err := open()
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if err = s.write(name, io.TeeReader(body, h)); err != nil {
+		_ = s.w.Delete(name)
+		return false, err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != digest {
+		_ = s.w.Delete(name)
+		return false, &ChecksumMismatchError{Name: name, Expected: digest, Actual: actual}
+	}
+
+	return false, nil
+}
+
+// reference points name to the blob, replacing the file a plain export would have written there
+func (s *Store) reference(name, digest string) error {
+	if err := s.write(name+RefSuffix, strings.NewReader(digest+"\n")); err != nil {
+		return err
+	}
+	if err := s.w.Delete(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) write(name string, r io.Reader) error {
+	w, err := aio.PutFile(s.w, name, 0644, s.sealer)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err = io.Copy(w, r); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// Resolve returns the name of the file holding the content of the named droplet or package. That is the file itself
+// in a plain export, or the blob its reference points to in a deduplicated one.
+func Resolve(fsys fs.FS, name string, opener crypt.Opener) (string, error) {
+	_, err := fs.Stat(fsys, name)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return name, err
+	}
+
+	ref, refErr := aio.OpenFS(fsys, name+RefSuffix, opener)
+	if refErr != nil {
+		if errors.Is(refErr, fs.ErrNotExist) {
+			return name, err
+		}
+		return name, refErr
+	}
+	defer ref.Close()
+
+	data, err := io.ReadAll(ref)
+	if err != nil {
+		return name, err
+	}
+
+	digest := strings.TrimSpace(string(data))
+	if !isDigest(digest) {
+		return name, fmt.Errorf("%s does not hold a sha256 digest", name+RefSuffix)
+	}
+
+	return Name(digest), nil
+}
+
+func isDigest(s string) bool {
+	if len(s) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
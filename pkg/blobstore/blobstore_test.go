@@ -0,0 +1,158 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func digestOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func opener(data string, calls *int) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		*calls++
+		return io.NopCloser(strings.NewReader(data)), nil
+	}
+}
+
+func TestStore_Put(t *testing.T) {
+	tests := []struct {
+		name       string
+		digest     string
+		data       string
+		wantReused []bool
+		wantErr    error
+	}{
+		{
+			name:       "stores a blob with a known digest once",
+			digest:     digestOf("droplet data"),
+			data:       "droplet data",
+			wantReused: []bool{false, true},
+		},
+		{
+			name:       "stores a blob with an unknown digest once",
+			data:       "droplet data",
+			wantReused: []bool{false, true},
+		},
+		{
+			name:    "rejects content not matching the digest",
+			digest:  digestOf("other data"),
+			data:    "droplet data",
+			wantErr: &ChecksumMismatchError{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := storage.NewMemory()
+			s := New(w, nil)
+
+			if tt.wantErr != nil {
+				var calls int
+				_, err := s.Put("org/space/app.tgz", tt.digest, opener(tt.data, &calls))
+				var mismatch *ChecksumMismatchError
+				require.True(t, errors.As(err, &mismatch), "got %v", err)
+				_, err = w.Stat(Name(tt.digest))
+				assert.ErrorIs(t, err, fs.ErrNotExist)
+				return
+			}
+
+			for i, wantReused := range tt.wantReused {
+				var calls int
+				reused, err := s.Put("org/space/app"+string(rune('a'+i))+".tgz", tt.digest, opener(tt.data, &calls))
+				require.NoError(t, err)
+				assert.Equal(t, wantReused, reused)
+				if tt.digest != "" && reused {
+					assert.Equal(t, 0, calls, "a stored blob with a known digest is not downloaded again")
+				}
+			}
+
+			name, err := Resolve(storage.NewFS(w), "org/space/appb.tgz", nil)
+			require.NoError(t, err)
+			assert.Equal(t, Name(digestOf(tt.data)), name)
+
+			data, err := fs.ReadFile(storage.NewFS(w), name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, string(data))
+		})
+	}
+}
+
+func TestStore_PutReplacesPlainFile(t *testing.T) {
+	w := storage.NewMemory()
+	plain, err := w.Put("org/space/app.zip", 0644)
+	require.NoError(t, err)
+	require.NoError(t, plain.Close())
+
+	var calls int
+	_, err = New(w, nil).Put("org/space/app.zip", "", opener("app bits", &calls))
+	require.NoError(t, err)
+
+	_, err = w.Stat("org/space/app.zip")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	name, err := Resolve(storage.NewFS(w), "org/space/app.zip", nil)
+	require.NoError(t, err)
+	assert.Equal(t, Name(digestOf("app bits")), name)
+}
+
+func TestStore_PutEncrypted(t *testing.T) {
+	key := crypt.NewPassphraseKey("correct horse battery staple")
+	w := storage.NewMemory()
+
+	var calls int
+	_, err := New(w, key).Put("org/space/app.tgz", digestOf("droplet data"), opener("droplet data", &calls))
+	require.NoError(t, err)
+
+	_, err = Resolve(storage.NewFS(w), "org/space/app.tgz", nil)
+	assert.Error(t, err, "the reference cannot be read without the key")
+
+	name, err := Resolve(storage.NewFS(w), "org/space/app.tgz", key)
+	require.NoError(t, err)
+	assert.Equal(t, Name(digestOf("droplet data")), name)
+}
+
+func TestResolve(t *testing.T) {
+	w := storage.NewMemory()
+	plain, err := w.Put("org/space/app.tgz", 0644)
+	require.NoError(t, err)
+	require.NoError(t, plain.Close())
+	ref, err := w.Put("org/space/bad.tgz"+RefSuffix, 0644)
+	require.NoError(t, err)
+	_, _ = io.WriteString(ref, "not a digest")
+	require.NoError(t, ref.Close())
+
+	name, err := Resolve(storage.NewFS(w), "org/space/app.tgz", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "org/space/app.tgz", name)
+
+	_, err = Resolve(storage.NewFS(w), "org/space/missing.tgz", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = Resolve(storage.NewFS(w), "org/space/bad.tgz", nil)
+	assert.ErrorContains(t, err, "does not hold a sha256 digest")
+}
@@ -0,0 +1,390 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package bundle packs an export directory into a single tar archive and reads the files of an export back from one.
+//
+// The first entry of an archive is an index listing every other entry along with the offset of its tar header,
+// so files can be found without reading the whole archive, and read directly from uncompressed archives.
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// IndexName is the name of the first entry of an archive
+	IndexName = "app-migrator-index.json"
+
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+
+	indexVersion = 1
+	blockSize    = 512
+)
+
+// Compressions lists the supported compressions
+var Compressions = []string{CompressionNone, CompressionGzip, CompressionZstd}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ErrNotBundle is returned when an archive does not start with an index
+var ErrNotBundle = errors.New("not an app-migrator bundle, the archive has no index")
+
+// Index lists the entries of an archive
+type Index struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is a file in an archive, Offset is the position of its tar header relative to the end of the index
+type Entry struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+	Offset  int64       `json:"offset"`
+}
+
+// IsValidCompression reports whether c is one of the supported compressions
+func IsValidCompression(c string) bool {
+	for _, s := range Compressions {
+		if s == c {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressionFor guesses the compression from the extension of an archive name, archives are not compressed by default
+func CompressionFor(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".zst"), strings.HasSuffix(name, ".tzst"):
+		return CompressionZstd
+	case strings.HasSuffix(name, ".gz"), strings.HasSuffix(name, ".tgz"):
+		return CompressionGzip
+	}
+	return CompressionNone
+}
+
+// Pack writes every file under dir to w as a tar archive with the given compression and returns the number of files
+func Pack(dir string, w io.Writer, compression string) (int, error) {
+	entries, err := listFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	for i := range entries {
+		entries[i].Offset = offset
+		size, err := headerSize(entries[i].header())
+		if err != nil {
+			return 0, err
+		}
+		offset += size + padded(entries[i].Size)
+	}
+
+	index, err := json.Marshal(Index{Version: indexVersion, Entries: entries})
+	if err != nil {
+		return 0, err
+	}
+
+	cw, err := compress(w, compression)
+	if err != nil {
+		return 0, err
+	}
+
+	tw := tar.NewWriter(cw)
+	err = tw.WriteHeader(&tar.Header{
+		Name:     IndexName,
+		Size:     int64(len(index)),
+		Mode:     0644,
+		ModTime:  time.Now().Truncate(time.Second),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tw.Write(index); err != nil {
+		return 0, err
+	}
+
+	for _, e := range entries {
+		if err = writeEntry(tw, dir, e); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return 0, err
+	}
+
+	return len(entries), cw.Close()
+}
+
+// Unpack extracts every file of the archive read from r into dir and returns the number of files
+func Unpack(r io.Reader, dir string) (int, error) {
+	dr, err := decompress(r)
+	if err != nil {
+		return 0, err
+	}
+	defer dr.Close()
+
+	tr := tar.NewReader(dr)
+	if _, err = readIndex(tr); err != nil {
+		return 0, err
+	}
+
+	var count int
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !fs.ValidPath(hdr.Name) {
+			return count, fmt.Errorf("invalid file name %q in archive", hdr.Name)
+		}
+
+		name := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return count, err
+		}
+		if err = extract(tr, name, hdr.FileInfo().Mode().Perm()); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+func extract(r io.Reader, name string, perm fs.FileMode) error {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(file, r); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func listFiles(dir string) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, Entry{
+			Name:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime().Truncate(time.Second).UTC(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+func (e Entry) header() *tar.Header {
+	return &tar.Header{
+		Name:     e.Name,
+		Size:     e.Size,
+		Mode:     int64(e.Mode.Perm()),
+		ModTime:  e.ModTime,
+		Typeflag: tar.TypeReg,
+	}
+}
+
+func writeEntry(tw *tar.Writer, dir string, e Entry) error {
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(e.Name)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = tw.WriteHeader(e.header()); err != nil {
+		return err
+	}
+
+	// the size was recorded in the index, a file that changed since then would corrupt the offsets
+	n, err := io.Copy(tw, io.LimitReader(file, e.Size))
+	if err != nil {
+		return err
+	}
+	if n != e.Size {
+		return fmt.Errorf("%s changed while it was being packed", e.Name)
+	}
+
+	return nil
+}
+
+// headerSize returns the number of bytes the tar writer uses for hdr, including any PAX records
+func headerSize(hdr *tar.Header) (int64, error) {
+	cw := &countingWriter{}
+	if err := tar.NewWriter(cw).WriteHeader(hdr); err != nil {
+		return 0, err
+	}
+	return cw.n, nil
+}
+
+func padded(size int64) int64 {
+	return (size + blockSize - 1) / blockSize * blockSize
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func compress(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone, "":
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return zw, nil
+	}
+	return nil, fmt.Errorf("unsupported compression %q, must be one of %s", compression, strings.Join(Compressions, "|"))
+}
+
+// decompress detects the compression of an archive from its first bytes
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case hasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case hasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+
+	return io.NopCloser(br), nil
+}
+
+func isCompressed(magic []byte) bool {
+	return hasPrefix(magic, gzipMagic) || hasPrefix(magic, zstdMagic)
+}
+
+func hasPrefix(b, prefix []byte) bool {
+	return len(b) >= len(prefix) && string(b[:len(prefix)]) == string(prefix)
+}
+
+func readIndex(tr *tar.Reader) (*Index, error) {
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != IndexName {
+		return nil, ErrNotBundle
+	}
+
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{}
+	if err = json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("invalid bundle index: %w", err)
+	}
+	if index.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported bundle index version %d", index.Version)
+	}
+	for _, e := range index.Entries {
+		if !fs.ValidPath(e.Name) {
+			return nil, fmt.Errorf("invalid file name %q in bundle index", e.Name)
+		}
+	}
+
+	return index, nil
+}
@@ -0,0 +1,165 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package bundle
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeExport(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"metadata.json":                       `{"last_run_times":{}}`,
+		"my_org/my_space/my_app_manifest.yml": "applications:\n- name: my_app\n",
+		"my_org/my_space/my_app.tgz":          strings.Repeat("droplet", 1000),
+		"my_org/my_space/my_app.zip":          "",
+		"my_org/other_space/" + strings.Repeat("a", 120) + "_manifest.yml": "applications:\n- name: long\n",
+		"other_org/my_space/my_app_autoscale_rules.json":                   "[]",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0640))
+	}
+	return dir, files
+}
+
+func TestPackOpen(t *testing.T) {
+	for _, compression := range Compressions {
+		t.Run(compression, func(t *testing.T) {
+			dir, files := writeExport(t)
+			archive := filepath.Join(t.TempDir(), "export.tar")
+
+			out, err := os.Create(archive)
+			require.NoError(t, err)
+			count, err := Pack(dir, out, compression)
+			require.NoError(t, err)
+			require.NoError(t, out.Close())
+			assert.Equal(t, len(files), count)
+
+			a, err := Open(archive)
+			require.NoError(t, err)
+			assert.Equal(t, compression != CompressionNone, a.compressed)
+			assert.Len(t, a.Entries(), len(files))
+
+			var names []string
+			for name, content := range files {
+				names = append(names, name)
+				data, err := fs.ReadFile(a, name)
+				require.NoError(t, err)
+				assert.Equal(t, content, string(data), name)
+			}
+			require.NoError(t, fstest.TestFS(a, names...))
+
+			matches, err := fs.Glob(a, "my_org/my_space/*_manifest.yml")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"my_org/my_space/my_app_manifest.yml"}, matches)
+
+			_, err = a.Open("my_org/my_space/my_app_autoscale_rules.json")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+
+			info, err := fs.Stat(a, "my_org/my_space/my_app.tgz")
+			require.NoError(t, err)
+			assert.Equal(t, int64(7000), info.Size())
+			assert.Equal(t, fs.FileMode(0640), info.Mode())
+		})
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	dir, files := writeExport(t)
+	buf := &bytes.Buffer{}
+	_, err := Pack(dir, buf, CompressionZstd)
+	require.NoError(t, err)
+
+	target := t.TempDir()
+	count, err := Unpack(buf, target)
+	require.NoError(t, err)
+	assert.Equal(t, len(files), count)
+
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data), name)
+	}
+	_, err = os.Stat(filepath.Join(target, IndexName))
+	assert.True(t, os.IsNotExist(err), "the index is not extracted")
+}
+
+func TestOpen_NotBundle(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "plain.tar")
+	require.NoError(t, os.WriteFile(archive, bytes.Repeat([]byte{0}, 1024), 0644))
+
+	_, err := Open(archive)
+	assert.ErrorIs(t, err, ErrNotBundle)
+
+	_, err = Unpack(bytes.NewReader(nil), t.TempDir())
+	assert.ErrorIs(t, err, ErrNotBundle)
+}
+
+func TestCompressionFor(t *testing.T) {
+	tests := map[string]string{
+		"export.tar":     CompressionNone,
+		"export.tar.gz":  CompressionGzip,
+		"export.tgz":     CompressionGzip,
+		"export.tar.zst": CompressionZstd,
+		"export.tzst":    CompressionZstd,
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, CompressionFor(name), name)
+	}
+}
+
+func TestArchive_OpenReadsConcurrently(t *testing.T) {
+	dir, files := writeExport(t)
+	archive := filepath.Join(t.TempDir(), "export.tar")
+	out, err := os.Create(archive)
+	require.NoError(t, err)
+	_, err = Pack(dir, out, CompressionNone)
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	a, err := Open(archive)
+	require.NoError(t, err)
+
+	first, err := a.Open("my_org/my_space/my_app.tgz")
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := a.Open("my_org/my_space/my_app_manifest.yml")
+	require.NoError(t, err)
+	defer second.Close()
+
+	head := make([]byte, 7)
+	_, err = io.ReadFull(first, head)
+	require.NoError(t, err)
+	data, err := io.ReadAll(second)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(first)
+	require.NoError(t, err)
+
+	assert.Equal(t, files["my_org/my_space/my_app_manifest.yml"], string(data))
+	assert.Equal(t, files["my_org/my_space/my_app.tgz"], string(head)+string(rest))
+}
@@ -0,0 +1,279 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+// Archive gives read access to the files of an archive written by Pack, it implements fs.FS.
+// Every file opened reads the archive independently, so files can be read concurrently.
+type Archive struct {
+	path       string
+	compressed bool
+	dataStart  int64
+	files      map[string]Entry
+	dirs       map[string][]fs.DirEntry
+}
+
+// Open reads the index of the archive at path
+func Open(path string) (*Archive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	magic, err := bufio.NewReader(file).Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	a := &Archive{
+		path:       path,
+		compressed: isCompressed(magic),
+		files:      make(map[string]Entry),
+		dirs:       map[string][]fs.DirEntry{".": nil},
+	}
+
+	dr, err := decompress(file)
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+
+	cr := &countingReader{r: dr}
+	index, err := readIndex(tar.NewReader(cr))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	a.dataStart = padded(cr.n)
+
+	for _, e := range index.Entries {
+		a.files[e.Name] = e
+		a.addToDir(e.Name, fs.FileInfoToDirEntry(fileInfo{entry: e}))
+	}
+	for _, entries := range a.dirs {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+
+	return a, nil
+}
+
+// Path returns the path of the archive
+func (a *Archive) Path() string {
+	return a.path
+}
+
+// Entries returns the names of the files in the archive
+func (a *Archive) Entries() []string {
+	var names []string
+	for name := range a.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *Archive) addToDir(name string, entry fs.DirEntry) {
+	dir := path.Dir(name)
+	_, seen := a.dirs[dir]
+	a.dirs[dir] = append(a.dirs[dir], entry)
+	if !seen && dir != "." {
+		a.addToDir(dir, fs.FileInfoToDirEntry(fileInfo{entry: Entry{Name: dir, Mode: fs.ModeDir | 0755}}))
+	}
+}
+
+// Open opens the named file or directory, file contents are read from the archive
+func (a *Archive) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if e, ok := a.files[name]; ok {
+		r, closer, err := a.openEntry(e)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &file{Reader: r, closer: closer, entry: e}, nil
+	}
+
+	if entries, ok := a.dirs[name]; ok {
+		return &dir{entry: Entry{Name: name, Mode: fs.ModeDir | 0755}, entries: entries}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns the entries of the named directory sorted by name
+func (a *Archive) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := a.dirs[name]
+	if !ok {
+		if _, isFile := a.files[name]; isFile {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry{}, entries...), nil
+}
+
+// Stat returns the file info of the named file or directory without reading the archive
+func (a *Archive) Stat(name string) (fs.FileInfo, error) {
+	if e, ok := a.files[name]; ok {
+		return fileInfo{entry: e}, nil
+	}
+	if _, ok := a.dirs[name]; ok {
+		return fileInfo{entry: Entry{Name: name, Mode: fs.ModeDir | 0755}}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// openEntry seeks straight to the entry in an uncompressed archive, a compressed one is read from the start
+func (a *Archive) openEntry(e Entry) (io.Reader, io.Closer, error) {
+	file, err := os.Open(a.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !a.compressed {
+		tr := tar.NewReader(io.NewSectionReader(file, a.dataStart+e.Offset, 1<<62))
+		if err = nextEntry(tr, e.Name); err != nil {
+			_ = file.Close()
+			return nil, nil, err
+		}
+		return tr, file, nil
+	}
+
+	dr, err := decompress(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	closer := multiCloser{dr, file}
+	tr := tar.NewReader(dr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			_ = closer.Close()
+			if err == io.EOF {
+				return nil, nil, fmt.Errorf("%s is in the index but not in the archive", e.Name)
+			}
+			return nil, nil, err
+		}
+		if hdr.Name == e.Name {
+			return tr, closer, nil
+		}
+	}
+}
+
+func nextEntry(tr *tar.Reader, name string) error {
+	hdr, err := tr.Next()
+	if err != nil {
+		return err
+	}
+	if hdr.Name != name {
+		return fmt.Errorf("expected %s at its offset in the archive, found %s", name, hdr.Name)
+	}
+	return nil
+}
+
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var err error
+	for _, c := range m {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+type fileInfo struct {
+	entry Entry
+}
+
+func (fi fileInfo) Name() string       { return path.Base(fi.entry.Name) }
+func (fi fileInfo) Size() int64        { return fi.entry.Size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.entry.Mode }
+func (fi fileInfo) ModTime() time.Time { return fi.entry.ModTime }
+func (fi fileInfo) IsDir() bool        { return fi.entry.Mode.IsDir() }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+type file struct {
+	io.Reader
+	closer io.Closer
+	entry  Entry
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return fileInfo{entry: f.entry}, nil
+}
+
+func (f *file) Close() error {
+	return f.closer.Close()
+}
+
+type dir struct {
+	entry   Entry
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return fileInfo{entry: d.entry}, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.Name, Err: fmt.Errorf("is a directory")}
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return append([]fs.DirEntry{}, rest...), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return append([]fs.DirEntry{}, rest[:n]...), nil
+}
+
+var _ interface {
+	fs.ReadDirFS
+	fs.StatFS
+} = (*Archive)(nil)
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
@@ -44,37 +45,77 @@ type cache struct {
 
 	domainNameCache map[string]string // domain name -> guid
 
-	cf    cf.Client
-	mutex sync.RWMutex
+	cf     cf.Client
+	key    uintptr
+	others map[uintptr]*cache // caches of the other clients, see GetCache
+	mutex  sync.RWMutex
 }
 
+// GetCache returns the cache of the foundation the client talks to. The first client to ask gets Cache,
+// any other client, such as the target client of a migration, gets a cache of its own.
 func GetCache(cf cf.Client) *cache {
 	if Cache == nil {
 		if cf == nil {
 			log.Fatal("cf client is nil")
 		}
-		Cache = &cache{
-			cf: cf,
-		}
+		Cache = newCache(cf)
+	}
 
-		Cache.orgCache = make(map[string]cfclient.Org)
-		Cache.spaceCache = make(map[string]cfclient.Space)
-		Cache.appCache = make(map[string]cfclient.App)
+	key, ok := clientKey(cf)
+	if !ok || key == Cache.key {
+		return Cache
+	}
 
-		Cache.orgNameCache = make(map[string]string)
-		Cache.spaceNameCache = make(map[string]map[string]string)
-		Cache.appNameCache = make(map[string]map[string]string)
+	Cache.mutex.Lock()
+	defer Cache.mutex.Unlock()
 
-		Cache.spaceOrgGUIDCache = make(map[string]string)
-		Cache.appSpaceGUIDCache = make(map[string]string)
+	if Cache.others == nil {
+		Cache.others = make(map[uintptr]*cache)
+	}
+	c, ok := Cache.others[key]
+	if !ok {
+		c = newCache(cf)
+		Cache.others[key] = c
+	}
 
-		Cache.stackNameCache = make(map[string]string)
-		Cache.stackGUIDCache = make(map[string]string)
+	return c
+}
 
-		Cache.domainNameCache = make(map[string]string)
+func newCache(cf cf.Client) *cache {
+	c := &cache{
+		cf: cf,
 	}
+	c.key, _ = clientKey(cf)
+
+	c.orgCache = make(map[string]cfclient.Org)
+	c.spaceCache = make(map[string]cfclient.Space)
+	c.appCache = make(map[string]cfclient.App)
 
-	return Cache
+	c.orgNameCache = make(map[string]string)
+	c.spaceNameCache = make(map[string]map[string]string)
+	c.appNameCache = make(map[string]map[string]string)
+
+	c.spaceOrgGUIDCache = make(map[string]string)
+	c.appSpaceGUIDCache = make(map[string]string)
+
+	c.stackNameCache = make(map[string]string)
+	c.stackGUIDCache = make(map[string]string)
+
+	c.domainNameCache = make(map[string]string)
+
+	return c
+}
+
+// clientKey identifies clients held by pointer, other clients cannot be told apart and share Cache
+func clientKey(cf cf.Client) (uintptr, bool) {
+	if cf == nil {
+		return 0, false
+	}
+	v := reflect.ValueOf(cf)
+	if v.Kind() != reflect.Ptr {
+		return 0, false
+	}
+	return v.Pointer(), true
 }
 
 func (c *cache) GetOrgByName(name string) (cfclient.Org, error) {
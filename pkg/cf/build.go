@@ -0,0 +1,61 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// CreateBuild starts staging the package with the lifecycle of its app
+func CreateBuild(c Client, packageGUID string) (cfclient.V3Build, error) {
+	var build cfclient.V3Build
+	data, err := json.Marshal(map[string]interface{}{
+		"package": cfclient.V3Relationship{GUID: packageGUID},
+	})
+	if err != nil {
+		return build, err
+	}
+
+	req := c.NewRequestWithBody(http.MethodPost, "/v3/builds", bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return build, fmt.Errorf("error staging package %s: %w", packageGUID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return build, fmt.Errorf("error staging package %s, response code: %d", packageGUID, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&build)
+	return build, err
+}
+
+// GetBuild returns the build with the given guid
+func GetBuild(c Client, buildGUID string) (cfclient.V3Build, error) {
+	var build cfclient.V3Build
+	body, err := c.Get(fmt.Sprintf("/v3/builds/%s", buildGUID))
+	if err != nil {
+		return build, err
+	}
+
+	err = json.Unmarshal(body, &build)
+	return build, err
+}
@@ -0,0 +1,112 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BuildpackReady is the state of the buildpacks that can be used for staging
+const BuildpackReady = "READY"
+
+// V3Buildpack is a buildpack as listed by the v3 api. Stack is empty for buildpacks that run on any stack.
+type V3Buildpack struct {
+	GUID     string `json:"guid"`
+	Name     string `json:"name"`
+	Stack    string `json:"stack"`
+	State    string `json:"state"`
+	Filename string `json:"filename"`
+	Position int    `json:"position"`
+	Enabled  bool   `json:"enabled"`
+	Locked   bool   `json:"locked"`
+}
+
+// BuildpackRequest holds the settings of a buildpack to create or update. Stack can only be set on creation.
+type BuildpackRequest struct {
+	Name     string `json:"name,omitempty"`
+	Stack    string `json:"stack,omitempty"`
+	Position int    `json:"position,omitempty"`
+	Enabled  bool   `json:"enabled"`
+	Locked   bool   `json:"locked"`
+}
+
+// ListBuildpacks returns every buildpack of the foundation, in the order they are tried when detecting
+func ListBuildpacks(c Client) ([]V3Buildpack, error) {
+	var buildpacks []V3Buildpack
+	err := listAll(c, "/v3/buildpacks?order_by=position", func(resources json.RawMessage) error {
+		var page []V3Buildpack
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		buildpacks = append(buildpacks, page...)
+		return nil
+	})
+
+	return buildpacks, err
+}
+
+// GetBuildpack returns the buildpack with the given guid
+func GetBuildpack(c Client, guid string) (V3Buildpack, error) {
+	var buildpack V3Buildpack
+	body, err := c.Get(fmt.Sprintf("/v3/buildpacks/%s", guid))
+	if err != nil {
+		return buildpack, err
+	}
+
+	err = json.Unmarshal(body, &buildpack)
+	return buildpack, err
+}
+
+// CreateBuildpack creates a buildpack without bits, to upload them to with StreamBuildpackBits
+func CreateBuildpack(c Client, r BuildpackRequest) (V3Buildpack, error) {
+	return sendBuildpack(c, http.MethodPost, "/v3/buildpacks", r.Name, r, http.StatusCreated)
+}
+
+// UpdateBuildpack changes the settings of the buildpack with the given guid
+func UpdateBuildpack(c Client, guid string, r BuildpackRequest) (V3Buildpack, error) {
+	return sendBuildpack(c, http.MethodPatch, fmt.Sprintf("/v3/buildpacks/%s", guid), guid, r, http.StatusOK)
+}
+
+func sendBuildpack(c Client, method, path, id string, r BuildpackRequest, status int) (V3Buildpack, error) {
+	var buildpack V3Buildpack
+	data, err := json.Marshal(r)
+	if err != nil {
+		return buildpack, err
+	}
+
+	req := c.NewRequestWithBody(method, path, bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return buildpack, fmt.Errorf("error saving buildpack %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return buildpack, fmt.Errorf("error saving buildpack %s, response code: %d", id, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&buildpack)
+	return buildpack, err
+}
+
+// DownloadBuildpack starts downloading the bits of the buildpack with the given guid, see Download.
+// The v3 api has no endpoint to download buildpacks, so the v2 one is used.
+func DownloadBuildpack(c Client, guid string) (io.ReadCloser, int64, error) {
+	return Download(c, fmt.Sprintf("/v2/buildpacks/%s/download", guid))
+}
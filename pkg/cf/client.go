@@ -52,6 +52,8 @@ type APIClient interface {
 
 	DeleteApp(guid string) error
 	DeleteOrg(guid string, recursive, async bool) error
+	DeleteRoute(guid string) error
+	DeleteServiceBinding(guid string) error
 
 	DoRequest(req *cfclient.Request) (*http.Response, error)
 	Do(req *http.Request) (*http.Response, error)
@@ -162,6 +164,14 @@ func (c *client) DeleteOrg(guid string, recursive, async bool) error {
 	return c.lazyLoadCacheClientOrDie().DeleteOrg(guid, recursive, async)
 }
 
+func (c *client) DeleteRoute(guid string) error {
+	return c.lazyLoadCacheClientOrDie().DeleteRoute(guid)
+}
+
+func (c *client) DeleteServiceBinding(guid string) error {
+	return c.lazyLoadCacheClientOrDie().DeleteServiceBinding(guid)
+}
+
 func (c *client) DoRequest(req *cfclient.Request) (*http.Response, error) {
 	return c.lazyLoadCacheClientOrDie().DoRequest(req)
 }
@@ -0,0 +1,122 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// DomainRelationships holds the org owning a private domain, and the orgs it is shared with. Shared domains are
+// owned by no org.
+type DomainRelationships struct {
+	Organization        *cfclient.V3ToOneRelationship   `json:"organization,omitempty"`
+	SharedOrganizations *cfclient.V3ToManyRelationships `json:"shared_organizations,omitempty"`
+}
+
+// DomainRouterGroup is the router group of a tcp domain
+type DomainRouterGroup struct {
+	GUID string `json:"guid"`
+}
+
+// Domain is a private or shared domain as returned by the v3 api
+type Domain struct {
+	GUID          string              `json:"guid,omitempty"`
+	Name          string              `json:"name"`
+	Internal      bool                `json:"internal"`
+	RouterGroup   *DomainRouterGroup  `json:"router_group,omitempty"`
+	Relationships DomainRelationships `json:"relationships"`
+}
+
+// IsPrivate tells whether the domain is owned by an org
+func (d Domain) IsPrivate() bool {
+	return d.Relationships.Organization != nil && d.Relationships.Organization.Data.GUID != ""
+}
+
+// ListDomains returns every domain of the foundation the user can see
+func ListDomains(c Client) ([]Domain, error) {
+	return listDomains(c, "/v3/domains")
+}
+
+// ListOrgDomains returns the domains the org can use: the domains it owns, the private domains shared with it, and
+// the shared domains
+func ListOrgDomains(c Client, orgGUID string) ([]Domain, error) {
+	return listDomains(c, fmt.Sprintf("/v3/organizations/%s/domains", orgGUID))
+}
+
+// GetDomainByName returns the domain with the given name, or nil when there is none
+func GetDomainByName(c Client, name string) (*Domain, error) {
+	domains, err := listDomains(c, "/v3/domains?"+url.Values{"names": []string{name}}.Encode())
+	if err != nil || len(domains) == 0 {
+		return nil, err
+	}
+	return &domains[0], nil
+}
+
+// CreateDomain creates a private domain owned by the org its relationships name, or a shared domain when they name
+// none
+func CreateDomain(c Client, d Domain) (Domain, error) {
+	var created Domain
+	d.GUID = ""
+	err := sendDomain(c, http.MethodPost, "/v3/domains", d.Name, d, http.StatusCreated, &created)
+	return created, err
+}
+
+// ShareDomain shares the private domain with the given guid with orgs
+func ShareDomain(c Client, guid string, orgGUIDs []string) error {
+	return sendDomain(c, http.MethodPost, fmt.Sprintf("/v3/domains/%s/relationships/shared_organizations", guid), guid, toMany(orgGUIDs), http.StatusOK, nil)
+}
+
+func listDomains(c Client, path string) ([]Domain, error) {
+	var domains []Domain
+	err := listAll(c, path, func(resources json.RawMessage) error {
+		var page []Domain
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		domains = append(domains, page...)
+		return nil
+	})
+
+	return domains, err
+}
+
+func sendDomain(c Client, method, path, id string, body interface{}, status int, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequestWithBody(method, path, bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("error saving domain %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("error saving domain %s, response code: %d", id, resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
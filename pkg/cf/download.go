@@ -0,0 +1,69 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Download starts a download from the api, following the redirect to the blobstore if there is one, and retrying
+// until the response begins. It returns the body of the response and its length, -1 when it is not known.
+func Download(c Client, url string) (io.ReadCloser, int64, error) {
+	var httpResp *http.Response
+	err := c.DoWithRetry(func() error {
+		var err error
+		req := c.NewRequest(http.MethodGet, url)
+		httpResp, err = c.DoRequest(req)
+		if err == nil {
+			if httpResp.StatusCode >= 500 && httpResp.StatusCode <= 599 {
+				defer httpResp.Body.Close()
+				return ErrRetry
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if httpResp.StatusCode == http.StatusFound {
+		httpResp.Body.Close()
+		locationURL := httpResp.Header.Get("location")
+		err = c.DoWithRetry(func() error {
+			var err error
+			httpResp, err = c.HTTPClient().Get(locationURL)
+			if err == nil {
+				if httpResp.StatusCode >= 500 && httpResp.StatusCode <= 599 {
+					defer httpResp.Body.Close()
+					return ErrRetry
+				}
+			}
+			return err
+		})
+
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if httpResp.StatusCode >= 400 {
+		httpResp.Body.Close()
+		return nil, 0, fmt.Errorf("error downloading %s, response code: %d", url, httpResp.StatusCode)
+	}
+
+	return httpResp.Body, httpResp.ContentLength, nil
+}
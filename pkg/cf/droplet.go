@@ -0,0 +1,86 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// CreateDroplet creates an empty droplet for the app with the given process types, to upload bits to with
+// StreamDropletBits. Unlike droplets uploaded with UploadDropletBits, it keeps the process types of the original one.
+func CreateDroplet(c Client, appGUID string, processTypes map[string]string) (cfclient.V3Droplet, error) {
+	var droplet cfclient.V3Droplet
+	data, err := json.Marshal(map[string]interface{}{
+		"relationships": map[string]cfclient.V3ToOneRelationship{
+			"app": {Data: cfclient.V3Relationship{GUID: appGUID}},
+		},
+		"process_types": processTypes,
+	})
+	if err != nil {
+		return droplet, err
+	}
+
+	req := c.NewRequestWithBody(http.MethodPost, "/v3/droplets", bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return droplet, fmt.Errorf("error creating droplet for app %s: %w", appGUID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return droplet, fmt.Errorf("error creating droplet for app %s, response code: %d", appGUID, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&droplet)
+	return droplet, err
+}
+
+// GetDroplet returns the droplet with the given guid
+func GetDroplet(c Client, dropletGUID string) (cfclient.V3Droplet, error) {
+	var droplet cfclient.V3Droplet
+	body, err := c.Get(fmt.Sprintf("/v3/droplets/%s", dropletGUID))
+	if err != nil {
+		return droplet, err
+	}
+
+	err = json.Unmarshal(body, &droplet)
+	return droplet, err
+}
+
+// SetCurrentDroplet makes the droplet the one the app runs with
+func SetCurrentDroplet(c Client, appGUID, dropletGUID string) error {
+	data, err := json.Marshal(cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: dropletGUID}})
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequestWithBody(http.MethodPatch, fmt.Sprintf("/v3/apps/%s/relationships/current_droplet", appGUID), bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("error setting the current droplet of app %s: %w", appGUID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error setting the current droplet of app %s, response code: %d", appGUID, resp.StatusCode)
+	}
+
+	return nil
+}
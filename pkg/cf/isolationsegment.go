@@ -0,0 +1,140 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// IsolationSegment is an isolation segment as returned by the v3 api
+type IsolationSegment struct {
+	GUID string `json:"guid"`
+	Name string `json:"name"`
+}
+
+// toOne is the body of a to-one relationship, its data is null when the relationship is unset
+type toOne struct {
+	Data *cfclient.V3Relationship `json:"data"`
+}
+
+// ListOrgIsolationSegments returns the isolation segments the org is entitled to
+func ListOrgIsolationSegments(c Client, orgGUID string) ([]IsolationSegment, error) {
+	return listIsolationSegments(c, url.Values{"organization_guids": []string{orgGUID}})
+}
+
+// GetIsolationSegmentByName returns the isolation segment with the given name, or nil when there is none
+func GetIsolationSegmentByName(c Client, name string) (*IsolationSegment, error) {
+	segments, err := listIsolationSegments(c, url.Values{"names": []string{name}})
+	if err != nil || len(segments) == 0 {
+		return nil, err
+	}
+	return &segments[0], nil
+}
+
+// GetIsolationSegment returns the isolation segment with the given guid
+func GetIsolationSegment(c Client, guid string) (IsolationSegment, error) {
+	var segment IsolationSegment
+	body, err := c.Get(fmt.Sprintf("/v3/isolation_segments/%s", guid))
+	if err != nil {
+		return segment, err
+	}
+
+	err = json.Unmarshal(body, &segment)
+	return segment, err
+}
+
+// GetOrgDefaultIsolationSegment returns the guid of the isolation segment the apps of the org run on by default, or
+// an empty guid when they run on the shared segment
+func GetOrgDefaultIsolationSegment(c Client, orgGUID string) (string, error) {
+	return getToOne(c, fmt.Sprintf("/v3/organizations/%s/relationships/default_isolation_segment", orgGUID))
+}
+
+// GetSpaceIsolationSegment returns the guid of the isolation segment assigned to the space, or an empty guid when
+// its apps run on the default segment of its org
+func GetSpaceIsolationSegment(c Client, spaceGUID string) (string, error) {
+	return getToOne(c, fmt.Sprintf("/v3/spaces/%s/relationships/isolation_segment", spaceGUID))
+}
+
+// EntitleIsolationSegment entitles the org to the isolation segment with the given guid
+func EntitleIsolationSegment(c Client, guid, orgGUID string) error {
+	return sendIsolationSegment(c, http.MethodPost, fmt.Sprintf("/v3/isolation_segments/%s/relationships/organizations", guid), guid, toMany([]string{orgGUID}))
+}
+
+// SetOrgDefaultIsolationSegment makes the apps of the org run on the isolation segment by default, the org must be
+// entitled to it
+func SetOrgDefaultIsolationSegment(c Client, orgGUID, guid string) error {
+	body := toOne{Data: &cfclient.V3Relationship{GUID: guid}}
+	return sendIsolationSegment(c, http.MethodPatch, fmt.Sprintf("/v3/organizations/%s/relationships/default_isolation_segment", orgGUID), guid, body)
+}
+
+// SetSpaceIsolationSegment assigns the isolation segment to the space, its org must be entitled to it. Apps that are
+// already running keep running on their segment until they are restarted.
+func SetSpaceIsolationSegment(c Client, spaceGUID, guid string) error {
+	body := toOne{Data: &cfclient.V3Relationship{GUID: guid}}
+	return sendIsolationSegment(c, http.MethodPatch, fmt.Sprintf("/v3/spaces/%s/relationships/isolation_segment", spaceGUID), guid, body)
+}
+
+func listIsolationSegments(c Client, query url.Values) ([]IsolationSegment, error) {
+	var segments []IsolationSegment
+	err := listAll(c, "/v3/isolation_segments?"+query.Encode(), func(resources json.RawMessage) error {
+		var page []IsolationSegment
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		segments = append(segments, page...)
+		return nil
+	})
+
+	return segments, err
+}
+
+func getToOne(c Client, path string) (string, error) {
+	body, err := c.Get(path)
+	if err != nil {
+		return "", err
+	}
+
+	var r toOne
+	if err = json.Unmarshal(body, &r); err != nil || r.Data == nil {
+		return "", err
+	}
+	return r.Data.GUID, nil
+}
+
+func sendIsolationSegment(c Client, method, path, id string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequestWithBody(method, path, bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("error saving isolation segment %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error saving isolation segment %s, response code: %d", id, resp.StatusCode)
+	}
+
+	return nil
+}
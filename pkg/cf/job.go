@@ -0,0 +1,64 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+const (
+	// JobComplete is the state of a job that succeeded
+	JobComplete = "COMPLETE"
+	// JobFailed is the state of a job that failed
+	JobFailed = "FAILED"
+)
+
+// Job is an asynchronous operation of the cloud controller, e.g. the deletion of a service instance by its broker
+type Job struct {
+	GUID      string `json:"guid"`
+	Operation string `json:"operation"`
+	State     string `json:"state"`
+	Errors    []struct {
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// Error is the detail of the errors of a failed job
+func (j Job) Error() string {
+	var details []string
+	for _, e := range j.Errors {
+		details = append(details, e.Detail)
+	}
+	return strings.Join(details, ", ")
+}
+
+// GetJob returns the job at location, the url the cloud controller returns in the Location header of a 202 Accepted
+func GetJob(c Client, location string) (Job, error) {
+	var job Job
+	u, err := url.Parse(location)
+	if err != nil {
+		return job, err
+	}
+
+	body, err := c.Get(u.RequestURI())
+	if err != nil {
+		return job, err
+	}
+
+	err = json.Unmarshal(body, &job)
+	return job, err
+}
@@ -0,0 +1,69 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// listAll gets every page of a v3 list, passing the resources of each page to add
+func listAll(c Client, path string, add func(resources json.RawMessage) error) error {
+	for path != "" {
+		body, err := c.Get(path)
+		if err != nil {
+			return err
+		}
+
+		var page struct {
+			Pagination cfclient.Pagination `json:"pagination"`
+			Resources  json.RawMessage     `json:"resources"`
+		}
+		if err = json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		if err = add(page.Resources); err != nil {
+			return err
+		}
+
+		path = ""
+		if next := page.Pagination.Next.Href; next != "" {
+			u, err := url.Parse(next)
+			if err != nil {
+				return err
+			}
+			path = u.RequestURI()
+		}
+	}
+
+	return nil
+}
+
+// ListStacks returns every stack of the foundation
+func ListStacks(c Client) ([]cfclient.V3Stack, error) {
+	var stacks []cfclient.V3Stack
+	err := listAll(c, "/v3/stacks", func(resources json.RawMessage) error {
+		var page []cfclient.V3Stack
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		stacks = append(stacks, page...)
+		return nil
+	})
+
+	return stacks, err
+}
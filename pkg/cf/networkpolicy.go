@@ -0,0 +1,110 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// policiesPath is the path of the external api of the policy server, which is served on the api domain
+const policiesPath = "/networking/v1/external/policies"
+
+// appsPerPolicyQuery is the number of app guids asked for at once, to keep the urls short
+const appsPerPolicyQuery = 50
+
+// PolicyPorts is the range of ports a network policy opens on its destination app
+type PolicyPorts struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// PolicySource is the app a network policy lets connect to its destination
+type PolicySource struct {
+	ID string `json:"id"`
+}
+
+// PolicyDestination is the app a network policy lets its source connect to, on the protocol and ports
+type PolicyDestination struct {
+	ID       string      `json:"id"`
+	Protocol string      `json:"protocol"`
+	Ports    PolicyPorts `json:"ports"`
+}
+
+// NetworkPolicy lets the source app connect to the destination app over the container network
+type NetworkPolicy struct {
+	Source      PolicySource      `json:"source"`
+	Destination PolicyDestination `json:"destination"`
+}
+
+// ListNetworkPolicies returns the network policies the apps with the given guids are the source or destination of
+func ListNetworkPolicies(c Client, appGUIDs []string) ([]NetworkPolicy, error) {
+	var policies []NetworkPolicy
+	seen := make(map[NetworkPolicy]bool)
+	for start := 0; start < len(appGUIDs); start += appsPerPolicyQuery {
+		end := start + appsPerPolicyQuery
+		if end > len(appGUIDs) {
+			end = len(appGUIDs)
+		}
+
+		query := url.Values{"id": []string{strings.Join(appGUIDs[start:end], ",")}}
+		body, err := c.Get(policiesPath + "?" + query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Policies []NetworkPolicy `json:"policies"`
+		}
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+
+		// a policy between apps of different queries is returned by both
+		for _, p := range page.Policies {
+			if !seen[p] {
+				seen[p] = true
+				policies = append(policies, p)
+			}
+		}
+	}
+
+	return policies, nil
+}
+
+// CreateNetworkPolicy creates the network policy, creating a policy that already exists does nothing
+func CreateNetworkPolicy(c Client, p NetworkPolicy) error {
+	data, err := json.Marshal(map[string][]NetworkPolicy{"policies": {p}})
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequestWithBody(http.MethodPost, policiesPath, bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("error creating network policy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error creating network policy, response code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// QuotaApps holds the app limits of a quota, nil limits are unlimited
+type QuotaApps struct {
+	TotalMemoryInMB              *int `json:"total_memory_in_mb"`
+	PerProcessMemoryInMB         *int `json:"per_process_memory_in_mb"`
+	TotalInstances               *int `json:"total_instances"`
+	PerAppTasks                  *int `json:"per_app_tasks"`
+	LogRateLimitInBytesPerSecond *int `json:"log_rate_limit_in_bytes_per_second"`
+}
+
+// QuotaServices holds the service limits of a quota, nil limits are unlimited
+type QuotaServices struct {
+	PaidServicesAllowed   bool `json:"paid_services_allowed"`
+	TotalServiceInstances *int `json:"total_service_instances"`
+	TotalServiceKeys      *int `json:"total_service_keys"`
+}
+
+// QuotaRoutes holds the route limits of a quota, nil limits are unlimited
+type QuotaRoutes struct {
+	TotalRoutes        *int `json:"total_routes"`
+	TotalReservedPorts *int `json:"total_reserved_ports"`
+}
+
+// QuotaDomains holds the domain limits of an organization quota, nil limits are unlimited
+type QuotaDomains struct {
+	TotalDomains *int `json:"total_domains"`
+}
+
+// QuotaRelationships holds the orgs or spaces a quota is applied to, and the org owning a space quota
+type QuotaRelationships struct {
+	Organization  *cfclient.V3ToOneRelationship   `json:"organization,omitempty"`
+	Organizations *cfclient.V3ToManyRelationships `json:"organizations,omitempty"`
+	Spaces        *cfclient.V3ToManyRelationships `json:"spaces,omitempty"`
+}
+
+// Quota is an organization or space quota as returned by the v3 api. Domains is only set on organization quotas.
+type Quota struct {
+	GUID          string              `json:"guid,omitempty"`
+	Name          string              `json:"name"`
+	Apps          QuotaApps           `json:"apps"`
+	Services      QuotaServices       `json:"services"`
+	Routes        QuotaRoutes         `json:"routes"`
+	Domains       *QuotaDomains       `json:"domains,omitempty"`
+	Relationships *QuotaRelationships `json:"relationships,omitempty"`
+}
+
+// ListOrgQuotas returns every organization quota of the foundation
+func ListOrgQuotas(c Client) ([]Quota, error) {
+	return listQuotas(c, "/v3/organization_quotas")
+}
+
+// ListSpaceQuotas returns every space quota of the foundation
+func ListSpaceQuotas(c Client) ([]Quota, error) {
+	return listQuotas(c, "/v3/space_quotas")
+}
+
+// GetOrgQuota returns the organization quota with the given guid
+func GetOrgQuota(c Client, guid string) (Quota, error) {
+	return getQuota(c, fmt.Sprintf("/v3/organization_quotas/%s", guid))
+}
+
+// GetSpaceQuota returns the space quota with the given guid
+func GetSpaceQuota(c Client, guid string) (Quota, error) {
+	return getQuota(c, fmt.Sprintf("/v3/space_quotas/%s", guid))
+}
+
+// CreateOrgQuota creates an organization quota, it can be applied to orgs on creation with its relationships
+func CreateOrgQuota(c Client, q Quota) (Quota, error) {
+	var created Quota
+	err := sendQuota(c, http.MethodPost, "/v3/organization_quotas", q.Name, q, http.StatusCreated, &created)
+	return created, err
+}
+
+// UpdateOrgQuota changes the name and limits of the organization quota with the given guid
+func UpdateOrgQuota(c Client, guid string, q Quota) (Quota, error) {
+	var updated Quota
+	q.GUID, q.Relationships = "", nil
+	err := sendQuota(c, http.MethodPatch, fmt.Sprintf("/v3/organization_quotas/%s", guid), q.Name, q, http.StatusOK, &updated)
+	return updated, err
+}
+
+// CreateSpaceQuota creates a space quota in the org its relationships name
+func CreateSpaceQuota(c Client, q Quota) (Quota, error) {
+	var created Quota
+	err := sendQuota(c, http.MethodPost, "/v3/space_quotas", q.Name, q, http.StatusCreated, &created)
+	return created, err
+}
+
+// UpdateSpaceQuota changes the name and limits of the space quota with the given guid
+func UpdateSpaceQuota(c Client, guid string, q Quota) (Quota, error) {
+	var updated Quota
+	q.GUID, q.Relationships, q.Domains = "", nil, nil
+	err := sendQuota(c, http.MethodPatch, fmt.Sprintf("/v3/space_quotas/%s", guid), q.Name, q, http.StatusOK, &updated)
+	return updated, err
+}
+
+// ApplyOrgQuota assigns the organization quota with the given guid to orgs
+func ApplyOrgQuota(c Client, guid string, orgGUIDs []string) error {
+	return sendQuota(c, http.MethodPost, fmt.Sprintf("/v3/organization_quotas/%s/relationships/organizations", guid), guid, toMany(orgGUIDs), http.StatusOK, nil)
+}
+
+// ApplySpaceQuota assigns the space quota with the given guid to spaces of its org
+func ApplySpaceQuota(c Client, guid string, spaceGUIDs []string) error {
+	return sendQuota(c, http.MethodPost, fmt.Sprintf("/v3/space_quotas/%s/relationships/spaces", guid), guid, toMany(spaceGUIDs), http.StatusOK, nil)
+}
+
+func toMany(guids []string) cfclient.V3ToManyRelationships {
+	var r cfclient.V3ToManyRelationships
+	for _, guid := range guids {
+		r.Data = append(r.Data, cfclient.V3Relationship{GUID: guid})
+	}
+	return r
+}
+
+func listQuotas(c Client, path string) ([]Quota, error) {
+	var quotas []Quota
+	err := listAll(c, path, func(resources json.RawMessage) error {
+		var page []Quota
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		quotas = append(quotas, page...)
+		return nil
+	})
+
+	return quotas, err
+}
+
+func getQuota(c Client, path string) (Quota, error) {
+	var quota Quota
+	body, err := c.Get(path)
+	if err != nil {
+		return quota, err
+	}
+
+	err = json.Unmarshal(body, &quota)
+	return quota, err
+}
+
+func sendQuota(c Client, method, path, id string, body interface{}, status int, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequestWithBody(method, path, bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("error saving quota %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("error saving quota %s, response code: %d", id, resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,208 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// Org and space role types of the v3 api
+const (
+	OrgUser           = "organization_user"
+	OrgAuditor        = "organization_auditor"
+	OrgManager        = "organization_manager"
+	OrgBillingManager = "organization_billing_manager"
+	SpaceAuditor      = "space_auditor"
+	SpaceDeveloper    = "space_developer"
+	SpaceManager      = "space_manager"
+	SpaceSupporter    = "space_supporter"
+)
+
+// usersPerPage is the number of user guids asked for at once, to keep the urls short
+const usersPerPage = 50
+
+// UserRole is a role of a user, who is identified by username and identity provider origin as guids differ between
+// foundations
+type UserRole struct {
+	Type     string `json:"type"`
+	Username string `json:"username"`
+	Origin   string `json:"origin"`
+}
+
+// V3Role is a role as returned by the v3 api
+type V3Role struct {
+	GUID          string            `json:"guid,omitempty"`
+	Type          string            `json:"type"`
+	Relationships RoleRelationships `json:"relationships"`
+}
+
+// RoleRelationships holds the user of a role, and its org or space
+type RoleRelationships struct {
+	User         RoleUser                      `json:"user"`
+	Organization *cfclient.V3ToOneRelationship `json:"organization,omitempty"`
+	Space        *cfclient.V3ToOneRelationship `json:"space,omitempty"`
+}
+
+// RoleUser references the user of a role, by guid in responses and by username and origin in requests
+type RoleUser struct {
+	Data struct {
+		GUID     string `json:"guid,omitempty"`
+		Username string `json:"username,omitempty"`
+		Origin   string `json:"origin,omitempty"`
+	} `json:"data"`
+}
+
+// V3User is a user as returned by the v3 api, username and origin are empty for clients
+type V3User struct {
+	GUID     string `json:"guid"`
+	Username string `json:"username"`
+	Origin   string `json:"origin"`
+}
+
+// ListOrgRoles returns the roles of the users of the org with the given guid, roles of clients are left out
+func ListOrgRoles(c Client, orgGUID string) ([]UserRole, error) {
+	return listUserRoles(c, url.Values{"organization_guids": []string{orgGUID}})
+}
+
+// ListSpaceRoles returns the roles of the users of the space with the given guid, roles of clients are left out
+func ListSpaceRoles(c Client, spaceGUID string) ([]UserRole, error) {
+	return listUserRoles(c, url.Values{"space_guids": []string{spaceGUID}})
+}
+
+// CreateOrgRole gives the user the role in the org with the given guid, and returns the guid of the role
+func CreateOrgRole(c Client, orgGUID string, r UserRole) (string, error) {
+	role := newRole(r)
+	role.Relationships.Organization = &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: orgGUID}}
+	return createRole(c, role)
+}
+
+// CreateSpaceRole gives the user the role in the space with the given guid, the user needs a role in its org. The
+// guid of the role is returned.
+func CreateSpaceRole(c Client, spaceGUID string, r UserRole) (string, error) {
+	role := newRole(r)
+	role.Relationships.Space = &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: spaceGUID}}
+	return createRole(c, role)
+}
+
+// IsUserNotFound tells whether a role could not be created because its user does not exist in the UAA of the
+// foundation
+func IsUserNotFound(err error) bool {
+	var cfErr cfclient.CloudFoundryError
+	return errors.As(err, &cfErr) && strings.Contains(cfErr.Description, "No user exists")
+}
+
+func newRole(r UserRole) V3Role {
+	role := V3Role{Type: r.Type}
+	role.Relationships.User.Data.Username = r.Username
+	role.Relationships.User.Data.Origin = r.Origin
+	return role
+}
+
+func createRole(c Client, role V3Role) (string, error) {
+	data, err := json.Marshal(role)
+	if err != nil {
+		return "", err
+	}
+
+	req := c.NewRequestWithBody(http.MethodPost, "/v3/roles", bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("error creating role %s: %w", role.Type, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("error creating role %s, response code: %d", role.Type, resp.StatusCode)
+	}
+
+	var created V3Role
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	return created.GUID, err
+}
+
+func listUserRoles(c Client, query url.Values) ([]UserRole, error) {
+	var roles []V3Role
+	err := listAll(c, "/v3/roles?"+query.Encode(), func(resources json.RawMessage) error {
+		var page []V3Role
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		roles = append(roles, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var guids []string
+	seen := make(map[string]bool)
+	for _, r := range roles {
+		if guid := r.Relationships.User.Data.GUID; !seen[guid] {
+			seen[guid] = true
+			guids = append(guids, guid)
+		}
+	}
+	users, err := listUsers(c, guids)
+	if err != nil {
+		return nil, err
+	}
+
+	userRoles := make([]UserRole, 0, len(roles))
+	for _, r := range roles {
+		user, ok := users[r.Relationships.User.Data.GUID]
+		if !ok || user.Username == "" {
+			continue
+		}
+		userRoles = append(userRoles, UserRole{Type: r.Type, Username: user.Username, Origin: user.Origin})
+	}
+
+	return userRoles, nil
+}
+
+// listUsers returns the users with the given guids by guid
+func listUsers(c Client, guids []string) (map[string]V3User, error) {
+	users := make(map[string]V3User)
+	for start := 0; start < len(guids); start += usersPerPage {
+		end := start + usersPerPage
+		if end > len(guids) {
+			end = len(guids)
+		}
+
+		query := url.Values{"guids": []string{strings.Join(guids[start:end], ",")}}
+		err := listAll(c, "/v3/users?"+query.Encode(), func(resources json.RawMessage) error {
+			var page []V3User
+			if err := json.Unmarshal(resources, &page); err != nil {
+				return err
+			}
+			for _, u := range page {
+				users[u.GUID] = u
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
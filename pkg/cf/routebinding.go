@@ -0,0 +1,112 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// RouteBindingRelationships holds the route and the route service a route binding is between
+type RouteBindingRelationships struct {
+	Route           cfclient.V3ToOneRelationship `json:"route"`
+	ServiceInstance cfclient.V3ToOneRelationship `json:"service_instance"`
+}
+
+// RouteBinding binds a route to a route service, which then gets the requests for the route before the apps
+type RouteBinding struct {
+	GUID          string                    `json:"guid,omitempty"`
+	Parameters    map[string]interface{}    `json:"parameters,omitempty"`
+	Relationships RouteBindingRelationships `json:"relationships"`
+}
+
+// ListRouteBindings returns the route service bindings of the routes with the given guids, a route is bound to at
+// most one route service
+func ListRouteBindings(c Client, routeGUIDs []string) ([]RouteBinding, error) {
+	query := url.Values{"route_guids": []string{strings.Join(routeGUIDs, ",")}}
+	var bindings []RouteBinding
+	err := listAll(c, "/v3/service_route_bindings?"+query.Encode(), func(resources json.RawMessage) error {
+		var page []RouteBinding
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		bindings = append(bindings, page...)
+		return nil
+	})
+
+	return bindings, err
+}
+
+// GetRouteBindingParameters returns the parameters of the route service binding with the given guid, which only
+// managed service instances whose broker allows fetching them have
+func GetRouteBindingParameters(c Client, guid string) (map[string]interface{}, error) {
+	body, err := c.Get(fmt.Sprintf("/v3/service_route_bindings/%s/parameters", guid))
+	if err != nil {
+		return nil, err
+	}
+
+	var params map[string]interface{}
+	err = json.Unmarshal(body, &params)
+	return params, err
+}
+
+// CreateRouteBinding binds the route to the route service and returns the guid of the binding. The broker of a
+// managed service instance completes the binding asynchronously.
+func CreateRouteBinding(c Client, routeGUID, serviceInstanceGUID string, params map[string]interface{}) (string, error) {
+	binding := RouteBinding{
+		Parameters: params,
+		Relationships: RouteBindingRelationships{
+			Route:           cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: routeGUID}},
+			ServiceInstance: cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: serviceInstanceGUID}},
+		},
+	}
+	data, err := json.Marshal(binding)
+	if err != nil {
+		return "", err
+	}
+
+	req := c.NewRequestWithBody(http.MethodPost, "/v3/service_route_bindings", bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("error binding route %s to service instance %s: %w", routeGUID, serviceInstanceGUID, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		var created RouteBinding
+		err = json.NewDecoder(resp.Body).Decode(&created)
+		return created.GUID, err
+	case http.StatusAccepted:
+		bindings, err := ListRouteBindings(c, []string{routeGUID})
+		if err != nil {
+			return "", err
+		}
+		for _, b := range bindings {
+			if b.Relationships.ServiceInstance.Data.GUID == serviceInstanceGUID {
+				return b.GUID, nil
+			}
+		}
+		return "", fmt.Errorf("error binding route %s to service instance %s, binding not found", routeGUID, serviceInstanceGUID)
+	default:
+		return "", fmt.Errorf("error binding route %s to service instance %s, response code: %d", routeGUID, serviceInstanceGUID, resp.StatusCode)
+	}
+}
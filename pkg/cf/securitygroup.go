@@ -0,0 +1,150 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// SecurityGroupRule lets the apps of the spaces a security group is bound to send traffic to the destination. Ports
+// are only set for tcp and udp, type and code only for icmp.
+type SecurityGroupRule struct {
+	Protocol    string `json:"protocol"`
+	Destination string `json:"destination"`
+	Ports       string `json:"ports,omitempty"`
+	Type        *int   `json:"type,omitempty"`
+	Code        *int   `json:"code,omitempty"`
+	Description string `json:"description,omitempty"`
+	Log         bool   `json:"log,omitempty"`
+}
+
+func (r SecurityGroupRule) String() string {
+	s := r.Protocol + " " + r.Destination
+	if r.Ports != "" {
+		s += " " + r.Ports
+	}
+	if r.Type != nil {
+		s += fmt.Sprintf(" type %d", *r.Type)
+	}
+	if r.Code != nil {
+		s += fmt.Sprintf(" code %d", *r.Code)
+	}
+	if r.Log {
+		s += " log"
+	}
+	return s
+}
+
+// SecurityGroupRelationships holds the spaces a security group is bound to for running apps and for staging them
+type SecurityGroupRelationships struct {
+	RunningSpaces cfclient.V3ToManyRelationships `json:"running_spaces"`
+	StagingSpaces cfclient.V3ToManyRelationships `json:"staging_spaces"`
+}
+
+// SecurityGroup is a security group as returned by the v3 api
+type SecurityGroup struct {
+	GUID          string                     `json:"guid,omitempty"`
+	Name          string                     `json:"name"`
+	Rules         []SecurityGroupRule        `json:"rules"`
+	Relationships SecurityGroupRelationships `json:"relationships"`
+}
+
+// ListRunningSecurityGroups returns the security groups bound to the space for its running apps, groups that are
+// enabled globally are not included
+func ListRunningSecurityGroups(c Client, spaceGUID string) ([]SecurityGroup, error) {
+	return listSecurityGroups(c, url.Values{"running_space_guids": []string{spaceGUID}})
+}
+
+// ListStagingSecurityGroups returns the security groups bound to the space for staging its apps, groups that are
+// enabled globally are not included
+func ListStagingSecurityGroups(c Client, spaceGUID string) ([]SecurityGroup, error) {
+	return listSecurityGroups(c, url.Values{"staging_space_guids": []string{spaceGUID}})
+}
+
+// GetSecurityGroupByName returns the security group with the given name, or nil when there is none
+func GetSecurityGroupByName(c Client, name string) (*SecurityGroup, error) {
+	groups, err := listSecurityGroups(c, url.Values{"names": []string{name}})
+	if err != nil || len(groups) == 0 {
+		return nil, err
+	}
+	return &groups[0], nil
+}
+
+// CreateSecurityGroup creates a security group with the rules, bound to no space
+func CreateSecurityGroup(c Client, name string, rules []SecurityGroupRule) (SecurityGroup, error) {
+	var created SecurityGroup
+	body := map[string]interface{}{"name": name, "rules": rules}
+	err := sendSecurityGroup(c, http.MethodPost, "/v3/security_groups", name, body, http.StatusCreated, &created)
+	return created, err
+}
+
+// UpdateSecurityGroupRules replaces the rules of the security group with the given guid
+func UpdateSecurityGroupRules(c Client, guid string, rules []SecurityGroupRule) error {
+	body := map[string]interface{}{"rules": rules}
+	return sendSecurityGroup(c, http.MethodPatch, fmt.Sprintf("/v3/security_groups/%s", guid), guid, body, http.StatusOK, nil)
+}
+
+// BindRunningSecurityGroup binds the security group with the given guid to the space for its running apps
+func BindRunningSecurityGroup(c Client, guid, spaceGUID string) error {
+	return sendSecurityGroup(c, http.MethodPost, fmt.Sprintf("/v3/security_groups/%s/relationships/running_spaces", guid), guid, toMany([]string{spaceGUID}), http.StatusOK, nil)
+}
+
+// BindStagingSecurityGroup binds the security group with the given guid to the space for staging its apps
+func BindStagingSecurityGroup(c Client, guid, spaceGUID string) error {
+	return sendSecurityGroup(c, http.MethodPost, fmt.Sprintf("/v3/security_groups/%s/relationships/staging_spaces", guid), guid, toMany([]string{spaceGUID}), http.StatusOK, nil)
+}
+
+func listSecurityGroups(c Client, query url.Values) ([]SecurityGroup, error) {
+	var groups []SecurityGroup
+	err := listAll(c, "/v3/security_groups?"+query.Encode(), func(resources json.RawMessage) error {
+		var page []SecurityGroup
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		groups = append(groups, page...)
+		return nil
+	})
+
+	return groups, err
+}
+
+func sendSecurityGroup(c Client, method, path, id string, body interface{}, status int, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req := c.NewRequestWithBody(method, path, bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("error saving security group %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("error saving security group %s, response code: %d", id, resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,147 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// ServiceCredentialBindingRelationships holds the service instance a credential binding is for, and the app for the
+// bindings of type app
+type ServiceCredentialBindingRelationships struct {
+	App             *cfclient.V3ToOneRelationship `json:"app,omitempty"`
+	ServiceInstance cfclient.V3ToOneRelationship  `json:"service_instance"`
+}
+
+// ServiceCredentialBinding is a binding of a service instance to an app, or a service key, as returned by the v3 api
+type ServiceCredentialBinding struct {
+	GUID          string                                `json:"guid,omitempty"`
+	Type          string                                `json:"type"`
+	Name          string                                `json:"name,omitempty"`
+	Parameters    map[string]interface{}                `json:"parameters,omitempty"`
+	LastOperation *LastOperation                        `json:"last_operation,omitempty"`
+	Relationships ServiceCredentialBindingRelationships `json:"relationships"`
+}
+
+// ListAppServiceBindings returns the service bindings of the app
+func ListAppServiceBindings(c Client, appGUID string) ([]ServiceCredentialBinding, error) {
+	return listServiceCredentialBindings(c, url.Values{"type": []string{"app"}, "app_guids": []string{appGUID}})
+}
+
+// ListServiceKeys returns the service keys of the service instance
+func ListServiceKeys(c Client, serviceInstanceGUID string) ([]ServiceCredentialBinding, error) {
+	return listServiceCredentialBindings(c, url.Values{"type": []string{"key"}, "service_instance_guids": []string{serviceInstanceGUID}})
+}
+
+// GetServiceCredentialBinding returns the service credential binding with the given guid
+func GetServiceCredentialBinding(c Client, guid string) (ServiceCredentialBinding, error) {
+	var binding ServiceCredentialBinding
+	body, err := c.Get(fmt.Sprintf("/v3/service_credential_bindings/%s", guid))
+	if err != nil {
+		return binding, err
+	}
+
+	err = json.Unmarshal(body, &binding)
+	return binding, err
+}
+
+// GetServiceCredentialBindingCredentials returns the credentials of the service credential binding with the given guid
+func GetServiceCredentialBindingCredentials(c Client, guid string) (map[string]interface{}, error) {
+	body, err := c.Get(fmt.Sprintf("/v3/service_credential_bindings/%s/details", guid))
+	if err != nil {
+		return nil, err
+	}
+
+	var details struct {
+		Credentials map[string]interface{} `json:"credentials"`
+	}
+	err = json.Unmarshal(body, &details)
+	return details.Credentials, err
+}
+
+// GetServiceCredentialBindingParameters returns the parameters of the service credential binding with the given guid,
+// which only managed service instances whose broker allows fetching them have
+func GetServiceCredentialBindingParameters(c Client, guid string) (map[string]interface{}, error) {
+	body, err := c.Get(fmt.Sprintf("/v3/service_credential_bindings/%s/parameters", guid))
+	if err != nil {
+		return nil, err
+	}
+
+	var params map[string]interface{}
+	err = json.Unmarshal(body, &params)
+	return params, err
+}
+
+// CreateServiceCredentialBinding creates the binding and returns it. The broker of a managed service instance may
+// complete the binding asynchronously, its LastOperation is then still in progress, poll it with
+// GetServiceCredentialBinding until it succeeds or fails.
+func CreateServiceCredentialBinding(c Client, b ServiceCredentialBinding) (ServiceCredentialBinding, error) {
+	var created ServiceCredentialBinding
+	data, err := json.Marshal(b)
+	if err != nil {
+		return created, err
+	}
+
+	siGUID := b.Relationships.ServiceInstance.Data.GUID
+	req := c.NewRequestWithBody(http.MethodPost, "/v3/service_credential_bindings", bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return created, fmt.Errorf("error creating %s binding %s of service instance %s: %w", b.Type, b.Name, siGUID, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		err = json.NewDecoder(resp.Body).Decode(&created)
+		return created, err
+	case http.StatusAccepted:
+		query := url.Values{"type": []string{b.Type}, "service_instance_guids": []string{siGUID}}
+		if b.Relationships.App != nil {
+			query.Set("app_guids", b.Relationships.App.Data.GUID)
+		} else {
+			query.Set("names", b.Name)
+		}
+		bindings, err := listServiceCredentialBindings(c, query)
+		if err != nil {
+			return created, err
+		}
+		if len(bindings) == 0 {
+			return created, fmt.Errorf("error creating %s binding %s of service instance %s, binding not found", b.Type, b.Name, siGUID)
+		}
+		return bindings[0], nil
+	default:
+		return created, fmt.Errorf("error creating %s binding %s of service instance %s, response code: %d", b.Type, b.Name, siGUID, resp.StatusCode)
+	}
+}
+
+func listServiceCredentialBindings(c Client, query url.Values) ([]ServiceCredentialBinding, error) {
+	var bindings []ServiceCredentialBinding
+	err := listAll(c, "/v3/service_credential_bindings?"+query.Encode(), func(resources json.RawMessage) error {
+		var page []ServiceCredentialBinding
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		bindings = append(bindings, page...)
+		return nil
+	})
+
+	return bindings, err
+}
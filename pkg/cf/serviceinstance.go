@@ -0,0 +1,150 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// ServiceInstanceRelationships holds the space of a service instance, and the service plan of a managed one
+type ServiceInstanceRelationships struct {
+	Space       *cfclient.V3ToOneRelationship `json:"space,omitempty"`
+	ServicePlan *cfclient.V3ToOneRelationship `json:"service_plan,omitempty"`
+}
+
+// ServiceInstance is a managed or user-provided service instance as returned by the v3 api
+type ServiceInstance struct {
+	GUID          string                       `json:"guid,omitempty"`
+	Name          string                       `json:"name"`
+	Type          string                       `json:"type"`
+	Tags          []string                     `json:"tags,omitempty"`
+	Parameters    map[string]interface{}       `json:"parameters,omitempty"`
+	LastOperation *LastOperation               `json:"last_operation,omitempty"`
+	Relationships ServiceInstanceRelationships `json:"relationships"`
+}
+
+// LastOperation is the last operation of a broker on a service instance or credential binding, its state is initial,
+// in progress, succeeded or failed
+type LastOperation struct {
+	Type        string `json:"type"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+}
+
+// IsManaged returns whether the service instance was provisioned by a service broker
+func (s ServiceInstance) IsManaged() bool {
+	return s.Type == "managed"
+}
+
+// PlanGUID returns the guid of the service plan of the service instance, user-provided service instances have none
+func (s ServiceInstance) PlanGUID() string {
+	if s.Relationships.ServicePlan == nil {
+		return ""
+	}
+	return s.Relationships.ServicePlan.Data.GUID
+}
+
+// GetServiceInstance returns the service instance with the given guid
+func GetServiceInstance(c Client, guid string) (ServiceInstance, error) {
+	var si ServiceInstance
+	body, err := c.Get(fmt.Sprintf("/v3/service_instances/%s", guid))
+	if err != nil {
+		return si, err
+	}
+
+	err = json.Unmarshal(body, &si)
+	return si, err
+}
+
+// ListSpaceServiceInstances returns the service instances of the space, instances shared with it are not included
+func ListSpaceServiceInstances(c Client, spaceGUID string) ([]ServiceInstance, error) {
+	return listServiceInstances(c, url.Values{"space_guids": []string{spaceGUID}})
+}
+
+// GetServiceInstanceByName returns the service instance with the given name in the space, or nil when there is none
+func GetServiceInstanceByName(c Client, name, spaceGUID string) (*ServiceInstance, error) {
+	instances, err := listServiceInstances(c, url.Values{"names": []string{name}, "space_guids": []string{spaceGUID}})
+	if err != nil || len(instances) == 0 {
+		return nil, err
+	}
+	return &instances[0], nil
+}
+
+// GetServiceInstanceParameters returns the parameters of the managed service instance with the given guid, which only
+// service instances whose broker allows fetching them have
+func GetServiceInstanceParameters(c Client, guid string) (map[string]interface{}, error) {
+	body, err := c.Get(fmt.Sprintf("/v3/service_instances/%s/parameters", guid))
+	if err != nil {
+		return nil, err
+	}
+
+	var params map[string]interface{}
+	err = json.Unmarshal(body, &params)
+	return params, err
+}
+
+// CreateServiceInstance creates the service instance and returns its guid. The broker of a managed service instance
+// provisions it asynchronously, until its last operation succeeds.
+func CreateServiceInstance(c Client, si ServiceInstance) (string, error) {
+	data, err := json.Marshal(si)
+	if err != nil {
+		return "", err
+	}
+
+	req := c.NewRequestWithBody(http.MethodPost, "/v3/service_instances", bytes.NewReader(data))
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("error creating service instance %s: %w", si.Name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		var created ServiceInstance
+		err = json.NewDecoder(resp.Body).Decode(&created)
+		return created.GUID, err
+	case http.StatusAccepted:
+		created, err := GetServiceInstanceByName(c, si.Name, si.Relationships.Space.Data.GUID)
+		if err != nil {
+			return "", err
+		}
+		if created == nil {
+			return "", fmt.Errorf("error creating service instance %s, service instance not found", si.Name)
+		}
+		return created.GUID, nil
+	default:
+		return "", fmt.Errorf("error creating service instance %s, response code: %d", si.Name, resp.StatusCode)
+	}
+}
+
+func listServiceInstances(c Client, query url.Values) ([]ServiceInstance, error) {
+	var instances []ServiceInstance
+	err := listAll(c, "/v3/service_instances?"+query.Encode(), func(resources json.RawMessage) error {
+		var page []ServiceInstance
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		instances = append(instances, page...)
+		return nil
+	})
+
+	return instances, err
+}
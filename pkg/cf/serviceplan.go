@@ -0,0 +1,98 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// ServicePlan is a service plan as returned by the v3 api, with the names of its service offering and broker when
+// they were included
+type ServicePlan struct {
+	GUID      string `json:"guid"`
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Offering  string `json:"-"`
+	Broker    string `json:"-"`
+}
+
+// GetServicePlan returns the service plan with the given guid, with the names of its service offering and broker
+func GetServicePlan(c Client, guid string) (ServicePlan, error) {
+	query := url.Values{
+		"include": []string{"service_offering"},
+		"fields[service_offering.service_broker]": []string{"name"},
+	}
+	body, err := c.Get(fmt.Sprintf("/v3/service_plans/%s?%s", guid, query.Encode()))
+	if err != nil {
+		return ServicePlan{}, err
+	}
+
+	var p struct {
+		ServicePlan
+		Included struct {
+			ServiceOfferings []struct {
+				Name string `json:"name"`
+			} `json:"service_offerings"`
+			ServiceBrokers []struct {
+				Name string `json:"name"`
+			} `json:"service_brokers"`
+		} `json:"included"`
+	}
+	if err = json.Unmarshal(body, &p); err != nil {
+		return ServicePlan{}, err
+	}
+	if len(p.Included.ServiceOfferings) == 0 {
+		return ServicePlan{}, fmt.Errorf("service plan %s has no service offering", guid)
+	}
+
+	plan := p.ServicePlan
+	plan.Offering = p.Included.ServiceOfferings[0].Name
+	if len(p.Included.ServiceBrokers) > 0 {
+		plan.Broker = p.Included.ServiceBrokers[0].Name
+	}
+	return plan, nil
+}
+
+// FindServicePlans returns the service plans with the given name of the service offering, only the ones of the
+// service broker when broker is set, and only the ones visible to the org when orgGUID is set
+func FindServicePlans(c Client, offering, plan, broker, orgGUID string) ([]ServicePlan, error) {
+	query := url.Values{
+		"names":                  []string{plan},
+		"service_offering_names": []string{offering},
+	}
+	if broker != "" {
+		query.Set("service_broker_names", broker)
+	}
+	if orgGUID != "" {
+		query.Set("organization_guids", orgGUID)
+	}
+
+	var plans []ServicePlan
+	err := listAll(c, "/v3/service_plans?"+query.Encode(), func(resources json.RawMessage) error {
+		var page []ServicePlan
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		for i := range page {
+			page[i].Offering = offering
+		}
+		plans = append(plans, page...)
+		return nil
+	})
+
+	return plans, err
+}
@@ -0,0 +1,89 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// StreamAppBits uploads the app bits read from r while they are read, unlike UploadAppBits which spools them to a
+// temporary file first. size is the number of bytes in r, or -1 when it is not known.
+// r can only be read once, so failed uploads are not retried.
+func StreamAppBits(c Client, appGUID string, r io.Reader, size int64) error {
+	return streamUpload(c, http.MethodPut, fmt.Sprintf("/v2/apps/%s/bits", appGUID), map[string]string{"resources": "[]"}, "application", "application.zip", r, size, http.StatusCreated)
+}
+
+// StreamDroplet uploads the droplet read from r while it is read, see StreamAppBits
+func StreamDroplet(c Client, appGUID string, r io.Reader, size int64) error {
+	return streamUpload(c, http.MethodPut, fmt.Sprintf("/v2/apps/%s/droplet/upload", appGUID), nil, "droplet", "droplet.tgz", r, size, http.StatusCreated)
+}
+
+// StreamDropletBits uploads the droplet read from r to a droplet created with CreateDroplet, see StreamAppBits.
+// The droplet is processed asynchronously, it is STAGED once it is ready to run.
+func StreamDropletBits(c Client, dropletGUID string, r io.Reader, size int64) error {
+	return streamUpload(c, http.MethodPost, fmt.Sprintf("/v3/droplets/%s/upload", dropletGUID), nil, "bits", "droplet.tgz", r, size, http.StatusAccepted)
+}
+
+// StreamBuildpackBits uploads the buildpack read from r to a buildpack created with CreateBuildpack, see StreamAppBits.
+// The buildpack is processed asynchronously, it is READY once it can be used for staging.
+func StreamBuildpackBits(c Client, buildpackGUID, fileName string, r io.Reader, size int64) error {
+	return streamUpload(c, http.MethodPost, fmt.Sprintf("/v3/buildpacks/%s/upload", buildpackGUID), nil, "bits", fileName, r, size, http.StatusAccepted)
+}
+
+func streamUpload(c Client, method, path string, fields map[string]string, fieldName, fileName string, r io.Reader, size int64, status int) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	if _, err := writer.CreateFormFile(fieldName, fileName); err != nil {
+		return err
+	}
+	head := append([]byte(nil), buf.Bytes()...)
+
+	buf.Reset()
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	tail := buf.Bytes()
+
+	req, err := http.NewRequest(method, c.Target()+path, io.MultiReader(bytes.NewReader(head), r, bytes.NewReader(tail)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = -1
+	if size >= 0 {
+		req.ContentLength = int64(len(head)) + size + int64(len(tail))
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("error uploading %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("error uploading %s, response code: %d", path, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -15,14 +15,57 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/bundle"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/transform"
 )
 
+// PreRunOpenBundle opens the archive the export is read from when one was given
+func PreRunOpenBundle(ctx *context.Context) error {
+	if ctx.BundleFile == "" {
+		return nil
+	}
+
+	archive, err := bundle.Open(ctx.BundleFile)
+	if err != nil {
+		return err
+	}
+	ctx.Bundle = archive
+
+	return nil
+}
+
+// PreRunOpenStorage sets up the storage of the export dir, a bucket of an S3-compatible object store for s3:// urls
+// and the local file system otherwise
+func PreRunOpenStorage(ctx *context.Context) error {
+	if ctx.DirWriter != nil {
+		return nil
+	}
+
+	if !storage.IsRemote(ctx.ExportDir) {
+		ctx.DirWriter = storage.NewLocal(ctx.ExportDir)
+		return nil
+	}
+
+	s3, err := storage.NewS3(ctx.ExportDir)
+	if err != nil {
+		return err
+	}
+	ctx.DirWriter = s3
+
+	return nil
+}
+
 func PreRunLoadMetadata(ctx *context.Context) error {
 	if err := getLatestRunTimes(ctx); err != nil {
 		return fmt.Errorf("error parsing metadata.json: %w", err)
@@ -34,61 +77,249 @@ func PostRunSaveMetadata(ctx *context.Context) error {
 	return saveLatestRunTime(ctx)
 }
 
-func DisplaySummary(commandCtx *context.Context) {
-	commandCtx.Summary.Display()
+// PreRunCreateJournal starts a new journal for the run, its file is only written once an action is recorded.
+// Journals are kept on the local file system, in the working directory when the export is held in an object store.
+// The settings apps had before they were updated hold their env vars, they are sealed for the recipient or with the key
+// of the export, or with the key of the secrets file.
+func PreRunCreateJournal(ctx *context.Context) error {
+	sealer, err := journalSealer(ctx)
+	if err != nil {
+		return err
+	}
+	ctx.Journal = journal.New(storage.LocalDir(ctx.ExportDir), sealer)
+
+	return nil
 }
 
-func saveLatestRunTime(commandCtx *context.Context) (err error) {
-	metadataFile := filepath.Join(commandCtx.ExportDir, "metadata.json")
-	if err := commandCtx.DirWriter.MkdirAll(commandCtx.ExportDir, 0755); err != nil {
-		log.Fatal(err)
+func journalSealer(ctx *context.Context) (crypt.Sealer, error) {
+	if ctx.Sealer != nil {
+		return ctx.Sealer, nil
+	}
+
+	switch opener := ctx.Opener.(type) {
+	case *crypt.Key:
+		return opener, nil
+	case *crypt.Identity:
+		return opener.Recipient()
 	}
-	var file *os.File
-	file, err = os.OpenFile(metadataFile, os.O_WRONLY, 0600)
-	defer func(file *os.File) {
-		closeErr := file.Close()
+
+	key, err := crypt.NewKey(ctx.SecretsKeyFile)
+	if err != nil || key == nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// PreRunLoadNameMapping loads the org, space and app name mapping file when one was given
+func PreRunLoadNameMapping(ctx *context.Context) error {
+	if ctx.NameMappingFile == "" {
+		return nil
+	}
+
+	names, err := mapping.Load(ctx.NameMappingFile)
+	if err != nil {
+		return err
+	}
+	ctx.NameMapping = names
+
+	return nil
+}
+
+// PreRunLoadEnvTransform loads the env var transformation file and its vars files when one was given
+func PreRunLoadEnvTransform(ctx *context.Context) error {
+	if ctx.EnvTransformFile == "" {
+		return nil
+	}
+
+	env, err := transform.Load(ctx.EnvTransformFile, ctx.VarsFiles)
+	if err != nil {
+		return err
+	}
+	ctx.EnvTransform = env
+
+	return nil
+}
+
+// PreRunLoadSecrets loads the encrypted secrets file from the export dir or bundle. When exporting with secrets externalized a
+// key is required, otherwise the file is only loaded when a key was given, and placeholders are only resolved from the
+// environment with --secrets-from-env.
+func PreRunLoadSecrets(ctx *context.Context) error {
+	key, err := crypt.NewKey(ctx.SecretsKeyFile)
+	if err != nil {
+		return err
+	}
+
+	if ctx.ExternalizeSecrets {
+		if key == nil {
+			return fmt.Errorf("a key file (--secrets-key-file) or a passphrase (%s) is required to externalize secrets", crypt.PassphraseEnv)
+		}
+
+		detector, err := secrets.NewDetector(ctx.SecretKeyPatterns, ctx.SecretMinEntropy)
 		if err != nil {
-			err = closeErr
+			return err
 		}
-	}(file)
 
-	if err != nil {
-		if os.IsNotExist(err) {
-			if _, err = os.Create(metadataFile); err != nil {
-				log.Fatal(err)
-			}
-			return
+		ctx.Secrets, err = secrets.LoadFS(ctx.ExportFS(), secrets.FileName, key, detector)
+		return err
+	}
+
+	if key == nil {
+		if ctx.SecretsFromEnv {
+			ctx.Secrets = secrets.New(nil, nil)
+			ctx.Secrets.LookupEnv = true
 		}
-		log.Fatal(err)
+		return nil
 	}
-	err = commandCtx.Metadata.SaveMetadata(file)
-	return
+
+	ctx.Secrets, err = secrets.LoadFS(ctx.ExportFS(), secrets.FileName, key, nil)
+	if err != nil {
+		return err
+	}
+	ctx.Secrets.LookupEnv = ctx.SecretsFromEnv
+	return nil
 }
 
-func getLatestRunTimes(ctx *context.Context) (err error) {
-	f := filepath.Join(ctx.ExportDir, "metadata.json")
-	if _, err = os.Stat(f); os.IsNotExist(err) {
-		if err = os.MkdirAll(ctx.ExportDir, 0700); err != nil {
-			log.Fatalf("failed to create directory %s, %s", ctx.ExportDir, err)
+// PreRunLoadEncryption sets up the opener used to decrypt the files of an encrypted export, from the identity file,
+// the key file or the passphrase, whichever was given. When encrypting the export, files are sealed for the recipient
+// when one was given, with the key otherwise.
+func PreRunLoadEncryption(ctx *context.Context) error {
+	if ctx.IdentityFile != "" {
+		id, err := crypt.LoadIdentity(ctx.IdentityFile)
+		if err != nil {
+			return err
+		}
+		ctx.Opener = id
+	} else {
+		key, err := crypt.NewKey(ctx.EncryptionKeyFile)
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			ctx.Opener = key
 		}
-		if _, err = os.Create(f); err != nil {
-			log.Fatalf("failed to create file %s, %s", f, err)
+	}
+
+	if ctx.Recipient != "" {
+		recipient, err := crypt.ParseRecipient(ctx.Recipient)
+		if err != nil {
+			return err
 		}
+		ctx.Sealer = recipient
+		return nil
 	}
+
+	if !ctx.EncryptExport {
+		return nil
+	}
+
+	key, ok := ctx.Opener.(*crypt.Key)
+	if !ok {
+		return fmt.Errorf("a recipient (--recipient), a key file (--encryption-key-file) or a passphrase (%s) is required to encrypt the export", crypt.PassphraseEnv)
+	}
+	ctx.Sealer = key
+
+	return nil
+}
+
+// PostRunSaveSecrets writes the externalized secrets to the encrypted secrets file in the export dir
+func PostRunSaveSecrets(ctx *context.Context) error {
+	if !ctx.ExternalizeSecrets || !ctx.Secrets.Changed() {
+		return nil
+	}
+
+	if err := saveSecrets(ctx); err != nil {
+		return fmt.Errorf("failed to save secrets to %s: %w", secrets.FileName, err)
+	}
+
+	if ctx.Secrets.Count() > 0 {
+		fmt.Printf("Stored %d secrets in %s\n", ctx.Secrets.Count(), path.Join(ctx.ExportDir, secrets.FileName))
+	}
+
+	return nil
+}
+
+func saveSecrets(ctx *context.Context) error {
+	data, err := ctx.Secrets.Encrypt()
 	if err != nil {
-		return
+		return err
+	}
+
+	file, err := ctx.DirWriter.Put(secrets.FileName, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = file.Write(data); err != nil {
+		return err
 	}
-	var file *os.File
-	file, err = os.Open(f)
+
+	return file.Close()
+}
+
+// PostRunCloseJournal closes the journal and tells the user how to undo the run if anything was recorded
+func PostRunCloseJournal(ctx *context.Context) error {
+	if ctx.Journal.Count() == 0 {
+		return ctx.Journal.Close()
+	}
+
+	fmt.Printf("Recorded %d changes in %s\nTo undo them run: app-migrator rollback --run %s\n", ctx.Journal.Count(), ctx.Journal.Path(), ctx.Journal.RunID)
+
+	return ctx.Journal.Close()
+}
+
+func DisplaySummary(commandCtx *context.Context) {
+	commandCtx.Summary.Display()
+	commandCtx.Resources.Display()
+	if commandCtx.MissingUsers != nil {
+		commandCtx.MissingUsers.Display()
+	}
+	if commandCtx.SecurityGroupDiffs != nil {
+		commandCtx.SecurityGroupDiffs.Display()
+	}
+	if commandCtx.ServiceKeyChanges != nil {
+		commandCtx.ServiceKeyChanges.Display()
+	}
+	if commandCtx.DataMoves != nil {
+		commandCtx.DataMoves.Display()
+	}
+}
+
+func saveLatestRunTime(ctx *context.Context) error {
+	// the metadata of a bundle is read only
+	if ctx.Bundle != nil {
+		return nil
+	}
+
+	file, err := ctx.DirWriter.Put("metadata.json", 0600)
 	if err != nil {
-		return
+		return err
+	}
+	defer file.Close()
+
+	if err = ctx.Metadata.SaveMetadata(file); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func getLatestRunTimes(ctx *context.Context) error {
+	if ctx.Bundle == nil {
+		if err := ctx.DirWriter.MkdirAll(".", 0700); err != nil {
+			return fmt.Errorf("failed to create directory %s, %w", ctx.ExportDir, err)
+		}
 	}
-	defer func(file *os.File) {
-		closeErr := file.Close()
-		if err == nil {
-			err = closeErr
+
+	file, err := ctx.ExportFS().Open("metadata.json")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ctx.Metadata.LoadMetadata(nil)
 		}
-	}(file)
-	err = ctx.Metadata.LoadMetadata(file)
-	return
+		return err
+	}
+	defer file.Close()
+
+	return ctx.Metadata.LoadMetadata(file)
 }
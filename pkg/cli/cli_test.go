@@ -0,0 +1,51 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cli_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cli"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestSaveAndLoadSecrets(t *testing.T) {
+	t.Setenv(crypt.PassphraseEnv, "passphrase")
+	dir := t.TempDir()
+
+	exportCtx := &context.Context{
+		ExportDir:          dir,
+		DirWriter:          storage.NewLocal(dir),
+		ExternalizeSecrets: true,
+		SecretKeyPatterns:  secrets.DefaultKeyPatterns,
+	}
+	require.NoError(t, cli.PreRunLoadSecrets(exportCtx))
+	env, err := exportCtx.Secrets.Externalize("my-org", "my-space", "my-app", map[string]interface{}{"DB_PASSWORD": "hunter2"})
+	require.NoError(t, err)
+	require.NoError(t, cli.PostRunSaveSecrets(exportCtx))
+	assert.FileExists(t, filepath.Join(dir, secrets.FileName))
+
+	importCtx := &context.Context{ExportDir: dir}
+	require.NoError(t, cli.PreRunLoadSecrets(importCtx))
+	resolved, err := importCtx.Secrets.Resolve(env)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"DB_PASSWORD": "hunter2"}, resolved)
+}
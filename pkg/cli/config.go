@@ -26,22 +26,31 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	ConfigDir        string
-	ConfigFile       string
-	Name             string
-	DomainsToReplace map[string]string
-	DomainsToAdd     []string        `mapstructure:"domains_to_add"`
-	ExportDir        string          `mapstructure:"export_dir"`
-	IncludedOrgs     []string        `mapstructure:"include_orgs"`
-	ExcludedOrgs     []string        `mapstructure:"exclude_orgs"`
-	SourceApi        CloudController `mapstructure:"source_api"`
-	TargetApi        CloudController `mapstructure:"target_api"`
-	ConcurrencyLimit int             `mapstructure:"concurrency_limit"`
-	DisplayProgress  bool            `mapstructure:"display_progress"`
-	Debug            bool
+	ConfigDir         string
+	ConfigFile        string
+	Name              string
+	DomainsToReplace  map[string]string
+	DomainsToAdd      []string          `mapstructure:"domains_to_add"`
+	ExportDir         string            `mapstructure:"export_dir"`
+	IncludedOrgs      []string          `mapstructure:"include_orgs"`
+	ExcludedOrgs      []string          `mapstructure:"exclude_orgs"`
+	SourceApi         CloudController   `mapstructure:"source_api"`
+	TargetApi         CloudController   `mapstructure:"target_api"`
+	ConcurrencyLimit  int               `mapstructure:"concurrency_limit"`
+	DisplayProgress   bool              `mapstructure:"display_progress"`
+	StackMappings     map[string]string `mapstructure:"-"`
+	BuildpackMappings map[string]string `mapstructure:"-"`
+	OriginMappings    map[string]string `mapstructure:"-"`
+	SegmentMappings   map[string]string `mapstructure:"-"`
+	ServiceMappings   mapping.Services  `mapstructure:"service_mappings"`
+	ImportStrategy    string            `mapstructure:"import_strategy"`
+	DataMovers        map[string]string
+	Debug             bool
 }
 
 type CloudController struct {
@@ -180,6 +189,40 @@ func (c *Config) initViperConfig() {
 		}
 	}
 	c.applyViperOverrides(v)
+
+	if v.ConfigFileUsed() != "" {
+		if err := c.readMappings(v.ConfigFileUsed()); err != nil {
+			log.Fatalf("Failed to load config file: %s, error: %s", v.ConfigFileUsed(), err)
+		}
+	}
+}
+
+// mappings are the sections of the config file that map names, viper splits their keys on dots and lowercases them,
+// which breaks buildpack urls, origins and case-sensitive names, so they are decoded from the file as they are
+type mappings struct {
+	StackMappings     map[string]string `yaml:"stack_mappings"`
+	BuildpackMappings map[string]string `yaml:"buildpack_mappings"`
+	OriginMappings    map[string]string `yaml:"origin_mappings"`
+	SegmentMappings   map[string]string `yaml:"isolation_segment_mappings"`
+}
+
+func (c *Config) readMappings(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var m mappings
+	if err = yaml.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	c.StackMappings = m.StackMappings
+	c.BuildpackMappings = m.BuildpackMappings
+	c.OriginMappings = m.OriginMappings
+	c.SegmentMappings = m.SegmentMappings
+
+	return nil
 }
 
 func (c *Config) applyViperOverrides(v *viper.Viper) {
@@ -191,6 +234,10 @@ func (c *Config) applyViperOverrides(v *viper.Viper) {
 	if len(v.GetStringMapString("domains_to_replace")) > 0 {
 		c.DomainsToReplace = v.GetStringMapString("domains_to_replace")
 	}
+	// the offerings of the data movers are read like the domains, viper would split the ones with dots
+	if len(v.GetStringMapString("data_movers")) > 0 {
+		c.DataMovers = v.GetStringMapString("data_movers")
+	}
 }
 
 func hasSuffix(configDir string) (string, bool) {
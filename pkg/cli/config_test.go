@@ -24,6 +24,7 @@ import (
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cli"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
 )
 
 func TestNewDefaultConfig(t *testing.T) {
@@ -127,6 +128,28 @@ func TestNewConfig(t *testing.T) {
 					"apps.cf1.example.com": "apps.cf2.example.com",
 				},
 				ExportDir: "service-export",
+				StackMappings: map[string]string{
+					"cflinuxfs3": "cflinuxfs4",
+				},
+				BuildpackMappings: map[string]string{
+					"java_buildpack_offline": "java_buildpack",
+				},
+				OriginMappings: map[string]string{
+					"ldap": "okta",
+				},
+				SegmentMappings: map[string]string{
+					"iso-1": "iso-east",
+				},
+				ServiceMappings: mapping.Services{
+					{
+						Source: mapping.ServicePlan{Offering: "p.mysql", Plan: "db-small", Broker: "legacy-broker"},
+						Target: mapping.ServicePlan{Plan: "small"},
+					},
+				},
+				DataMovers: map[string]string{
+					"p.mysql": "/usr/local/bin/mysql-mover",
+				},
+				ImportStrategy: "auto",
 				SourceApi: cli.CloudController{
 					URL:          "https://api.cf1.example.com",
 					Username:     "cf1-api-username",
@@ -168,6 +191,31 @@ func TestNewConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "keeps the dots and the case of the keys of the mappings",
+			args: args{
+				configFile: filepath.Join(pwd, "testdata", "config_mappings.yml"),
+			},
+			want: &cli.Config{
+				ConfigFile:       filepath.Join(pwd, "testdata", "config_mappings.yml"),
+				Name:             "app-migrator",
+				ConcurrencyLimit: export.DefaultConcurrencyLimit,
+				ExportDir:        filepath.Join(pwd, "export"),
+				StackMappings: map[string]string{
+					"cflinuxfs3": "cflinuxfs4",
+				},
+				BuildpackMappings: map[string]string{
+					"https://github.com/cloudfoundry/java-buildpack.git": "java_buildpack",
+					"Java_Buildpack_Offline":                             "java_buildpack",
+				},
+				OriginMappings: map[string]string{
+					"ldap.example.com": "okta",
+				},
+				SegmentMappings: map[string]string{
+					"ISO-1": "iso-east",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,117 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateBundleCommand() *cobra.Command {
+	bundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Work with the files of an export directory",
+		Long: `Work with the files of an export directory.
+
+An export can be encrypted while it is written with export --encrypt, or afterwards with bundle seal. Import
+decrypts encrypted files transparently when it is given the key, identity file or passphrase they were
+encrypted with.
+
+An export can be packed into a single archive with bundle pack, which import reads directly with --bundle.`,
+	}
+	return bundleCmd
+}
+
+func CreateBundleSealCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	sealCmd := &cobra.Command{
+		Use:   "seal",
+		Short: "Encrypt the files of an existing export",
+		Long: `Encrypt the files of an existing export in place.
+
+Files are encrypted for the recipient when one is given, with the key file or the passphrase in
+APP_MIGRATOR_PASSPHRASE otherwise. Files that are already encrypted are left as is, as are metadata.json,
+the import journals and the secrets file.`,
+		Example: `app-migrator bundle seal --export-dir=/tmp/export --recipient amr1...
+APP_MIGRATOR_PASSPHRASE=... app-migrator bundle seal --export-dir=/tmp/export`,
+		RunE: runBundle(ctx, r),
+	}
+	return sealCmd
+}
+
+func CreateBundleOpenCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	openCmd := &cobra.Command{
+		Use:   "open",
+		Short: "Decrypt the files of an encrypted export",
+		Long: `Decrypt the files of an encrypted export in place.
+
+Files are decrypted with the identity file when one is given, with the key file or the passphrase in
+APP_MIGRATOR_PASSPHRASE otherwise.`,
+		Example: `app-migrator bundle open --export-dir=/tmp/export --identity-file migrator.key
+APP_MIGRATOR_PASSPHRASE=... app-migrator bundle open --export-dir=/tmp/export`,
+		RunE: runBundle(ctx, r),
+	}
+	return openCmd
+}
+
+func CreateBundlePackCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	packCmd := &cobra.Command{
+		Use:   "pack",
+		Short: "Pack an export into a single archive",
+		Long: `Pack every file of an export directory into a single tar archive.
+
+The first entry of the archive is an index of the files it holds, so that import --bundle can read single apps
+without extracting the whole archive. The archive is compressed with gzip or zstd when asked for, or when the
+name of the archive ends with .gz, .tgz, .zst or .tzst. Uncompressed archives are the fastest to import from,
+droplets and packages are already compressed.`,
+		Example: `app-migrator bundle pack --export-dir=/tmp/export --output export.tar
+app-migrator bundle pack --export-dir=/tmp/export --output export.tar.zst`,
+		RunE: runBundle(ctx, r),
+	}
+	return packCmd
+}
+
+func CreateBundleUnpackCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	unpackCmd := &cobra.Command{
+		Use:     "unpack",
+		Short:   "Extract an archive created by bundle pack",
+		Long:    `Extract every file of an archive created by bundle pack into an empty export directory.`,
+		Example: `app-migrator bundle unpack --bundle export.tar.zst --export-dir=/tmp/export`,
+		RunE:    runBundle(ctx, r),
+	}
+	return unpackCmd
+}
+
+func CreateBundleKeygenCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	keygenCmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Create an identity file to encrypt exports for",
+		Long: `Create an identity file holding a new private key and print its recipient.
+
+Pass the recipient to export --recipient or bundle seal --recipient, only the holder of the identity file
+can decrypt the export with import --identity-file or bundle open --identity-file.`,
+		Example: `app-migrator bundle keygen --output migrator.key`,
+		RunE:    runBundle(ctx, r),
+	}
+	return keygenCmd
+}
+
+func runBundle(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateExportBuildpacksCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var exportBuildpacks = &cobra.Command{
+		Use:     "buildpacks",
+		Aliases: []string{"b"},
+		Short:   "Export admin buildpacks",
+		Long: `Export the admin buildpacks of the source foundation.
+
+The bits of every buildpack are downloaded to the .app-migrator/buildpacks directory of the export, and the
+buildpacks are listed with their position, stack, enabled and locked settings in
+.app-migrator/buildpacks/buildpacks.json.`,
+		Example: "app-migrator export buildpacks",
+		RunE:    exportBuildpacks(ctx, r),
+	}
+	return exportBuildpacks
+}
+
+func exportBuildpacks(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateExportDomainsCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var exportDomains = &cobra.Command{
+		Use:     "domains",
+		Aliases: []string{"d"},
+		Short:   "Export private and shared domains",
+		Long: `Export the private domains of the exported orgs and the shared domains their apps use.
+
+Run it after exporting the apps. The private domains owned by or shared with the orgs are written to
+.app-migrator/domains/domains.json, with the name of the org owning them and of the orgs they are shared with. The shared domains
+the routes of the exported apps are on are written along with them, except tcp domains. Orgs left out with
+--include-orgs or --exclude-orgs are not exported.`,
+		Example: `app-migrator export domains
+app-migrator export domains --exclude-orgs system`,
+		RunE: exportDomains(ctx, r),
+	}
+	return exportDomains
+}
+
+func exportDomains(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
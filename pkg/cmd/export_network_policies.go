@@ -0,0 +1,47 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateExportNetworkPoliciesCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var exportNetworkPolicies = &cobra.Command{
+		Use:     "network-policies",
+		Aliases: []string{"np"},
+		Short:   "Export container to container network policies",
+		Long: `Export the container to container network policies of the exported apps.
+
+Run it after exporting the apps, every policy with an exported app at either end is written to
+.app-migrator/network_policies/policies.json, naming the source and destination apps by org, space and app name with the
+protocol and ports the policy opens. Apps of orgs left out with --include-orgs or --exclude-orgs are not
+looked up.`,
+		Example: `app-migrator export network-policies
+app-migrator export network-policies --exclude-orgs system`,
+		RunE: exportNetworkPolicies(ctx, r),
+	}
+	return exportNetworkPolicies
+}
+
+func exportNetworkPolicies(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateExportQuotasCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var exportQuotas = &cobra.Command{
+		Use:     "quotas",
+		Aliases: []string{"q"},
+		Short:   "Export org and space quotas",
+		Long: `Export the org and space quotas of the source foundation.
+
+The limits of every quota are written to .app-migrator/quotas/quotas.json, with the names of the orgs and spaces
+the quotas are assigned to. Assignments to orgs left out with --include-orgs or --exclude-orgs are not exported, nor are
+the space quotas of those orgs.`,
+		Example: `app-migrator export quotas
+app-migrator export quotas --exclude-orgs system`,
+		RunE: exportQuotas(ctx, r),
+	}
+	return exportQuotas
+}
+
+func exportQuotas(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateImportBuildpacksCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var importBuildpacks = &cobra.Command{
+		Use:     "buildpacks",
+		Aliases: []string{"b"},
+		Short:   "Import admin buildpacks",
+		Long: `Import the admin buildpacks exported with export buildpacks.
+
+Buildpacks are created on the target foundation with their exported bits and settings, in the order of their
+positions. A buildpack that already exists with the same name and stack is left alone when its bits have the
+same checksum as the exported ones and its settings match, its bits are replaced when their checksum differs.`,
+		Example: `app-migrator import buildpacks
+app-migrator import buildpacks --stack-mappings cflinuxfs3=cflinuxfs4`,
+		RunE: importBuildpacks(ctx, r),
+	}
+	return importBuildpacks
+}
+
+func importBuildpacks(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
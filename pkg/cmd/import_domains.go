@@ -0,0 +1,49 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateImportDomainsCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var importDomains = &cobra.Command{
+		Use:     "domains",
+		Aliases: []string{"d"},
+		Short:   "Import private and shared domains",
+		Long: `Import the private and shared domains exported with export domains.
+
+Private domains missing on the target foundation are created in the org owning them on the source foundation, after
+applying --name-mapping, and shared with the same orgs, so import the orgs first. Existing private domains of the
+org are shared with the orgs they are not shared with yet.
+
+Shared domains missing on the target foundation are only reported, unless --shared-domains is given, which creates
+them and takes an admin of the target foundation.`,
+		Example: `app-migrator import domains
+app-migrator import domains --shared-domains`,
+		RunE: importDomains(ctx, r),
+	}
+	return importDomains
+}
+
+func importDomains(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
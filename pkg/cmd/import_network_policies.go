@@ -0,0 +1,45 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateImportNetworkPoliciesCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var importNetworkPolicies = &cobra.Command{
+		Use:     "network-policies",
+		Aliases: []string{"np"},
+		Short:   "Import container to container network policies",
+		Long: `Import the container to container network policies exported with export network-policies.
+
+Import the apps first, the source and destination apps of every policy are looked up on the target foundation
+by org, space and app name after applying --name-mapping. Policies whose source or destination app was not
+migrated are skipped and listed in the summary, policies that already exist are left unchanged.`,
+		Example: "app-migrator import network-policies",
+		RunE:    importNetworkPolicies(ctx, r),
+	}
+	return importNetworkPolicies
+}
+
+func importNetworkPolicies(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
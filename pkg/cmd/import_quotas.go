@@ -0,0 +1,46 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateImportQuotasCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	var importQuotas = &cobra.Command{
+		Use:     "quotas",
+		Aliases: []string{"q"},
+		Short:   "Import org and space quotas",
+		Long: `Import the org and space quotas exported with export quotas.
+
+Quotas are created on the target foundation with their exported limits, space quotas in the org they belonged to
+on the source foundation. A quota that already exists with the same name gets the exported limits. Quotas are then
+assigned to the orgs and spaces they were assigned to on the source foundation, after applying --name-mapping,
+so import the orgs and spaces first.`,
+		Example: "app-migrator import quotas",
+		RunE:    importQuotas(ctx, r),
+	}
+	return importQuotas
+}
+
+func importQuotas(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
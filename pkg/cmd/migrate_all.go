@@ -0,0 +1,45 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateMigrateCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate Cloud Foundry applications directly from the source to the target foundation",
+		Long: `Migrate exports apps from the source foundation and imports them into the target foundation in one pass.
+Droplets and packages are streamed from the source download to the target upload without being written to the
+export dir, which only holds the import journal unless --tee-export asks for a copy of the export.`,
+		Example: `app-migrator migrate
+app-migrator migrate --exclude-orgs='^system$'
+app-migrator migrate --include-orgs='system,test' --domains-to-replace 'tas1.example.com=tas2.example.com'
+app-migrator migrate --tee-export --export-dir=/tmp/audit`,
+		RunE: migrateAll(ctx, r),
+	}
+	return migrate
+}
+
+func migrateAll(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,49 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreatePreflightCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	preflightCmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that the target foundation provides what the exported apps depend on",
+		Long: `Check that the target foundation provides what the exported apps depend on.
+
+Preflight reads every exported manifest and lists the buildpacks and stacks the apps reference, once the
+buildpack and stack mappings are applied. Each of them is looked up on the target foundation, and the apps
+referencing a missing or disabled buildpack, or a missing stack, are reported as failed. The memory and
+instances of the apps are added up per org and space and compared with the quotas of the target org and
+space, the apps of an org or space over its quota are reported as failed too. Nothing is changed on the
+target foundation.`,
+		Example: `app-migrator preflight
+app-migrator preflight --buildpack-mappings java_buildpack_offline=java_buildpack --export-dir=/tmp
+app-migrator preflight --stack-mappings cflinuxfs3=cflinuxfs4 --import-strategy=auto`,
+		RunE: preflight(ctx, r),
+	}
+	return preflightCmd
+}
+
+func preflight(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
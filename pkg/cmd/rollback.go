@@ -0,0 +1,51 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+func CreateRollbackCommand(ctx *context.Context, r context.CommandRunner) *cobra.Command {
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Undo the changes made to the target foundation by an import run",
+		Long: `Undo the changes made to the target foundation by an import run.
+
+Every import run records the apps, routes and bindings it creates and the previous settings of the apps it
+updates in a journal file in the export directory, as well as the changes it makes to service instances and keys,
+domains, security groups, quotas, roles, network policies, isolation segments and buildpacks. Rollback replays that
+journal in reverse, deleting what was created and restoring what was updated. The bits of an existing buildpack
+replaced by an import are not kept, rollback reports that buildpack as failed and they must be uploaded again.
+
+The previous settings are sealed with the identity or key the import was given to read the export, or with the
+key of the secrets file, the same identity or key is needed to roll back. The env vars of the updated apps are
+only journaled, and restored, when the import had a key to seal them with.`,
+		Example: `app-migrator rollback --run 20220728T153000Z-3f9c2a
+app-migrator rollback --run 20220728T153000Z-3f9c2a --export-dir=/tmp`,
+		RunE: rollback(ctx, r),
+	}
+	return rollbackCmd
+}
+
+func rollback(ctx *context.Context, r context.CommandRunner) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := r.Run(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -18,17 +18,24 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/bundle"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cli"
 	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cli"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/commands"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
 	im "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/import"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/process"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
 )
 
 var ErrSilent = errors.New("SilentErr")
@@ -52,7 +59,39 @@ func CreateRootCommand(ctx *context.Context) *cobra.Command {
 
 	// load metadata before command runs
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
-		err := cli.PreRunLoadMetadata(ctx)
+		err := checkImportStrategy(cmd, ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunOpenBundle(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunOpenStorage(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunLoadMetadata(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunLoadNameMapping(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunLoadEnvTransform(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunLoadSecrets(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunLoadEncryption(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = cli.PreRunCreateJournal(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -67,14 +106,26 @@ func CreateRootCommand(ctx *context.Context) *cobra.Command {
 		if err != nil {
 			log.Fatalln(err)
 		}
+		err = cli.PostRunSaveSecrets(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
 		cli.DisplaySummary(ctx)
+		err = cli.PostRunCloseJournal(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
 	}
 
 	addExportCommands(rootCmd, ctx)
 	addImportCommands(rootCmd, ctx)
+	addMigrateCommands(rootCmd, ctx)
+	addRollbackCommand(rootCmd, ctx)
+	addPreflightCommand(rootCmd, ctx)
+	addBundleCommands(rootCmd, ctx)
 
 	rootCmd.PersistentFlags().BoolVar(&ctx.Debug, "debug", false, "Enable debug logging")
-	rootCmd.PersistentFlags().StringVar(&ctx.ExportDir, "export-dir", ctx.ExportDir, "Directory where apps will be placed or read")
+	rootCmd.PersistentFlags().StringVar(&ctx.ExportDir, "export-dir", ctx.ExportDir, "Directory where apps will be placed or read, or an s3://bucket/prefix url to use an object store")
 	rootCmd.PersistentFlags().BoolVar(&ctx.DisplayProgress, "display-progress", true, "Display progress bar")
 
 	return rootCmd
@@ -88,6 +139,9 @@ func addExportCommands(rootCmd *cobra.Command, ctx *context.Context) {
 	exportCmd.PersistentFlags().StringToStringVar(&ctx.DomainsToReplace, "domains-to-replace", map[string]string{}, "Domains to replace in any found application routes")
 	exportCmd.Flags().StringSliceVar(&ctx.IncludedOrgs, "include-orgs", ctx.IncludedOrgs, "Only orgs matching the regex(es) specified will be included")
 	exportCmd.Flags().StringSliceVar(&ctx.ExcludedOrgs, "exclude-orgs", ctx.ExcludedOrgs, "Any orgs matching the regex(es) specified will be excluded")
+	exportCmd.PersistentFlags().BoolVar(&ctx.DedupeBlobs, "dedupe-blobs", false, "Store each distinct droplet and package once, in a content-addressed blobs dir")
+	addSecretsFlags(exportCmd.PersistentFlags(), ctx)
+	addEncryptionFlags(exportCmd.PersistentFlags(), ctx)
 
 	exportAppCmd := CreateExportAppCommand(ctx, &commands.ExportApp{})
 	exportAppCmd.Flags().StringP("org", "o", "", "org to which the app belongs")
@@ -104,9 +158,30 @@ func addExportCommands(rootCmd *cobra.Command, ctx *context.Context) {
 		log.Fatalln(err.Error())
 	}
 	exportCmd.AddCommand(exportSpaceCmd)
+
+	exportBuildpacksCmd := CreateExportBuildpacksCommand(ctx, &commands.ExportBuildpacks{})
+	exportCmd.AddCommand(exportBuildpacksCmd)
+
+	exportQuotasCmd := CreateExportQuotasCommand(ctx, &commands.ExportQuotas{})
+	exportQuotasCmd.Flags().StringSliceVar(&ctx.IncludedOrgs, "include-orgs", ctx.IncludedOrgs, "Only orgs matching the regex(es) specified will be included")
+	exportQuotasCmd.Flags().StringSliceVar(&ctx.ExcludedOrgs, "exclude-orgs", ctx.ExcludedOrgs, "Any orgs matching the regex(es) specified will be excluded")
+	exportCmd.AddCommand(exportQuotasCmd)
+
+	exportNetworkPoliciesCmd := CreateExportNetworkPoliciesCommand(ctx, &commands.ExportNetworkPolicies{})
+	exportNetworkPoliciesCmd.Flags().StringSliceVar(&ctx.IncludedOrgs, "include-orgs", ctx.IncludedOrgs, "Only orgs matching the regex(es) specified will be included")
+	exportNetworkPoliciesCmd.Flags().StringSliceVar(&ctx.ExcludedOrgs, "exclude-orgs", ctx.ExcludedOrgs, "Any orgs matching the regex(es) specified will be excluded")
+	exportCmd.AddCommand(exportNetworkPoliciesCmd)
+
+	exportDomainsCmd := CreateExportDomainsCommand(ctx, &commands.ExportDomains{})
+	exportDomainsCmd.Flags().StringSliceVar(&ctx.IncludedOrgs, "include-orgs", ctx.IncludedOrgs, "Only orgs matching the regex(es) specified will be included")
+	exportDomainsCmd.Flags().StringSliceVar(&ctx.ExcludedOrgs, "exclude-orgs", ctx.ExcludedOrgs, "Any orgs matching the regex(es) specified will be excluded")
+	exportCmd.AddCommand(exportDomainsCmd)
 	rootCmd.AddCommand(exportCmd)
 
 	exportIncCmd := CreateExportIncrementalCommand(ctx, &commands.ExportIncremental{})
+	exportIncCmd.Flags().BoolVar(&ctx.DedupeBlobs, "dedupe-blobs", false, "Store each distinct droplet and package once, in a content-addressed blobs dir")
+	addSecretsFlags(exportIncCmd.Flags(), ctx)
+	addEncryptionFlags(exportIncCmd.Flags(), ctx)
 	rootCmd.AddCommand(exportIncCmd)
 }
 
@@ -115,6 +190,7 @@ func addImportCommands(rootCmd *cobra.Command, ctx *context.Context) {
 	importCmd := CreateImportCommand(ctx, &commands.ImportAll{})
 	importCmd.Flags().StringSliceVar(&ctx.IncludedOrgs, "include-orgs", ctx.IncludedOrgs, "Only orgs matching the regex(es) specified will be included")
 	importCmd.Flags().StringSliceVar(&ctx.ExcludedOrgs, "exclude-orgs", ctx.ExcludedOrgs, "Any orgs matching the regex(es) specified will be excluded")
+	addImportFlags(importCmd.PersistentFlags(), ctx)
 
 	importAppCmd := CreateImportAppCommand(ctx, &commands.ImportApp{})
 	importAppCmd.Flags().StringP("org", "o", "", "org to which the app belongs")
@@ -131,12 +207,240 @@ func addImportCommands(rootCmd *cobra.Command, ctx *context.Context) {
 		log.Fatalln(err.Error())
 	}
 	importCmd.AddCommand(importSpaceCmd)
+
+	importBuildpacksCmd := CreateImportBuildpacksCommand(ctx, &commands.ImportBuildpacks{})
+	importCmd.AddCommand(importBuildpacksCmd)
+
+	importQuotasCmd := CreateImportQuotasCommand(ctx, &commands.ImportQuotas{})
+	importCmd.AddCommand(importQuotasCmd)
+
+	importNetworkPoliciesCmd := CreateImportNetworkPoliciesCommand(ctx, &commands.ImportNetworkPolicies{})
+	importCmd.AddCommand(importNetworkPoliciesCmd)
+
+	importDomainsCmd := CreateImportDomainsCommand(ctx, &commands.ImportDomains{})
+	importDomainsCmd.Flags().BoolVar(&ctx.SharedDomains, "shared-domains", false, "Also create the shared domains missing on the target foundation, which takes an admin")
+	importCmd.AddCommand(importDomainsCmd)
 	rootCmd.AddCommand(importCmd)
 
 	importIncCmd := CreateImportIncrementalCommand(ctx, &commands.ImportIncremental{})
+	addImportFlags(importIncCmd.Flags(), ctx)
 	rootCmd.AddCommand(importIncCmd)
 }
 
+// addMigrateCommands adds the commands moving apps straight from the source to the target foundation,
+// they use both of the clients set up by addExportCommands and addImportCommands
+func addMigrateCommands(rootCmd *cobra.Command, ctx *context.Context) {
+	migrateCmd := CreateMigrateCommand(ctx, &commands.MigrateAll{})
+	migrateCmd.PersistentFlags().IntVarP(&ctx.ConcurrencyLimit, "concurrency-limit", "l", ctx.ConcurrencyLimit, "Number of apps to migrate concurrently")
+	migrateCmd.PersistentFlags().StringArrayVar(&ctx.DomainsToAdd, "domains-to-add", []string{}, "Domains to add in any found application routes")
+	migrateCmd.PersistentFlags().StringToStringVar(&ctx.DomainsToReplace, "domains-to-replace", map[string]string{}, "Domains to replace in any found application routes")
+	migrateCmd.PersistentFlags().IntVar(&ctx.StreamBufferSize, "buffer-size", aio.DefaultPipeSize, "Bytes of each droplet and package held in memory between the source download and the target upload")
+	migrateCmd.PersistentFlags().BoolVar(&ctx.TeeExport, "tee-export", false, "Also write the migrated apps to the export dir, as export would")
+	migrateCmd.Flags().StringSliceVar(&ctx.IncludedOrgs, "include-orgs", ctx.IncludedOrgs, "Only orgs matching the regex(es) specified will be included")
+	migrateCmd.Flags().StringSliceVar(&ctx.ExcludedOrgs, "exclude-orgs", ctx.ExcludedOrgs, "Any orgs matching the regex(es) specified will be excluded")
+	addTargetFlags(migrateCmd.PersistentFlags(), ctx)
+	addEncryptionFlags(migrateCmd.PersistentFlags(), ctx)
+
+	migrateAppCmd := CreateMigrateAppCommand(ctx, &commands.MigrateApp{})
+	migrateAppCmd.Flags().StringP("org", "o", "", "org to which the app belongs")
+	migrateAppCmd.Flags().StringP("space", "s", "", "space to which the app belongs")
+	migrateCmd.AddCommand(migrateAppCmd)
+
+	migrateOrgCmd := CreateMigrateOrgCommand(ctx, &commands.MigrateOrg{})
+	migrateCmd.AddCommand(migrateOrgCmd)
+
+	migrateSpaceCmd := CreateMigrateSpaceCommand(ctx, &commands.MigrateSpace{})
+	migrateSpaceCmd.Flags().StringP("org", "o", "", "org to which the space belongs")
+	err := migrateSpaceCmd.MarkFlagRequired("org")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	migrateCmd.AddCommand(migrateSpaceCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// addImportFlags adds the flags shared by all the import commands
+func addImportFlags(flags *pflag.FlagSet, ctx *context.Context) {
+	addTargetFlags(flags, ctx)
+	flags.StringVar(&ctx.SecretsKeyFile, "secrets-key-file", "", fmt.Sprintf("Key file used to decrypt the secrets file, the passphrase in %s is used otherwise", crypt.PassphraseEnv))
+	flags.BoolVar(&ctx.SecretsFromEnv, "secrets-from-env", false, "Resolve the ((placeholders)) of the exported secrets missing from the secrets file from the environment variables of the same name")
+	addDecryptionFlags(flags, ctx)
+	flags.StringVar(&ctx.BundleFile, "bundle", "", "Archive created by bundle pack to import from instead of the export dir")
+}
+
+// addTargetFlags adds the flags controlling how apps are created on the target foundation
+func addTargetFlags(flags *pflag.FlagSet, ctx *context.Context) {
+	ctx.OnConflict = commands.ConflictUpdate
+	flags.Var(conflictPolicyValue{policy: &ctx.OnConflict}, "on-conflict", fmt.Sprintf("What to do when an app already exists in the target space, one of %s", strings.Join(commands.ConflictPolicies, "|")))
+	flags.StringVar(&ctx.ConflictSuffix, "conflict-suffix", commands.DefaultConflictSuffix, "Suffix added to the name and route hosts of apps imported with --on-conflict=rename")
+	flags.BoolVar(&ctx.UpdateUnownedApps, "update-unowned", false, "Allow updating existing apps that were not created by app-migrator")
+	flags.StringVar(&ctx.NameMappingFile, "name-mapping", "", "File mapping source org, space and app names to the names to use on the target")
+	flags.StringToStringVar(&ctx.OriginMappings, "origin-mappings", ctx.OriginMappings, "Identity provider origins to give the users of the imported org and space roles instead of their origin on the source, e.g. ldap=okta")
+	flags.StringToStringVar(&ctx.SegmentMappings, "isolation-segment-mappings", ctx.SegmentMappings, "Isolation segments to entitle the imported orgs to and assign to the imported spaces instead of their segment on the source, e.g. iso-1=iso-east")
+	flags.StringToStringVar(&ctx.DataMovers, "data-movers", ctx.DataMovers, "Executables moving the data of the service instances of an offering bound to the imported apps before they are started, e.g. p.mysql=/usr/local/bin/mysql-mover")
+	flags.BoolVar(&ctx.MoveRunningAppData, "data-movers-on-running-apps", false, "Also run the data movers for the apps that already exist on the target and are not stopped, overwriting the data of their service instances")
+	flags.DurationVar(&ctx.DataMoverTimeout, "data-mover-timeout", time.Hour, "How long a data mover can run before it is killed and the import of its app fails, 0 for no limit")
+	flags.BoolVar(&ctx.SecurityGroups, "security-groups", false, "Create or update the security groups bound to the imported spaces and bind them to the target spaces")
+	addMappingFlags(flags, ctx)
+	flags.StringVar(&ctx.EnvTransformFile, "env-transform", "", "File with the rules used to change the env vars of the imported apps")
+	flags.StringArrayVar(&ctx.VarsFiles, "vars-file", []string{}, "File with the variables expanded in the replacement strings and set values of --env-transform, the env vars of the exported manifests are never expanded, can be repeated")
+}
+
+// addMappingFlags adds the flags deciding the stack and buildpacks the apps get on the target foundation
+func addMappingFlags(flags *pflag.FlagSet, ctx *context.Context) {
+	flags.StringToStringVar(&ctx.StackMappings, "stack-mappings", ctx.StackMappings, "Stacks to give the imported apps instead of their exported stack, e.g. cflinuxfs3=cflinuxfs4")
+	flags.StringToStringVar(&ctx.BuildpackMappings, "buildpack-mappings", ctx.BuildpackMappings, "Buildpacks to give the imported apps instead of their exported buildpacks, e.g. java_buildpack_offline=java_buildpack")
+	if ctx.ImportStrategy == "" {
+		ctx.ImportStrategy = commands.ImportDroplet
+	}
+	flags.Var(importStrategyValue{strategy: &ctx.ImportStrategy}, "import-strategy", fmt.Sprintf("How the imported apps get a droplet, one of %s: upload the exported droplet, stage the exported package on the target, or stage it only when the stack of the app changed or is missing on the target", strings.Join(commands.ImportStrategies, "|")))
+}
+
+// addSecretsFlags adds the flags used to keep secrets out of the exported manifests
+func addSecretsFlags(flags *pflag.FlagSet, ctx *context.Context) {
+	flags.BoolVar(&ctx.ExternalizeSecrets, "externalize-secrets", false, "Replace sensitive env vars with ((placeholders)) and store their values in an encrypted secrets file")
+	flags.StringArrayVar(&ctx.SecretKeyPatterns, "secret-key-patterns", secrets.DefaultKeyPatterns, "Regex(es) matching the names of sensitive env vars")
+	flags.Float64Var(&ctx.SecretMinEntropy, "secret-min-entropy", 4.5, "Env var values with at least this many bits of entropy per character are treated as sensitive, 0 to disable")
+	flags.StringVar(&ctx.SecretsKeyFile, "secrets-key-file", "", fmt.Sprintf("Key file used to encrypt the secrets file, the passphrase in %s is used otherwise", crypt.PassphraseEnv))
+	flags.StringToStringVar(&ctx.DataMovers, "data-movers", ctx.DataMovers, "Data movers of offerings, the credentials of the bindings of their service instances are stored in the secrets file for the data movers to run on import, e.g. p.mysql=/usr/local/bin/mysql-mover")
+}
+
+// addEncryptionFlags adds the flags used to encrypt the files written to the export dir
+func addEncryptionFlags(flags *pflag.FlagSet, ctx *context.Context) {
+	flags.BoolVar(&ctx.EncryptExport, "encrypt", false, "Encrypt every file written to the export dir")
+	flags.StringVar(&ctx.EncryptionKeyFile, "encryption-key-file", "", fmt.Sprintf("Key file used to encrypt the export, the passphrase in %s is used otherwise", crypt.PassphraseEnv))
+	flags.StringVar(&ctx.Recipient, "recipient", "", "Public key (amr1...) of the recipient the export is encrypted for, implies --encrypt")
+}
+
+// addDecryptionFlags adds the flags used to read the files of an encrypted export
+func addDecryptionFlags(flags *pflag.FlagSet, ctx *context.Context) {
+	flags.StringVar(&ctx.EncryptionKeyFile, "encryption-key-file", "", fmt.Sprintf("Key file used to decrypt an encrypted export, the passphrase in %s is used otherwise", crypt.PassphraseEnv))
+	flags.StringVar(&ctx.IdentityFile, "identity-file", "", "Identity file holding the private key of the recipient an export was encrypted for")
+}
+
+// conflictPolicyValue is a flag value that only accepts one of the supported conflict policies
+type conflictPolicyValue struct {
+	policy *string
+}
+
+func (v conflictPolicyValue) String() string {
+	if v.policy == nil {
+		return ""
+	}
+	return *v.policy
+}
+
+func (v conflictPolicyValue) Set(s string) error {
+	if !commands.IsValidConflictPolicy(s) {
+		return fmt.Errorf("must be one of %s", strings.Join(commands.ConflictPolicies, "|"))
+	}
+	*v.policy = s
+	return nil
+}
+
+func (v conflictPolicyValue) Type() string {
+	return "string"
+}
+
+// checkImportStrategy fails the commands taking --import-strategy when the import_strategy of the config file is not
+// one of the supported import strategies, the flag itself only accepts those
+func checkImportStrategy(cmd *cobra.Command, ctx *context.Context) error {
+	if cmd.Flags().Lookup("import-strategy") == nil || commands.IsValidImportStrategy(ctx.ImportStrategy) {
+		return nil
+	}
+	return fmt.Errorf("import_strategy must be one of %s", strings.Join(commands.ImportStrategies, "|"))
+}
+
+// importStrategyValue is a flag value that only accepts one of the supported import strategies
+type importStrategyValue struct {
+	strategy *string
+}
+
+func (v importStrategyValue) String() string {
+	if v.strategy == nil {
+		return ""
+	}
+	return *v.strategy
+}
+
+func (v importStrategyValue) Set(s string) error {
+	if !commands.IsValidImportStrategy(s) {
+		return fmt.Errorf("must be one of %s", strings.Join(commands.ImportStrategies, "|"))
+	}
+	*v.strategy = s
+	return nil
+}
+
+func (v importStrategyValue) Type() string {
+	return "string"
+}
+
+func addRollbackCommand(rootCmd *cobra.Command, ctx *context.Context) {
+	rollback := &commands.Rollback{}
+	rollbackCmd := CreateRollbackCommand(ctx, rollback)
+	rollbackCmd.Flags().StringVar(&rollback.RunID, "run", "", "id of the import run to roll back")
+	rollbackCmd.Flags().StringVar(&ctx.SecretsKeyFile, "secrets-key-file", "", fmt.Sprintf("Key file the journal was sealed with when the import had no other key, the passphrase in %s is used otherwise", crypt.PassphraseEnv))
+	addDecryptionFlags(rollbackCmd.Flags(), ctx)
+	err := rollbackCmd.MarkFlagRequired("run")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func addPreflightCommand(rootCmd *cobra.Command, ctx *context.Context) {
+	preflightCmd := CreatePreflightCommand(ctx, &commands.Preflight{})
+	preflightCmd.Flags().StringSliceVar(&ctx.IncludedOrgs, "include-orgs", ctx.IncludedOrgs, "Only orgs matching the regex(es) specified will be included")
+	preflightCmd.Flags().StringSliceVar(&ctx.ExcludedOrgs, "exclude-orgs", ctx.ExcludedOrgs, "Any orgs matching the regex(es) specified will be excluded")
+	preflightCmd.Flags().StringVar(&ctx.BundleFile, "bundle", "", "Archive created by bundle pack to check instead of the export dir")
+	preflightCmd.Flags().StringVar(&ctx.NameMappingFile, "name-mapping", "", "File mapping source org, space and app names to the names to use on the target")
+	addMappingFlags(preflightCmd.Flags(), ctx)
+	addDecryptionFlags(preflightCmd.Flags(), ctx)
+	rootCmd.AddCommand(preflightCmd)
+}
+
+func addBundleCommands(rootCmd *cobra.Command, ctx *context.Context) {
+	bundleCmd := CreateBundleCommand()
+
+	sealCmd := CreateBundleSealCommand(ctx, &commands.BundleSeal{})
+	sealCmd.Flags().StringVar(&ctx.EncryptionKeyFile, "encryption-key-file", "", fmt.Sprintf("Key file used to encrypt the export, the passphrase in %s is used otherwise", crypt.PassphraseEnv))
+	sealCmd.Flags().StringVar(&ctx.Recipient, "recipient", "", "Public key (amr1...) of the recipient the export is encrypted for")
+	bundleCmd.AddCommand(sealCmd)
+
+	openCmd := CreateBundleOpenCommand(ctx, &commands.BundleOpen{})
+	addDecryptionFlags(openCmd.Flags(), ctx)
+	bundleCmd.AddCommand(openCmd)
+
+	pack := &commands.BundlePack{}
+	packCmd := CreateBundlePackCommand(ctx, pack)
+	packCmd.Flags().StringVarP(&pack.Output, "output", "o", "", "path of the archive to create")
+	packCmd.Flags().StringVar(&pack.Compression, "compression", "", fmt.Sprintf("Compression of the archive, one of %s, guessed from the archive name by default", strings.Join(bundle.Compressions, "|")))
+	err := packCmd.MarkFlagRequired("output")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	bundleCmd.AddCommand(packCmd)
+
+	unpackCmd := CreateBundleUnpackCommand(ctx, &commands.BundleUnpack{})
+	unpackCmd.Flags().StringVar(&ctx.BundleFile, "bundle", "", "Archive created by bundle pack to extract")
+	err = unpackCmd.MarkFlagRequired("bundle")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	bundleCmd.AddCommand(unpackCmd)
+
+	keygen := &commands.BundleKeygen{}
+	keygenCmd := CreateBundleKeygenCommand(ctx, keygen)
+	keygenCmd.Flags().StringVarP(&keygen.Output, "output", "o", "", "path of the identity file to create")
+	err = keygenCmd.MarkFlagRequired("output")
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	bundleCmd.AddCommand(keygenCmd)
+
+	rootCmd.AddCommand(bundleCmd)
+}
+
 func newCFClient(ctx *context.Context, isExport bool) {
 	cfg, err := cli.NewDefaultConfig()
 	if err != nil {
@@ -168,6 +472,15 @@ func newCFClient(ctx *context.Context, isExport bool) {
 	ctx.Debug = cfg.Debug
 	ctx.DomainsToAdd = cfg.DomainsToAdd
 	ctx.DomainsToReplace = cfg.DomainsToReplace
+	ctx.StackMappings = cfg.StackMappings
+	ctx.BuildpackMappings = cfg.BuildpackMappings
+	ctx.OriginMappings = cfg.OriginMappings
+	ctx.SegmentMappings = cfg.SegmentMappings
+	ctx.ServiceMappings = cfg.ServiceMappings
+	ctx.DataMovers = cfg.DataMovers
+	if cfg.ImportStrategy != "" {
+		ctx.ImportStrategy = cfg.ImportStrategy
+	}
 	ctx.ConcurrencyLimit = cfg.ConcurrencyLimit
 	ctx.ExportDir = cfg.ExportDir
 	ctx.IncludedOrgs = cfg.IncludedOrgs
@@ -116,3 +116,15 @@ func NewFakeCommand() *cobra.Command {
 		},
 	}
 }
+
+func TestCheckImportStrategy(t *testing.T) {
+	ctx := &context.Context{ImportStrategy: "push"}
+
+	importCmd := NewFakeCommand()
+	addMappingFlags(importCmd.Flags(), ctx)
+	require.EqualError(t, checkImportStrategy(importCmd, ctx), "import_strategy must be one of droplet|restage|auto")
+	require.NoError(t, checkImportStrategy(NewFakeCommand(), ctx))
+
+	ctx.ImportStrategy = "restage"
+	require.NoError(t, checkImportStrategy(importCmd, ctx))
+}
@@ -0,0 +1,41 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"strings"
+)
+
+// mapBuildpacks returns the buildpacks an app gets on the target foundation, replacing the ones named in mappings
+func mapBuildpacks(mappings map[string]string, buildpacks []string) []string {
+	if len(mappings) == 0 || len(buildpacks) == 0 {
+		return buildpacks
+	}
+
+	mapped := make([]string, len(buildpacks))
+	for idx, b := range buildpacks {
+		mapped[idx] = b
+		if target, ok := mappings[b]; ok {
+			mapped[idx] = target
+		}
+	}
+	return mapped
+}
+
+// isCustomBuildpack tells whether a buildpack is referenced by the url of its repository or archive rather than by
+// the name of a buildpack installed on the foundation
+func isCustomBuildpack(buildpack string) bool {
+	return strings.Contains(buildpack, "://") || strings.HasPrefix(buildpack, "git@")
+}
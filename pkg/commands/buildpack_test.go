@@ -0,0 +1,55 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mapBuildpacks(t *testing.T) {
+	mappings := map[string]string{
+		"java_buildpack_offline":                "java_buildpack",
+		"https://github.com/example/custom.git": "custom_buildpack",
+	}
+	tests := []struct {
+		name       string
+		mappings   map[string]string
+		buildpacks []string
+		want       []string
+	}{
+		{
+			name:       "keeps the buildpacks without mappings",
+			buildpacks: []string{"java_buildpack_offline"},
+			want:       []string{"java_buildpack_offline"},
+		},
+		{
+			name:       "maps buildpacks by name and url",
+			mappings:   mappings,
+			buildpacks: []string{"https://github.com/example/custom.git", "java_buildpack_offline", "go_buildpack"},
+			want:       []string{"custom_buildpack", "java_buildpack", "go_buildpack"},
+		},
+		{
+			name:     "leaves apps without buildpacks alone",
+			mappings: mappings,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mapBuildpacks(tt.mappings, tt.buildpacks))
+		})
+	}
+}
@@ -0,0 +1,335 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/bundle"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+// BundleSeal encrypts the files of an existing plain export in place
+type BundleSeal struct{}
+
+// BundleOpen decrypts the files of an encrypted export in place
+type BundleOpen struct{}
+
+// BundlePack writes an export dir to a single archive file
+type BundlePack struct {
+	Output      string
+	Compression string
+}
+
+// BundleUnpack extracts the archive an export was packed into to the export dir
+type BundleUnpack struct{}
+
+// BundleKeygen creates an identity file and prints the recipient exports can be encrypted for
+type BundleKeygen struct {
+	Output string
+	Stdout io.Writer
+}
+
+// Run encrypts every plain file in the export dir for the recipient, or with the key when no recipient was given.
+// Files that are already encrypted are left as is.
+func (b *BundleSeal) Run(ctx *context.Context) error {
+	sealer := ctx.Sealer
+	if key, ok := ctx.Opener.(*crypt.Key); ok && sealer == nil {
+		sealer = key
+	}
+	if sealer == nil {
+		return fmt.Errorf("a recipient (--recipient), a key file (--encryption-key-file) or a passphrase (%s) is required to seal an export", crypt.PassphraseEnv)
+	}
+	if err := requireLocalExport(ctx); err != nil {
+		return err
+	}
+
+	count, err := rewriteExport(ctx.ExportDir, false, func(path string) error {
+		ctx.Logger.Infof("Encrypting %s", path)
+		return rewriteFile(path, nil, sealer)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Encrypted %d files in %s\n", count, ctx.ExportDir)
+
+	return nil
+}
+
+// Run decrypts every encrypted file in the export dir
+func (b *BundleOpen) Run(ctx *context.Context) error {
+	if ctx.Opener == nil {
+		return fmt.Errorf("an identity file (--identity-file), a key file (--encryption-key-file) or a passphrase (%s) is required to open an export", crypt.PassphraseEnv)
+	}
+	if err := requireLocalExport(ctx); err != nil {
+		return err
+	}
+
+	count, err := rewriteExport(ctx.ExportDir, true, func(path string) error {
+		ctx.Logger.Infof("Decrypting %s", path)
+		return rewriteFile(path, ctx.Opener, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Decrypted %d files in %s\n", count, ctx.ExportDir)
+
+	return nil
+}
+
+// Run writes a new identity to the output file, refusing to overwrite an existing one
+func (b *BundleKeygen) Run(ctx *context.Context) error {
+	if b.Output == "" {
+		return errors.New("the path of the identity file to create is required")
+	}
+
+	id, err := crypt.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+	recipient, err := id.Recipient()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(b.Output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "# created: %s\n# recipient: %s\n%s\n", time.Now().UTC().Format(time.RFC3339), recipient, id)
+	if err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	out := b.Stdout
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err = fmt.Fprintf(out, "Recipient: %s\n", recipient)
+
+	return err
+}
+
+// Run packs every file in the export dir into the output archive, compressed as asked for or as its extension suggests
+func (b *BundlePack) Run(ctx *context.Context) error {
+	if b.Output == "" {
+		return errors.New("the path of the archive to create is required")
+	}
+
+	compression := b.Compression
+	if compression == "" {
+		compression = bundle.CompressionFor(b.Output)
+	}
+	if !bundle.IsValidCompression(compression) {
+		return fmt.Errorf("unsupported compression %q, must be one of %s", compression, strings.Join(bundle.Compressions, "|"))
+	}
+	if err := requireLocalExport(ctx); err != nil {
+		return err
+	}
+
+	inside, err := isInside(ctx.ExportDir, b.Output)
+	if err != nil {
+		return err
+	}
+	if inside {
+		return fmt.Errorf("the archive %s cannot be written inside the export dir %s", b.Output, ctx.ExportDir)
+	}
+
+	file, err := os.Create(b.Output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx.Logger.Infof("Packing %s into %s", ctx.ExportDir, b.Output)
+	count, err := bundle.Pack(ctx.ExportDir, file, compression)
+	if err != nil {
+		_ = os.Remove(b.Output)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Packed %d files into %s\n", count, b.Output)
+
+	return nil
+}
+
+// Run extracts every file of the bundle into the export dir, which must be empty
+func (b *BundleUnpack) Run(ctx *context.Context) error {
+	if ctx.BundleFile == "" {
+		return errors.New("the path of the archive to unpack is required")
+	}
+	if err := requireLocalExport(ctx); err != nil {
+		return err
+	}
+
+	empty, err := ctx.DirWriter.IsEmpty(".")
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fmt.Errorf("the export dir %s is not empty", ctx.ExportDir)
+	}
+
+	file, err := os.Open(ctx.BundleFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx.Logger.Infof("Unpacking %s into %s", ctx.BundleFile, ctx.ExportDir)
+	count, err := bundle.Unpack(file, ctx.ExportDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Unpacked %d files into %s\n", count, ctx.ExportDir)
+
+	return nil
+}
+
+// requireLocalExport fails for exports held in an object store, bundles are made from and into local directories
+func requireLocalExport(ctx *context.Context) error {
+	if storage.IsRemote(ctx.ExportDir) {
+		return fmt.Errorf("the export dir %s must be a local directory", ctx.ExportDir)
+	}
+	return nil
+}
+
+func isInside(dir, path string) (bool, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false, nil
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// rewriteExport calls rewrite for every file in the export dir that is encrypted or not, as asked for.
+// The metadata, journal and secrets files are never rewritten, they are either needed in plain text or already encrypted.
+func rewriteExport(dir string, encrypted bool, rewrite func(path string) error) (int, error) {
+	var count int
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() || keptPlain(dir, path) {
+			return nil
+		}
+
+		isEncrypted, err := isEncryptedFile(path)
+		if err != nil {
+			return err
+		}
+		if isEncrypted != encrypted {
+			return nil
+		}
+
+		if err = rewrite(path); err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", path, err)
+		}
+		count++
+
+		return nil
+	})
+
+	return count, err
+}
+
+// keptPlain reports whether path is one of the files at the top of the export dir that bundle seal leaves alone
+func keptPlain(dir, path string) bool {
+	if filepath.Dir(path) != filepath.Clean(dir) {
+		return false
+	}
+	name := filepath.Base(path)
+	return name == "metadata.json" || name == secrets.FileName || (strings.HasPrefix(name, "journal-") && strings.HasSuffix(name, ".jsonl"))
+}
+
+func isEncryptedFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, crypt.StreamMagicSize())
+	n, err := io.ReadFull(file, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	return crypt.IsEncryptedStream(magic[:n]), nil
+}
+
+// rewriteFile replaces the file at path with a copy read with opener and written with sealer, keeping its permissions
+func rewriteFile(path string, opener crypt.Opener, sealer crypt.Sealer) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	src, err := aio.OpenFile(path, opener)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".tmp"
+	dst, err := aio.CreateFile(tmp, sealer)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer dst.Close()
+
+	if _, err = io.Copy(dst, src); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,206 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/bundle"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func writeExport(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"metadata.json":                               `{"last_run_times":{}}`,
+		"journal-20220728T153000Z.jsonl":              `{"action":"create-app"}`,
+		secrets.FileName:                              "already encrypted",
+		"my_org/my_space/my_app_manifest.yml":         "applications:\n- name: my_app\n",
+		"my_org/my_space/my_app.tgz":                  "droplet",
+		"my_org/my_space/my_app_autoscale_rules.json": "[]",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir, files
+}
+
+func TestBundleSealOpen_Run(t *testing.T) {
+	id, err := crypt.GenerateIdentity()
+	require.NoError(t, err)
+	recipient, err := id.Recipient()
+	require.NoError(t, err)
+	key := crypt.NewPassphraseKey("correct horse battery staple")
+
+	tests := []struct {
+		name   string
+		sealer crypt.Sealer
+		opener crypt.Opener
+	}{
+		{
+			name:   "sealed for a recipient and opened with its identity",
+			sealer: recipient,
+			opener: id,
+		},
+		{
+			name:   "sealed and opened with a key",
+			opener: key,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, files := writeExport(t)
+			ctx := &context.Context{ExportDir: dir, Logger: log.New(), Sealer: tt.sealer, Opener: tt.opener}
+
+			require.NoError(t, (&BundleSeal{}).Run(ctx))
+			for name, content := range files {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				if strings.HasPrefix(name, "my_org") {
+					assert.True(t, crypt.IsEncryptedStream(data), "%s should be encrypted", name)
+				} else {
+					assert.Equal(t, content, string(data), "%s should be left as is", name)
+				}
+			}
+			info, err := os.Stat(filepath.Join(dir, "my_org/my_space/my_app.tgz"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+			sealed, err := os.ReadFile(filepath.Join(dir, "my_org/my_space/my_app.tgz"))
+			require.NoError(t, err)
+			require.NoError(t, (&BundleSeal{}).Run(ctx))
+			resealed, err := os.ReadFile(filepath.Join(dir, "my_org/my_space/my_app.tgz"))
+			require.NoError(t, err)
+			assert.Equal(t, sealed, resealed, "encrypted files are not sealed twice")
+
+			require.NoError(t, (&BundleOpen{}).Run(ctx))
+			for name, content := range files {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				assert.Equal(t, content, string(data))
+			}
+			_, err = os.Stat(filepath.Join(dir, "my_org/my_space/my_app.tgz.tmp"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestBundleSealOpen_RunWithoutKey(t *testing.T) {
+	ctx := &context.Context{ExportDir: t.TempDir(), Logger: log.New()}
+
+	err := (&BundleSeal{}).Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is required to seal an export")
+
+	err = (&BundleOpen{}).Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is required to open an export")
+}
+
+func TestBundleOpen_RunWithWrongKey(t *testing.T) {
+	dir, _ := writeExport(t)
+	ctx := &context.Context{ExportDir: dir, Logger: log.New(), Opener: crypt.NewPassphraseKey("correct horse battery staple")}
+	require.NoError(t, (&BundleSeal{}).Run(ctx))
+
+	ctx.Opener = crypt.NewPassphraseKey("another passphrase")
+	require.Error(t, (&BundleOpen{}).Run(ctx))
+
+	data, err := os.ReadFile(filepath.Join(dir, "my_org/my_space/my_app_manifest.yml"))
+	require.NoError(t, err)
+	assert.True(t, crypt.IsEncryptedStream(data), "a file that fails to decrypt is left encrypted")
+}
+
+func packTestdata(t *testing.T) *bundle.Archive {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "export.tar")
+	require.NoError(t, (&BundlePack{Output: archive}).Run(&context.Context{ExportDir: "testdata/apps", Logger: log.New()}))
+
+	a, err := bundle.Open(archive)
+	require.NoError(t, err)
+	return a
+}
+
+func TestBundlePackUnpack_Run(t *testing.T) {
+	for _, output := range []string{"export.tar", "export.tar.gz", "export.tar.zst"} {
+		t.Run(output, func(t *testing.T) {
+			dir, files := writeExport(t)
+			archive := filepath.Join(t.TempDir(), output)
+			require.NoError(t, (&BundlePack{Output: archive}).Run(&context.Context{ExportDir: dir, Logger: log.New()}))
+
+			a, err := bundle.Open(archive)
+			require.NoError(t, err)
+			assert.Len(t, a.Entries(), len(files))
+
+			target := filepath.Join(t.TempDir(), "export")
+			ctx := &context.Context{ExportDir: target, BundleFile: archive, DirWriter: storage.NewLocal(target), Logger: log.New()}
+			require.NoError(t, (&BundleUnpack{}).Run(ctx))
+			for name, content := range files {
+				data, err := os.ReadFile(filepath.Join(target, name))
+				require.NoError(t, err)
+				assert.Equal(t, content, string(data))
+			}
+
+			err = (&BundleUnpack{}).Run(ctx)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "is not empty")
+		})
+	}
+}
+
+func TestBundlePack_RunErrors(t *testing.T) {
+	dir, _ := writeExport(t)
+	ctx := &context.Context{ExportDir: dir, Logger: log.New()}
+
+	err := (&BundlePack{Output: filepath.Join(dir, "export.tar")}).Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be written inside the export dir")
+
+	err = (&BundlePack{Output: filepath.Join(t.TempDir(), "export.tar"), Compression: "xz"}).Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported compression "xz"`)
+}
+
+func TestBundleKeygen_Run(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "migrator.key")
+	stdout := &bytes.Buffer{}
+	keygen := &BundleKeygen{Output: output, Stdout: stdout}
+
+	require.NoError(t, keygen.Run(&context.Context{}))
+
+	info, err := os.Stat(output)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	id, err := crypt.LoadIdentity(output)
+	require.NoError(t, err)
+	recipient, err := id.Recipient()
+	require.NoError(t, err)
+	assert.Equal(t, "Recipient: "+recipient.String()+"\n", stdout.String())
+
+	require.Error(t, keygen.Run(&context.Context{}), "an existing identity file is not overwritten")
+}
@@ -0,0 +1,178 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+)
+
+// Policies for an app that already exists in the target space
+const (
+	ConflictUpdate = "update"
+	ConflictSkip   = "skip"
+	ConflictFail   = "fail"
+	ConflictRename = "rename"
+
+	DefaultConflictSuffix = "-migrated"
+
+	// OwnerAnnotation marks the apps created by app-migrator on the target foundation
+	OwnerAnnotation      = "app-migrator.tanzu.vmware.com/created-by"
+	OwnerAnnotationValue = "app-migrator"
+)
+
+// ConflictPolicies lists every supported conflict policy
+var ConflictPolicies = []string{ConflictUpdate, ConflictSkip, ConflictFail, ConflictRename}
+
+// ErrAppSkipped is returned when an existing app is left untouched because of the conflict policy
+var ErrAppSkipped = errors.New("app already exists")
+
+// AppExistsError is returned when an app already exists in the target space and cannot be updated
+type AppExistsError struct {
+	Org    string
+	Space  string
+	App    string
+	Reason string
+}
+
+func (e *AppExistsError) Error() string {
+	return fmt.Sprintf("app %s already exists in %s/%s, %s", e.App, e.Org, e.Space, e.Reason)
+}
+
+// IsValidConflictPolicy reports whether policy is one of the supported conflict policies
+func IsValidConflictPolicy(policy string) bool {
+	for _, p := range ConflictPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveConflict applies the conflict policy to an app that already exists in the target space. It returns the
+// app to update, or an empty app when a new one should be created, after renaming app when the policy is rename.
+func (i *ImportApp) resolveConflict(ctx *appcontext.Context, space cfclient.Space, existing cfclient.App, app *export.Application) (cfclient.App, error) {
+	policy := ctx.OnConflict
+	if policy == "" {
+		policy = ConflictUpdate
+	}
+
+	switch policy {
+	case ConflictUpdate:
+	case ConflictSkip:
+		ctx.Logger.Infof("App %s/%s/%s already exists, skipping it", i.Org, i.Space, app.Name)
+		return cfclient.App{}, ErrAppSkipped
+	case ConflictFail:
+		return cfclient.App{}, &AppExistsError{Org: i.Org, Space: i.Space, App: app.Name, Reason: "refusing to change it"}
+	case ConflictRename:
+		suffix := ctx.ConflictSuffix
+		if suffix == "" {
+			suffix = DefaultConflictSuffix
+		}
+		original := app.Name
+		renameApp(app, suffix)
+		ctx.Logger.Infof("App %s/%s/%s already exists, importing it as %s", i.Org, i.Space, original, app.Name)
+
+		var err error
+		existing, err = cache.GetCache(ctx.ImportCFClient).GetAppByName(app.Name, space.Guid)
+		if err != nil {
+			if cache.IsNotFound(err) {
+				return cfclient.App{}, nil
+			}
+			return cfclient.App{}, err
+		}
+		if existing.Guid == "" {
+			return cfclient.App{}, nil
+		}
+	default:
+		return cfclient.App{}, fmt.Errorf("unknown conflict policy %q, expected one of %s", policy, strings.Join(ConflictPolicies, ", "))
+	}
+
+	if ctx.UpdateUnownedApps {
+		return existing, nil
+	}
+
+	owned, err := isOwnedByMigrator(ctx, existing.Guid)
+	if err != nil {
+		return cfclient.App{}, err
+	}
+	if !owned {
+		return cfclient.App{}, &AppExistsError{Org: i.Org, Space: i.Space, App: app.Name, Reason: "it was not created by app-migrator"}
+	}
+
+	return existing, nil
+}
+
+// renameApp appends suffix to the app name and to the host of each of its routes, so that the
+// renamed app does not receive the traffic of the app it is deployed next to
+func renameApp(app *export.Application, suffix string) {
+	app.Name += suffix
+	for idx, r := range app.Routes {
+		hostParts := strings.SplitN(r.Route, ".", 2)
+		if len(hostParts) < 2 {
+			continue
+		}
+		app.Routes[idx].Route = hostParts[0] + suffix + "." + hostParts[1]
+	}
+}
+
+// isOwnedByMigrator reports whether the app carries the annotation app-migrator sets on the apps it creates
+func isOwnedByMigrator(ctx *appcontext.Context, appGUID string) (bool, error) {
+	var v3app cfclient.V3App
+	err := withRetry(ctx, func() error {
+		body, err := ctx.ImportCFClient.Get(fmt.Sprintf("/v3/apps/%s", appGUID))
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &v3app)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return v3app.Metadata.Annotations[OwnerAnnotation] == OwnerAnnotationValue, nil
+}
+
+// annotateOwner marks an app as created by app-migrator so that later imports are allowed to update it
+func annotateOwner(ctx *appcontext.Context, appGUID string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": cfclient.V3Metadata{
+			Annotations: map[string]string{OwnerAnnotation: OwnerAnnotationValue},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return withRetry(ctx, func() error {
+		req := ctx.ImportCFClient.NewRequestWithBody(http.MethodPatch, fmt.Sprintf("/v3/apps/%s", appGUID), bytes.NewReader(data))
+		resp, err := ctx.ImportCFClient.DoRequest(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return nil
+	})
+}
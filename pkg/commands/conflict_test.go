@@ -0,0 +1,210 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
+)
+
+func TestImportApp_createAppOnConflict(t *testing.T) {
+	existingApp := cfclient.App{
+		Guid:  "4b2ac8e4-5ee1-4e4c-8a37-8f9a8e1dd4cd",
+		Name:  "my_app",
+		State: "STARTED",
+	}
+	pwd, _ := os.Getwd()
+	tests := []struct {
+		name             string
+		onConflict       string
+		updateUnowned    bool
+		annotations      map[string]string
+		wantErr          string
+		wantCreatedApp   string
+		wantCreatedHost  string
+		wantUpdatedApp   string
+		wantSuccessCount int
+	}{
+		{
+			name:             "updates an app created by app-migrator by default",
+			annotations:      map[string]string{OwnerAnnotation: OwnerAnnotationValue},
+			wantUpdatedApp:   existingApp.Guid,
+			wantSuccessCount: 1,
+		},
+		{
+			name:       "refuses to update an app not created by app-migrator",
+			onConflict: ConflictUpdate,
+			wantErr:    "app my_app already exists in my_org/my_space, it was not created by app-migrator",
+		},
+		{
+			name:             "updates an app not created by app-migrator when asked to",
+			onConflict:       ConflictUpdate,
+			updateUnowned:    true,
+			wantUpdatedApp:   existingApp.Guid,
+			wantSuccessCount: 1,
+		},
+		{
+			name:       "skips an existing app",
+			onConflict: ConflictSkip,
+			wantErr:    ErrAppSkipped.Error(),
+		},
+		{
+			name:        "fails on an existing app",
+			onConflict:  ConflictFail,
+			annotations: map[string]string{OwnerAnnotation: OwnerAnnotationValue},
+			wantErr:     "app my_app already exists in my_org/my_space, refusing to change it",
+		},
+		{
+			name:             "imports an existing app side-by-side with a suffix",
+			onConflict:       ConflictRename,
+			wantCreatedApp:   "my_app-migrated",
+			wantCreatedHost:  "a-hostname-migrated",
+			wantSuccessCount: 1,
+		},
+		{
+			name:       "rejects an unknown policy",
+			onConflict: "merge",
+			wantErr:    `unknown conflict policy "merge", expected one of update, skip, fail, rename`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+			fakeClient := &fakes.FakeClient{
+				ListAppsByQueryStub: func(values url.Values) ([]cfclient.App, error) {
+					if values.Get("q") == "name:"+existingApp.Name {
+						return []cfclient.App{existingApp}, nil
+					}
+					return []cfclient.App{}, nil
+				},
+				GetOrgByNameStub: func(s string) (cfclient.Org, error) {
+					return cfclient.Org{Name: "my_org"}, nil
+				},
+				GetSpaceByNameStub: func(string, string) (cfclient.Space, error) {
+					return cfclient.Space{Name: "my_space"}, nil
+				},
+				CreateAppStub: func(req cfclient.AppCreateRequest) (cfclient.App, error) {
+					return cfclient.App{Guid: "0f2a9a3c-7f1b-4b5e-b5d1-7cf8c2d2a2a1", Name: req.Name}, nil
+				},
+				UpdateAppStub: func(guid string, req cfclient.AppUpdateResource) (cfclient.UpdateResponse, error) {
+					resp := cfclient.UpdateResponse{}
+					resp.Metadata.Guid = guid
+					resp.Entity.Name = req.Name
+					return resp, nil
+				},
+				CreateRouteStub: func(cfclient.RouteRequest) (cfclient.Route, error) {
+					return cfclient.Route{}, nil
+				},
+				ListUserProvidedServiceInstancesByQueryStub: func(url.Values) ([]cfclient.UserProvidedServiceInstance, error) {
+					return []cfclient.UserProvidedServiceInstance{{Name: "name-1508"}}, nil
+				},
+				DoRequestStub: func(*cfclient.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader("{}")),
+					}, nil
+				},
+			}
+			ctx := &context.Context{
+				Logger:            log.New(),
+				ExportDir:         filepath.Join(pwd, "testdata/apps"),
+				Metadata:          metadata.NewMetadata(),
+				Summary:           report.NewSummary(&bytes.Buffer{}),
+				OnConflict:        tt.onConflict,
+				UpdateUnownedApps: tt.updateUnowned,
+				ImportCFClient: StubClient{
+					FakeClient: fakeClient,
+					DoWithRetryFunc: func(f func() error) error {
+						return f()
+					},
+					GetFunc: func(url string) ([]byte, error) {
+						assert.Equal(t, "/v3/apps/"+existingApp.Guid, url)
+						return json.Marshal(cfclient.V3App{
+							GUID:     existingApp.Guid,
+							Metadata: cfclient.V3Metadata{Annotations: tt.annotations},
+						})
+					},
+				},
+			}
+
+			i := &ImportApp{
+				ImportSpace: ImportSpace{
+					ImportOrg: ImportOrg{Org: "my_org"},
+					Space:     "my_space",
+				},
+				AppName: "my_app",
+			}
+			err := i.createApp(ctx)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantSuccessCount, ctx.Summary.AppSuccessCount())
+			assert.Equal(t, tt.wantUpdatedApp != "", i.runningApp, "the data movers only run for the created apps")
+
+			if tt.wantCreatedApp != "" {
+				assert.Equal(t, 1, fakeClient.CreateAppCallCount())
+				assert.Equal(t, tt.wantCreatedApp, fakeClient.CreateAppArgsForCall(0).Name)
+				assert.Equal(t, tt.wantCreatedHost, fakeClient.CreateRouteArgsForCall(0).Host)
+				method, path, body := fakeClient.NewRequestWithBodyArgsForCall(0)
+				annotation, _ := io.ReadAll(body)
+				assert.Equal(t, http.MethodPatch, method)
+				assert.Equal(t, "/v3/apps/0f2a9a3c-7f1b-4b5e-b5d1-7cf8c2d2a2a1", path)
+				assert.JSONEq(t, `{"metadata":{"annotations":{"app-migrator.tanzu.vmware.com/created-by":"app-migrator"}}}`, string(annotation))
+			} else {
+				assert.Equal(t, 0, fakeClient.CreateAppCallCount())
+			}
+
+			if tt.wantUpdatedApp != "" {
+				assert.Equal(t, 1, fakeClient.UpdateAppCallCount())
+				guid, _ := fakeClient.UpdateAppArgsForCall(0)
+				assert.Equal(t, tt.wantUpdatedApp, guid)
+			} else {
+				assert.Equal(t, 0, fakeClient.UpdateAppCallCount())
+			}
+		})
+	}
+}
+
+func TestRenameApp(t *testing.T) {
+	app := export.Application{Name: "my_app"}
+	app.Routes = append(app.Routes, export.Route{Route: "my-host.apps.example.com/path"})
+
+	renameApp(&app, "-v2")
+
+	assert.Equal(t, "my_app-v2", app.Name)
+	assert.Equal(t, "my-host-v2.apps.example.com/path", app.Routes[0].Route)
+}
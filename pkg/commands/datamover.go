@@ -0,0 +1,223 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/datamover"
+)
+
+// credentialsFunc returns the credentials of the binding of the service instance to the app on the source foundation
+type credentialsFunc func(serviceInstance string) (map[string]interface{}, error)
+
+// moveServiceData runs the data movers of the offerings of the managed service instances bound to the imported app,
+// one after the other, while the app is stopped. Each data mover is given the credentials of the binding on the source
+// foundation, from sourceCredentials, and on the target foundation. The runs are added to the data mover report, the
+// first one that fails fails the import of the app. The data movers are skipped for apps that were already running on
+// the target foundation, e.g. when importing again, unless ctx.MoveRunningAppData is set. The data of a service
+// instance bound to several apps is only moved with the first of them, it is skipped for the others.
+func (i *ImportApp) moveServiceData(ctx *context.Context, sourceCredentials credentialsFunc) error {
+	if len(ctx.DataMovers) == 0 || len(i.services) == 0 {
+		return nil
+	}
+
+	c := cache.GetCache(ctx.ImportCFClient)
+	org, err := c.GetOrgByName(i.targetOrg(ctx))
+	if err != nil {
+		return err
+	}
+	space, err := c.GetSpaceByName(i.targetSpace(ctx), org.Guid)
+	if err != nil {
+		return err
+	}
+
+	var bindings []cf.ServiceCredentialBinding
+	err = withRetry(ctx, func() (err error) {
+		bindings, err = cf.ListAppServiceBindings(ctx.ImportCFClient, i.appGUID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range i.services {
+		var si *cf.ServiceInstance
+		err = withRetry(ctx, func() (err error) {
+			si, err = cf.GetServiceInstanceByName(ctx.ImportCFClient, s.Name, space.Guid)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if si == nil {
+			return &cache.ServiceInstanceNotFoundError{ServiceInstanceName: s.Name}
+		}
+		if !si.IsManaged() {
+			continue
+		}
+
+		var plan cf.ServicePlan
+		err = withRetry(ctx, func() (err error) {
+			plan, err = cf.GetServicePlan(ctx.ImportCFClient, si.PlanGUID())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		offering := plan.Offering
+		mover, ok := ctx.DataMovers[offering]
+		if !ok {
+			continue
+		}
+
+		if i.runningApp && !ctx.MoveRunningAppData {
+			message := "the app already existed on the target foundation and is not stopped, pass --data-movers-on-running-apps to overwrite its data"
+			ctx.Logger.Warnf("Not moving the data of service %s of app %s/%s/%s: %s", s.Name, org.Name, space.Name, i.targetName, message)
+			if ctx.DataMoves != nil {
+				ctx.DataMoves.Add(org.Name, space.Name, i.targetName, s.Name, offering, datamover.Skipped, message)
+			}
+			continue
+		}
+
+		if claimedBy, ok := ctx.MovedInstances.Claim(org.Name, space.Name, si.Name, i.targetName); !ok {
+			message := fmt.Sprintf("the data of the service instance is moved with app %s", claimedBy)
+			ctx.Logger.Infof("Not moving the data of service %s of app %s/%s/%s: %s", s.Name, org.Name, space.Name, i.targetName, message)
+			if ctx.DataMoves != nil {
+				ctx.DataMoves.Add(org.Name, space.Name, i.targetName, s.Name, offering, datamover.Skipped, message)
+			}
+			continue
+		}
+
+		bindingGUID := ""
+		for _, b := range bindings {
+			if b.Relationships.ServiceInstance.Data.GUID == si.GUID {
+				bindingGUID = b.GUID
+				break
+			}
+		}
+		if bindingGUID == "" {
+			return fmt.Errorf("app %s is not bound to service %s", i.targetName, s.Name)
+		}
+
+		req := datamover.Request{
+			Offering: offering,
+			Plan:     plan.Name,
+			Source:   datamover.Binding{Org: i.Org, Space: i.Space, App: i.sourceName, ServiceInstance: s.Name},
+			Target:   datamover.Binding{Org: org.Name, Space: space.Name, App: i.targetName, ServiceInstance: si.Name},
+		}
+		req.Source.Credentials, err = sourceCredentials(s.Name)
+		if err != nil {
+			return err
+		}
+		err = withRetry(ctx, func() (err error) {
+			req.Target.Credentials, err = cf.GetServiceCredentialBindingCredentials(ctx.ImportCFClient, bindingGUID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		ctx.Logger.Infof("Moving the data of service %s of app %s/%s/%s with %s", s.Name, org.Name, space.Name, i.targetName, mover)
+		result, err := datamover.Run(mover, req, ctx.DataMoverTimeout)
+		if result.Status == "" {
+			result.Status = datamover.Failed
+			if err != nil {
+				result.Message = err.Error()
+			}
+		}
+		if ctx.DataMoves != nil {
+			ctx.DataMoves.Add(org.Name, space.Name, i.targetName, s.Name, offering, result.Status, result.Message)
+		}
+		if err != nil {
+			return fmt.Errorf("could not move the data of service %s: %w", s.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// exportedCredentials returns the credentials of the binding of the service instance to the app on the source
+// foundation, which were stored in the secrets file on export
+func (i *ImportApp) exportedCredentials(ctx *context.Context) credentialsFunc {
+	return func(serviceInstance string) (map[string]interface{}, error) {
+		value, ok := ctx.Secrets.Value(datamover.CredentialsSecret(i.Org, i.Space, i.sourceName, serviceInstance).Name())
+		if !ok {
+			return nil, fmt.Errorf("the credentials of the binding of service %s on the source foundation were not exported, export the app with --externalize-secrets and the data mover of its offering in --data-movers", serviceInstance)
+		}
+
+		var credentials map[string]interface{}
+		err := json.Unmarshal([]byte(value), &credentials)
+		return credentials, err
+	}
+}
+
+// sourceBindingCredentials returns the credentials of the binding of the service instance to the app on the source
+// foundation, read from the source foundation. The bindings of the app are only looked up on the first call.
+func sourceBindingCredentials(ctx *context.Context, app cfclient.App) credentialsFunc {
+	var bindings map[string]string
+	return func(serviceInstance string) (map[string]interface{}, error) {
+		if bindings == nil {
+			var err error
+			if bindings, err = sourceBindings(ctx, app.Guid); err != nil {
+				return nil, err
+			}
+		}
+
+		bindingGUID, ok := bindings[serviceInstance]
+		if !ok {
+			return nil, fmt.Errorf("app %s is not bound to service %s on the source foundation", app.Name, serviceInstance)
+		}
+
+		var credentials map[string]interface{}
+		err := withSourceRetry(ctx, func() (err error) {
+			credentials, err = cf.GetServiceCredentialBindingCredentials(ctx.ExportCFClient, bindingGUID)
+			return err
+		})
+		return credentials, err
+	}
+}
+
+// sourceBindings returns the guids of the bindings of the app on the source foundation, by service instance name
+func sourceBindings(ctx *context.Context, appGUID string) (map[string]string, error) {
+	var bindings []cf.ServiceCredentialBinding
+	err := withSourceRetry(ctx, func() (err error) {
+		bindings, err = cf.ListAppServiceBindings(ctx.ExportCFClient, appGUID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	guids := make(map[string]string, len(bindings))
+	for _, b := range bindings {
+		var si cf.ServiceInstance
+		err = withSourceRetry(ctx, func() (err error) {
+			si, err = cf.GetServiceInstance(ctx.ExportCFClient, b.Relationships.ServiceInstance.Data.GUID)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		guids[si.Name] = b.GUID
+	}
+
+	return guids, nil
+}
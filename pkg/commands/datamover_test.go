@@ -0,0 +1,252 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/datamover"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+)
+
+func TestImportApp_moveServiceData(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     string
+		exported   bool
+		runningApp bool
+		moveData   bool
+		claimedBy  string
+		wantMoves  []report.DataMove
+		wantErr    string
+	}{
+		{
+			name:     "runs the data movers of the offerings of the bound service instances",
+			result:   `{"status": "succeeded", "message": "copied 3 tables"}`,
+			exported: true,
+			wantMoves: []report.DataMove{
+				{Org: "my_org", Space: "my_space", App: "my_app", ServiceInstance: "my-db", Offering: "p.mysql", Status: "succeeded", Message: "copied 3 tables"},
+			},
+		},
+		{
+			name:     "fails when a data mover fails",
+			result:   `{"status": "failed", "message": "table locked"}`,
+			exported: true,
+			wantMoves: []report.DataMove{
+				{Org: "my_org", Space: "my_space", App: "my_app", ServiceInstance: "my-db", Offering: "p.mysql", Status: "failed", Message: "table locked"},
+			},
+			wantErr: "could not move the data of service my-db",
+		},
+		{
+			name:       "skips the data movers of apps that were already running on the target",
+			result:     `{"status": "succeeded"}`,
+			exported:   true,
+			runningApp: true,
+			wantMoves: []report.DataMove{
+				{Org: "my_org", Space: "my_space", App: "my_app", ServiceInstance: "my-db", Offering: "p.mysql", Status: "skipped", Message: "the app already existed on the target foundation and is not stopped, pass --data-movers-on-running-apps to overwrite its data"},
+			},
+		},
+		{
+			name:       "runs the data movers of apps that were already running when asked to",
+			result:     `{"status": "succeeded", "message": "copied 3 tables"}`,
+			exported:   true,
+			runningApp: true,
+			moveData:   true,
+			wantMoves: []report.DataMove{
+				{Org: "my_org", Space: "my_space", App: "my_app", ServiceInstance: "my-db", Offering: "p.mysql", Status: "succeeded", Message: "copied 3 tables"},
+			},
+		},
+		{
+			name:      "skips the data movers of service instances moved with another app",
+			result:    `{"status": "succeeded"}`,
+			exported:  true,
+			claimedBy: "other_app",
+			wantMoves: []report.DataMove{
+				{Org: "my_org", Space: "my_space", App: "my_app", ServiceInstance: "my-db", Offering: "p.mysql", Status: "skipped", Message: "the data of the service instance is moved with app other_app"},
+			},
+		},
+		{
+			name:    "fails when the credentials of the source binding were not exported",
+			wantErr: "the credentials of the binding of service my-db on the source foundation were not exported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+
+			dir := t.TempDir()
+			mover := filepath.Join(dir, "mover")
+			request := filepath.Join(dir, "request.json")
+			err := os.WriteFile(mover, []byte("#!/bin/sh\ncat > "+request+"\necho '"+tt.result+"'\n"), 0o755)
+			require.NoError(t, err)
+
+			client := &fakes.FakeClient{
+				DoWithRetryStub: func(f func() error) error {
+					return f()
+				},
+				GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+					return cfclient.Org{Guid: "org-guid", Name: name}, nil
+				},
+				GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+					return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+				},
+				GetStub: func(path string) ([]byte, error) {
+					switch path {
+					case "/v3/service_credential_bindings?app_guids=app-guid&type=app":
+						return []byte(`{"pagination": {}, "resources": [
+  {"guid": "db-binding-guid", "type": "app", "relationships": {"service_instance": {"data": {"guid": "db-guid"}}}},
+  {"guid": "cache-binding-guid", "type": "app", "relationships": {"service_instance": {"data": {"guid": "cache-guid"}}}},
+  {"guid": "config-binding-guid", "type": "app", "relationships": {"service_instance": {"data": {"guid": "config-guid"}}}}
+]}`), nil
+					case "/v3/service_instances?" + url.Values{"names": []string{"my-db"}, "space_guids": []string{"space-guid"}}.Encode():
+						return []byte(`{"pagination": {}, "resources": [{"guid": "db-guid", "name": "my-db", "type": "managed", "relationships": {"service_plan": {"data": {"guid": "db-plan-guid"}}}}]}`), nil
+					case "/v3/service_instances?" + url.Values{"names": []string{"my-cache"}, "space_guids": []string{"space-guid"}}.Encode():
+						return []byte(`{"pagination": {}, "resources": [{"guid": "cache-guid", "name": "my-cache", "type": "managed", "relationships": {"service_plan": {"data": {"guid": "cache-plan-guid"}}}}]}`), nil
+					case "/v3/service_instances?" + url.Values{"names": []string{"my-config"}, "space_guids": []string{"space-guid"}}.Encode():
+						return []byte(`{"pagination": {}, "resources": [{"guid": "config-guid", "name": "my-config", "type": "user-provided"}]}`), nil
+					case "/v3/service_plans/db-plan-guid?fields%5Bservice_offering.service_broker%5D=name&include=service_offering":
+						return []byte(`{"name": "db-small", "included": {"service_offerings": [{"name": "p.mysql"}]}}`), nil
+					case "/v3/service_plans/cache-plan-guid?fields%5Bservice_offering.service_broker%5D=name&include=service_offering":
+						return []byte(`{"name": "cache-small", "included": {"service_offerings": [{"name": "p.redis"}]}}`), nil
+					case "/v3/service_credential_bindings/db-binding-guid/details":
+						return []byte(`{"credentials": {"uri": "mysql://target"}}`), nil
+					}
+					return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+				},
+			}
+			ctx := &context.Context{
+				Logger:             log.New(),
+				ImportCFClient:     client,
+				DataMovers:         map[string]string{"p.mysql": mover},
+				DataMoves:          report.NewDataMoves(nil),
+				Secrets:            secrets.New(crypt.NewPassphraseKey("passphrase"), nil),
+				MoveRunningAppData: tt.moveData,
+				MovedInstances:     datamover.NewInstances(),
+			}
+			if tt.claimedBy != "" {
+				ctx.MovedInstances.Claim("my_org", "my_space", "my-db", tt.claimedBy)
+			}
+			if tt.exported {
+				require.NoError(t, ctx.Secrets.Add(datamover.CredentialsSecret("my_org", "my_space", "my_app", "my-db"), `{"uri": "mysql://source"}`))
+			}
+
+			i := &ImportApp{
+				ImportSpace: ImportSpace{ImportOrg: ImportOrg{Org: "my_org"}, Space: "my_space"},
+				AppName:     "my_app",
+				appGUID:     "app-guid",
+				services:    []export.Service{{Name: "my-db"}, {Name: "my-cache"}, {Name: "my-config"}},
+				sourceName:  "my_app",
+				targetName:  "my_app",
+				runningApp:  tt.runningApp,
+			}
+			err = i.moveServiceData(ctx, i.exportedCredentials(ctx))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantMoves, ctx.DataMoves.Moves())
+			if !tt.exported || tt.wantMoves[0].Status == datamover.Skipped {
+				assert.NoFileExists(t, request)
+				return
+			}
+
+			data, err := os.ReadFile(request)
+			require.NoError(t, err)
+			var req datamover.Request
+			require.NoError(t, json.Unmarshal(data, &req))
+			assert.Equal(t, datamover.Request{
+				Offering: "p.mysql",
+				Plan:     "db-small",
+				Source:   datamover.Binding{Org: "my_org", Space: "my_space", App: "my_app", ServiceInstance: "my-db", Credentials: map[string]interface{}{"uri": "mysql://source"}},
+				Target:   datamover.Binding{Org: "my_org", Space: "my_space", App: "my_app", ServiceInstance: "my-db", Credentials: map[string]interface{}{"uri": "mysql://target"}},
+			}, req)
+		})
+	}
+}
+
+func TestSourceBindingCredentials(t *testing.T) {
+	calls := make(map[string]int)
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			for {
+				if err := f(); !errors.Is(err, cf.ErrRetry) {
+					return err
+				}
+			}
+		},
+		GetStub: func(path string) ([]byte, error) {
+			calls[path]++
+			if calls[path] == 1 {
+				return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusBadGateway}
+			}
+			switch path {
+			case "/v3/service_credential_bindings?app_guids=app-guid&type=app":
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "db-binding-guid", "type": "app", "relationships": {"service_instance": {"data": {"guid": "db-guid"}}}},
+  {"guid": "cache-binding-guid", "type": "app", "relationships": {"service_instance": {"data": {"guid": "cache-guid"}}}}
+]}`), nil
+			case "/v3/service_instances/db-guid":
+				return []byte(`{"guid": "db-guid", "name": "my-db", "type": "managed"}`), nil
+			case "/v3/service_instances/cache-guid":
+				return []byte(`{"guid": "cache-guid", "name": "my-cache", "type": "managed"}`), nil
+			case "/v3/service_credential_bindings/db-binding-guid/details":
+				return []byte(`{"credentials": {"uri": "mysql://source"}}`), nil
+			case "/v3/service_credential_bindings/cache-binding-guid/details":
+				return []byte(`{"credentials": {"uri": "redis://source"}}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{Logger: log.New(), ExportCFClient: client}
+
+	credentials := sourceBindingCredentials(ctx, cfclient.App{Guid: "app-guid", Name: "my_app"})
+	got, err := credentials("my-db")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"uri": "mysql://source"}, got)
+	got, err = credentials("my-cache")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"uri": "redis://source"}, got)
+	_, err = credentials("my-config")
+	assert.EqualError(t, err, "app my_app is not bound to service my-config on the source foundation")
+
+	assert.Equal(t, map[string]int{
+		"/v3/service_credential_bindings?app_guids=app-guid&type=app": 2,
+		"/v3/service_instances/db-guid":                               2,
+		"/v3/service_instances/cache-guid":                            2,
+		"/v3/service_credential_bindings/db-binding-guid/details":     2,
+		"/v3/service_credential_bindings/cache-binding-guid/details":  2,
+	}, calls, "the bindings are looked up once and server errors are retried")
+}
@@ -30,6 +30,21 @@ type ExportAll struct {
 }
 
 func (e *ExportAll) Run(ctx *context.Context) error {
+	return forEachSourceOrg(ctx, func(org cfclient.Org) {
+		exportOrgCmd := &ExportOrg{
+			Org: org.Name,
+		}
+
+		err := exportOrgCmd.Run(ctx, org.Name)
+		if err != nil {
+			// TODO: Should we ignore errors here?
+			return
+		}
+	})
+}
+
+// forEachSourceOrg calls fn with every org of the source foundation that is included and not excluded
+func forEachSourceOrg(ctx *context.Context, fn func(org cfclient.Org)) error {
 	page := 1
 	var (
 		orgs []cfclient.Org
@@ -64,15 +79,7 @@ func (e *ExportAll) Run(ctx *context.Context) error {
 				continue
 			}
 
-			exportOrgCmd := &ExportOrg{
-				Org: org.Name,
-			}
-
-			err := exportOrgCmd.Run(ctx, org.Name)
-			if err != nil {
-				// TODO: Should we ignore errors here?
-				continue
-			}
+			fn(org)
 		}
 
 		if len(orgs) < 50 {
@@ -26,9 +26,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
-	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context/fakes"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
 
@@ -36,6 +36,7 @@ import (
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
 
 	"github.com/stretchr/testify/assert"
@@ -60,12 +61,9 @@ func TestExportAll_Run(t *testing.T) {
 			name: "returns no export error",
 			args: args{
 				&context.Context{
-					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
+					Logger:             log.New(),
+					ExportDir:          filepath.Join(pwd, "testdata/apps"),
+					DirWriter:          storage.NewLocal(t.TempDir()),
 					Metadata:           metadata.NewMetadata(),
 					Summary:            report.NewSummary(&bytes.Buffer{}),
 					DropletExporter:    export.NewDropletExporter(),
@@ -81,12 +79,9 @@ func TestExportAll_Run(t *testing.T) {
 			name: "returns no export error on retry",
 			args: args{
 				&context.Context{
-					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
+					Logger:             log.New(),
+					ExportDir:          filepath.Join(pwd, "testdata/apps"),
+					DirWriter:          storage.NewLocal(t.TempDir()),
 					Metadata:           metadata.NewMetadata(),
 					Summary:            report.NewSummary(&bytes.Buffer{}),
 					DropletExporter:    export.NewDropletExporter(),
@@ -110,12 +105,9 @@ func TestExportAll_Run(t *testing.T) {
 			name: "returns no error when orgs excluded",
 			args: args{
 				&context.Context{
-					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
+					Logger:             log.New(),
+					ExportDir:          filepath.Join(pwd, "testdata/apps"),
+					DirWriter:          storage.NewLocal(t.TempDir()),
 					Metadata:           metadata.NewMetadata(),
 					Summary:            report.NewSummary(&bytes.Buffer{}),
 					DropletExporter:    export.NewDropletExporter(),
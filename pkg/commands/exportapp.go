@@ -16,7 +16,7 @@ package commands
 
 import (
 	"fmt"
-	"path/filepath"
+	"path"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
@@ -34,7 +34,7 @@ func (e *ExportApp) SetAppName(name string) {
 }
 
 func (e *ExportApp) Run(ctx *context.Context, orgName, spaceName string) error {
-	exportDir := filepath.Join(ctx.ExportDir, orgName, spaceName)
+	exportDir := path.Join(orgName, spaceName)
 
 	if err := ctx.DirWriter.Mkdir(exportDir); err != nil {
 		return fmt.Errorf("cannot create target directory: %w", err)
@@ -57,33 +57,7 @@ func NewExportAppSequence(orgName, spaceName, appName string, exportDir string)
 		appName,
 		StepWithProgressBar(
 			func(ctx *context.Context, r Result) (Result, error) {
-				c := cache.GetCache(ctx.ExportCFClient)
-
-				var org cfclient.Org
-				org, err := c.GetOrgByName(orgName)
-				if err != nil {
-					ctx.Logger.Errorf("Error getting org by name %s/%s/%s: %v", orgName, spaceName, appName, err)
-					return nil, err
-				}
-
-				var space cfclient.Space
-				space, err = c.GetSpaceByName(spaceName, org.Guid)
-				if err != nil {
-					ctx.Logger.Errorf("Error getting space by name %s/%s/%s: %v", orgName, spaceName, appName, err)
-					return nil, err
-				}
-
-				var app cfclient.App
-				app, err = c.GetAppByName(appName, space.Guid)
-				if err != nil {
-					ctx.Logger.Errorf("Error getting app by name %s/%s/%s: %v", orgName, spaceName, appName, err)
-					return nil, err
-				}
-				return ExportAppResult{
-					org:   org,
-					space: space,
-					app:   app,
-				}, nil
+				return loadSourceApp(ctx, orgName, spaceName, appName)
 			},
 			"Loading Cache",
 		),
@@ -145,6 +119,37 @@ func NewExportAppSequence(orgName, spaceName, appName string, exportDir string)
 	)
 }
 
+// loadSourceApp looks up the org, space and app on the source foundation
+func loadSourceApp(ctx *context.Context, orgName, spaceName, appName string) (Result, error) {
+	c := cache.GetCache(ctx.ExportCFClient)
+
+	var org cfclient.Org
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		ctx.Logger.Errorf("Error getting org by name %s/%s/%s: %v", orgName, spaceName, appName, err)
+		return nil, err
+	}
+
+	var space cfclient.Space
+	space, err = c.GetSpaceByName(spaceName, org.Guid)
+	if err != nil {
+		ctx.Logger.Errorf("Error getting space by name %s/%s/%s: %v", orgName, spaceName, appName, err)
+		return nil, err
+	}
+
+	var app cfclient.App
+	app, err = c.GetAppByName(appName, space.Guid)
+	if err != nil {
+		ctx.Logger.Errorf("Error getting app by name %s/%s/%s: %v", orgName, spaceName, appName, err)
+		return nil, err
+	}
+	return ExportAppResult{
+		org:   org,
+		space: space,
+		app:   app,
+	}, nil
+}
+
 func exportPackages(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
 	// Don't export packages if app is a Docker image
 	if app.DockerImage != "" {
@@ -162,13 +167,7 @@ func exportPackages(ctx *context.Context, org cfclient.Org, space cfclient.Space
 		return nil
 	}
 
-	err = ctx.DropletExporter.DownloadDroplet(ctx, org, space, app, exportDir)
-	if err != nil {
-		ctx.Logger.Error(err)
-		return err
-	}
-
-	if err = ctx.DropletExporter.DownloadPackages(ctx, org, space, app, exportDir); err != nil {
+	if err = ctx.DropletExporter.DownloadBlobs(ctx, org, space, app, exportDir); err != nil {
 		ctx.Logger.Error(err)
 		return err
 	}
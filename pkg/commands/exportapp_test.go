@@ -21,6 +21,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -34,15 +36,18 @@ import (
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	cffakes "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cli"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExportApp_Run(t *testing.T) {
@@ -119,6 +124,7 @@ func TestExportApp_Run(t *testing.T) {
 
 func TestExportApp_RunWithHTTPServer(t *testing.T) {
 	pwd, _ := os.Getwd()
+	exportDir := t.TempDir()
 	logger := log.New()
 	tests := []struct {
 		name             string
@@ -136,13 +142,9 @@ func TestExportApp_RunWithHTTPServer(t *testing.T) {
 			name:    "success",
 			Handler: ExportTestHandler(t),
 			Context: &context.Context{
-				Logger:    logger,
-				ExportDir: filepath.Join(pwd, "testdata/apps"),
-				DirWriter: &fakes.FakeDirWriter{
-					MkdirStub: func(s string) error {
-						return nil
-					},
-				},
+				Logger:             logger,
+				ExportDir:          exportDir,
+				DirWriter:          storage.NewLocal(exportDir),
 				Metadata:           metadata.NewMetadata(),
 				Summary:            report.NewSummary(&bytes.Buffer{}),
 				AutoScalerExporter: export.NewAutoScalerExporter(),
@@ -204,6 +206,42 @@ func TestExportApp_RunWithHTTPServer(t *testing.T) {
 	}
 }
 
+func TestExportApp_RunToObjectStore(t *testing.T) {
+	bucket := NewFakeS3(t, "exports")
+	t.Setenv("AWS_ACCESS_KEY_ID", "some-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "some-secret")
+	t.Setenv(storage.S3EndpointEnv, bucket.URL)
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	ts := httptest.NewServer(ExportTestHandler(t))
+	defer ts.Close()
+	ctx := &context.Context{
+		ExportDir:          "s3://exports/prod",
+		ExportCFClient:     NewTestCFClient(t, ts),
+		Metadata:           metadata.NewMetadata(),
+		Summary:            report.NewSummary(&bytes.Buffer{}),
+		AutoScalerExporter: export.NewAutoScalerExporter(),
+		DropletExporter:    export.NewDropletExporter(),
+		ManifestExporter:   export.NewManifestExporter(),
+	}
+	require.NoError(t, cli.PreRunOpenStorage(ctx))
+
+	executeTests(t, TestCase{
+		Context:          ctx,
+		Org:              "my_org",
+		Space:            "my_space",
+		ShouldNotContain: "Error occurred",
+		Assertion:        SuccessfulAppCount(t, 1),
+	})
+
+	assert.Contains(t, bucket.Keys(), "prod/my_org/my_space/my_app_manifest.yml")
+	manifest, err := fs.ReadFile(ctx.ExportFS(), "my_org/my_space/my_app_manifest.yml")
+	require.NoError(t, err)
+	assert.Contains(t, string(manifest), "name: my_app")
+}
+
 func executeTests(t *testing.T, tc TestCase) {
 	exportApp := ExportApp{
 		ExportSpace: ExportSpace{
@@ -279,18 +317,23 @@ func SuccessfulAppCount(t *testing.T, count int) AssertionFunc {
 
 type stubDropletExporter struct {
 	numberOfPackages func(ctx *context.Context, app cfclient.App) (float64, error)
-	downloadDroplet  func(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error
-	downloadPackages func(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error
+	downloadBlobs    func(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error
+	openDroplet      func(ctx *context.Context, app cfclient.App) (io.ReadCloser, int64, error)
+	openPackage      func(ctx *context.Context, app cfclient.App) (io.ReadCloser, int64, error)
 }
 
 func (s stubDropletExporter) NumberOfPackages(ctx *context.Context, app cfclient.App) (float64, error) {
 	return s.numberOfPackages(ctx, app)
 }
 
-func (s stubDropletExporter) DownloadDroplet(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
-	return s.downloadDroplet(ctx, org, space, app, exportDir)
+func (s stubDropletExporter) DownloadBlobs(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
+	return s.downloadBlobs(ctx, org, space, app, exportDir)
+}
+
+func (s stubDropletExporter) OpenDroplet(ctx *context.Context, app cfclient.App) (io.ReadCloser, int64, error) {
+	return s.openDroplet(ctx, app)
 }
 
-func (s stubDropletExporter) DownloadPackages(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
-	return s.downloadPackages(ctx, org, space, app, exportDir)
+func (s stubDropletExporter) OpenPackage(ctx *context.Context, app cfclient.App) (io.ReadCloser, int64, error) {
+	return s.openPackage(ctx, app)
 }
@@ -0,0 +1,57 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+)
+
+type ExportBuildpacks struct {
+}
+
+// Run downloads the bits of the admin buildpacks of the source foundation to the export dir, and lists them with their
+// settings in export.BuildpacksFile
+func (e *ExportBuildpacks) Run(ctx *context.Context) error {
+	buildpacks, err := cf.ListBuildpacks(ctx.ExportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the buildpacks of the source foundation: %w", err)
+	}
+
+	var exported []export.Buildpack
+	for _, b := range buildpacks {
+		name := export.Buildpack{Name: b.Name, Stack: b.Stack}.String()
+		if b.State != cf.BuildpackReady || b.Filename == "" {
+			ctx.Logger.Warnf("Skipping buildpack %s, it has no bits", name)
+			ctx.Resources.AddSkipped("buildpack", "", "", name, "no bits")
+			continue
+		}
+
+		ctx.Logger.Infof("Exporting buildpack %s", name)
+		exportedBuildpack, err := export.ExportBuildpack(ctx, b)
+		if err != nil {
+			ctx.Logger.Errorf("Error exporting buildpack %s: %s", name, err)
+			ctx.Resources.AddFailed("buildpack", "", "", name, err)
+			continue
+		}
+		exported = append(exported, exportedBuildpack)
+		ctx.Resources.AddSucceeded("buildpack", "", "", name)
+	}
+
+	return export.WriteBuildpacks(ctx, exported)
+}
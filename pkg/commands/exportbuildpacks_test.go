@@ -0,0 +1,74 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
+)
+
+func TestExportBuildpacks_Run(t *testing.T) {
+	api := newFakeBuildpackAPI(t, []cf.V3Buildpack{
+		{GUID: "java-guid", Name: "java_buildpack", Stack: "cflinuxfs3", Position: 1, Enabled: true, Locked: true, State: cf.BuildpackReady, Filename: "java-buildpack-v4.50.zip"},
+		{GUID: "node-guid", Name: "nodejs_buildpack", Position: 2, State: "AWAITING_UPLOAD"},
+		{GUID: "ruby-guid", Name: "ruby_buildpack", Position: 3, Enabled: true, State: cf.BuildpackReady, Filename: "ruby-buildpack.zip"},
+	}, map[string]string{"java-guid": "java bits"})
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ExportCFClient: NewTestCFClient(t, server),
+		Resources:      report.NewResources(&bytes.Buffer{}),
+	}
+
+	require.NoError(t, (&ExportBuildpacks{}).Run(ctx))
+	assert.Equal(t, 1, ctx.Resources.Count(report.ResourceSucceeded))
+	assert.Equal(t, 1, ctx.Resources.Count(report.ResourceSkipped))
+	assert.Equal(t, 1, ctx.Resources.Count(report.ResourceFailed))
+
+	buildpacks, err := export.ReadBuildpacks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []export.Buildpack{{
+		Name:     "java_buildpack",
+		Stack:    "cflinuxfs3",
+		Position: 1,
+		Enabled:  true,
+		Locked:   true,
+		Filename: "java-buildpack-v4.50.zip",
+		Bits:     ".app-migrator/buildpacks/java-guid.zip",
+		Checksum: checksumOf("java bits"),
+	}}, buildpacks)
+
+	bits, err := ctx.DirWriter.Get(".app-migrator/buildpacks/java-guid.zip")
+	require.NoError(t, err)
+	defer bits.Close()
+	data, err := io.ReadAll(bits)
+	require.NoError(t, err)
+	assert.Equal(t, "java bits", string(data))
+}
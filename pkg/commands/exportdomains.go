@@ -0,0 +1,178 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+)
+
+type ExportDomains struct {
+}
+
+// Run exports the private domains owned by or shared with the exported orgs, and the shared domains the routes of the
+// exported apps use, to export.DomainsFile. Export the apps first, the shared domains are found from their routes.
+func (e *ExportDomains) Run(ctx *context.Context) error {
+	private := make(map[string]cf.Domain)
+	err := forEachSourceOrg(ctx, func(org cfclient.Org) {
+		domains, err := cf.ListOrgDomains(ctx.ExportCFClient, org.Guid)
+		if err != nil {
+			ctx.Logger.Errorf("Error listing the domains of org %s: %s", org.Name, err)
+			ctx.Resources.AddFailed("domains", org.Name, "", "", err)
+			return
+		}
+		for _, d := range domains {
+			if d.IsPrivate() {
+				private[d.GUID] = d
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	var domains export.Domains
+	for _, d := range private {
+		ctx.Logger.Infof("Exporting private domain %s", d.Name)
+		exported, err := e.privateDomain(ctx, d)
+		if err != nil {
+			ctx.Logger.Errorf("Error exporting private domain %s: %s", d.Name, err)
+			ctx.Resources.AddFailed("private domain", "", "", d.Name, err)
+			continue
+		}
+		domains.PrivateDomains = append(domains.PrivateDomains, exported)
+		ctx.Resources.AddSucceeded("private domain", exported.Org, "", d.Name)
+	}
+	sort.Slice(domains.PrivateDomains, func(i, j int) bool {
+		return domains.PrivateDomains[i].Name < domains.PrivateDomains[j].Name
+	})
+
+	domains.SharedDomains, err = e.sharedDomains(ctx)
+	if err != nil {
+		return err
+	}
+
+	return export.WriteDomains(ctx, domains)
+}
+
+// privateDomain names the org owning the private domain and the exported orgs it is shared with
+func (e *ExportDomains) privateDomain(ctx *context.Context, d cf.Domain) (export.PrivateDomain, error) {
+	c := cache.GetCache(ctx.ExportCFClient)
+	owner, err := c.GetOrgByGUID(d.Relationships.Organization.Data.GUID)
+	if err != nil {
+		return export.PrivateDomain{}, err
+	}
+
+	exported := export.PrivateDomain{Name: d.Name, Internal: d.Internal, Org: owner.Name}
+	if d.Relationships.SharedOrganizations != nil {
+		for _, r := range d.Relationships.SharedOrganizations.Data {
+			org, err := c.GetOrgByGUID(r.GUID)
+			if err != nil {
+				return export.PrivateDomain{}, err
+			}
+			if isOrgExcluded(ctx, org.Name) || !isOrgIncluded(ctx, org.Name) {
+				continue
+			}
+			exported.SharedOrgs = append(exported.SharedOrgs, org.Name)
+		}
+	}
+	sort.Strings(exported.SharedOrgs)
+
+	return exported, nil
+}
+
+// sharedDomains returns the shared domains of the source foundation the routes of the exported apps use, sorted by
+// name. Tcp domains are left out, their router group cannot be exported.
+func (e *ExportDomains) sharedDomains(ctx *context.Context) ([]export.SharedDomain, error) {
+	all, err := cf.ListDomains(ctx.ExportCFClient)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the domains of the source foundation: %w", err)
+	}
+	var names []string
+	for _, d := range all {
+		names = append(names, d.Name)
+	}
+
+	manifests, err := fs.Glob(ctx.ExportFS(), path.Join("*", "*", "*_manifest.yml"))
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, manifestPath := range manifests {
+		org, space := path.Split(path.Dir(manifestPath))
+		org = path.Clean(org)
+		if isOrgExcluded(ctx, org) || !isOrgIncluded(ctx, org) {
+			continue
+		}
+
+		app, err := readManifest(ctx, manifestPath)
+		if err != nil {
+			ctx.Summary.AddFailedApp(org, space, strings.TrimSuffix(path.Base(manifestPath), "_manifest.yml"), err)
+			continue
+		}
+		for _, r := range app.Routes {
+			if domain := routeDomain(r.Route, names); domain != "" {
+				used[domain] = true
+			}
+		}
+	}
+
+	shared := make([]export.SharedDomain, 0, len(used))
+	for _, d := range all {
+		if d.IsPrivate() || !used[d.Name] {
+			continue
+		}
+		if d.RouterGroup != nil {
+			ctx.Logger.Warnf("Skipping tcp domain %s, create it on the target foundation with its router group", d.Name)
+			ctx.Resources.AddSkipped("shared domain", "", "", d.Name, "tcp domains are not exported")
+			continue
+		}
+		ctx.Logger.Infof("Exporting shared domain %s", d.Name)
+		shared = append(shared, export.SharedDomain{Name: d.Name, Internal: d.Internal})
+		ctx.Resources.AddSucceeded("shared domain", "", "", d.Name)
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		return shared[i].Name < shared[j].Name
+	})
+
+	return shared, nil
+}
+
+// routeDomain returns the longest of the domains the route of a manifest is on, e.g. apps.example.com for
+// my-app.apps.example.com/path, or an empty string when it is on none of them
+func routeDomain(route string, domains []string) string {
+	host := route
+	if i := strings.IndexAny(host, "/:"); i >= 0 {
+		host = host[:i]
+	}
+
+	var longest string
+	for _, d := range domains {
+		if (host == d || strings.HasSuffix(host, "."+d)) && len(d) > len(longest) {
+			longest = d
+		}
+	}
+	return longest
+}
@@ -0,0 +1,137 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+const (
+	sharedDomainsJSON = `
+  {"guid": "apps-guid", "name": "apps.example.com", "relationships": {"organization": {"data": null}, "shared_organizations": {"data": []}}},
+  {"guid": "internal-guid", "name": "apps.internal", "internal": true, "relationships": {"organization": {"data": null}, "shared_organizations": {"data": []}}},
+  {"guid": "tcp-guid", "name": "tcp.example.com", "router_group": {"guid": "default-tcp"}, "relationships": {"organization": {"data": null}, "shared_organizations": {"data": []}}}`
+	myDomainJSON = `
+  {"guid": "my-guid", "name": "my.example.com", "relationships": {"organization": {"data": {"guid": "org-guid"}}, "shared_organizations": {"data": [{"guid": "system-guid"}, {"guid": "other-guid"}]}}}`
+	otherDomainJSON = `
+  {"guid": "other-domain-guid", "name": "other.example.com", "relationships": {"organization": {"data": {"guid": "other-guid"}}, "shared_organizations": {"data": []}}}`
+)
+
+func TestExportDomains_Run(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	orgs := map[string]string{"org-guid": "my_org", "other-guid": "other_org", "system-guid": "system"}
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		ListOrgsByQueryStub: func(url.Values) ([]cfclient.Org, error) {
+			return []cfclient.Org{{Guid: "org-guid", Name: "my_org"}, {Guid: "other-guid", Name: "other_org"}, {Guid: "system-guid", Name: "system"}}, nil
+		},
+		GetOrgByGuidStub: func(guid string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: guid, Name: orgs[guid]}, nil
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/organizations/org-guid/domains":
+				return []byte(`{"pagination": {}, "resources": [` + sharedDomainsJSON + `,` + myDomainJSON + `]}`), nil
+			case "/v3/organizations/other-guid/domains":
+				return []byte(`{"pagination": {}, "resources": [` + sharedDomainsJSON + `,` + myDomainJSON + `,` + otherDomainJSON + `]}`), nil
+			case "/v3/domains":
+				return []byte(`{"pagination": {}, "resources": [` + sharedDomainsJSON + `,` + myDomainJSON + `,` + otherDomainJSON + `,
+  {"guid": "unused-guid", "name": "unused.example.com", "relationships": {"organization": {"data": null}, "shared_organizations": {"data": []}}}
+]}`), nil
+			}
+			return nil, errors.New("unexpected request " + path)
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ExportCFClient: client,
+		ExcludedOrgs:   []string{"^system$"},
+		Summary:        report.NewSummary(&bytes.Buffer{}),
+		Resources:      report.NewResources(&bytes.Buffer{}),
+	}
+	f, err := aio.PutFile(ctx.DirWriter, "my_org/my_space/web_manifest.yml", 0644, nil)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(`applications:
+- name: web
+  routes:
+  - route: web.apps.example.com/path
+  - route: web.my.example.com
+  - route: web.apps.internal
+  - route: tcp.example.com:1024
+`))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.NoError(t, (&ExportDomains{}).Run(ctx))
+	assert.Equal(t, 4, ctx.Resources.Count(report.ResourceSucceeded))
+	assert.Equal(t, 1, ctx.Resources.Count(report.ResourceSkipped))
+	assert.Equal(t, 0, ctx.Resources.Count(report.ResourceFailed))
+
+	domains, err := export.ReadDomains(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, export.Domains{
+		PrivateDomains: []export.PrivateDomain{
+			{Name: "my.example.com", Org: "my_org", SharedOrgs: []string{"other_org"}},
+			{Name: "other.example.com", Org: "other_org"},
+		},
+		SharedDomains: []export.SharedDomain{
+			{Name: "apps.example.com"},
+			{Name: "apps.internal", Internal: true},
+		},
+	}, domains)
+}
+
+func TestRouteDomain(t *testing.T) {
+	domains := []string{"example.com", "apps.example.com", "tcp.example.com"}
+	tests := []struct {
+		route string
+		want  string
+	}{
+		{route: "my-app.apps.example.com", want: "apps.example.com"},
+		{route: "my-app.apps.example.com/api/v1", want: "apps.example.com"},
+		{route: "apps.example.com", want: "apps.example.com"},
+		{route: "tcp.example.com:1024", want: "tcp.example.com"},
+		{route: "www.example.com", want: "example.com"},
+		{route: "my-app.myapps.example.org", want: ""},
+		{route: "notexample.com", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			assert.Equal(t, tt.want, routeDomain(tt.route, domains))
+		})
+	}
+}
@@ -27,7 +27,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
-	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context/fakes"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
 
@@ -35,6 +34,7 @@ import (
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
 )
 
@@ -58,13 +58,9 @@ func TestExportIncremental(t *testing.T) {
 			app:     ExportIncremental{},
 			handler: ExportTestHandler(t),
 			context: &context.Context{
-				Logger:    logger,
-				ExportDir: filepath.Join(pwd, "testdata/apps"),
-				DirWriter: &fakes.FakeDirWriter{
-					MkdirStub: func(s string) error {
-						return nil
-					},
-				},
+				Logger:             logger,
+				ExportDir:          filepath.Join(pwd, "testdata/apps"),
+				DirWriter:          storage.NewLocal(t.TempDir()),
 				Metadata:           metadata.NewMetadata(),
 				Summary:            report.NewSummary(&bytes.Buffer{}),
 				DropletExporter:    export.NewDropletExporter(),
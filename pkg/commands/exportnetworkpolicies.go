@@ -0,0 +1,144 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+)
+
+type ExportNetworkPolicies struct {
+}
+
+// Run exports the network policies of the exported apps to export.NetworkPoliciesFile, naming the apps at both ends
+// by org, space and app name. Export the apps first, only policies with an exported app at one end are exported.
+func (e *ExportNetworkPolicies) Run(ctx *context.Context) error {
+	apps, err := e.exportedApps(ctx)
+	if err != nil {
+		return err
+	}
+
+	guids := make([]string, 0, len(apps))
+	for guid := range apps {
+		guids = append(guids, guid)
+	}
+	sort.Strings(guids)
+
+	policies, err := cf.ListNetworkPolicies(ctx.ExportCFClient, guids)
+	if err != nil {
+		return fmt.Errorf("error listing the network policies of the source foundation: %w", err)
+	}
+
+	exported := make([]export.NetworkPolicy, 0, len(policies))
+	for _, p := range policies {
+		source, err := e.appRef(ctx, apps, p.Source.ID)
+		if err != nil {
+			ctx.Logger.Errorf("Error exporting network policy from app %s: %s", p.Source.ID, err)
+			continue
+		}
+		destination, err := e.appRef(ctx, apps, p.Destination.ID)
+		if err != nil {
+			ctx.Logger.Errorf("Error exporting network policy to app %s: %s", p.Destination.ID, err)
+			continue
+		}
+
+		policy := export.NetworkPolicy{
+			Source:      source,
+			Destination: destination,
+			Protocol:    p.Destination.Protocol,
+			Ports:       p.Destination.Ports,
+		}
+		ctx.Logger.Infof("Exporting network policy %s", policy)
+		exported = append(exported, policy)
+		ctx.Resources.AddSucceeded("network policy", source.Org, source.Space, policy.String())
+	}
+
+	return export.WriteNetworkPolicies(ctx, exported)
+}
+
+// exportedApps returns the apps of the export by their guid on the source foundation, leaving out the excluded orgs
+func (e *ExportNetworkPolicies) exportedApps(ctx *context.Context) (map[string]export.AppRef, error) {
+	manifests, err := fs.Glob(ctx.ExportFS(), path.Join("*", "*", "*_manifest.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := cache.GetCache(ctx.ExportCFClient)
+	apps := make(map[string]export.AppRef)
+	for _, manifestPath := range manifests {
+		orgName, spaceName := path.Split(path.Dir(manifestPath))
+		orgName = path.Clean(orgName)
+		if isOrgExcluded(ctx, orgName) || !isOrgIncluded(ctx, orgName) {
+			continue
+		}
+
+		appName := strings.TrimSuffix(path.Base(manifestPath), "_manifest.yml")
+		app, err := readManifest(ctx, manifestPath)
+		if err != nil {
+			ctx.Summary.AddFailedApp(orgName, spaceName, appName, err)
+			continue
+		}
+
+		org, err := c.GetOrgByName(orgName)
+		if err != nil {
+			ctx.Summary.AddFailedApp(orgName, spaceName, app.Name, err)
+			continue
+		}
+		space, err := c.GetSpaceByName(spaceName, org.Guid)
+		if err != nil {
+			ctx.Summary.AddFailedApp(orgName, spaceName, app.Name, err)
+			continue
+		}
+		sourceApp, err := c.GetAppByName(app.Name, space.Guid)
+		if err != nil {
+			ctx.Summary.AddFailedApp(orgName, spaceName, app.Name, err)
+			continue
+		}
+		apps[sourceApp.Guid] = export.AppRef{Org: orgName, Space: spaceName, App: app.Name}
+	}
+
+	return apps, nil
+}
+
+// appRef names the app with the given guid, looking up the apps that were not exported on the source foundation
+func (e *ExportNetworkPolicies) appRef(ctx *context.Context, apps map[string]export.AppRef, guid string) (export.AppRef, error) {
+	if ref, ok := apps[guid]; ok {
+		return ref, nil
+	}
+
+	c := cache.GetCache(ctx.ExportCFClient)
+	app, ok := c.GetAppByGUID(guid)
+	if !ok {
+		return export.AppRef{}, fmt.Errorf("app %s not found", guid)
+	}
+	space, err := c.GetSpaceByGUID(app.SpaceGuid)
+	if err != nil {
+		return export.AppRef{}, err
+	}
+	org, err := c.GetOrgByGUID(space.OrganizationGuid)
+	if err != nil {
+		return export.AppRef{}, err
+	}
+
+	return export.AppRef{Org: org.Name, Space: space.Name, App: app.Name}, nil
+}
@@ -0,0 +1,121 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestExportNetworkPolicies_Run(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var query string
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(path string) ([]byte, error) {
+			query = path
+			return []byte(`{"total_policies": 3, "policies": [
+  {"source": {"id": "frontend-guid"}, "destination": {"id": "api-guid", "protocol": "tcp", "ports": {"start": 8080, "end": 8080}}},
+  {"source": {"id": "api-guid"}, "destination": {"id": "db-guid", "protocol": "tcp", "ports": {"start": 5432, "end": 5433}}},
+  {"source": {"id": "deleted-guid"}, "destination": {"id": "api-guid", "protocol": "udp", "ports": {"start": 53, "end": 53}}}
+]}`), nil
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: name + "-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: name + "-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		ListAppsByQueryStub: func(values url.Values) ([]cfclient.App, error) {
+			name := values["q"][0][len("name:"):]
+			return []cfclient.App{{Guid: name + "-guid", Name: name, SpaceGuid: "my_space-guid"}}, nil
+		},
+		GetAppByGuidNoInlineCallStub: func(guid string) (cfclient.App, error) {
+			if guid == "db-guid" {
+				return cfclient.App{Guid: guid, Name: "db", SpaceGuid: "data-guid"}, nil
+			}
+			return cfclient.App{}, errors.New("app not found")
+		},
+		GetSpaceByGuidStub: func(guid string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: guid, Name: "data", OrganizationGuid: "other_org-guid"}, nil
+		},
+		GetOrgByGuidStub: func(guid string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: guid, Name: "other_org"}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ExportCFClient: client,
+		ExcludedOrgs:   []string{"^system$"},
+		Summary:        report.NewSummary(&bytes.Buffer{}),
+		Resources:      report.NewResources(&bytes.Buffer{}),
+	}
+	manifests := map[string]string{
+		"my_org/my_space/frontend_manifest.yml": "applications:\n- name: frontend\n",
+		"my_org/my_space/api_manifest.yml":      "applications:\n- name: api\n",
+		"system/tools/worker_manifest.yml":      "applications:\n- name: worker\n",
+	}
+	for name, content := range manifests {
+		f, err := aio.PutFile(ctx.DirWriter, name, 0644, nil)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	require.NoError(t, (&ExportNetworkPolicies{}).Run(ctx))
+	assert.Equal(t, "/networking/v1/external/policies?id=api-guid%2Cfrontend-guid", query)
+	assert.Equal(t, 2, ctx.Resources.Count(report.ResourceSucceeded))
+	assert.Equal(t, 0, ctx.Resources.Count(report.ResourceFailed))
+
+	policies, err := export.ReadNetworkPolicies(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []export.NetworkPolicy{
+		{
+			Source:      export.AppRef{Org: "my_org", Space: "my_space", App: "frontend"},
+			Destination: export.AppRef{Org: "my_org", Space: "my_space", App: "api"},
+			Protocol:    "tcp",
+			Ports:       cf.PolicyPorts{Start: 8080, End: 8080},
+		},
+		{
+			Source:      export.AppRef{Org: "my_org", Space: "my_space", App: "api"},
+			Destination: export.AppRef{Org: "other_org", Space: "data", App: "db"},
+			Protocol:    "tcp",
+			Ports:       cf.PolicyPorts{Start: 5432, End: 5433},
+		},
+	}, policies)
+}
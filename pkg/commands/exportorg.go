@@ -33,6 +33,35 @@ type ExportOrg struct {
 }
 
 func (e *ExportOrg) Run(ctx *context.Context, orgName string) error {
+	org, err := cache.GetCache(ctx.ExportCFClient).GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+
+	runSteps(ctx, "exporting", orgName, "",
+		step{"roles", func() error { return exportOrgRoles(ctx, org) }},
+		step{"isolation segments", func() error { return exportOrgIsolationSegments(ctx, org) }},
+	)
+
+	return forEachSourceSpace(ctx, orgName, func(space cfclient.Space) {
+		exportSpaceCmd := &ExportSpace{
+			ExportOrg: ExportOrg{
+				Org: orgName,
+			},
+
+			Space: space.Name,
+		}
+
+		err := exportSpaceCmd.Run(ctx, orgName, space.Name)
+		if err != nil {
+			log.Errorf("%v", err)
+			// TODO: Should we ignore errors here?
+		}
+	})
+}
+
+// forEachSourceSpace calls fn with every space of the org on the source foundation
+func forEachSourceSpace(ctx *context.Context, orgName string, fn func(space cfclient.Space)) error {
 	globalCache := cache.GetCache(ctx.ExportCFClient)
 
 	org, err := globalCache.GetOrgByName(orgName)
@@ -69,20 +98,7 @@ func (e *ExportOrg) Run(ctx *context.Context, orgName string) error {
 		}
 
 		for _, space := range spaces {
-			exportSpaceCmd := &ExportSpace{
-				ExportOrg: ExportOrg{
-					Org: orgName,
-				},
-
-				Space: space.Name,
-			}
-
-			err := exportSpaceCmd.Run(ctx, orgName, space.Name)
-			if err != nil {
-				log.Errorf("%v", err)
-				// TODO: Should we ignore errors here?
-				continue
-			}
+			fn(space)
 		}
 
 		if len(spaces) < resultsPerPage {
@@ -23,13 +23,13 @@ import (
 	"path/filepath"
 	"testing"
 
-	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context/fakes"
-
+	log "github.com/sirupsen/logrus"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
 
 	"github.com/stretchr/testify/assert"
@@ -53,14 +53,11 @@ func TestExportOrg_Run(t *testing.T) {
 			name: "returns no error",
 			args: args{
 				ctx: &context.Context{
+					Logger:    log.New(),
 					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
-					Metadata: metadata.NewMetadata(),
-					Summary:  report.NewSummary(&bytes.Buffer{}),
+					DirWriter: storage.NewLocal(t.TempDir()),
+					Metadata:  metadata.NewMetadata(),
+					Summary:   report.NewSummary(&bytes.Buffer{}),
 					SpaceExporter: stubSpaceExporter{
 						err:             nil,
 						failedAppCount:  0,
@@ -83,14 +80,11 @@ func TestExportOrg_Run(t *testing.T) {
 			name: "returns retry error",
 			args: args{
 				ctx: &context.Context{
+					Logger:    log.New(),
 					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
-					Metadata: metadata.NewMetadata(),
-					Summary:  report.NewSummary(&bytes.Buffer{}),
+					DirWriter: storage.NewLocal(t.TempDir()),
+					Metadata:  metadata.NewMetadata(),
+					Summary:   report.NewSummary(&bytes.Buffer{}),
 				},
 				org: "my_org",
 			},
@@ -0,0 +1,132 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+)
+
+type ExportQuotas struct {
+}
+
+// Run exports the org and space quotas of the source foundation to export.QuotasFile, with the names of the orgs and
+// spaces they are assigned to. Orgs that are excluded from the export are left out.
+func (e *ExportQuotas) Run(ctx *context.Context) error {
+	c := cache.GetCache(ctx.ExportCFClient)
+
+	orgQuotas, err := cf.ListOrgQuotas(ctx.ExportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the org quotas of the source foundation: %w", err)
+	}
+	spaceQuotas, err := cf.ListSpaceQuotas(ctx.ExportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the space quotas of the source foundation: %w", err)
+	}
+
+	var quotas export.Quotas
+	for _, q := range orgQuotas {
+		ctx.Logger.Infof("Exporting org quota %s", q.Name)
+		exported := export.OrgQuota{Name: q.Name, Apps: q.Apps, Services: q.Services, Routes: q.Routes}
+		if q.Domains != nil {
+			exported.Domains = *q.Domains
+		}
+
+		exported.Orgs, err = e.orgNames(ctx, q)
+		if err != nil {
+			ctx.Logger.Errorf("Error exporting org quota %s: %s", q.Name, err)
+			ctx.Resources.AddFailed("org quota", "", "", q.Name, err)
+			continue
+		}
+		quotas.OrgQuotas = append(quotas.OrgQuotas, exported)
+		ctx.Resources.AddSucceeded("org quota", "", "", q.Name)
+	}
+
+	for _, q := range spaceQuotas {
+		if q.Relationships == nil || q.Relationships.Organization == nil {
+			continue
+		}
+		org, err := c.GetOrgByGUID(q.Relationships.Organization.Data.GUID)
+		if err != nil {
+			ctx.Logger.Errorf("Error exporting space quota %s: %s", q.Name, err)
+			ctx.Resources.AddFailed("space quota", "", "", q.Name, err)
+			continue
+		}
+		if isOrgExcluded(ctx, org.Name) || !isOrgIncluded(ctx, org.Name) {
+			continue
+		}
+
+		ctx.Logger.Infof("Exporting space quota %s of org %s", q.Name, org.Name)
+		exported := export.SpaceQuota{Name: q.Name, Org: org.Name, Apps: q.Apps, Services: q.Services, Routes: q.Routes}
+		exported.Spaces, err = e.spaceNames(ctx, q)
+		if err != nil {
+			ctx.Logger.Errorf("Error exporting space quota %s of org %s: %s", q.Name, org.Name, err)
+			ctx.Resources.AddFailed("space quota", org.Name, "", q.Name, err)
+			continue
+		}
+		quotas.SpaceQuotas = append(quotas.SpaceQuotas, exported)
+		ctx.Resources.AddSucceeded("space quota", org.Name, "", q.Name)
+	}
+
+	return export.WriteQuotas(ctx, quotas)
+}
+
+// orgNames returns the names of the orgs the quota is assigned to, leaving out the excluded orgs
+func (e *ExportQuotas) orgNames(ctx *context.Context, q cf.Quota) ([]string, error) {
+	if q.Relationships == nil || q.Relationships.Organizations == nil {
+		return nil, nil
+	}
+
+	c := cache.GetCache(ctx.ExportCFClient)
+	var names []string
+	for _, r := range q.Relationships.Organizations.Data {
+		org, err := c.GetOrgByGUID(r.GUID)
+		if err != nil {
+			return nil, err
+		}
+		if isOrgExcluded(ctx, org.Name) || !isOrgIncluded(ctx, org.Name) {
+			continue
+		}
+		names = append(names, org.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// spaceNames returns the names of the spaces the quota is assigned to
+func (e *ExportQuotas) spaceNames(ctx *context.Context, q cf.Quota) ([]string, error) {
+	if q.Relationships.Spaces == nil {
+		return nil, nil
+	}
+
+	c := cache.GetCache(ctx.ExportCFClient)
+	var names []string
+	for _, r := range q.Relationships.Spaces.Data {
+		space, err := c.GetSpaceByGUID(r.GUID)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, space.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
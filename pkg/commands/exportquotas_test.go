@@ -0,0 +1,107 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestExportQuotas_Run(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	orgs := map[string]string{"org-guid": "my_org", "other-guid": "other_org", "system-guid": "system"}
+	spaces := map[string]string{"dev-guid": "dev", "test-guid": "test"}
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(url string) ([]byte, error) {
+			switch url {
+			case "/v3/organization_quotas":
+				return []byte(`{"pagination": {}, "resources": [{
+  "guid": "default-guid", "name": "default",
+  "apps": {"total_memory_in_mb": 10240, "per_process_memory_in_mb": null, "total_instances": 100, "per_app_tasks": null, "log_rate_limit_in_bytes_per_second": null},
+  "services": {"paid_services_allowed": true, "total_service_instances": 10, "total_service_keys": null},
+  "routes": {"total_routes": 1000, "total_reserved_ports": 0},
+  "domains": {"total_domains": null},
+  "relationships": {"organizations": {"data": [{"guid": "system-guid"}, {"guid": "other-guid"}, {"guid": "org-guid"}]}}
+}]}`), nil
+			case "/v3/space_quotas":
+				return []byte(`{"pagination": {}, "resources": [{
+  "guid": "dev-quota-guid", "name": "dev-quota",
+  "apps": {"total_memory_in_mb": 2048},
+  "relationships": {"organization": {"data": {"guid": "org-guid"}}, "spaces": {"data": [{"guid": "test-guid"}, {"guid": "dev-guid"}]}}
+}, {
+  "guid": "system-quota-guid", "name": "system-quota",
+  "relationships": {"organization": {"data": {"guid": "system-guid"}}, "spaces": {"data": []}}
+}]}`), nil
+			}
+			return nil, errors.New("unexpected request " + url)
+		},
+		GetOrgByGuidStub: func(guid string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: guid, Name: orgs[guid]}, nil
+		},
+		GetSpaceByGuidStub: func(guid string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: guid, Name: spaces[guid], OrganizationGuid: "org-guid"}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ExportCFClient: client,
+		ExcludedOrgs:   []string{"^system$"},
+		Resources:      report.NewResources(&bytes.Buffer{}),
+	}
+
+	require.NoError(t, (&ExportQuotas{}).Run(ctx))
+	assert.Equal(t, 2, ctx.Resources.Count(report.ResourceSucceeded))
+	assert.Equal(t, 0, ctx.Resources.Count(report.ResourceFailed))
+
+	quotas, err := export.ReadQuotas(ctx)
+	require.NoError(t, err)
+	memory, instances, serviceInstances, routes, ports, devMemory := 10240, 100, 10, 1000, 0, 2048
+	assert.Equal(t, export.Quotas{
+		OrgQuotas: []export.OrgQuota{{
+			Name:     "default",
+			Apps:     cf.QuotaApps{TotalMemoryInMB: &memory, TotalInstances: &instances},
+			Services: cf.QuotaServices{PaidServicesAllowed: true, TotalServiceInstances: &serviceInstances},
+			Routes:   cf.QuotaRoutes{TotalRoutes: &routes, TotalReservedPorts: &ports},
+			Orgs:     []string{"my_org", "other_org"},
+		}},
+		SpaceQuotas: []export.SpaceQuota{{
+			Name:   "dev-quota",
+			Org:    "my_org",
+			Apps:   cf.QuotaApps{TotalMemoryInMB: &devMemory},
+			Spaces: []string{"dev", "test"},
+		}},
+	}, quotas)
+}
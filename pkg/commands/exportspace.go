@@ -48,6 +48,14 @@ func (e *ExportSpace) Run(ctx *context.Context, orgName, spaceName string) error
 		return err
 	}
 
+	runSteps(ctx, "exporting", orgName, spaceName,
+		step{"roles", func() error { return exportSpaceRoles(ctx, org, space) }},
+		step{"security groups", func() error { return exportSpaceSecurityGroups(ctx, org, space) }},
+		step{"isolation segment", func() error { return exportSpaceIsolationSegment(ctx, org, space) }},
+		step{"service instances", func() error { return exportSpaceServiceInstances(ctx, org, space) }},
+		step{"service keys", func() error { return exportSpaceServiceKeys(ctx, org, space) }},
+	)
+
 	exportApp := func(ctx *context.Context, r context.QueryResult) context.ProcessResult {
 		appExporter := &ExportApp{
 			ExportSpace: *e,
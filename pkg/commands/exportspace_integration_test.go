@@ -28,6 +28,7 @@ import (
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cmd"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
 	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	"io"
 	"os"
 	"os/exec"
@@ -97,7 +98,7 @@ func TestExportSpace(t *testing.T) {
 
 func CreateCmdContext(exportDir string) *cmdcontext.Context {
 	ctx := &cmdcontext.Context{
-		DirWriter:          aio.NewDirWriter(),
+		DirWriter:          storage.NewLocal(exportDir),
 		ExportDir:          exportDir,
 		Metadata:           metadata.NewMetadata(),
 		Summary:            report.NewSummary(os.Stdout),
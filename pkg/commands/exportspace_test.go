@@ -22,14 +22,13 @@ import (
 	"path/filepath"
 	"testing"
 
-	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context/fakes"
-
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
 
 	"github.com/stretchr/testify/assert"
@@ -60,17 +59,16 @@ func TestExportSpace_Run(t *testing.T) {
 				space: "my_space",
 				ctx: &context.Context{
 					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
-					Metadata: metadata.NewMetadata(),
-					Summary:  report.NewSummary(&bytes.Buffer{}),
+					DirWriter: storage.NewLocal(t.TempDir()),
+					Metadata:  metadata.NewMetadata(),
+					Summary:   report.NewSummary(&bytes.Buffer{}),
 					ExportCFClient: testsupport.StubClient{
 						DoWithRetryFunc: func(f func() error) error {
 							return nil
 						},
+						GetFunc: func(url string) ([]byte, error) {
+							return []byte(`{"pagination": {}, "resources": []}`), nil
+						},
 					},
 					SpaceExporter: stubSpaceExporter{successAppCount: 1},
 				},
@@ -85,17 +83,16 @@ func TestExportSpace_Run(t *testing.T) {
 				space: "my_space",
 				ctx: &context.Context{
 					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
-					Metadata: metadata.NewMetadata(),
-					Summary:  report.NewSummary(&bytes.Buffer{}),
+					DirWriter: storage.NewLocal(t.TempDir()),
+					Metadata:  metadata.NewMetadata(),
+					Summary:   report.NewSummary(&bytes.Buffer{}),
 					ExportCFClient: testsupport.StubClient{
 						DoWithRetryFunc: func(f func() error) error {
 							return nil
 						},
+						GetFunc: func(url string) ([]byte, error) {
+							return []byte(`{"pagination": {}, "resources": []}`), nil
+						},
 					},
 					SpaceExporter: stubSpaceExporter{err: errors.New("some error")},
 				},
@@ -110,17 +107,16 @@ func TestExportSpace_Run(t *testing.T) {
 				space: "my_space",
 				ctx: &context.Context{
 					ExportDir: filepath.Join(pwd, "testdata/apps"),
-					DirWriter: &fakes.FakeDirWriter{
-						MkdirStub: func(s string) error {
-							return nil
-						},
-					},
-					Metadata: metadata.NewMetadata(),
-					Summary:  report.NewSummary(&bytes.Buffer{}),
+					DirWriter: storage.NewLocal(t.TempDir()),
+					Metadata:  metadata.NewMetadata(),
+					Summary:   report.NewSummary(&bytes.Buffer{}),
 					ExportCFClient: testsupport.StubClient{
 						DoWithRetryFunc: func(f func() error) error {
 							return nil
 						},
+						GetFunc: func(url string) ([]byte, error) {
+							return []byte(`{"pagination": {}, "resources": []}`), nil
+						},
 					},
 					SpaceExporter: stubSpaceExporter{err: errors.New("some error"), failedAppCount: 1, successAppCount: 0},
 				},
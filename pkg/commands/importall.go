@@ -18,24 +18,28 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"path/filepath"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
 )
 
 type ImportAll struct {
 }
 
 func (i *ImportAll) Run(ctx *context.Context) error {
-	err := filepath.WalkDir(ctx.ExportDir, func(path string, d fs.DirEntry, err error) error {
-		if path == ctx.ExportDir {
+	err := fs.WalkDir(ctx.ExportFS(), ".", func(path string, d fs.DirEntry, err error) error {
+		if path == "." {
 			return nil
 		}
 
 		if d.IsDir() {
+			if path == aio.ReservedDir {
+				return fs.SkipDir
+			}
+
 			fmt.Fprintf(os.Stderr, "Importing from org %s\n", d.Name())
 			if isOrgExcluded(ctx, d.Name()) || !isOrgIncluded(ctx, d.Name()) {
-				return filepath.SkipDir
+				return fs.SkipDir
 			}
 
 			importOrg := &ImportOrg{
@@ -47,7 +51,7 @@ func (i *ImportAll) Run(ctx *context.Context) error {
 				return err
 			}
 
-			return filepath.SkipDir
+			return fs.SkipDir
 		}
 
 		return nil
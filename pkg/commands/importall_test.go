@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context/fakes"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
@@ -88,3 +89,25 @@ func TestImportAll_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestImportAll_RunSkipsReservedDir(t *testing.T) {
+	exportDir := t.TempDir()
+	for name, content := range map[string]string{
+		"blobs/sha256/f1d2d2f924e986ac86fdf7b36c94bcdf32beec15": "droplet data",
+		"buildpacks/buildpacks.json":                            "[]",
+		"quotas/quotas.json":                                    "{}",
+		"network_policies/policies.json":                        "[]",
+		"domains/domains.json":                                  "{}",
+	} {
+		file := filepath.Join(exportDir, aio.ReservedDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0700))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+	}
+
+	ctx := &context.Context{
+		ExportDir: exportDir,
+		Summary:   report.NewSummary(&bytes.Buffer{}),
+	}
+	require.NoError(t, (&ImportAll{}).Run(ctx))
+	assert.Equal(t, 0, ctx.Summary.AppFailureCount())
+}
@@ -16,23 +16,33 @@ package commands
 
 import (
 	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"io/fs"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
 
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/blobstore"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 	"gopkg.in/yaml.v2"
@@ -44,6 +54,20 @@ type ImportApp struct {
 	AppName  string `help:"the app to import" short:"a" env:"CF_APP_NAME"`
 	appGUID  string
 	AppCount int
+	// sourceStack is the stack of the exported app, and stack the one it gets on the target, empty for the default
+	sourceStack  string
+	stack        string
+	stackMissing bool
+	// routeGUIDs holds the guids of the routes bound to the app, by route
+	routeGUIDs map[string]string
+	// services are the service instances bound to the app, sourceName and targetName the names of the app on the
+	// source and target foundations, for the data movers of the offerings of the service instances
+	services   []export.Service
+	sourceName string
+	targetName string
+	// runningApp is true when the app already existed on the target foundation and was not stopped, its data is
+	// only moved with --data-movers-on-running-apps
+	runningApp bool
 }
 
 func (i *ImportApp) SetOrgName(name string) {
@@ -58,13 +82,23 @@ func (i *ImportApp) SetAppName(name string) {
 	i.AppName = name
 }
 
+// Run imports the app on its own, after the service instances and keys of its space so that it can be bound to them
 func (i *ImportApp) Run(ctx *appcontext.Context) error {
+	importSpaceServices(ctx, i.Org, i.Space)
+	return i.importApp(ctx)
+}
 
+// importApp imports the app, the service instances it is bound to must already exist
+func (i *ImportApp) importApp(ctx *appcontext.Context) error {
 	if i.Sequence == nil {
 		i.Sequence = NewImportAppSequence(i)
 	}
 
 	if _, err := i.Sequence.Run(ctx, nil); err != nil {
+		if errors.Is(err, ErrAppSkipped) {
+			ctx.Summary.AddSkippedApp(mapping.Display(i.Org, i.targetOrg(ctx)), mapping.Display(i.Space, i.targetSpace(ctx)), i.AppName, err.Error())
+			return nil
+		}
 		ctx.Logger.Errorf("Error occurred importing app %s/%s/%s: %s", i.Org, i.Space, i.AppName, err)
 		return err
 	}
@@ -91,6 +125,13 @@ func NewImportAppSequence(i *ImportApp) Sequence {
 			},
 			"Creating app",
 		),
+		StepWithProgressBar(
+			func(ctx *appcontext.Context, r Result) (Result, error) {
+				err := i.moveServiceData(ctx, i.exportedCredentials(ctx))
+				return nil, err
+			},
+			"Moving service data",
+		),
 		StepWithProgressBar(
 			func(ctx *appcontext.Context, r Result) (Result, error) {
 				err := i.uploadBlob(ctx)
@@ -125,37 +166,58 @@ func NewImportAppSequence(i *ImportApp) Sequence {
 func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 	c := cache.GetCache(ctx.ImportCFClient)
 
-	org, err := c.GetOrgByName(i.Org)
+	org, err := c.GetOrgByName(i.targetOrg(ctx))
 	if err != nil {
 		return err
 	}
 
-	space, err := c.GetSpaceByName(i.Space, org.Guid)
+	space, err := c.GetSpaceByName(i.targetSpace(ctx), org.Guid)
 	if err != nil {
 		return err
 	}
 
-	cachedApp, err := c.GetAppByName(i.AppName, space.Guid)
-	if err != nil && !cache.IsNotFound(err) {
-		return err
-	}
-
-	manifestPath := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+"_manifest.yml")
+	manifestPath := path.Join(i.Org, i.Space, i.AppName+"_manifest.yml")
 	manifest := export.AppManifest{}
 
-	manifestFile, err := os.Open(manifestPath)
+	manifestFile, err := aio.OpenFS(ctx.ExportFS(), manifestPath, ctx.Opener)
 	if err != nil {
 		return err
 	}
 	defer manifestFile.Close()
 
-	ctx.Logger.Infof("Attempting to read %s to create app %s/%s/%s", manifestFile.Name(), i.Org, i.Space, i.AppName)
+	ctx.Logger.Infof("Attempting to read %s to create app %s/%s/%s", manifestPath, i.Org, i.Space, i.AppName)
 
 	if err = yaml.NewDecoder(manifestFile).Decode(&manifest); err != nil {
 		return err
 	}
 
 	app := manifest.Applications[0]
+	sourceName := app.Name
+	app.Name = ctx.NameMapping.App(i.Org, i.Space, app.Name)
+	app.Env, err = ctx.Secrets.Resolve(app.Env)
+	if err != nil {
+		return err
+	}
+	app.Env = ctx.EnvTransform.Apply(i.Org, i.Space, sourceName, app.Env)
+	app.Buildpacks = mapBuildpacks(ctx.BuildpackMappings, app.Buildpacks)
+
+	cachedApp, err := c.GetAppByName(app.Name, space.Guid)
+	if err != nil && !cache.IsNotFound(err) {
+		return err
+	}
+
+	if cachedApp.Guid != "" {
+		cachedApp, err = i.resolveConflict(ctx, space, cachedApp, &app)
+		if err != nil {
+			return err
+		}
+	}
+	i.runningApp = cachedApp.Guid != "" && cachedApp.State != string(cfclient.APP_STOPPED)
+
+	stackGUID, err := i.resolveStack(ctx, app.Stack)
+	if err != nil {
+		return err
+	}
 
 	var cfApp cfclient.App
 	var retryFunc func() error
@@ -186,14 +248,7 @@ func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 				appRequest.Buildpack = app.Buildpacks[0]
 			}
 
-			if app.Stack != "" {
-				guid, err := c.GetStackGUIDByName(app.Stack)
-				if err != nil {
-					return err
-				}
-
-				appRequest.StackGuid = guid
-			}
+			appRequest.StackGuid = stackGUID
 
 			cfApp, err = ctx.ImportCFClient.CreateApp(appRequest)
 			if err != nil {
@@ -232,14 +287,7 @@ func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 				appRequest.Buildpack = app.Buildpacks[0]
 			}
 
-			if app.Stack != "" {
-				guid, err := c.GetStackGUIDByName(app.Stack)
-				if err != nil {
-					return err
-				}
-
-				appRequest.StackGuid = guid
-			}
+			appRequest.StackGuid = stackGUID
 			appRes, err := ctx.ImportCFClient.UpdateApp(cachedApp.Guid, appRequest)
 			if err != nil {
 				cfErr := cfclient.CloudFoundryHTTPError{}
@@ -309,9 +357,21 @@ func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 
 	i.appGUID = newApp.Guid
 
+	if cachedApp.Guid == "" {
+		err = i.record(ctx, journal.CreateApp, cfApp.Guid, nil)
+		if err == nil {
+			err = annotateOwner(ctx, cfApp.Guid)
+		}
+	} else {
+		err = i.record(ctx, journal.UpdateApp, cfApp.Guid, i.previousAppSettings(ctx, cachedApp))
+	}
+	if err != nil {
+		return err
+	}
+
 	if app.Docker.Image == "" && len(app.Buildpacks) > 1 {
-		stackName := "cflinuxfs3"
-		if newApp.StackGuid != "" {
+		stackName := i.stack
+		if stackName == "" && newApp.StackGuid != "" {
 			sn, err := c.GetStackNameByGUID(newApp.StackGuid)
 			if err != nil {
 				return err
@@ -320,7 +380,7 @@ func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 		}
 
 		updateRequest := cfclient.UpdateV3AppRequest{}
-		updateRequest.Name = i.AppName
+		updateRequest.Name = app.Name
 		updateRequest.Lifecycle = &cfclient.V3Lifecycle{
 			BuildpackData: cfclient.V3BuildpackLifecycle{
 				Buildpacks: app.Buildpacks,
@@ -330,6 +390,14 @@ func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 		}
 		updateRequest.Metadata = &cfclient.V3Metadata{}
 
+		var previousLifecycle *cfclient.UpdateV3AppRequest
+		if cachedApp.Guid != "" && ctx.Journal != nil {
+			previousLifecycle, err = i.getAppLifecycle(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
 		ctx.Logger.Infof("Attempting to update app %s/%s/%s by using V3 API", i.Org, i.Space, i.AppName)
 		err = ctx.ImportCFClient.DoWithRetry(func() error {
 			_, err = ctx.ImportCFClient.UpdateV3App(i.appGUID, updateRequest)
@@ -347,6 +415,12 @@ func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 		if err != nil {
 			return err
 		}
+
+		if previousLifecycle != nil {
+			if err = i.record(ctx, journal.UpdateAppLifecycle, i.appGUID, previousLifecycle); err != nil {
+				return err
+			}
+		}
 	}
 
 	routes := make([]string, 0, len(app.Routes))
@@ -360,19 +434,27 @@ func (i *ImportApp) createApp(ctx *appcontext.Context) error {
 		}
 	}
 
+	i.services = app.Services
+	i.sourceName = sourceName
+	i.targetName = app.Name
 	err = i.bindServices(ctx, app.Services)
 
 	if err != nil {
 		return err
 	}
 
-	ctx.Summary.AddSuccessfulApp(org.Name, space.Name, app.Name)
+	if err = i.bindRouteServices(ctx, app.Routes); err != nil {
+		return err
+	}
+
+	ctx.Summary.AddSuccessfulApp(mapping.Display(i.Org, org.Name), mapping.Display(i.Space, space.Name), mapping.Display(sourceName, app.Name))
 
 	return nil
 }
 
 func (i *ImportApp) bindRoutes(ctx *appcontext.Context, routes []string) error {
 	globalCache := cache.GetCache(ctx.ImportCFClient)
+	i.routeGUIDs = make(map[string]string, len(routes))
 
 	for _, route := range routes {
 		ctx.Logger.Infof("Binding route %s to app %s in org/space %s/%s\n", route, i.AppName, i.Org, i.Space)
@@ -390,12 +472,12 @@ func (i *ImportApp) bindRoutes(ctx *appcontext.Context, routes []string) error {
 			path = "/" + path
 		}
 
-		org, err := globalCache.GetOrgByName(i.Org)
+		org, err := globalCache.GetOrgByName(i.targetOrg(ctx))
 		if err != nil {
 			return err
 		}
 
-		space, err := globalCache.GetSpaceByName(i.Space, org.Guid)
+		space, err := globalCache.GetSpaceByName(i.targetSpace(ctx), org.Guid)
 		if err != nil {
 			return err
 		}
@@ -447,6 +529,9 @@ func (i *ImportApp) bindRoutes(ctx *appcontext.Context, routes []string) error {
 			}
 
 			routeGUID = cfRoute.Guid
+			if err = i.record(ctx, journal.CreateRoute, routeGUID, nil); err != nil {
+				return err
+			}
 		case len(routes) == 1:
 			routeGUID = routes[0].Guid
 			if routes[0].SpaceGuid != space.Guid {
@@ -455,8 +540,9 @@ func (i *ImportApp) bindRoutes(ctx *appcontext.Context, routes []string) error {
 		default:
 			return fmt.Errorf("should have found at most 1 route, but found %d", len(routes))
 		}
+		i.routeGUIDs[route] = routeGUID
 
-		if err = ctx.ImportCFClient.DoWithRetry(func() error {
+		err = ctx.ImportCFClient.DoWithRetry(func() error {
 			err = ctx.ImportCFClient.BindRoute(routeGUID, i.appGUID)
 			cfErr := cfclient.CloudFoundryHTTPError{}
 			if ok := errors.As(err, &cfErr); ok {
@@ -465,7 +551,15 @@ func (i *ImportApp) bindRoutes(ctx *appcontext.Context, routes []string) error {
 				}
 			}
 			return err
-		}); err != nil && !cfclient.IsRouteMappingTakenError(err) {
+		})
+		switch {
+		case cfclient.IsRouteMappingTakenError(err):
+			continue
+		case err != nil:
+			return err
+		}
+
+		if err = i.record(ctx, journal.BindRoute, routeGUID, nil); err != nil {
 			return err
 		}
 	}
@@ -473,24 +567,25 @@ func (i *ImportApp) bindRoutes(ctx *appcontext.Context, routes []string) error {
 	return nil
 }
 
-func (i *ImportApp) bindServices(ctx *appcontext.Context, serviceNames []string) error {
-	if len(serviceNames) == 0 {
+func (i *ImportApp) bindServices(ctx *appcontext.Context, services []export.Service) error {
+	if len(services) == 0 {
 		return nil
 	}
 
 	c := cache.GetCache(ctx.ImportCFClient)
-	org, err := c.GetOrgByName(i.Org)
+	org, err := c.GetOrgByName(i.targetOrg(ctx))
 	if err != nil {
 		return err
 	}
 
-	space, err := c.GetSpaceByName(i.Space, org.Guid)
+	space, err := c.GetSpaceByName(i.targetSpace(ctx), org.Guid)
 	if err != nil {
 		return err
 	}
 
 	siGUID := ""
-	for _, serviceName := range serviceNames {
+	for _, service := range services {
+		serviceName := service.Name
 		ctx.Logger.Infof("Binding service %s to app %s in org/space %s/%s\n", serviceName, i.AppName, i.Org, i.Space)
 		// find the SI that we're going to bind to this app
 		params := url.Values{"q": []string{"name:" + serviceName, "space_guid:" + space.Guid}}
@@ -539,9 +634,17 @@ func (i *ImportApp) bindServices(ctx *appcontext.Context, serviceNames []string)
 		} else {
 			siGUID = sis[0].Guid
 		}
+		if service.BindingName != "" || len(service.Parameters) > 0 {
+			if err = i.bindService(ctx, service, siGUID); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// bind the SI to the app
+		var binding *cfclient.ServiceBinding
 		err = ctx.ImportCFClient.DoWithRetry(func() error {
-			_, err = ctx.ImportCFClient.CreateServiceBinding(i.appGUID, siGUID)
+			binding, err = ctx.ImportCFClient.CreateServiceBinding(i.appGUID, siGUID)
 			if err != nil && !cfclient.IsServiceBindingAppServiceTakenError(err) {
 				cfErr := cfclient.CloudFoundryHTTPError{}
 				if ok := errors.As(err, &cfErr); ok {
@@ -553,17 +656,129 @@ func (i *ImportApp) bindServices(ctx *appcontext.Context, serviceNames []string)
 			}
 			return nil
 		})
+
+		if err == nil && binding != nil {
+			if err = i.record(ctx, journal.CreateServiceBinding, binding.Guid, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bindRouteServices binds the routes of the app to the route services they were bound to on the source foundation,
+// once the routes exist. The route services must already exist in the space.
+func (i *ImportApp) bindRouteServices(ctx *appcontext.Context, routes []export.Route) error {
+	var space cfclient.Space
+	for _, route := range routes {
+		routeGUID := i.routeGUIDs[route.Route]
+		if route.RouteService == nil || routeGUID == "" {
+			continue
+		}
+
+		if space.Guid == "" {
+			c := cache.GetCache(ctx.ImportCFClient)
+			org, err := c.GetOrgByName(i.targetOrg(ctx))
+			if err != nil {
+				return err
+			}
+			space, err = c.GetSpaceByName(i.targetSpace(ctx), org.Guid)
+			if err != nil {
+				return err
+			}
+		}
+
+		name := route.RouteService.Name
+		ctx.Logger.Infof("Binding route %s to route service %s in org/space %s/%s\n", route.Route, name, i.Org, i.Space)
+		var si *cf.ServiceInstance
+		err := withRetry(ctx, func() (err error) {
+			si, err = cf.GetServiceInstanceByName(ctx.ImportCFClient, name, space.Guid)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if si == nil {
+			return &cache.ServiceInstanceNotFoundError{ServiceInstanceName: name}
+		}
+
+		var bindings []cf.RouteBinding
+		err = withRetry(ctx, func() (err error) {
+			bindings, err = cf.ListRouteBindings(ctx.ImportCFClient, []string{routeGUID})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if len(bindings) > 0 {
+			if bindings[0].Relationships.ServiceInstance.Data.GUID != si.GUID {
+				return fmt.Errorf("route %s is already bound to another route service", route.Route)
+			}
+			continue
+		}
+
+		var bindingGUID string
+		err = withRetry(ctx, func() (err error) {
+			bindingGUID, err = cf.CreateRouteBinding(ctx.ImportCFClient, routeGUID, si.GUID, route.RouteService.Parameters)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if err = i.record(ctx, journal.CreateRouteBinding, bindingGUID, nil); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// bindService binds the service instance to the app with the name and the parameters of the binding, which takes the
+// v3 api. Service instances that are already bound to the app are left alone.
+func (i *ImportApp) bindService(ctx *appcontext.Context, service export.Service, siGUID string) error {
+	var bindings []cf.ServiceCredentialBinding
+	err := withRetry(ctx, func() (err error) {
+		bindings, err = cf.ListAppServiceBindings(ctx.ImportCFClient, i.appGUID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, b := range bindings {
+		if b.Relationships.ServiceInstance.Data.GUID == siGUID {
+			return nil
+		}
+	}
+
+	binding := cf.ServiceCredentialBinding{
+		Type:       "app",
+		Name:       service.BindingName,
+		Parameters: service.Parameters,
+		Relationships: cf.ServiceCredentialBindingRelationships{
+			App:             &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: i.appGUID}},
+			ServiceInstance: cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: siGUID}},
+		},
+	}
+	bindingGUID, err := createServiceCredentialBinding(ctx, binding, fmt.Sprintf("binding of service instance %s", service.Name))
+	if bindingGUID == "" {
+		return err
+	}
+
+	// a binding the broker failed to complete is still recorded, so that rollback deletes it
+	if rerr := i.record(ctx, journal.CreateServiceBinding, bindingGUID, nil); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
 func (i *ImportApp) uploadBlob(ctx *appcontext.Context) error {
-	dropletPath := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+".tgz")
-	appBitsPath := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+".zip")
+	dropletPath := path.Join(i.Org, i.Space, i.AppName+".tgz")
+	appBitsPath := path.Join(i.Org, i.Space, i.AppName+".zip")
 
-	dropletInfo, dErr := os.Stat(dropletPath)
-	appBitsInfo, bErr := os.Stat(appBitsPath)
+	dropletInfo, dErr := statBlob(ctx, dropletPath)
+	appBitsInfo, bErr := statBlob(ctx, appBitsPath)
 
 	if dErr != nil && bErr != nil {
 		app, ok := cache.GetCache(ctx.ImportCFClient).GetAppByGUID(i.appGUID)
@@ -576,13 +791,37 @@ func (i *ImportApp) uploadBlob(ctx *appcontext.Context) error {
 		return fmt.Errorf("could not find %s or %s", dropletPath, appBitsPath)
 	}
 
+	info, err := i.readDropletInfo(ctx)
+	if err != nil {
+		return err
+	}
+	restage := i.restage(ctx, info)
+
 	switch {
-	case dropletInfo == nil:
+	case dropletInfo == nil && !restage:
 		return fmt.Errorf("there is no droplet for app %s", i.AppName)
 	case appBitsInfo == nil:
 		return fmt.Errorf("there are no app bits for app %s", i.AppName)
 	}
 
+	if restage {
+		if err = i.verifyBlobs(ctx, info, "", appBitsPath); err != nil {
+			return err
+		}
+		if err = i.uploadAppBits(ctx); err != nil {
+			return err
+		}
+
+		ctx.Logger.Infof("Staging app %s/%s/%s on the target foundation", i.Org, i.Space, i.AppName)
+		return i.putDroplet(ctx, func() error {
+			return i.stageApp(ctx)
+		})
+	}
+
+	if err = i.verifyBlobs(ctx, info, dropletPath, appBitsPath); err != nil {
+		return err
+	}
+
 	if err := i.uploadAppBits(ctx); err != nil {
 		ctx.Logger.Error(err)
 	}
@@ -594,29 +833,192 @@ func (i *ImportApp) uploadBlob(ctx *appcontext.Context) error {
 	return nil
 }
 
+// statBlob stats the named droplet or package, following its reference to the blob store of a deduplicated export
+func statBlob(ctx *appcontext.Context, name string) (fs.FileInfo, error) {
+	name, err := blobstore.Resolve(ctx.ExportFS(), name, ctx.Opener)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(ctx.ExportFS(), name)
+}
+
+// openBlob opens the named droplet or package, following its reference to the blob store of a deduplicated export
+func openBlob(ctx *appcontext.Context, name string) (io.ReadCloser, error) {
+	name, err := blobstore.Resolve(ctx.ExportFS(), name, ctx.Opener)
+	if err != nil {
+		return nil, err
+	}
+	return aio.OpenFS(ctx.ExportFS(), name, ctx.Opener)
+}
+
 func (i *ImportApp) uploadDroplet(c *appcontext.Context) error {
-	dropletFilePath := filepath.Join(c.ExportDir, i.Org, i.Space, i.AppName+".tgz")
+	dropletFilePath := path.Join(i.Org, i.Space, i.AppName+".tgz")
 	c.Logger.Infof("Uploading droplet for app %s/%s/%s", i.Org, i.Space, i.AppName)
-	dropletReader, err := os.Open(dropletFilePath)
+	dropletReader, err := openBlob(c, dropletFilePath)
 	if err != nil {
 		return err
 	}
 	defer dropletReader.Close()
 
-	err = c.ImportCFClient.DoWithRetry(func() error {
-		_, err = c.ImportCFClient.UploadDropletBits(dropletReader, i.appGUID)
-		cfErr := cfclient.CloudFoundryHTTPError{}
-		if ok := errors.As(err, &cfErr); ok {
-			if cfErr.StatusCode >= 500 && cfErr.StatusCode <= 599 {
-				return fmt.Errorf("received HTTP error: %d", cfErr.StatusCode)
+	info, err := i.readDropletInfo(c)
+	if err != nil {
+		return err
+	}
+	if info != nil && len(info.ProcessTypes) > 0 {
+		size := int64(-1)
+		if fi, err := statBlob(c, dropletFilePath); err == nil && c.Opener == nil {
+			size = fi.Size()
+		}
+		return i.putDroplet(c, func() error {
+			return i.uploadDropletWithProcessTypes(c, dropletReader, size, info.ProcessTypes)
+		})
+	}
+
+	return i.putDroplet(c, func() error {
+		return c.ImportCFClient.DoWithRetry(func() error {
+			_, err := c.ImportCFClient.UploadDropletBits(dropletReader, i.appGUID)
+			cfErr := cfclient.CloudFoundryHTTPError{}
+			if ok := errors.As(err, &cfErr); ok {
+				if cfErr.StatusCode >= 500 && cfErr.StatusCode <= 599 {
+					return fmt.Errorf("received HTTP error: %d", cfErr.StatusCode)
+				}
 			}
+			return err
+		})
+	})
+}
+
+// uploadDropletWithProcessTypes uploads the droplet read from r to a new droplet with the process types of the exported
+// one, and makes it the current droplet of the app once it is processed
+func (i *ImportApp) uploadDropletWithProcessTypes(c *appcontext.Context, r io.Reader, size int64, processTypes map[string]string) error {
+	var droplet cfclient.V3Droplet
+	err := c.ImportCFClient.DoWithRetry(func() error {
+		var err error
+		droplet, err = cf.CreateDroplet(c.ImportCFClient, i.appGUID, processTypes)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = cf.StreamDropletBits(c.ImportCFClient, droplet.GUID, r, size); err != nil {
+		return err
+	}
+
+	err = waitUntil(15*time.Minute, "droplet to be processed", func() (bool, error) {
+		if droplet, err = cf.GetDroplet(c.ImportCFClient, droplet.GUID); err != nil {
+			return false, err
 		}
+		if droplet.State == "FAILED" || droplet.State == "EXPIRED" {
+			return false, fmt.Errorf("bad droplet state %s", droplet.State)
+		}
+		return droplet.State == "STAGED", nil
+	})
+	if err != nil {
 		return err
+	}
+
+	return c.ImportCFClient.DoWithRetry(func() error {
+		return cf.SetCurrentDroplet(c.ImportCFClient, i.appGUID, droplet.GUID)
 	})
+}
+
+// readDropletInfo reads the description of the exported droplet, it returns nil for exports that have none
+func (i *ImportApp) readDropletInfo(ctx *appcontext.Context) (*export.DropletInfo, error) {
+	file, err := aio.OpenFS(ctx.ExportFS(), path.Join(i.Org, i.Space, i.AppName+export.DropletInfoSuffix), ctx.Opener)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var info export.DropletInfo
+	if err = json.NewDecoder(file).Decode(&info); err != nil {
+		return nil, fmt.Errorf("error reading the droplet info of %s: %w", i.AppName, err)
+	}
+
+	return &info, nil
+}
+
+// verifyBlobs checks the exported droplet and package against the checksums the source foundation reported for them,
+// so that an app is never imported with a droplet staged from other bits than the package imported with it. The
+// droplet is not checked when dropletPath is empty.
+func (i *ImportApp) verifyBlobs(ctx *appcontext.Context, info *export.DropletInfo, dropletPath, appBitsPath string) error {
+	if info == nil {
+		return nil
+	}
+
+	if dropletPath != "" {
+		if err := verifyChecksum(ctx, dropletPath, info.Checksum); err != nil {
+			return err
+		}
+	}
+	if info.Package != nil {
+		return verifyChecksum(ctx, appBitsPath, info.Package.Checksum)
+	}
+
+	return nil
+}
+
+func verifyChecksum(ctx *appcontext.Context, name string, checksum export.Checksum) error {
+	var h hash.Hash
+	switch strings.ToLower(checksum.Type) {
+	case "sha256":
+		h = sha256.New()
+	case "sha1":
+		h = sha1.New()
+	default:
+		return nil
+	}
+
+	resolved, err := blobstore.Resolve(ctx.ExportFS(), name, ctx.Opener)
 	if err != nil {
 		return err
 	}
 
+	// the blob store checks the content of the blobs against their digest as it writes them, so a deduplicated blob
+	// is identified by its name without reading it
+	if dir, digest := path.Split(resolved); path.Clean(dir) == blobstore.Dir && strings.EqualFold(checksum.Type, "sha256") {
+		if !strings.EqualFold(digest, checksum.Value) {
+			return fmt.Errorf("%s checksum of %s is %s, expected %s", checksum.Type, name, digest, checksum.Value)
+		}
+		return nil
+	}
+
+	file, err := aio.OpenFS(ctx.ExportFS(), resolved, ctx.Opener)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(h, file); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, checksum.Value) {
+		return fmt.Errorf("%s checksum of %s is %s, expected %s", checksum.Type, name, actual, checksum.Value)
+	}
+
+	return nil
+}
+
+// putDroplet runs upload to replace the droplet of the app, then waits for the new droplet to be staged
+func (i *ImportApp) putDroplet(c *appcontext.Context, upload func() error) error {
+	var previousDropletGUID string
+	if c.Journal != nil {
+		var err error
+		previousDropletGUID, err = i.getCurrentDropletGUID(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := upload(); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
 
@@ -684,13 +1086,13 @@ func (i *ImportApp) uploadDroplet(c *appcontext.Context) error {
 	}
 	c.Logger.Infof("Close uploading droplet for app %s/%s/%s", i.Org, i.Space, i.AppName)
 
-	return nil
+	return i.record(c, journal.UploadDroplet, droplet.GUID, previousDropletGUID)
 }
 
 func (i *ImportApp) uploadAppBits(ctx *appcontext.Context) error {
-	zipFilePath := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+".zip")
+	zipFilePath := path.Join(i.Org, i.Space, i.AppName+".zip")
 	ctx.Logger.Infof("Uploading bits for app %s/%s/%s", i.Org, i.Space, i.AppName)
-	zipFile, err := os.Open(zipFilePath)
+	zipFile, err := openBlob(ctx, zipFilePath)
 	if err != nil {
 		return err
 	}
@@ -709,11 +1111,10 @@ func (i *ImportApp) uploadAppBits(ctx *appcontext.Context) error {
 }
 
 func (i *ImportApp) applyAutoscalerRules(ctx *appcontext.Context) error {
-	rulesFile := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+"_autoscale_rules.json")
-	contents, err := os.Open(rulesFile)
+	rulesFile := path.Join(i.Org, i.Space, i.AppName+"_autoscale_rules.json")
+	contents, err := aio.OpenFS(ctx.ExportFS(), rulesFile, ctx.Opener)
 	if err != nil {
 		if os.IsNotExist(err) {
-			defer contents.Close()
 			ctx.Logger.Infof("No autoscaler rules exist for app %s/%s/%s", i.Org, i.Space, i.AppName)
 			return nil
 		}
@@ -726,7 +1127,13 @@ func (i *ImportApp) applyAutoscalerRules(ctx *appcontext.Context) error {
 
 	autoscalerBase := strings.Replace(ctx.ImportCFClient.Target(), "/api.", "/autoscale.", 1)
 
-	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/api/v2/apps/%s/rules", autoscalerBase, i.appGUID), contents)
+	autoscalerURL := fmt.Sprintf("%s/api/v2/apps/%s/rules", autoscalerBase, i.appGUID)
+	previous, err := i.getAutoscalerState(ctx, autoscalerURL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, autoscalerURL, contents)
 	if err != nil {
 		defer req.Body.Close()
 		return err
@@ -755,17 +1162,20 @@ func (i *ImportApp) applyAutoscalerRules(ctx *appcontext.Context) error {
 		return fmt.Errorf("expected an HTTP 2xx code, got %d instead", resp.StatusCode)
 	}
 
-	return nil
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return i.record(ctx, journal.UpdateAutoscalerRules, i.appGUID, previous)
 
 }
 
 func (i *ImportApp) applyAutoscalerInstances(ctx *appcontext.Context) error {
-	instanceLimitsFile := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+"_autoscale_instances.json")
+	instanceLimitsFile := path.Join(i.Org, i.Space, i.AppName+"_autoscale_instances.json")
 
-	contents, err := os.Open(instanceLimitsFile)
+	contents, err := aio.OpenFS(ctx.ExportFS(), instanceLimitsFile, ctx.Opener)
 	if err != nil {
 		if os.IsNotExist(err) {
-			defer contents.Close()
 			ctx.Logger.Infof("No autoscaler instance limits exist for app %s/%s/%s", i.Org, i.Space, i.AppName)
 			return nil
 		}
@@ -778,7 +1188,13 @@ func (i *ImportApp) applyAutoscalerInstances(ctx *appcontext.Context) error {
 
 	autoscalerBase := strings.Replace(ctx.ImportCFClient.Target(), "/api.", "/autoscale.", 1)
 
-	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/api/v2/apps/%s", autoscalerBase, i.appGUID), contents)
+	autoscalerURL := fmt.Sprintf("%s/api/v2/apps/%s", autoscalerBase, i.appGUID)
+	previous, err := i.getAutoscalerState(ctx, autoscalerURL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, autoscalerURL, contents)
 	if err != nil {
 		defer req.Body.Close()
 		return err
@@ -808,16 +1224,19 @@ func (i *ImportApp) applyAutoscalerInstances(ctx *appcontext.Context) error {
 		return fmt.Errorf("expected an HTTP 2xx code, got %d instead", resp.StatusCode)
 	}
 
-	return nil
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return i.record(ctx, journal.UpdateAutoscalerInstances, i.appGUID, previous)
 }
 
 func (i *ImportApp) applyAutoscalerSchedules(ctx *appcontext.Context) error {
-	scheduleFile := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+"_autoscale_schedules.json")
+	scheduleFile := path.Join(i.Org, i.Space, i.AppName+"_autoscale_schedules.json")
 
-	contents, err := os.Open(scheduleFile)
+	contents, err := aio.OpenFS(ctx.ExportFS(), scheduleFile, ctx.Opener)
 	if err != nil {
 		if os.IsNotExist(err) {
-			defer contents.Close()
 			ctx.Logger.Infof("No autoscaler schedules exist for app %s/%s/%s", i.Org, i.Space, i.AppName)
 			return nil
 		}
@@ -830,7 +1249,13 @@ func (i *ImportApp) applyAutoscalerSchedules(ctx *appcontext.Context) error {
 
 	autoscalerBase := strings.Replace(ctx.ImportCFClient.Target(), "/api.", "/autoscale.", 1)
 
-	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/api/v2/apps/%s/scheduled_limit_changes", autoscalerBase, i.appGUID), contents)
+	autoscalerURL := fmt.Sprintf("%s/api/v2/apps/%s/scheduled_limit_changes", autoscalerBase, i.appGUID)
+	previous, err := i.getAutoscalerState(ctx, autoscalerURL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, autoscalerURL, contents)
 	if err != nil {
 		defer req.Body.Close()
 		return err
@@ -859,13 +1284,17 @@ func (i *ImportApp) applyAutoscalerSchedules(ctx *appcontext.Context) error {
 		return fmt.Errorf("expected an HTTP 2xx code, got %d instead", resp.StatusCode)
 	}
 
-	return nil
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return i.record(ctx, journal.UpdateAutoscalerSchedules, i.appGUID, previous)
 }
 
 func (i *ImportApp) getAppNameFromManifest(ctx *appcontext.Context) (string, error) {
-	fileName := filepath.Join(ctx.ExportDir, i.Org, i.Space, i.AppName+"_manifest.yml")
+	fileName := path.Join(i.Org, i.Space, i.AppName+"_manifest.yml")
 
-	file, err := os.Open(fileName)
+	file, err := aio.OpenFS(ctx.ExportFS(), fileName, ctx.Opener)
 	if err != nil {
 		return "", err
 	}
@@ -887,6 +1316,175 @@ func (i *ImportApp) getAppNameFromManifest(ctx *appcontext.Context) (string, err
 	return sanitizedAppName, nil
 }
 
+func (i *ImportApp) record(ctx *appcontext.Context, action journal.Action, guid string, previous interface{}) error {
+	entry := journal.Entry{
+		Action:  action,
+		Org:     i.Org,
+		Space:   i.Space,
+		App:     i.AppName,
+		GUID:    guid,
+		AppGUID: i.appGUID,
+	}
+
+	if err := record(ctx, entry, previous); err != nil {
+		return fmt.Errorf("failed to record %s for app %s/%s/%s: %w", action, i.Org, i.Space, i.AppName, err)
+	}
+
+	return nil
+}
+
+// recordResource journals an action taken on a resource that is not an app, which the entry names, so that a
+// rollback can undo it
+func recordResource(ctx *appcontext.Context, entry journal.Entry, previous interface{}) error {
+	if err := record(ctx, entry, previous); err != nil {
+		return fmt.Errorf("failed to record %s of %s: %w", entry.Action, entry.Name, err)
+	}
+
+	return nil
+}
+
+// record adds the entry to the journal of the run, with the previous state of its resource when there is one
+func record(ctx *appcontext.Context, entry journal.Entry, previous interface{}) error {
+	if ctx.Journal == nil {
+		return nil
+	}
+
+	if previous != nil {
+		data, err := json.Marshal(previous)
+		if err != nil {
+			return err
+		}
+		entry.Previous = data
+	}
+
+	return ctx.Journal.Record(entry)
+}
+
+// previousAppSettings captures the settings of an existing app so they can be restored by a rollback.
+// Its env vars are only captured when the journal is sealed, they are not written to the journal in plain text.
+func (i *ImportApp) previousAppSettings(ctx *appcontext.Context, app cfclient.App) cfclient.AppUpdateResource {
+	previous := cfclient.AppUpdateResource{
+		Name:                    app.Name,
+		Memory:                  app.Memory,
+		Instances:               app.Instances,
+		DiskQuota:               app.DiskQuota,
+		StackGuid:               app.StackGuid,
+		Command:                 app.Command,
+		Buildpack:               app.Buildpack,
+		HealthCheckHttpEndpoint: app.HealthCheckHttpEndpoint,
+		HealthCheckType:         app.HealthCheckType,
+		HealthCheckTimeout:      app.HealthCheckTimeout,
+		DockerImage:             app.DockerImage,
+	}
+	if ctx.Journal.Sealed() {
+		previous.Environment = app.Environment
+	} else if ctx.Journal != nil && len(app.Environment) > 0 {
+		ctx.Logger.Warnf("No key to seal the journal with, the env vars of app %s/%s/%s are not journaled and will not be restored by a rollback", i.Org, i.Space, i.AppName)
+	}
+	if app.DockerImage != "" {
+		previous.DockerCredentials = map[string]interface{}{
+			"username": app.DockerCredentials.Username,
+		}
+	}
+
+	return previous
+}
+
+func (i *ImportApp) getAppLifecycle(ctx *appcontext.Context) (*cfclient.UpdateV3AppRequest, error) {
+	body, err := ctx.ImportCFClient.Get(fmt.Sprintf("/v3/apps/%s", i.appGUID))
+	if err != nil {
+		return nil, err
+	}
+
+	var v3app cfclient.V3App
+	if err = json.Unmarshal(body, &v3app); err != nil {
+		return nil, err
+	}
+
+	return &cfclient.UpdateV3AppRequest{
+		Name:      v3app.Name,
+		Lifecycle: &v3app.Lifecycle,
+		Metadata:  &cfclient.V3Metadata{},
+	}, nil
+}
+
+func (i *ImportApp) getCurrentDropletGUID(ctx *appcontext.Context) (string, error) {
+	var droplet cfclient.V3Droplet
+	err := ctx.ImportCFClient.DoWithRetry(func() error {
+		req := ctx.ImportCFClient.NewRequest(http.MethodGet, fmt.Sprintf("/v3/apps/%s/droplets/current", i.appGUID))
+		resp, err := ctx.ImportCFClient.DoRequest(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 500 && resp.StatusCode <= 599 {
+			return cf.ErrRetry
+		}
+
+		return json.NewDecoder(resp.Body).Decode(&droplet)
+	})
+	if err != nil {
+		if cfclient.IsResourceNotFoundError(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return droplet.GUID, nil
+}
+
+// getAutoscalerState returns the autoscaler configuration stored at url before it is overwritten,
+// or nil if there isn't any
+func (i *ImportApp) getAutoscalerState(ctx *appcontext.Context, url string) (json.RawMessage, error) {
+	if ctx.Journal == nil {
+		return nil, nil
+	}
+
+	var state json.RawMessage
+	err := ctx.ImportCFClient.DoWithRetry(func() error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := ctx.ImportCFClient.Do(req)
+		if err != nil {
+			cfErr := cfclient.CloudFoundryHTTPError{}
+			if errors.As(err, &cfErr) && cfErr.StatusCode == http.StatusNotFound {
+				return nil
+			}
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 500 && resp.StatusCode <= 599:
+			return cf.ErrRetry
+		case resp.StatusCode != http.StatusOK:
+			return nil
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		// rules and schedules are listed as a page of resources but replaced with a plain list
+		var page struct {
+			Resources json.RawMessage `json:"resources"`
+		}
+		if err = json.Unmarshal(body, &page); err == nil && page.Resources != nil {
+			state = page.Resources
+		} else if json.Valid(body) {
+			state = body
+		}
+
+		return nil
+	})
+
+	return state, err
+}
+
 func getSizeFromString(sizeStr string) int {
 	lastChar := sizeStr[len(sizeStr)-1:]
 	size := sizeStr[:len(sizeStr)-1]
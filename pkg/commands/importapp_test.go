@@ -16,6 +16,8 @@ package commands
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -30,13 +32,18 @@ import (
 
 	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
 	ctxfakes "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context/fakes"
 
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/blobstore"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
 )
 
@@ -143,6 +150,89 @@ func TestImportApp_Run(t *testing.T) {
 			},
 			successfulAppCount: 1,
 		},
+		{
+			name: "imports app from a bundle",
+			fields: fields{
+				ImportSpace: ImportSpace{
+					ImportOrg: ImportOrg{Org: "my_org"},
+					Space:     "my_space",
+				},
+				AppName:  "my_app",
+				appGUID:  "6064d98a-95e6-400b-bc03-be65e6d59622",
+				out:      &bytes.Buffer{},
+				AppCount: 1,
+			},
+			args: args{
+				ctx: &context.Context{
+					Logger:    logger,
+					ExportDir: "/doesnotexist",
+					Bundle:    packTestdata(t),
+					Metadata:  metadata.NewMetadata(),
+					Summary:   report.NewSummary(&bytes.Buffer{}),
+					ImportCFClient: &fakes.FakeClient{
+						DoWithRetryStub: func(f func() error) error {
+							return f()
+						},
+						GetOrgByNameStub: func(s string) (cfclient.Org, error) {
+							return cfclient.Org{
+								Name: "my_org",
+							}, nil
+						},
+						GetSpaceByNameStub: func(string, string) (cfclient.Space, error) {
+							return cfclient.Space{
+								Name: "my_space",
+							}, nil
+						},
+						ListAppsByQueryStub: func(url.Values) ([]cfclient.App, error) {
+							return []cfclient.App{
+								{
+									Name: "my_app",
+								},
+							}, nil
+						},
+						GetAppByGuidNoInlineCallStub: func(string) (cfclient.App, error) {
+							return cfclient.App{
+								Name: "my_app",
+							}, nil
+						},
+						ListUserProvidedServiceInstancesByQueryStub: func(url.Values) ([]cfclient.UserProvidedServiceInstance, error) {
+							return []cfclient.UserProvidedServiceInstance{
+								{
+									Name: "my_ups",
+								},
+							}, nil
+						},
+						DoRequestStub: func(*cfclient.Request) (*http.Response, error) {
+							data, err := ioutil.ReadFile("testdata/v3droplets.json")
+							assert.NoError(t, err)
+							stringReader := strings.NewReader(string(data))
+							resp := &http.Response{
+								Body: io.NopCloser(stringReader),
+							}
+							return resp, nil
+						},
+						DoStub: func(req *http.Request) (*http.Response, error) {
+							stringReader := strings.NewReader("")
+							resp := &http.Response{
+								Body:       io.NopCloser(stringReader),
+								StatusCode: http.StatusOK,
+							}
+							return resp, nil
+						},
+					},
+					SpaceImporter: &ctxfakes.FakeSpaceImporter{
+						ImportSpaceStub: func(ctx *context.Context, processFunc context.ProcessFunc, strings []string) (<-chan context.ProcessResult, error) {
+							results := make(chan context.ProcessResult, 1)
+							defer close(results)
+							results <- context.ProcessResult{Value: "my_app"}
+							ctx.Summary.AddSuccessfulApp("my_org", "my_space", "my_app")
+							return results, nil
+						},
+					},
+				},
+			},
+			successfulAppCount: 1,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -481,6 +571,97 @@ func TestImportApp_bindRoutes(t *testing.T) {
 	}
 }
 
+func TestImportApp_bindRouteServices(t *testing.T) {
+	gateway := &export.RouteService{Name: "gateway", Parameters: map[string]interface{}{"rate": "100"}}
+	tests := []struct {
+		name         string
+		routes       []export.Route
+		bindings     string
+		wantRequests []string
+		wantErr      string
+	}{
+		{
+			name:     "binds the route to its route service",
+			routes:   []export.Route{{Route: "a.example.com", RouteService: gateway}, {Route: "b.example.com"}},
+			bindings: `[]`,
+			wantRequests: []string{
+				`POST /v3/service_route_bindings {"parameters":{"rate":"100"},"relationships":{"route":{"data":{"guid":"route-a-guid"}},"service_instance":{"data":{"guid":"gateway-guid"}}}}`,
+			},
+		},
+		{
+			name:     "skips routes already bound to the route service",
+			routes:   []export.Route{{Route: "a.example.com", RouteService: gateway}},
+			bindings: `[{"guid": "binding-guid", "relationships": {"route": {"data": {"guid": "route-a-guid"}}, "service_instance": {"data": {"guid": "gateway-guid"}}}}]`,
+		},
+		{
+			name:     "fails for routes bound to another route service",
+			routes:   []export.Route{{Route: "a.example.com", RouteService: gateway}},
+			bindings: `[{"guid": "binding-guid", "relationships": {"route": {"data": {"guid": "route-a-guid"}}, "service_instance": {"data": {"guid": "waf-guid"}}}}]`,
+			wantErr:  "route a.example.com is already bound to another route service",
+		},
+		{
+			name:     "fails when the route service does not exist",
+			routes:   []export.Route{{Route: "a.example.com", RouteService: &export.RouteService{Name: "waf"}}},
+			bindings: `[]`,
+			wantErr:  "Expected to find one service instance named waf, but found 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+
+			var requests []string
+			client := &fakes.FakeClient{
+				DoWithRetryStub: func(f func() error) error {
+					return f()
+				},
+				GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+					return cfclient.Org{Guid: "org-guid", Name: name}, nil
+				},
+				GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+					return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+				},
+				GetStub: func(path string) ([]byte, error) {
+					switch path {
+					case "/v3/service_instances?" + url.Values{"names": []string{"gateway"}, "space_guids": []string{"space-guid"}}.Encode():
+						return []byte(`{"pagination": {}, "resources": [{"guid": "gateway-guid", "name": "gateway", "type": "managed"}]}`), nil
+					case "/v3/service_instances?" + url.Values{"names": []string{"waf"}, "space_guids": []string{"space-guid"}}.Encode():
+						return []byte(`{"pagination": {}, "resources": []}`), nil
+					case "/v3/service_route_bindings?route_guids=route-a-guid":
+						return []byte(`{"pagination": {}, "resources": ` + tt.bindings + `}`), nil
+					}
+					return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+				},
+				NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+					data, err := io.ReadAll(body)
+					assert.NoError(t, err)
+					requests = append(requests, method+" "+path+" "+string(data))
+					return &cfclient.Request{}
+				},
+				DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{"guid": "new-binding-guid"}`))}, nil
+				},
+			}
+			ctx := &context.Context{Logger: log.New(), ImportCFClient: client}
+
+			i := &ImportApp{
+				ImportSpace: ImportSpace{ImportOrg: ImportOrg{Org: "my_org"}, Space: "my_space"},
+				AppName:     "my_app",
+				routeGUIDs:  map[string]string{"a.example.com": "route-a-guid", "b.example.com": "route-b-guid"},
+			}
+			err := i.bindRouteServices(ctx, tt.routes)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRequests, requests)
+		})
+	}
+}
+
 func TestImportApp_bindServices(t *testing.T) {
 	type fields struct {
 		ImportSpace ImportSpace
@@ -490,8 +671,8 @@ func TestImportApp_bindServices(t *testing.T) {
 		AppCount    int
 	}
 	type args struct {
-		ctx          *context.Context
-		serviceNames []string
+		ctx      *context.Context
+		services []export.Service
 	}
 	pwd, _ := os.Getwd()
 	logger := log.New()
@@ -549,7 +730,7 @@ func TestImportApp_bindServices(t *testing.T) {
 						},
 					},
 				},
-				serviceNames: []string{"my-service"},
+				services: []export.Service{{Name: "my-service"}},
 			},
 		},
 		{
@@ -595,7 +776,7 @@ func TestImportApp_bindServices(t *testing.T) {
 						},
 					},
 				},
-				serviceNames: []string{"my-service"},
+				services: []export.Service{{Name: "my-service"}},
 			},
 		},
 	}
@@ -610,13 +791,85 @@ func TestImportApp_bindServices(t *testing.T) {
 				appGUID:     tt.fields.appGUID,
 				AppCount:    tt.fields.AppCount,
 			}
-			if err := i.bindServices(tt.args.ctx, tt.args.serviceNames); (err != nil) != tt.wantErr {
+			if err := i.bindServices(tt.args.ctx, tt.args.services); (err != nil) != tt.wantErr {
 				t.Errorf("bindServices() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
+func TestImportApp_bindServicesWithBindingNames(t *testing.T) {
+	tests := []struct {
+		name         string
+		services     []export.Service
+		bindings     string
+		wantRequests []string
+	}{
+		{
+			name:     "binds with the binding name and parameters",
+			services: []export.Service{{Name: "my-service", BindingName: "db", Parameters: map[string]interface{}{"role": "reader"}}},
+			bindings: `[]`,
+			wantRequests: []string{
+				`POST /v3/service_credential_bindings {"type":"app","name":"db","parameters":{"role":"reader"},"relationships":{"app":{"data":{"guid":"app-guid"}},"service_instance":{"data":{"guid":"si-guid"}}}}`,
+			},
+		},
+		{
+			name:     "skips service instances already bound to the app",
+			services: []export.Service{{Name: "my-service", BindingName: "db"}},
+			bindings: `[{"guid": "binding-guid", "type": "app", "name": "db", "relationships": {"app": {"data": {"guid": "app-guid"}}, "service_instance": {"data": {"guid": "si-guid"}}}}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+
+			var requests []string
+			client := &fakes.FakeClient{
+				DoWithRetryStub: func(f func() error) error {
+					return f()
+				},
+				GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+					return cfclient.Org{Guid: "org-guid", Name: name}, nil
+				},
+				GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+					return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+				},
+				ListServiceInstancesByQueryStub: func(url.Values) ([]cfclient.ServiceInstance, error) {
+					return []cfclient.ServiceInstance{{Guid: "si-guid", Name: "my-service"}}, nil
+				},
+				GetStub: func(path string) ([]byte, error) {
+					query := url.Values{"type": []string{"app"}, "app_guids": []string{"app-guid"}}
+					if path == "/v3/service_credential_bindings?"+query.Encode() {
+						return []byte(`{"pagination": {}, "resources": ` + tt.bindings + `}`), nil
+					}
+					return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+				},
+				NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+					data, err := io.ReadAll(body)
+					assert.NoError(t, err)
+					requests = append(requests, method+" "+path+" "+string(data))
+					return &cfclient.Request{}
+				},
+				DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{"guid": "new-binding-guid"}`))}, nil
+				},
+			}
+			ctx := &context.Context{Logger: log.New(), ImportCFClient: client}
+
+			i := &ImportApp{
+				ImportSpace: ImportSpace{ImportOrg: ImportOrg{Org: "my_org"}, Space: "my_space"},
+				AppName:     "my_app",
+				appGUID:     "app-guid",
+			}
+			require.NoError(t, i.bindServices(ctx, tt.services))
+			assert.Equal(t, tt.wantRequests, requests)
+			assert.Equal(t, 0, client.CreateServiceBindingCallCount())
+		})
+	}
+}
+
 func TestImportApp_createApp(t *testing.T) {
 	type fields struct {
 		ImportSpace ImportSpace
@@ -693,6 +946,12 @@ func TestImportApp_createApp(t *testing.T) {
 									Name: "service-provided-instance",
 								}}, nil
 							},
+							DoRequestStub: func(*cfclient.Request) (*http.Response, error) {
+								return &http.Response{
+									StatusCode: http.StatusOK,
+									Body:       io.NopCloser(strings.NewReader("{}")),
+								}, nil
+							},
 						},
 						DoWithRetryFunc: func(f func() error) error {
 							return f()
@@ -725,6 +984,130 @@ func TestImportApp_createApp(t *testing.T) {
 	}
 }
 
+func TestImportApp_createAppWithNameMapping(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+	pwd, _ := os.Getwd()
+	fakeClient := &fakes.FakeClient{
+		ListAppsByQueryStub: func(url.Values) ([]cfclient.App, error) {
+			return []cfclient.App{}, nil
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "f2a8f9f4-51fa-4f0a-b1a3-4f2f8a8a5d2e", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name string, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "3d3a9ab2-1e7c-4a41-a5d4-1f0c7c8d2f51", Name: name}, nil
+		},
+		CreateAppStub: func(req cfclient.AppCreateRequest) (cfclient.App, error) {
+			return cfclient.App{Guid: "6064d98a-95e6-400b-bc03-be65e6d59622", Name: req.Name}, nil
+		},
+		ListUserProvidedServiceInstancesByQueryStub: func(url.Values) ([]cfclient.UserProvidedServiceInstance, error) {
+			return []cfclient.UserProvidedServiceInstance{{Name: "name-1508"}}, nil
+		},
+		DoRequestStub: func(*cfclient.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("{}")),
+			}, nil
+		},
+	}
+	ctx := &context.Context{
+		Logger:    log.New(),
+		ExportDir: filepath.Join(pwd, "testdata/apps"),
+		Metadata:  metadata.NewMetadata(),
+		Summary:   report.NewSummary(&bytes.Buffer{}),
+		NameMapping: &mapping.Names{
+			Orgs:   mapping.Mapping{Names: map[string]string{"my_org": "new_org"}},
+			Spaces: mapping.Mapping{Names: map[string]string{"my_org/my_space": "new_space"}},
+			Apps:   mapping.Mapping{Names: map[string]string{"my_app": "new_app"}},
+		},
+		ImportCFClient: StubClient{
+			FakeClient: fakeClient,
+			DoWithRetryFunc: func(f func() error) error {
+				return f()
+			},
+		},
+	}
+	i := &ImportApp{
+		ImportSpace: ImportSpace{
+			ImportOrg: ImportOrg{Org: "my_org"},
+			Space:     "my_space",
+		},
+		AppName: "my_app",
+	}
+
+	err := i.createApp(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new_org", fakeClient.GetOrgByNameArgsForCall(0))
+	spaceName, _ := fakeClient.GetSpaceByNameArgsForCall(0)
+	assert.Equal(t, "new_space", spaceName)
+	assert.Equal(t, "new_app", fakeClient.CreateAppArgsForCall(0).Name)
+	assert.Equal(t, []report.Result{{
+		OrgName:   "my_org -> new_org",
+		SpaceName: "my_space -> new_space",
+		AppName:   "my_app -> new_app",
+		Message:   "successful",
+	}}, ctx.Summary.Results())
+}
+
+func TestImportApp_createAppWithBuildpackMapping(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+	exportDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(exportDir, "my_org", "my_space"), 0700))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(exportDir, "my_org", "my_space", "my_app_manifest.yml"),
+		[]byte("applications:\n- name: my_app\n  buildpacks: [java_buildpack_offline]\n  memory: 1G\n  disk_quota: 1G\n"),
+		0600,
+	))
+	fakeClient := &fakes.FakeClient{
+		ListAppsByQueryStub: func(url.Values) ([]cfclient.App, error) {
+			return []cfclient.App{}, nil
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "f2a8f9f4-51fa-4f0a-b1a3-4f2f8a8a5d2e", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name string, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "3d3a9ab2-1e7c-4a41-a5d4-1f0c7c8d2f51", Name: name}, nil
+		},
+		CreateAppStub: func(req cfclient.AppCreateRequest) (cfclient.App, error) {
+			return cfclient.App{Guid: "6064d98a-95e6-400b-bc03-be65e6d59622", Name: req.Name}, nil
+		},
+		DoRequestStub: func(*cfclient.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("{}")),
+			}, nil
+		},
+	}
+	ctx := &context.Context{
+		Logger:            log.New(),
+		ExportDir:         exportDir,
+		Metadata:          metadata.NewMetadata(),
+		Summary:           report.NewSummary(&bytes.Buffer{}),
+		BuildpackMappings: map[string]string{"java_buildpack_offline": "java_buildpack"},
+		ImportCFClient: StubClient{
+			FakeClient: fakeClient,
+			DoWithRetryFunc: func(f func() error) error {
+				return f()
+			},
+		},
+	}
+	i := &ImportApp{
+		ImportSpace: ImportSpace{
+			ImportOrg: ImportOrg{Org: "my_org"},
+			Space:     "my_space",
+		},
+		AppName: "my_app",
+	}
+
+	require.NoError(t, i.createApp(ctx))
+	assert.Equal(t, "java_buildpack", fakeClient.CreateAppArgsForCall(0).Buildpack)
+}
+
 func TestImportApp_getAppNameFromManifest(t *testing.T) {
 	type fields struct {
 		ImportSpace ImportSpace
@@ -798,6 +1181,11 @@ func TestImportApp_uploadAppBits(t *testing.T) {
 	}
 	pwd, _ := os.Getwd()
 	logger := log.New()
+	dedupeDir := t.TempDir()
+	_, err := blobstore.New(storage.NewLocal(dedupeDir), nil).Put("my_org/my_space/my_app.zip", "", func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("deduplicated app bits")), nil
+	})
+	require.NoError(t, err)
 	tests := []struct {
 		name    string
 		fields  fields
@@ -829,6 +1217,36 @@ func TestImportApp_uploadAppBits(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "uploads app bits from a deduplicated export",
+			fields: fields{
+				ImportSpace: ImportSpace{
+					ImportOrg: ImportOrg{Org: "my_org"},
+					Space:     "my_space",
+				},
+				AppName: "my_app",
+				appGUID: "6064d98a-95e6-400b-bc03-be65e6d59622",
+			},
+			args: args{
+				ctx: &context.Context{
+					Logger:    logger,
+					ExportDir: dedupeDir,
+					ImportCFClient: StubClient{
+						FakeClient: &fakes.FakeClient{
+							UploadAppBitsStub: func(r io.Reader, appGUID string) error {
+								data, err := io.ReadAll(r)
+								assert.NoError(t, err)
+								assert.Equal(t, "deduplicated app bits", string(data))
+								return nil
+							},
+						},
+						DoWithRetryFunc: func(f func() error) error {
+							return f()
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -992,6 +1410,156 @@ func TestImportApp_uploadDroplet(t *testing.T) {
 	}
 }
 
+func TestImportApp_uploadDropletWithProcessTypes(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+	exportDir := t.TempDir()
+	spaceDir := filepath.Join(exportDir, "my_org", "my_space")
+	require.NoError(t, os.MkdirAll(spaceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(spaceDir, "my_app.tgz"), []byte("droplet data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(spaceDir, "my_app"+export.DropletInfoSuffix), []byte(`{"guid":"585bc3c1-3743-497d-88b0-403ad6b56d16","process_types":{"web":"bundle exec rackup","worker":"bundle exec sidekiq"}}`), 0644))
+
+	requests := map[*cfclient.Request]string{}
+	var created map[string]interface{}
+	var uploaded, current string
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		NewRequestStub: func(method, path string) *cfclient.Request {
+			req := &cfclient.Request{}
+			requests[req] = method + " " + path
+			return req
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			req := &cfclient.Request{}
+			requests[req] = method + " " + path
+			switch path {
+			case "/v3/droplets":
+				assert.NoError(t, json.NewDecoder(body).Decode(&created))
+			case "/v3/apps/6064d98a-95e6-400b-bc03-be65e6d59622/relationships/current_droplet":
+				data, err := io.ReadAll(body)
+				assert.NoError(t, err)
+				current = string(data)
+			}
+			return req
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			switch requests[req] {
+			case "POST /v3/droplets":
+				return &http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       io.NopCloser(strings.NewReader(`{"guid":"new-droplet","state":"AWAITING_UPLOAD"}`)),
+				}, nil
+			case "PATCH /v3/apps/6064d98a-95e6-400b-bc03-be65e6d59622/relationships/current_droplet":
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+			}
+			data, err := os.ReadFile("testdata/v3droplets.json")
+			assert.NoError(t, err)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(data))}, nil
+		},
+		DoStub: func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "/v3/droplets/new-droplet/upload", req.URL.Path)
+			file, _, err := req.FormFile("bits")
+			assert.NoError(t, err)
+			data, err := io.ReadAll(file)
+			assert.NoError(t, err)
+			uploaded = string(data)
+			return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		},
+		GetStub: func(url string) ([]byte, error) {
+			assert.Equal(t, "/v3/droplets/new-droplet", url)
+			return []byte(`{"guid":"new-droplet","state":"STAGED"}`), nil
+		},
+	}
+	client.TargetReturns("https://api.example.org")
+
+	i := &ImportApp{
+		ImportSpace: ImportSpace{
+			ImportOrg: ImportOrg{Org: "my_org"},
+			Space:     "my_space",
+		},
+		AppName: "my_app",
+		appGUID: "6064d98a-95e6-400b-bc03-be65e6d59622",
+	}
+	err := i.uploadDroplet(&context.Context{
+		Logger:             log.New(),
+		ExportDir:          exportDir,
+		ImportCFClient:     client,
+		DropletCountToKeep: 2,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"web": "bundle exec rackup", "worker": "bundle exec sidekiq"}, created["process_types"])
+	assert.Equal(t, "droplet data", uploaded)
+	assert.JSONEq(t, `{"data":{"guid":"new-droplet"}}`, current)
+}
+
+func TestImportApp_uploadBlobChecksumMismatch(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+	exportDir := t.TempDir()
+	spaceDir := filepath.Join(exportDir, "my_org", "my_space")
+	require.NoError(t, os.MkdirAll(spaceDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(spaceDir, "my_app.tgz"), []byte("droplet data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(spaceDir, "my_app.zip"), []byte("newer app bits"), 0644))
+	info := fmt.Sprintf(`{"guid":"585bc3c1-3743-497d-88b0-403ad6b56d16","checksum":{"type":"sha256","value":"%x"},"package":{"guid":"8222f76a-9e09-4360-b3aa-1ed329945e92","checksum":{"type":"sha256","value":"%x"}}}`,
+		sha256.Sum256([]byte("droplet data")), sha256.Sum256([]byte("app bits")))
+	require.NoError(t, os.WriteFile(filepath.Join(spaceDir, "my_app"+export.DropletInfoSuffix), []byte(info), 0644))
+
+	client := &fakes.FakeClient{}
+	i := &ImportApp{
+		ImportSpace: ImportSpace{
+			ImportOrg: ImportOrg{Org: "my_org"},
+			Space:     "my_space",
+		},
+		AppName: "my_app",
+	}
+	err := i.uploadBlob(&context.Context{
+		Logger:         log.New(),
+		ExportDir:      exportDir,
+		ImportCFClient: client,
+	})
+	require.ErrorContains(t, err, "sha256 checksum of my_org/my_space/my_app.zip")
+	require.Zero(t, client.UploadAppBitsCallCount())
+	require.Zero(t, client.UploadDropletBitsCallCount())
+}
+
+func TestImportApp_verifyBlobsTrustsDeduplicatedBlobs(t *testing.T) {
+	exportDir := t.TempDir()
+	store := blobstore.New(storage.NewLocal(exportDir), nil)
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte("app bits")))
+	_, err := store.Put("my_org/my_space/my_app.zip", digest, func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("app bits")), nil
+	})
+	require.NoError(t, err)
+	_, err = store.Put("my_org/my_space/other_app.zip", "", func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("other app bits")), nil
+	})
+	require.NoError(t, err)
+	// the blob is not read again, its name tells its content
+	require.NoError(t, os.WriteFile(filepath.Join(exportDir, filepath.FromSlash(blobstore.Name(digest))), []byte("changed"), 0644))
+
+	ctx := &context.Context{
+		Logger:    log.New(),
+		ExportDir: exportDir,
+	}
+	i := &ImportApp{
+		ImportSpace: ImportSpace{
+			ImportOrg: ImportOrg{Org: "my_org"},
+			Space:     "my_space",
+		},
+		AppName: "my_app",
+	}
+	info := &export.DropletInfo{Package: &export.PackageInfo{Checksum: export.Checksum{Type: "sha256", Value: strings.ToUpper(digest)}}}
+	require.NoError(t, i.verifyBlobs(ctx, info, "", "my_org/my_space/my_app.zip"))
+
+	err = i.verifyBlobs(ctx, info, "", "my_org/my_space/other_app.zip")
+	require.ErrorContains(t, err, "sha256 checksum of my_org/my_space/other_app.zip")
+}
+
 func Test_getSizeFromString(t *testing.T) {
 	type args struct {
 		sizeStr string
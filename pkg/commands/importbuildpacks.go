@@ -0,0 +1,202 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+)
+
+type ImportBuildpacks struct {
+}
+
+// Run recreates the exported admin buildpacks on the target foundation, in the order of their positions. Buildpacks
+// that already exist with the same name and stack get the exported bits only when their checksum differs, and the
+// exported settings.
+func (i *ImportBuildpacks) Run(ctx *context.Context) error {
+	buildpacks, err := export.ReadBuildpacks(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing, err := cf.ListBuildpacks(ctx.ImportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the buildpacks of the target foundation: %w", err)
+	}
+
+	sort.SliceStable(buildpacks, func(x, y int) bool {
+		return buildpacks[x].Position < buildpacks[y].Position
+	})
+	for _, b := range buildpacks {
+		if target, ok := ctx.StackMappings[b.Stack]; ok {
+			b.Stack = target
+		}
+
+		unchanged, err := i.importBuildpack(ctx, b, findBuildpack(existing, b.Name, b.Stack))
+		switch {
+		case err != nil:
+			ctx.Logger.Errorf("Error importing buildpack %s: %s", b, err)
+			ctx.Resources.AddFailed("buildpack", "", "", b.String(), err)
+		case unchanged:
+			ctx.Logger.Infof("Buildpack %s is up to date", b)
+			ctx.Resources.AddSkipped("buildpack", "", "", b.String(), "unchanged")
+		default:
+			ctx.Resources.AddSucceeded("buildpack", "", "", b.String())
+		}
+	}
+
+	return nil
+}
+
+// importBuildpack creates the buildpack, or updates the current one, and tells whether it was left unchanged
+func (i *ImportBuildpacks) importBuildpack(ctx *context.Context, b export.Buildpack, current *cf.V3Buildpack) (bool, error) {
+	settings := cf.BuildpackRequest{Position: b.Position, Enabled: b.Enabled, Locked: b.Locked}
+
+	if current == nil {
+		ctx.Logger.Infof("Creating buildpack %s", b)
+		var created cf.V3Buildpack
+		err := withRetry(ctx, func() error {
+			var err error
+			created, err = cf.CreateBuildpack(ctx.ImportCFClient, cf.BuildpackRequest{Name: b.Name, Stack: b.Stack, Position: b.Position, Enabled: b.Enabled})
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		if err = recordResource(ctx, journal.Entry{Action: journal.CreateBuildpack, Name: b.String(), GUID: created.GUID}, nil); err != nil {
+			return false, err
+		}
+		if err = uploadBuildpack(ctx, created.GUID, b); err != nil {
+			return false, err
+		}
+		if b.Locked {
+			return false, updateBuildpack(ctx, b, created.GUID, settings, cf.BuildpackRequest{Position: b.Position, Enabled: b.Enabled})
+		}
+		return false, nil
+	}
+
+	same, err := hasBits(ctx, *current, b.Checksum)
+	if err != nil {
+		return false, err
+	}
+	previous := cf.BuildpackRequest{Position: current.Position, Enabled: current.Enabled, Locked: current.Locked}
+	if same {
+		if current.Position == b.Position && current.Enabled == b.Enabled && current.Locked == b.Locked {
+			return true, nil
+		}
+		ctx.Logger.Infof("Updating the settings of buildpack %s", b)
+		return false, updateBuildpack(ctx, b, current.GUID, settings, previous)
+	}
+
+	ctx.Logger.Infof("Updating buildpack %s", b)
+	if current.Locked {
+		// the bits of locked buildpacks cannot be replaced
+		unlocked := cf.BuildpackRequest{Position: current.Position, Enabled: current.Enabled}
+		if err = updateBuildpack(ctx, b, current.GUID, unlocked, previous); err != nil {
+			return false, err
+		}
+		previous = unlocked
+	}
+	if err = uploadBuildpack(ctx, current.GUID, b); err != nil {
+		return false, err
+	}
+	// the previous bits are not kept, the rollback cannot restore them
+	if err = recordResource(ctx, journal.Entry{Action: journal.UploadBuildpack, Name: b.String(), GUID: current.GUID}, nil); err != nil {
+		return false, err
+	}
+	return false, updateBuildpack(ctx, b, current.GUID, settings, previous)
+}
+
+// updateBuildpack gives the buildpack with the given guid the settings, and journals the previous ones
+func updateBuildpack(ctx *context.Context, b export.Buildpack, guid string, settings, previous cf.BuildpackRequest) error {
+	err := withRetry(ctx, func() error {
+		_, err := cf.UpdateBuildpack(ctx.ImportCFClient, guid, settings)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordResource(ctx, journal.Entry{Action: journal.UpdateBuildpack, Name: b.String(), GUID: guid}, previous)
+}
+
+// uploadBuildpack uploads the exported bits of a buildpack, and waits until the buildpack can be used for staging
+func uploadBuildpack(ctx *context.Context, guid string, b export.Buildpack) error {
+	r, err := aio.OpenFS(ctx.ExportFS(), b.Bits, ctx.Opener)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	size := int64(-1)
+	if fi, err := fs.Stat(ctx.ExportFS(), b.Bits); err == nil && ctx.Opener == nil {
+		size = fi.Size()
+	}
+
+	ctx.Logger.Infof("Uploading the bits of buildpack %s", b)
+	if err = cf.StreamBuildpackBits(ctx.ImportCFClient, guid, b.Filename, r, size); err != nil {
+		return err
+	}
+
+	return waitUntil(5*time.Minute, "buildpack to be processed", func() (bool, error) {
+		buildpack, err := cf.GetBuildpack(ctx.ImportCFClient, guid)
+		if err != nil {
+			return false, err
+		}
+		return buildpack.State == cf.BuildpackReady, nil
+	})
+}
+
+// hasBits tells whether a buildpack of the target foundation holds the bits with the given checksum
+func hasBits(ctx *context.Context, b cf.V3Buildpack, checksum export.Checksum) (bool, error) {
+	if b.State != cf.BuildpackReady || b.Filename == "" || !strings.EqualFold(checksum.Type, "sha256") {
+		return false, nil
+	}
+
+	r, _, err := cf.DownloadBuildpack(ctx.ImportCFClient, b.GUID)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, r); err != nil {
+		return false, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)) == checksum.Value, nil
+}
+
+// findBuildpack returns the buildpack with the given name and stack, if there is one
+func findBuildpack(buildpacks []cf.V3Buildpack, name, stack string) *cf.V3Buildpack {
+	for idx := range buildpacks {
+		if buildpacks[idx].Name == name && buildpacks[idx].Stack == stack {
+			return &buildpacks[idx]
+		}
+	}
+	return nil
+}
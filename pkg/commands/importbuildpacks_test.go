@@ -0,0 +1,239 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
+)
+
+// fakeBuildpackAPI serves the buildpack endpoints of the Cloud Controller from memory
+type fakeBuildpackAPI struct {
+	t          *testing.T
+	mutex      sync.Mutex
+	buildpacks []cf.V3Buildpack
+	bits       map[string][]byte
+	changes    []string
+}
+
+func newFakeBuildpackAPI(t *testing.T, buildpacks []cf.V3Buildpack, bits map[string]string) *fakeBuildpackAPI {
+	f := &fakeBuildpackAPI{t: t, buildpacks: buildpacks, bits: make(map[string][]byte)}
+	for guid, b := range bits {
+		f.bits[guid] = []byte(b)
+	}
+	return f
+}
+
+func (f *fakeBuildpackAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if req.Method != http.MethodGet {
+		f.changes = append(f.changes, req.Method+" "+req.URL.Path)
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/v3/buildpacks":
+		f.write(w, http.StatusOK, map[string]interface{}{"pagination": map[string]interface{}{}, "resources": f.buildpacks})
+	case req.Method == http.MethodGet && len(parts) == 4 && parts[0] == "v2" && parts[3] == "download":
+		bits, ok := f.bits[parts[2]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(bits)
+	case req.Method == http.MethodPost && req.URL.Path == "/v3/buildpacks":
+		var r cf.BuildpackRequest
+		require.NoError(f.t, json.NewDecoder(req.Body).Decode(&r))
+		b := cf.V3Buildpack{GUID: "created-" + r.Name, Name: r.Name, Stack: r.Stack, Position: r.Position, Enabled: r.Enabled, Locked: r.Locked, State: "AWAITING_UPLOAD"}
+		f.buildpacks = append(f.buildpacks, b)
+		f.write(w, http.StatusCreated, b)
+	case len(parts) >= 3 && parts[1] == "buildpacks":
+		b := f.find(parts[2])
+		if b == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch {
+		case req.Method == http.MethodGet:
+			f.write(w, http.StatusOK, b)
+		case req.Method == http.MethodPatch:
+			var r cf.BuildpackRequest
+			require.NoError(f.t, json.NewDecoder(req.Body).Decode(&r))
+			if r.Position != 0 {
+				b.Position = r.Position
+			}
+			b.Enabled, b.Locked = r.Enabled, r.Locked
+			f.write(w, http.StatusOK, b)
+		case req.Method == http.MethodPost && len(parts) == 4 && parts[3] == "upload":
+			if b.Locked {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			file, header, err := req.FormFile("bits")
+			require.NoError(f.t, err)
+			bits, err := io.ReadAll(file)
+			require.NoError(f.t, err)
+			f.bits[b.GUID] = bits
+			b.Filename, b.State = header.Filename, cf.BuildpackReady
+			w.WriteHeader(http.StatusAccepted)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// handler serves the buildpack endpoints along with the info endpoint the cf client starts with
+func (f *fakeBuildpackAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/info", InfoTestHandler(f.t))
+	mux.Handle("/", f)
+	return mux
+}
+
+func (f *fakeBuildpackAPI) find(guid string) *cf.V3Buildpack {
+	for idx := range f.buildpacks {
+		if f.buildpacks[idx].GUID == guid {
+			return &f.buildpacks[idx]
+		}
+	}
+	return nil
+}
+
+func (f *fakeBuildpackAPI) write(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	require.NoError(f.t, json.NewEncoder(w).Encode(v))
+}
+
+func checksumOf(data string) export.Checksum {
+	sum := sha256.Sum256([]byte(data))
+	return export.Checksum{Type: "sha256", Value: hex.EncodeToString(sum[:])}
+}
+
+func TestImportBuildpacks_Run(t *testing.T) {
+	javaBuildpack := export.Buildpack{
+		Name:     "java_buildpack",
+		Stack:    "cflinuxfs3",
+		Position: 1,
+		Enabled:  true,
+		Locked:   true,
+		Filename: "java-buildpack-v4.50.zip",
+		Bits:     ".app-migrator/buildpacks/java-guid.zip",
+		Checksum: checksumOf("java bits"),
+	}
+	tests := []struct {
+		name          string
+		existing      []cf.V3Buildpack
+		existingBits  map[string]string
+		successCount  int
+		skippedCount  int
+		wantChanges   []string
+		wantBuildpack cf.V3Buildpack
+	}{
+		{
+			name:         "creates missing buildpacks",
+			successCount: 1,
+			wantChanges: []string{
+				"POST /v3/buildpacks",
+				"POST /v3/buildpacks/created-java_buildpack/upload",
+				"PATCH /v3/buildpacks/created-java_buildpack",
+			},
+			wantBuildpack: cf.V3Buildpack{GUID: "created-java_buildpack", Name: "java_buildpack", Stack: "cflinuxfs4", Position: 1, Enabled: true, Locked: true, State: cf.BuildpackReady, Filename: "java-buildpack-v4.50.zip"},
+		},
+		{
+			name:          "skips buildpacks with the same bits and settings",
+			existing:      []cf.V3Buildpack{{GUID: "target-guid", Name: "java_buildpack", Stack: "cflinuxfs4", Position: 1, Enabled: true, Locked: true, State: cf.BuildpackReady, Filename: "java.zip"}},
+			existingBits:  map[string]string{"target-guid": "java bits"},
+			skippedCount:  1,
+			wantBuildpack: cf.V3Buildpack{GUID: "target-guid", Name: "java_buildpack", Stack: "cflinuxfs4", Position: 1, Enabled: true, Locked: true, State: cf.BuildpackReady, Filename: "java.zip"},
+		},
+		{
+			name:          "updates the settings of buildpacks with the same bits",
+			existing:      []cf.V3Buildpack{{GUID: "target-guid", Name: "java_buildpack", Stack: "cflinuxfs4", Position: 3, State: cf.BuildpackReady, Filename: "java.zip"}},
+			existingBits:  map[string]string{"target-guid": "java bits"},
+			successCount:  1,
+			wantChanges:   []string{"PATCH /v3/buildpacks/target-guid"},
+			wantBuildpack: cf.V3Buildpack{GUID: "target-guid", Name: "java_buildpack", Stack: "cflinuxfs4", Position: 1, Enabled: true, Locked: true, State: cf.BuildpackReady, Filename: "java.zip"},
+		},
+		{
+			name:         "replaces the bits of locked buildpacks with other bits",
+			existing:     []cf.V3Buildpack{{GUID: "target-guid", Name: "java_buildpack", Stack: "cflinuxfs4", Position: 1, Enabled: true, Locked: true, State: cf.BuildpackReady, Filename: "java.zip"}},
+			existingBits: map[string]string{"target-guid": "old java bits"},
+			successCount: 1,
+			wantChanges: []string{
+				"PATCH /v3/buildpacks/target-guid",
+				"POST /v3/buildpacks/target-guid/upload",
+				"PATCH /v3/buildpacks/target-guid",
+			},
+			wantBuildpack: cf.V3Buildpack{GUID: "target-guid", Name: "java_buildpack", Stack: "cflinuxfs4", Position: 1, Enabled: true, Locked: true, State: cf.BuildpackReady, Filename: "java-buildpack-v4.50.zip"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeBuildpackAPI(t, tt.existing, tt.existingBits)
+			server := httptest.NewServer(api.handler())
+			defer server.Close()
+
+			ctx := &context.Context{
+				Logger:         log.New(),
+				DirWriter:      storage.NewMemory(),
+				ImportCFClient: NewTestCFClient(t, server),
+				Resources:      report.NewResources(&bytes.Buffer{}),
+				StackMappings:  map[string]string{"cflinuxfs3": "cflinuxfs4"},
+			}
+			writeExportFile(t, ctx, javaBuildpack.Bits, "java bits")
+			require.NoError(t, export.WriteBuildpacks(ctx, []export.Buildpack{javaBuildpack}))
+
+			require.NoError(t, (&ImportBuildpacks{}).Run(ctx))
+			assert.Equal(t, tt.successCount, ctx.Resources.Count(report.ResourceSucceeded))
+			assert.Equal(t, tt.skippedCount, ctx.Resources.Count(report.ResourceSkipped))
+			assert.Equal(t, 0, ctx.Resources.Count(report.ResourceFailed), "%v", ctx.Resources.Resources())
+			assert.Equal(t, tt.wantChanges, api.changes)
+			require.Len(t, api.buildpacks, 1)
+			assert.Equal(t, tt.wantBuildpack, api.buildpacks[0])
+			assert.Equal(t, "java bits", string(api.bits[api.buildpacks[0].GUID]))
+		})
+	}
+}
+
+func writeExportFile(t *testing.T, ctx *context.Context, name, content string) {
+	t.Helper()
+	f, err := ctx.DirWriter.Put(name, 0644)
+	require.NoError(t, err)
+	_, err = fmt.Fprint(f, content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
@@ -0,0 +1,176 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+)
+
+type ImportDomains struct {
+}
+
+// Run creates the exported private domains that are missing on the target foundation in the org owning them, and
+// shares them with the same orgs, after applying --name-mapping. Shared domains that are missing on the target
+// foundation are only created with --shared-domains, which takes an admin.
+func (i *ImportDomains) Run(ctx *context.Context) error {
+	domains, err := export.ReadDomains(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range domains.PrivateDomains {
+		unchanged, err := i.importPrivateDomain(ctx, d)
+		addDomainResult(ctx, "private domain", ctx.NameMapping.Org(d.Org), d.Name, unchanged, err)
+	}
+
+	for _, d := range domains.SharedDomains {
+		current, err := cf.GetDomainByName(ctx.ImportCFClient, d.Name)
+		switch {
+		case err != nil:
+			addDomainResult(ctx, "shared domain", "", d.Name, false, err)
+		case current != nil:
+			addDomainResult(ctx, "shared domain", "", d.Name, true, nil)
+		case !ctx.SharedDomains:
+			ctx.Logger.Warnf("Shared domain %s does not exist on the target foundation, create it with --shared-domains", d.Name)
+			ctx.Resources.AddSkipped("shared domain", "", "", d.Name, "missing shared domain, create it with --shared-domains")
+		default:
+			ctx.Logger.Infof("Creating shared domain %s", d.Name)
+			var created cf.Domain
+			err = withRetry(ctx, func() (err error) {
+				created, err = cf.CreateDomain(ctx.ImportCFClient, cf.Domain{Name: d.Name, Internal: d.Internal})
+				return err
+			})
+			if err == nil {
+				err = recordResource(ctx, journal.Entry{Action: journal.CreateDomain, Name: d.Name, GUID: created.GUID}, nil)
+			}
+			addDomainResult(ctx, "shared domain", "", d.Name, false, err)
+		}
+	}
+
+	return nil
+}
+
+// importPrivateDomain creates the private domain in its org, or shares the existing domain with the orgs it is not
+// shared with yet, it tells whether nothing changed. Orgs that do not exist on the target foundation fail the domain
+// after it was shared with the others.
+func (i *ImportDomains) importPrivateDomain(ctx *context.Context, d export.PrivateDomain) (bool, error) {
+	c := cache.GetCache(ctx.ImportCFClient)
+	orgName := ctx.NameMapping.Org(d.Org)
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return false, err
+	}
+
+	var guids, missing []string
+	for _, name := range d.SharedOrgs {
+		shared, err := c.GetOrgByName(ctx.NameMapping.Org(name))
+		if err != nil {
+			missing = append(missing, ctx.NameMapping.Org(name))
+			continue
+		}
+		guids = append(guids, shared.Guid)
+	}
+
+	current, err := cf.GetDomainByName(ctx.ImportCFClient, d.Name)
+	if err != nil {
+		return false, err
+	}
+
+	unchanged := false
+	switch {
+	case current == nil:
+		ctx.Logger.Infof("Creating private domain %s in org %s", d.Name, orgName)
+		var created cf.Domain
+		err = withRetry(ctx, func() (err error) {
+			created, err = cf.CreateDomain(ctx.ImportCFClient, cf.Domain{
+				Name:     d.Name,
+				Internal: d.Internal,
+				Relationships: cf.DomainRelationships{
+					Organization:        &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: org.Guid}},
+					SharedOrganizations: toManyOrNil(guids),
+				},
+			})
+			return err
+		})
+		if err == nil {
+			err = recordResource(ctx, journal.Entry{Action: journal.CreateDomain, Org: orgName, Name: d.Name, GUID: created.GUID}, nil)
+		}
+	case !current.IsPrivate():
+		return false, fmt.Errorf("domain %s is a shared domain on the target foundation", d.Name)
+	case current.Relationships.Organization.Data.GUID != org.Guid:
+		return false, fmt.Errorf("domain %s is owned by another org on the target foundation", d.Name)
+	default:
+		var share []string
+		for _, guid := range guids {
+			if !hasRelationship(current.Relationships.SharedOrganizations, guid) {
+				share = append(share, guid)
+			}
+		}
+		unchanged = len(share) == 0
+		if !unchanged {
+			ctx.Logger.Infof("Sharing private domain %s with %d orgs", d.Name, len(share))
+			err = withRetry(ctx, func() error {
+				return cf.ShareDomain(ctx.ImportCFClient, current.GUID, share)
+			})
+			if err == nil {
+				// the orgs the domain was shared with are kept to unshare it from them
+				err = recordResource(ctx, journal.Entry{Action: journal.ShareDomain, Org: orgName, Name: d.Name, GUID: current.GUID}, share)
+			}
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if len(missing) > 0 {
+		return false, fmt.Errorf("orgs %s not found on the target foundation", strings.Join(missing, ", "))
+	}
+	return unchanged, nil
+}
+
+// toManyOrNil returns the relationships to the given guids, or nil when there are none
+func toManyOrNil(guids []string) *cfclient.V3ToManyRelationships {
+	if len(guids) == 0 {
+		return nil
+	}
+
+	var r cfclient.V3ToManyRelationships
+	for _, guid := range guids {
+		r.Data = append(r.Data, cfclient.V3Relationship{GUID: guid})
+	}
+	return &r
+}
+
+func addDomainResult(ctx *context.Context, kind, org, name string, unchanged bool, err error) {
+	switch {
+	case err != nil:
+		ctx.Logger.Errorf("Error importing domain %s: %s", name, err)
+		ctx.Resources.AddFailed(kind, org, "", name, err)
+	case unchanged:
+		ctx.Logger.Infof("Domain %s is up to date", name)
+		ctx.Resources.AddSkipped(kind, org, "", name, "unchanged")
+	default:
+		ctx.Resources.AddSucceeded(kind, org, "", name)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestImportDomains_Run(t *testing.T) {
+	tests := []struct {
+		name          string
+		sharedDomains bool
+		requests      []string
+		results       []string
+	}{
+		{
+			name: "reports missing shared domains",
+			requests: []string{
+				`POST /v3/domains {"name":"new.example.com","internal":false,"relationships":{"organization":{"data":{"guid":"my_org-guid"}},"shared_organizations":{"data":[{"guid":"other_org-guid"}]}}}`,
+				`POST /v3/domains/my-guid/relationships/shared_organizations {"data":[{"guid":"other_org-guid"}]}`,
+				`POST /v3/domains {"name":"other.example.com","internal":false,"relationships":{"organization":{"data":{"guid":"other_org-guid"}}}}`,
+			},
+			results: []string{
+				"my_org my.example.com succeeded",
+				"my_org new.example.com succeeded",
+				"my_org taken.example.com failed domain taken.example.com is owned by another org on the target foundation",
+				"my_org up-to-date.example.com skipped unchanged",
+				"other_org other.example.com failed orgs gone not found on the target foundation",
+				"apps.example.com skipped unchanged",
+				"apps.internal skipped missing shared domain, create it with --shared-domains",
+			},
+		},
+		{
+			name:          "creates missing shared domains",
+			sharedDomains: true,
+			requests: []string{
+				`POST /v3/domains {"name":"new.example.com","internal":false,"relationships":{"organization":{"data":{"guid":"my_org-guid"}},"shared_organizations":{"data":[{"guid":"other_org-guid"}]}}}`,
+				`POST /v3/domains/my-guid/relationships/shared_organizations {"data":[{"guid":"other_org-guid"}]}`,
+				`POST /v3/domains {"name":"other.example.com","internal":false,"relationships":{"organization":{"data":{"guid":"other_org-guid"}}}}`,
+				`POST /v3/domains {"name":"apps.internal","internal":true,"relationships":{}}`,
+			},
+			results: []string{
+				"my_org my.example.com succeeded",
+				"my_org new.example.com succeeded",
+				"my_org taken.example.com failed domain taken.example.com is owned by another org on the target foundation",
+				"my_org up-to-date.example.com skipped unchanged",
+				"other_org other.example.com failed orgs gone not found on the target foundation",
+				"apps.example.com skipped unchanged",
+				"apps.internal succeeded",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+
+			var requests []string
+			client := &fakes.FakeClient{
+				DoWithRetryStub: func(f func() error) error {
+					return f()
+				},
+				GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+					if name == "gone" {
+						return cfclient.Org{}, cfclient.NewOrganizationNotFoundError()
+					}
+					return cfclient.Org{Guid: name + "-guid", Name: name}, nil
+				},
+				GetStub: func(path string) ([]byte, error) {
+					switch path {
+					case "/v3/domains?names=apps.example.com":
+						return []byte(`{"pagination": {}, "resources": [{"guid": "apps-guid", "name": "apps.example.com", "relationships": {"organization": {"data": null}}}]}`), nil
+					case "/v3/domains?names=my.example.com":
+						return []byte(`{"pagination": {}, "resources": [{"guid": "my-guid", "name": "my.example.com", "relationships": {"organization": {"data": {"guid": "my_org-guid"}}, "shared_organizations": {"data": []}}}]}`), nil
+					case "/v3/domains?names=up-to-date.example.com":
+						return []byte(`{"pagination": {}, "resources": [{"guid": "up-to-date-guid", "name": "up-to-date.example.com", "relationships": {"organization": {"data": {"guid": "my_org-guid"}}, "shared_organizations": {"data": [{"guid": "other_org-guid"}]}}}]}`), nil
+					case "/v3/domains?names=taken.example.com":
+						return []byte(`{"pagination": {}, "resources": [{"guid": "taken-guid", "name": "taken.example.com", "relationships": {"organization": {"data": {"guid": "someone-guid"}}, "shared_organizations": {"data": []}}}]}`), nil
+					case "/v3/domains?names=new.example.com", "/v3/domains?names=apps.internal", "/v3/domains?names=other.example.com":
+						return []byte(`{"pagination": {}, "resources": []}`), nil
+					}
+					return nil, errors.New("unexpected request " + path)
+				},
+				NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+					data, err := io.ReadAll(body)
+					assert.NoError(t, err)
+					requests = append(requests, method+" "+path+" "+string(data))
+					return &cfclient.Request{}
+				},
+				DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+					if strings.HasPrefix(requests[len(requests)-1], "POST /v3/domains {") {
+						return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+					}
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+				},
+			}
+
+			ctx := &context.Context{
+				Logger:         log.New(),
+				DirWriter:      storage.NewMemory(),
+				ImportCFClient: client,
+				SharedDomains:  tt.sharedDomains,
+				Resources:      report.NewResources(&bytes.Buffer{}),
+			}
+			require.NoError(t, export.WriteDomains(ctx, export.Domains{
+				PrivateDomains: []export.PrivateDomain{
+					{Name: "new.example.com", Org: "my_org", SharedOrgs: []string{"other_org"}},
+					{Name: "my.example.com", Org: "my_org", SharedOrgs: []string{"other_org"}},
+					{Name: "up-to-date.example.com", Org: "my_org", SharedOrgs: []string{"other_org"}},
+					{Name: "taken.example.com", Org: "my_org"},
+					{Name: "other.example.com", Org: "other_org", SharedOrgs: []string{"gone"}},
+				},
+				SharedDomains: []export.SharedDomain{
+					{Name: "apps.example.com"},
+					{Name: "apps.internal", Internal: true},
+				},
+			}))
+
+			require.NoError(t, (&ImportDomains{}).Run(ctx))
+			assert.Equal(t, tt.requests, requests)
+
+			var results []string
+			for _, r := range ctx.Resources.Resources() {
+				results = append(results, strings.TrimSpace(r.Org+" "+r.Name+" "+r.Status+" "+r.Message))
+			}
+			assert.Equal(t, tt.results, results)
+		})
+	}
+}
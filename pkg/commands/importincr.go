@@ -18,13 +18,14 @@ import (
 	"bytes"
 	"io/fs"
 	"math"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 
+	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
 )
 
 type ImportIncremental struct {
@@ -56,7 +57,7 @@ func (i *ImportIncremental) Run(ctx *context.Context) error {
 
 				ctx.Logger.Infoln(buf.String())
 
-				err := appImporter.Run(ctx)
+				err := appImporter.importApp(ctx)
 				if err != nil {
 					// TODO: Should we ignore errors here?
 					continue
@@ -65,7 +66,12 @@ func (i *ImportIncremental) Run(ctx *context.Context) error {
 		}()
 	}
 
-	err := filepath.WalkDir(ctx.ExportDir, func(path string, d fs.DirEntry, err error) error {
+	// the service instances and keys of a space are created before its first app is queued
+	spaces := make(map[string]bool)
+	err := fs.WalkDir(ctx.ExportFS(), ".", func(path string, d fs.DirEntry, err error) error {
+		if path == aio.ReservedDir {
+			return fs.SkipDir
+		}
 
 		if !d.IsDir() && strings.HasSuffix(path, "_manifest.yml") {
 			if err != nil {
@@ -73,35 +79,44 @@ func (i *ImportIncremental) Run(ctx *context.Context) error {
 				return nil
 			}
 
-			newPath := strings.TrimPrefix(path, ctx.ExportDir+"/")
-			orgSpaceApp := strings.SplitN(newPath, "/", 3)
+			orgSpaceApp := strings.SplitN(path, "/", 3)
 
 			c := cache.GetCache(ctx.ImportCFClient)
 
-			org, err := c.GetOrgByName(orgSpaceApp[0])
-			if err != nil {
-				return err
-			}
+			orgName, spaceName, appName := orgSpaceApp[0], orgSpaceApp[1], strings.TrimSuffix(orgSpaceApp[2], "_manifest.yml")
 
-			if isOrgExcluded(ctx, org.Name) || !isOrgIncluded(ctx, org.Name) {
+			if isOrgExcluded(ctx, orgName) || !isOrgIncluded(ctx, orgName) {
 				return nil
 			}
 
-			space, err := c.GetSpaceByName(orgSpaceApp[1], org.Guid)
+			org, err := c.GetOrgByName(ctx.NameMapping.Org(orgName))
+			if err != nil {
+				return err
+			}
+
+			space, err := c.GetSpaceByName(ctx.NameMapping.Space(orgName, spaceName), org.Guid)
 			if err != nil {
 				return err
 			}
 
-			app, err := c.GetAppByName(strings.TrimSuffix(orgSpaceApp[2], "_manifest.yml"), space.Guid)
+			app, err := c.GetAppByName(ctx.NameMapping.App(orgName, spaceName, appName), space.Guid)
 			if err != nil && !cache.IsNotFound(err) {
 				return err
 			}
 
-			if !ctx.Metadata.HasNewerLocally(app, space, org) {
-				ctx.Logger.Infof("%s has not been modified since the last run of app-migrator, so skip that app", newPath)
+			// the metadata of the last export is recorded under the source names
+			if app.Guid != "" {
+				app.Name = appName
+			}
+			if !ctx.Metadata.HasNewerLocally(app, cfclient.Space{Name: spaceName}, cfclient.Org{Name: orgName}) {
+				ctx.Logger.Infof("%s has not been modified since the last run of app-migrator, so skip that app", path)
 				return nil
 			}
-			workerChan <- newPath
+			if key := orgName + "/" + spaceName; !spaces[key] {
+				spaces[key] = true
+				importSpaceServices(ctx, orgName, spaceName)
+			}
+			workerChan <- path
 		}
 
 		return nil
@@ -0,0 +1,145 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+)
+
+type ImportNetworkPolicies struct {
+}
+
+// Run recreates the exported network policies between the apps on the target foundation, after applying
+// --name-mapping. Policies with an app at either end that was not migrated are skipped and reported.
+func (i *ImportNetworkPolicies) Run(ctx *context.Context) error {
+	exported, err := export.ReadNetworkPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		policies []export.NetworkPolicy
+		wanted   []cf.NetworkPolicy
+		guids    []string
+	)
+	for _, p := range exported {
+		source := i.targetRef(ctx, p.Source)
+		sourceGUID, err := i.appGUID(ctx, source)
+		if err != nil {
+			i.addSkipped(ctx, p, "source", source, err)
+			continue
+		}
+		destination := i.targetRef(ctx, p.Destination)
+		destinationGUID, err := i.appGUID(ctx, destination)
+		if err != nil {
+			i.addSkipped(ctx, p, "destination", destination, err)
+			continue
+		}
+
+		policies = append(policies, p)
+		wanted = append(wanted, cf.NetworkPolicy{
+			Source:      cf.PolicySource{ID: sourceGUID},
+			Destination: cf.PolicyDestination{ID: destinationGUID, Protocol: p.Protocol, Ports: p.Ports},
+		})
+		guids = append(guids, sourceGUID, destinationGUID)
+	}
+	sort.Strings(guids)
+
+	current, err := cf.ListNetworkPolicies(ctx.ImportCFClient, guids)
+	if err != nil {
+		return fmt.Errorf("error listing the network policies of the target foundation: %w", err)
+	}
+	existing := make(map[cf.NetworkPolicy]bool, len(current))
+	for _, p := range current {
+		existing[p] = true
+	}
+
+	for n, p := range policies {
+		if existing[wanted[n]] {
+			ctx.Logger.Infof("Network policy %s already exists", p)
+			ctx.Resources.AddSkipped("network policy", p.Source.Org, p.Source.Space, p.String(), "unchanged")
+			continue
+		}
+
+		ctx.Logger.Infof("Importing network policy %s", p)
+		err = withRetry(ctx, func() error {
+			return cf.CreateNetworkPolicy(ctx.ImportCFClient, wanted[n])
+		})
+		if err == nil {
+			entry := journal.Entry{Action: journal.CreateNetworkPolicy, Org: p.Source.Org, Space: p.Source.Space, Name: p.String(), GUID: wanted[n].Source.ID, RelatedGUID: wanted[n].Destination.ID}
+			err = recordResource(ctx, entry, wanted[n])
+		}
+		if err != nil {
+			ctx.Logger.Errorf("Error importing network policy %s: %s", p, err)
+			ctx.Resources.AddFailed("network policy", p.Source.Org, p.Source.Space, p.String(), err)
+			continue
+		}
+		existing[wanted[n]] = true
+		ctx.Resources.AddSucceeded("network policy", p.Source.Org, p.Source.Space, p.String())
+	}
+
+	return nil
+}
+
+// targetRef returns the names the app has on the target foundation
+func (i *ImportNetworkPolicies) targetRef(ctx *context.Context, ref export.AppRef) export.AppRef {
+	return export.AppRef{
+		Org:   ctx.NameMapping.Org(ref.Org),
+		Space: ctx.NameMapping.Space(ref.Org, ref.Space),
+		App:   ctx.NameMapping.App(ref.Org, ref.Space, ref.App),
+	}
+}
+
+// appGUID looks up the guid of the app on the target foundation
+func (i *ImportNetworkPolicies) appGUID(ctx *context.Context, ref export.AppRef) (string, error) {
+	c := cache.GetCache(ctx.ImportCFClient)
+	org, err := c.GetOrgByName(ref.Org)
+	if err != nil {
+		return "", err
+	}
+	space, err := c.GetSpaceByName(ref.Space, org.Guid)
+	if err != nil {
+		return "", err
+	}
+	app, err := c.GetAppByName(ref.App, space.Guid)
+	if err != nil {
+		return "", err
+	}
+
+	return app.Guid, nil
+}
+
+// addSkipped reports a policy that cannot be imported because the app at one of its ends is missing, errors other
+// than a missing org, space or app fail the policy
+func (i *ImportNetworkPolicies) addSkipped(ctx *context.Context, p export.NetworkPolicy, end string, ref export.AppRef, err error) {
+	if !cache.IsNotFound(err) && !cfclient.IsOrganizationNotFoundError(err) && !cfclient.IsSpaceNotFoundError(err) {
+		ctx.Logger.Errorf("Error importing network policy %s: %s", p, err)
+		ctx.Resources.AddFailed("network policy", p.Source.Org, p.Source.Space, p.String(), err)
+		return
+	}
+
+	reason := fmt.Sprintf("%s app %s was not migrated", end, ref)
+	ctx.Logger.Warnf("Skipping network policy %s, %s", p, reason)
+	ctx.Resources.AddSkipped("network policy", p.Source.Org, p.Source.Space, p.String(), reason)
+}
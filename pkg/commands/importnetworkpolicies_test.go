@@ -0,0 +1,132 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestImportNetworkPolicies_Run(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var requests []string
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(path string) ([]byte, error) {
+			return []byte(`{"total_policies": 1, "policies": [
+  {"source": {"id": "frontend-guid"}, "destination": {"id": "api-guid", "protocol": "tcp", "ports": {"start": 8080, "end": 8080}}}
+]}`), nil
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			if name == "other_org" {
+				return cfclient.Org{}, cfclient.NewOrganizationNotFoundError()
+			}
+			return cfclient.Org{Guid: name + "-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: name + "-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		ListAppsByQueryStub: func(values url.Values) ([]cfclient.App, error) {
+			name := strings.TrimPrefix(values["q"][0], "name:")
+			if name == "gone" {
+				return nil, nil
+			}
+			return []cfclient.App{{Guid: name + "-guid", Name: name, SpaceGuid: "my_space-guid"}}, nil
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			requests = append(requests, method+" "+path+" "+string(data))
+			return &cfclient.Request{}
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ImportCFClient: client,
+		Resources:      report.NewResources(&bytes.Buffer{}),
+	}
+	require.NoError(t, export.WriteNetworkPolicies(ctx, []export.NetworkPolicy{
+		{
+			Source:      export.AppRef{Org: "my_org", Space: "my_space", App: "frontend"},
+			Destination: export.AppRef{Org: "my_org", Space: "my_space", App: "api"},
+			Protocol:    "tcp",
+			Ports:       cf.PolicyPorts{Start: 8080, End: 8080},
+		},
+		{
+			Source:      export.AppRef{Org: "my_org", Space: "my_space", App: "worker"},
+			Destination: export.AppRef{Org: "my_org", Space: "my_space", App: "api"},
+			Protocol:    "udp",
+			Ports:       cf.PolicyPorts{Start: 9000, End: 9010},
+		},
+		{
+			Source:      export.AppRef{Org: "my_org", Space: "my_space", App: "api"},
+			Destination: export.AppRef{Org: "other_org", Space: "data", App: "db"},
+			Protocol:    "tcp",
+			Ports:       cf.PolicyPorts{Start: 5432, End: 5432},
+		},
+		{
+			Source:      export.AppRef{Org: "my_org", Space: "my_space", App: "gone"},
+			Destination: export.AppRef{Org: "my_org", Space: "my_space", App: "api"},
+			Protocol:    "tcp",
+			Ports:       cf.PolicyPorts{Start: 8080, End: 8080},
+		},
+	}))
+
+	require.NoError(t, (&ImportNetworkPolicies{}).Run(ctx))
+	assert.Equal(t, 1, ctx.Resources.Count(report.ResourceSucceeded))
+	assert.Equal(t, 3, ctx.Resources.Count(report.ResourceSkipped))
+	assert.Equal(t, 0, ctx.Resources.Count(report.ResourceFailed))
+
+	assert.Equal(t, []string{
+		`POST /networking/v1/external/policies {"policies":[{"source":{"id":"worker-guid"},"destination":{"id":"api-guid","protocol":"udp","ports":{"start":9000,"end":9010}}}]}`,
+	}, requests)
+
+	var messages []string
+	for _, r := range ctx.Resources.Resources() {
+		messages = append(messages, strings.TrimSpace(r.Name+" "+r.Status+" "+r.Message))
+	}
+	assert.Equal(t, []string{
+		"my_org/my_space/api -> other_org/data/db tcp 5432 skipped destination app other_org/data/db was not migrated",
+		"my_org/my_space/frontend -> my_org/my_space/api tcp 8080 skipped unchanged",
+		"my_org/my_space/gone -> my_org/my_space/api tcp 8080 skipped source app my_org/my_space/gone was not migrated",
+		"my_org/my_space/worker -> my_org/my_space/api udp 9000-9010 succeeded",
+	}, messages)
+}
@@ -18,11 +18,11 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"path/filepath"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
 )
 
 type ImportOrg struct {
@@ -30,9 +30,10 @@ type ImportOrg struct {
 }
 
 func (i *ImportOrg) Run(ctx *context.Context) error {
-	rootDir := filepath.Join(ctx.ExportDir, i.Org)
+	exportFS := ctx.ExportFS()
+	rootDir := i.Org
 
-	item, err := os.Stat(rootDir)
+	item, err := fs.Stat(exportFS, rootDir)
 	if err != nil {
 		return err
 	}
@@ -42,19 +43,24 @@ func (i *ImportOrg) Run(ctx *context.Context) error {
 	}
 
 	globalCache := cache.GetCache(ctx.ImportCFClient)
-	org, err := globalCache.GetOrgByName(i.Org)
+	org, err := globalCache.GetOrgByName(i.targetOrg(ctx))
 	if err != nil {
 		return err
 	}
 
-	err = filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, e error) error {
+	runSteps(ctx, "importing", mapping.Display(i.Org, i.targetOrg(ctx)), "",
+		step{"roles", func() error { return importOrgRoles(ctx, i.Org) }},
+		step{"isolation segments", func() error { return importOrgIsolationSegments(ctx, i.Org) }},
+	)
+
+	err = fs.WalkDir(exportFS, rootDir, func(path string, d fs.DirEntry, e error) error {
 		if rootDir == path {
 			return nil
 		}
 
 		if d.IsDir() {
 			_, _ = fmt.Fprintf(os.Stderr, "Found space %s in org %s\n", d.Name(), i.Org)
-			_, err = globalCache.GetSpaceByName(d.Name(), org.Guid)
+			_, err = globalCache.GetSpaceByName(ctx.NameMapping.Space(i.Org, d.Name()), org.Guid)
 			if err != nil {
 				return err
 			}
@@ -68,10 +74,11 @@ func (i *ImportOrg) Run(ctx *context.Context) error {
 				return err
 			}
 
-			return filepath.SkipDir
+			return fs.SkipDir
 		}
 
-		return filepath.SkipDir
+		// files such as the roles of the org
+		return nil
 	})
 
 	if err != nil {
@@ -80,3 +87,8 @@ func (i *ImportOrg) Run(ctx *context.Context) error {
 
 	return nil
 }
+
+// targetOrg is the name of the org on the target foundation
+func (i *ImportOrg) targetOrg(ctx *context.Context) string {
+	return ctx.NameMapping.Org(i.Org)
+}
@@ -0,0 +1,267 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+)
+
+type ImportQuotas struct {
+}
+
+// quotaActions are the journal actions of creating and updating org or space quotas
+type quotaActions struct {
+	create, update journal.Action
+}
+
+var (
+	orgQuotaActions   = quotaActions{create: journal.CreateOrgQuota, update: journal.UpdateOrgQuota}
+	spaceQuotaActions = quotaActions{create: journal.CreateSpaceQuota, update: journal.UpdateSpaceQuota}
+)
+
+// Run recreates the exported org and space quotas on the target foundation and assigns them to the orgs and spaces
+// they were assigned to on the source foundation. Quotas that already exist with the same name get the exported
+// limits. Orgs and spaces that do not exist on the target foundation fail the quota after the others got it.
+func (i *ImportQuotas) Run(ctx *context.Context) error {
+	quotas, err := export.ReadQuotas(ctx)
+	if err != nil {
+		return err
+	}
+
+	orgQuotas, err := cf.ListOrgQuotas(ctx.ImportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the org quotas of the target foundation: %w", err)
+	}
+	for _, q := range quotas.OrgQuotas {
+		unchanged, err := i.importOrgQuota(ctx, q, findQuota(orgQuotas, q.Name, ""))
+		addQuotaResult(ctx, "org quota", "", q.Name, unchanged, err)
+	}
+
+	spaceQuotas, err := cf.ListSpaceQuotas(ctx.ImportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the space quotas of the target foundation: %w", err)
+	}
+	for _, q := range quotas.SpaceQuotas {
+		orgName := ctx.NameMapping.Org(q.Org)
+		org, err := cache.GetCache(ctx.ImportCFClient).GetOrgByName(orgName)
+		if err != nil {
+			addQuotaResult(ctx, "space quota", orgName, q.Name, false, err)
+			continue
+		}
+		unchanged, err := i.importSpaceQuota(ctx, q, org.Guid, findQuota(spaceQuotas, q.Name, org.Guid))
+		addQuotaResult(ctx, "space quota", orgName, q.Name, unchanged, err)
+	}
+
+	return nil
+}
+
+// importOrgQuota creates or updates the org quota and assigns it to its orgs, it tells whether nothing changed
+func (i *ImportQuotas) importOrgQuota(ctx *context.Context, q export.OrgQuota, current *cf.Quota) (bool, error) {
+	domains := q.Domains
+	want := cf.Quota{Name: q.Name, Apps: q.Apps, Services: q.Services, Routes: q.Routes, Domains: &domains}
+
+	entry := journal.Entry{Name: q.Name}
+	guid, unchanged, err := saveQuota(ctx, entry, orgQuotaActions, want, current, cf.CreateOrgQuota, cf.UpdateOrgQuota)
+	if err != nil {
+		return false, err
+	}
+
+	var guids, missing []string
+	previous := make(map[string]string)
+	for _, name := range q.Orgs {
+		org, err := cache.GetCache(ctx.ImportCFClient).GetOrgByName(ctx.NameMapping.Org(name))
+		if err != nil {
+			missing = append(missing, ctx.NameMapping.Org(name))
+			continue
+		}
+		if current == nil || current.Relationships == nil || !hasRelationship(current.Relationships.Organizations, org.Guid) {
+			guids = append(guids, org.Guid)
+			previous[org.Guid] = org.QuotaDefinitionGuid
+		}
+	}
+
+	entry.Action, entry.GUID = journal.ApplyOrgQuota, guid
+	return applyQuota(ctx, entry, guids, previous, missing, "orgs", cf.ApplyOrgQuota, unchanged)
+}
+
+// importSpaceQuota creates or updates the space quota in its org and assigns it to its spaces, it tells whether
+// nothing changed
+func (i *ImportQuotas) importSpaceQuota(ctx *context.Context, q export.SpaceQuota, orgGUID string, current *cf.Quota) (bool, error) {
+	want := cf.Quota{Name: q.Name, Apps: q.Apps, Services: q.Services, Routes: q.Routes}
+	if current == nil {
+		want.Relationships = &cf.QuotaRelationships{Organization: &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: orgGUID}}}
+	}
+
+	entry := journal.Entry{Org: ctx.NameMapping.Org(q.Org), Name: q.Name}
+	guid, unchanged, err := saveQuota(ctx, entry, spaceQuotaActions, want, current, cf.CreateSpaceQuota, cf.UpdateSpaceQuota)
+	if err != nil {
+		return false, err
+	}
+
+	var guids, missing []string
+	previous := make(map[string]string)
+	for _, name := range q.Spaces {
+		spaceName := ctx.NameMapping.Space(q.Org, name)
+		space, err := cache.GetCache(ctx.ImportCFClient).GetSpaceByName(spaceName, orgGUID)
+		if err != nil {
+			missing = append(missing, spaceName)
+			continue
+		}
+		if current == nil || current.Relationships == nil || !hasRelationship(current.Relationships.Spaces, space.Guid) {
+			guids = append(guids, space.Guid)
+			previous[space.Guid] = space.QuotaDefinitionGuid
+		}
+	}
+
+	entry.Action, entry.GUID = journal.ApplySpaceQuota, guid
+	return applyQuota(ctx, entry, guids, previous, missing, "spaces", cf.ApplySpaceQuota, unchanged)
+}
+
+// saveQuota creates the quota, or updates the current one when its limits differ, and returns its guid and whether
+// it was left unchanged. What it did is journaled with the actions, in entries like entry.
+func saveQuota(
+	ctx *context.Context,
+	entry journal.Entry,
+	actions quotaActions,
+	want cf.Quota,
+	current *cf.Quota,
+	create func(cf.Client, cf.Quota) (cf.Quota, error),
+	update func(cf.Client, string, cf.Quota) (cf.Quota, error),
+) (string, bool, error) {
+	if current == nil {
+		ctx.Logger.Infof("Creating quota %s", want.Name)
+		var created cf.Quota
+		err := withRetry(ctx, func() error {
+			var err error
+			created, err = create(ctx.ImportCFClient, want)
+			return err
+		})
+		if err != nil {
+			return "", false, err
+		}
+		entry.Action, entry.GUID = actions.create, created.GUID
+		return created.GUID, false, recordResource(ctx, entry, nil)
+	}
+
+	if sameLimits(*current, want) {
+		return current.GUID, true, nil
+	}
+
+	ctx.Logger.Infof("Updating the limits of quota %s", want.Name)
+	err := withRetry(ctx, func() error {
+		_, err := update(ctx.ImportCFClient, current.GUID, want)
+		return err
+	})
+	if err != nil {
+		return "", false, err
+	}
+	entry.Action, entry.GUID = actions.update, current.GUID
+	return current.GUID, false, recordResource(ctx, entry, *current)
+}
+
+// applyQuota assigns the quota of the entry to the orgs or spaces with the given guids, and fails when some of them are
+// missing. Each assignment is journaled with the quota the org or space had before, from previous.
+func applyQuota(
+	ctx *context.Context,
+	entry journal.Entry,
+	guids []string,
+	previous map[string]string,
+	missing []string,
+	what string,
+	apply func(cf.Client, string, []string) error,
+	unchanged bool,
+) (bool, error) {
+	if len(guids) > 0 {
+		ctx.Logger.Infof("Assigning quota %s to %d %s", entry.Name, len(guids), what)
+		err := withRetry(ctx, func() error {
+			return apply(ctx.ImportCFClient, entry.GUID, guids)
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, guid := range guids {
+			entry.RelatedGUID = guid
+			if err = recordResource(ctx, entry, previous[guid]); err != nil {
+				return false, err
+			}
+		}
+		unchanged = false
+	}
+	if len(missing) > 0 {
+		return false, fmt.Errorf("%s %s not found on the target foundation", what, strings.Join(missing, ", "))
+	}
+
+	return unchanged, nil
+}
+
+// addQuotaResult records the outcome of importing a quota with the other resources
+func addQuotaResult(ctx *context.Context, kind, org, name string, unchanged bool, err error) {
+	switch {
+	case err != nil:
+		ctx.Logger.Errorf("Error importing quota %s: %s", name, err)
+		ctx.Resources.AddFailed(kind, org, "", name, err)
+	case unchanged:
+		ctx.Logger.Infof("Quota %s is up to date", name)
+		ctx.Resources.AddSkipped(kind, org, "", name, "unchanged")
+	default:
+		ctx.Resources.AddSucceeded(kind, org, "", name)
+	}
+}
+
+// findQuota returns the quota with the given name, owned by the given org for space quotas
+func findQuota(quotas []cf.Quota, name, orgGUID string) *cf.Quota {
+	for i, q := range quotas {
+		if q.Name != name {
+			continue
+		}
+		if orgGUID == "" || (q.Relationships != nil && q.Relationships.Organization != nil && q.Relationships.Organization.Data.GUID == orgGUID) {
+			return &quotas[i]
+		}
+	}
+	return nil
+}
+
+// sameLimits tells whether the quotas have the same limits
+func sameLimits(current, want cf.Quota) bool {
+	if want.Domains != nil && !reflect.DeepEqual(current.Domains, want.Domains) {
+		return false
+	}
+	return reflect.DeepEqual(current.Apps, want.Apps) &&
+		reflect.DeepEqual(current.Services, want.Services) &&
+		reflect.DeepEqual(current.Routes, want.Routes)
+}
+
+// hasRelationship tells whether the relationships contain the given guid
+func hasRelationship(r *cfclient.V3ToManyRelationships, guid string) bool {
+	if r == nil {
+		return false
+	}
+	for _, d := range r.Data {
+		if d.GUID == guid {
+			return true
+		}
+	}
+	return false
+}
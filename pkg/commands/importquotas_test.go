@@ -0,0 +1,134 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestImportQuotas_Run(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var (
+		mutex    sync.Mutex
+		pending  = make(map[*cfclient.Request]string)
+		requests []string
+	)
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(url string) ([]byte, error) {
+			switch url {
+			case "/v3/organization_quotas":
+				return []byte(`{"pagination": {}, "resources": [{
+  "guid": "default-guid", "name": "default", "apps": {"total_memory_in_mb": 10240}, "domains": {"total_domains": null},
+  "relationships": {"organizations": {"data": [{"guid": "my_org-guid"}]}}
+}, {
+  "guid": "small-guid", "name": "small", "apps": {"total_memory_in_mb": 1024}, "domains": {"total_domains": null},
+  "relationships": {"organizations": {"data": []}}
+}]}`), nil
+			case "/v3/space_quotas":
+				return []byte(`{"pagination": {}, "resources": [{
+  "guid": "other-dev-quota-guid", "name": "dev-quota",
+  "relationships": {"organization": {"data": {"guid": "other_org-guid"}}, "spaces": {"data": []}}
+}]}`), nil
+			}
+			return nil, errors.New("unexpected request " + url)
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			if name == "gone" {
+				return cfclient.Org{}, errors.New("org gone not found")
+			}
+			return cfclient.Org{Guid: name + "-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: name + "-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			req := &cfclient.Request{}
+			mutex.Lock()
+			defer mutex.Unlock()
+			pending[req] = method + " " + path
+			requests = append(requests, method+" "+path+" "+string(data))
+			return req
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch pending[req] {
+			case "POST /v3/organization_quotas":
+				return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{"guid": "new-guid", "name": "new"}`))}, nil
+			case "POST /v3/space_quotas":
+				return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{"guid": "dev-quota-guid", "name": "dev-quota"}`))}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ImportCFClient: client,
+		Resources:      report.NewResources(&bytes.Buffer{}),
+	}
+	defaultMemory, smallMemory, devMemory := 10240, 2048, 512
+	require.NoError(t, export.WriteQuotas(ctx, export.Quotas{
+		OrgQuotas: []export.OrgQuota{
+			{Name: "default", Apps: cf.QuotaApps{TotalMemoryInMB: &defaultMemory}, Orgs: []string{"my_org"}},
+			{Name: "small", Apps: cf.QuotaApps{TotalMemoryInMB: &smallMemory}, Orgs: []string{"other_org"}},
+			{Name: "new", Orgs: []string{"my_org", "gone"}},
+		},
+		SpaceQuotas: []export.SpaceQuota{
+			{Name: "dev-quota", Org: "my_org", Apps: cf.QuotaApps{TotalMemoryInMB: &devMemory}, Spaces: []string{"dev"}},
+		},
+	}))
+
+	require.NoError(t, (&ImportQuotas{}).Run(ctx))
+	assert.Equal(t, 2, ctx.Resources.Count(report.ResourceSucceeded))
+	assert.Equal(t, 1, ctx.Resources.Count(report.ResourceSkipped))
+	assert.Equal(t, 1, ctx.Resources.Count(report.ResourceFailed))
+
+	require.Len(t, requests, 6)
+	assert.Equal(t, `PATCH /v3/organization_quotas/small-guid {"name":"small","apps":{"total_memory_in_mb":2048,"per_process_memory_in_mb":null,"total_instances":null,"per_app_tasks":null,"log_rate_limit_in_bytes_per_second":null},"services":{"paid_services_allowed":false,"total_service_instances":null,"total_service_keys":null},"routes":{"total_routes":null,"total_reserved_ports":null},"domains":{"total_domains":null}}`, requests[0])
+	assert.Equal(t, `POST /v3/organization_quotas/small-guid/relationships/organizations {"data":[{"guid":"other_org-guid"}]}`, requests[1])
+	assert.True(t, strings.HasPrefix(requests[2], `POST /v3/organization_quotas {"name":"new",`), requests[2])
+	assert.Equal(t, `POST /v3/organization_quotas/new-guid/relationships/organizations {"data":[{"guid":"my_org-guid"}]}`, requests[3])
+	assert.True(t, strings.HasPrefix(requests[4], `POST /v3/space_quotas {"name":"dev-quota",`), requests[4])
+	assert.Contains(t, requests[4], `"relationships":{"organization":{"data":{"guid":"my_org-guid"}}}`)
+	assert.Equal(t, `POST /v3/space_quotas/dev-quota-guid/relationships/spaces {"data":[{"guid":"dev-guid"}]}`, requests[5])
+}
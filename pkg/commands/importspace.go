@@ -17,10 +17,12 @@ package commands
 import (
 	"errors"
 	"fmt"
-	"path/filepath"
+	"io/fs"
+	"path"
 	"strings"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
 )
 
 type ImportSpace struct {
@@ -32,8 +34,16 @@ type ImportSpace struct {
 func (i *ImportSpace) Run(ctx *context.Context) error {
 	var err error
 	var files []string
-	glob := filepath.Join(ctx.ExportDir, i.Org, i.Space, "*_manifest.yml")
-	files, err = filepath.Glob(glob)
+
+	runSteps(ctx, "importing", mapping.Display(i.Org, i.targetOrg(ctx)), mapping.Display(i.Space, i.targetSpace(ctx)),
+		step{"roles", func() error { return importSpaceRoles(ctx, i.Org, i.Space) }},
+		step{"security groups", func() error { return importSpaceSecurityGroups(ctx, i.Org, i.Space) }},
+		step{"isolation segment", func() error { return importSpaceIsolationSegment(ctx, i.Org, i.Space) }},
+	)
+	importSpaceServices(ctx, i.Org, i.Space)
+
+	glob := path.Join(i.Org, i.Space, "*_manifest.yml")
+	files, err = fs.Glob(ctx.ExportFS(), glob)
 	if err != nil {
 		return err
 	}
@@ -50,7 +60,7 @@ func (i *ImportSpace) Run(ctx *context.Context) error {
 			},
 			AppName: fmt.Sprintf("%v", r.Value),
 		}
-		err := appImporter.Run(ctx)
+		err := appImporter.importApp(ctx)
 		return context.ProcessResult{Value: r.Value, Err: err}
 	}
 
@@ -63,9 +73,23 @@ func (i *ImportSpace) Run(ctx *context.Context) error {
 	for r := range results {
 		if r.Err != nil {
 			appPath := strings.Join([]string{i.Org, i.Space, fmt.Sprintf("%v", r.Value)}, "/")
-			ctx.Summary.AddFailedApp(i.Org, i.Space, appPath, r.Err)
+			ctx.Summary.AddFailedApp(mapping.Display(i.Org, i.targetOrg(ctx)), mapping.Display(i.Space, i.targetSpace(ctx)), appPath, r.Err)
 		}
 	}
 
 	return nil
 }
+
+// importSpaceServices creates the service instances and keys of the space, it runs before any of its apps is imported,
+// also when they are imported on their own, so that the apps can be bound to the service instances
+func importSpaceServices(ctx *context.Context, org, space string) {
+	runSteps(ctx, "importing", mapping.Display(org, ctx.NameMapping.Org(org)), mapping.Display(space, ctx.NameMapping.Space(org, space)),
+		step{"service instances", func() error { return importSpaceServiceInstances(ctx, org, space) }},
+		step{"service keys", func() error { return importSpaceServiceKeys(ctx, org, space) }},
+	)
+}
+
+// targetSpace is the name of the space on the target foundation
+func (i *ImportSpace) targetSpace(ctx *context.Context) string {
+	return ctx.NameMapping.Space(i.Org, i.Space)
+}
@@ -0,0 +1,275 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+)
+
+// exportOrgIsolationSegments writes the isolation segments of the org on the source foundation to the export
+func exportOrgIsolationSegments(ctx *context.Context, org cfclient.Org) error {
+	segments, err := orgIsolationSegments(ctx, org)
+	if err != nil {
+		return fmt.Errorf("error getting the isolation segments of org %s: %w", org.Name, err)
+	}
+
+	return export.WriteOrgIsolationSegments(ctx, org.Name, segments)
+}
+
+// exportSpaceIsolationSegment writes the isolation segment of the space on the source foundation to the export
+func exportSpaceIsolationSegment(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	segment, err := spaceIsolationSegment(ctx, space)
+	if err != nil {
+		return fmt.Errorf("error getting the isolation segment of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return export.WriteSpaceIsolationSegment(ctx, org.Name, space.Name, segment)
+}
+
+// migrateOrgIsolationSegments entitles the org of the target foundation to the isolation segments of the org on the
+// source foundation
+func migrateOrgIsolationSegments(ctx *context.Context, org cfclient.Org) error {
+	segments, err := orgIsolationSegments(ctx, org)
+	if err != nil {
+		return fmt.Errorf("error getting the isolation segments of org %s: %w", org.Name, err)
+	}
+
+	return entitleOrg(ctx, ctx.NameMapping.Org(org.Name), segments)
+}
+
+// migrateSpaceIsolationSegment assigns the isolation segment of the space on the source foundation to the space of
+// the target foundation
+func migrateSpaceIsolationSegment(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	segment, err := spaceIsolationSegment(ctx, space)
+	if err != nil {
+		return fmt.Errorf("error getting the isolation segment of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return assignSpace(ctx, ctx.NameMapping.Org(org.Name), ctx.NameMapping.Space(org.Name, space.Name), segment)
+}
+
+// importOrgIsolationSegments entitles the target org to the exported isolation segments of the org. Exports made
+// before isolation segments were exported have none to import.
+func importOrgIsolationSegments(ctx *context.Context, sourceOrg string) error {
+	segments, err := export.ReadOrgIsolationSegments(ctx, sourceOrg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return entitleOrg(ctx, ctx.NameMapping.Org(sourceOrg), segments)
+}
+
+// importSpaceIsolationSegment assigns the exported isolation segment of the space to the target space. Exports made
+// before isolation segments were exported have none to import.
+func importSpaceIsolationSegment(ctx *context.Context, sourceOrg, sourceSpace string) error {
+	segment, err := export.ReadSpaceIsolationSegment(ctx, sourceOrg, sourceSpace)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return assignSpace(ctx, ctx.NameMapping.Org(sourceOrg), ctx.NameMapping.Space(sourceOrg, sourceSpace), segment)
+}
+
+// orgIsolationSegments returns the names of the isolation segments the org is entitled to on the source foundation
+func orgIsolationSegments(ctx *context.Context, org cfclient.Org) (export.OrgIsolationSegments, error) {
+	var segments export.OrgIsolationSegments
+	entitled, err := cf.ListOrgIsolationSegments(ctx.ExportCFClient, org.Guid)
+	if err != nil {
+		return segments, err
+	}
+	defaultGUID, err := cf.GetOrgDefaultIsolationSegment(ctx.ExportCFClient, org.Guid)
+	if err != nil {
+		return segments, err
+	}
+
+	segments.Entitled = make([]string, 0, len(entitled))
+	for _, s := range entitled {
+		segments.Entitled = append(segments.Entitled, s.Name)
+		if s.GUID == defaultGUID {
+			segments.Default = s.Name
+		}
+	}
+	sort.Strings(segments.Entitled)
+
+	return segments, nil
+}
+
+// spaceIsolationSegment returns the name of the isolation segment assigned to the space on the source foundation
+func spaceIsolationSegment(ctx *context.Context, space cfclient.Space) (export.SpaceIsolationSegment, error) {
+	guid, err := cf.GetSpaceIsolationSegment(ctx.ExportCFClient, space.Guid)
+	if err != nil || guid == "" {
+		return export.SpaceIsolationSegment{}, err
+	}
+
+	segment, err := cf.GetIsolationSegment(ctx.ExportCFClient, guid)
+	if err != nil {
+		return export.SpaceIsolationSegment{}, err
+	}
+
+	return export.SpaceIsolationSegment{Name: segment.Name}, nil
+}
+
+// entitleOrg entitles the org of the target foundation to the isolation segments it is not entitled to yet, and makes
+// the default segment of the source org its default segment
+func entitleOrg(ctx *context.Context, orgName string, segments export.OrgIsolationSegments) error {
+	org, err := cache.GetCache(ctx.ImportCFClient).GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+
+	entitled, err := cf.ListOrgIsolationSegments(ctx.ImportCFClient, org.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the isolation segments of org %s: %w", orgName, err)
+	}
+
+	var errs []string
+	for _, name := range segments.Entitled {
+		if _, err := entitle(ctx, org, mapIsolationSegment(ctx, name), &entitled); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if segments.Default != "" {
+		if err := setOrgDefault(ctx, org, mapIsolationSegment(ctx, segments.Default), &entitled); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// setOrgDefault makes the isolation segment the default segment of the org of the target foundation
+func setOrgDefault(ctx *context.Context, org cfclient.Org, name string, entitled *[]cf.IsolationSegment) error {
+	guid, err := entitle(ctx, org, name, entitled)
+	if err != nil {
+		return err
+	}
+
+	current, err := cf.GetOrgDefaultIsolationSegment(ctx.ImportCFClient, org.Guid)
+	if err != nil || current == guid {
+		return err
+	}
+
+	ctx.Logger.Infof("Making isolation segment %s the default segment of org %s", name, org.Name)
+	err = withRetry(ctx, func() error {
+		return cf.SetOrgDefaultIsolationSegment(ctx.ImportCFClient, org.Guid, guid)
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordResource(ctx, journal.Entry{Action: journal.SetOrgIsolationSegment, Org: org.Name, Name: name, GUID: guid, RelatedGUID: org.Guid}, current)
+}
+
+// assignSpace assigns the isolation segment to the space of the target foundation, entitling its org to the segment
+// first when it is not
+func assignSpace(ctx *context.Context, orgName, spaceName string, segment export.SpaceIsolationSegment) error {
+	if segment.Name == "" {
+		return nil
+	}
+
+	c := cache.GetCache(ctx.ImportCFClient)
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+	space, err := c.GetSpaceByName(spaceName, org.Guid)
+	if err != nil {
+		return err
+	}
+
+	entitled, err := cf.ListOrgIsolationSegments(ctx.ImportCFClient, org.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the isolation segments of org %s: %w", orgName, err)
+	}
+	name := mapIsolationSegment(ctx, segment.Name)
+	guid, err := entitle(ctx, org, name, &entitled)
+	if err != nil {
+		return err
+	}
+
+	current, err := cf.GetSpaceIsolationSegment(ctx.ImportCFClient, space.Guid)
+	if err != nil || current == guid {
+		return err
+	}
+
+	ctx.Logger.Infof("Assigning isolation segment %s to space %s/%s", name, orgName, spaceName)
+	err = withRetry(ctx, func() error {
+		return cf.SetSpaceIsolationSegment(ctx.ImportCFClient, space.Guid, guid)
+	})
+	if err != nil {
+		return err
+	}
+
+	entry := journal.Entry{Action: journal.SetSpaceIsolationSegment, Org: orgName, Space: spaceName, Name: name, GUID: guid, RelatedGUID: space.Guid}
+	return recordResource(ctx, entry, current)
+}
+
+// entitle entitles the org to the isolation segment with the given name when it is not among the entitled segments,
+// which it is added to, and returns the guid of the segment
+func entitle(ctx *context.Context, org cfclient.Org, name string, entitled *[]cf.IsolationSegment) (string, error) {
+	for _, s := range *entitled {
+		if s.Name == name {
+			return s.GUID, nil
+		}
+	}
+
+	segment, err := cf.GetIsolationSegmentByName(ctx.ImportCFClient, name)
+	if err != nil {
+		return "", err
+	}
+	if segment == nil {
+		return "", fmt.Errorf("isolation segment %s does not exist on the target foundation", name)
+	}
+
+	ctx.Logger.Infof("Entitling org %s to isolation segment %s", org.Name, name)
+	err = withRetry(ctx, func() error {
+		return cf.EntitleIsolationSegment(ctx.ImportCFClient, segment.GUID, org.Guid)
+	})
+	if err != nil {
+		return "", err
+	}
+	err = recordResource(ctx, journal.Entry{Action: journal.EntitleIsolationSegment, Org: org.Name, Name: name, GUID: segment.GUID, RelatedGUID: org.Guid}, nil)
+	if err != nil {
+		return "", err
+	}
+	*entitled = append(*entitled, *segment)
+
+	return segment.GUID, nil
+}
+
+// mapIsolationSegment returns the name of the isolation segment of the target foundation for a segment of the source
+// foundation
+func mapIsolationSegment(ctx *context.Context, name string) string {
+	if target, ok := ctx.SegmentMappings[name]; ok {
+		return target
+	}
+	return name
+}
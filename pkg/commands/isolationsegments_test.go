@@ -0,0 +1,143 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestExportIsolationSegments(t *testing.T) {
+	client := &fakes.FakeClient{
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/isolation_segments?" + url.Values{"organization_guids": []string{"org-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "shared-guid", "name": "shared"},
+  {"guid": "iso-1-guid", "name": "iso-1"},
+  {"guid": "iso-2-guid", "name": "iso-2"}
+]}`), nil
+			case "/v3/organizations/org-guid/relationships/default_isolation_segment":
+				return []byte(`{"data": {"guid": "iso-1-guid"}}`), nil
+			case "/v3/spaces/space-guid/relationships/isolation_segment":
+				return []byte(`{"data": {"guid": "iso-2-guid"}}`), nil
+			case "/v3/spaces/other-space-guid/relationships/isolation_segment":
+				return []byte(`{"data": null}`), nil
+			case "/v3/isolation_segments/iso-2-guid":
+				return []byte(`{"guid": "iso-2-guid", "name": "iso-2"}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{DirWriter: storage.NewMemory(), ExportCFClient: client}
+	org := cfclient.Org{Guid: "org-guid", Name: "my_org"}
+
+	require.NoError(t, exportOrgIsolationSegments(ctx, org))
+	require.NoError(t, exportSpaceIsolationSegment(ctx, org, cfclient.Space{Guid: "space-guid", Name: "my_space"}))
+	require.NoError(t, exportSpaceIsolationSegment(ctx, org, cfclient.Space{Guid: "other-space-guid", Name: "other_space"}))
+
+	orgSegments, err := export.ReadOrgIsolationSegments(ctx, "my_org")
+	require.NoError(t, err)
+	assert.Equal(t, export.OrgIsolationSegments{Entitled: []string{"iso-1", "iso-2", "shared"}, Default: "iso-1"}, orgSegments)
+
+	segment, err := export.ReadSpaceIsolationSegment(ctx, "my_org", "my_space")
+	require.NoError(t, err)
+	assert.Equal(t, export.SpaceIsolationSegment{Name: "iso-2"}, segment)
+
+	segment, err = export.ReadSpaceIsolationSegment(ctx, "my_org", "other_space")
+	require.NoError(t, err)
+	assert.Equal(t, export.SpaceIsolationSegment{}, segment)
+}
+
+func TestImportIsolationSegments(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var (
+		requests []string
+		entitled = `{"guid": "shared-guid", "name": "shared"}`
+	)
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "org-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/isolation_segments?" + url.Values{"organization_guids": []string{"org-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [` + entitled + `]}`), nil
+			case "/v3/isolation_segments?names=iso-east":
+				return []byte(`{"pagination": {}, "resources": [{"guid": "iso-east-guid", "name": "iso-east"}]}`), nil
+			case "/v3/isolation_segments?names=iso-2":
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/organizations/org-guid/relationships/default_isolation_segment",
+				"/v3/spaces/space-guid/relationships/isolation_segment":
+				return []byte(`{"data": null}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			requests = append(requests, method+" "+path+" "+string(data))
+			return &cfclient.Request{}
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			if strings.HasPrefix(requests[len(requests)-1], "POST /v3/isolation_segments/iso-east-guid/relationships/organizations") {
+				entitled += `, {"guid": "iso-east-guid", "name": "iso-east"}`
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:          log.New(),
+		DirWriter:       storage.NewMemory(),
+		ImportCFClient:  client,
+		SegmentMappings: map[string]string{"iso-1": "iso-east"},
+	}
+	require.NoError(t, export.WriteOrgIsolationSegments(ctx, "my_org", export.OrgIsolationSegments{Entitled: []string{"iso-1", "iso-2", "shared"}, Default: "iso-1"}))
+	require.NoError(t, export.WriteSpaceIsolationSegment(ctx, "my_org", "my_space", export.SpaceIsolationSegment{Name: "iso-1"}))
+
+	err := importOrgIsolationSegments(ctx, "my_org")
+	assert.EqualError(t, err, "isolation segment iso-2 does not exist on the target foundation")
+	require.NoError(t, importSpaceIsolationSegment(ctx, "my_org", "my_space"))
+	require.NoError(t, importSpaceIsolationSegment(ctx, "my_org", "space_without_export"))
+
+	assert.Equal(t, []string{
+		`POST /v3/isolation_segments/iso-east-guid/relationships/organizations {"data":[{"guid":"org-guid"}]}`,
+		`PATCH /v3/organizations/org-guid/relationships/default_isolation_segment {"data":{"guid":"iso-east-guid"}}`,
+		`PATCH /v3/spaces/space-guid/relationships/isolation_segment {"data":{"guid":"iso-east-guid"}}`,
+	}, requests)
+}
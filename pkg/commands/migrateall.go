@@ -0,0 +1,39 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+type MigrateAll struct {
+}
+
+// Run migrates the apps of every org on the source foundation that is included and not excluded
+func (m *MigrateAll) Run(ctx *context.Context) error {
+	return forEachSourceOrg(ctx, func(org cfclient.Org) {
+		migrateOrgCmd := &MigrateOrg{
+			Org: org.Name,
+		}
+
+		if err := migrateOrgCmd.Run(ctx, org.Name); err != nil {
+			log.Errorf("Error migrating org %s: %v", org.Name, err)
+		}
+	})
+}
@@ -0,0 +1,314 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+// MigrateApp exports an app from the source foundation and imports it into the target foundation in one go.
+// The manifest and autoscaler settings are staged in memory, while the package and droplet are streamed from
+// the source download to the target upload, so the app is never written to the export dir unless
+// ctx.TeeExport asks for a copy.
+type MigrateApp struct {
+	MigrateSpace
+	Sequence Sequence
+	AppName  string `help:"the app to migrate" short:"a" env:"CF_APP_NAME"`
+}
+
+func (m *MigrateApp) SetAppName(name string) {
+	m.AppName = name
+}
+
+func (m *MigrateApp) Run(ctx *context.Context, orgName, spaceName string) error {
+	importer := &ImportApp{
+		ImportSpace: ImportSpace{
+			ImportOrg: ImportOrg{
+				Org: orgName,
+			},
+			Space: spaceName,
+		},
+		AppName: getAppFileName(m.AppName),
+	}
+
+	if m.Sequence == nil {
+		m.Sequence = NewMigrateAppSequence(orgName, spaceName, m.AppName, importer)
+	}
+
+	if _, err := m.Sequence.Run(ctx, nil); err != nil {
+		if errors.Is(err, ErrAppSkipped) {
+			ctx.Summary.AddSkippedApp(mapping.Display(orgName, importer.targetOrg(ctx)), mapping.Display(spaceName, importer.targetSpace(ctx)), m.AppName, err.Error())
+			return nil
+		}
+		ctx.Logger.Errorf("Error occurred migrating app %s/%s/%s: %s", orgName, spaceName, m.AppName, err)
+		return err
+	}
+
+	return nil
+}
+
+func NewMigrateAppSequence(orgName, spaceName, appName string, importer *ImportApp) Sequence {
+	exportDir := path.Join(orgName, spaceName)
+	stage := storage.NewMemory()
+
+	// the export half writes to the stage and the import half reads from it, neither touches the export dir
+	stageContext := func(ctx *context.Context) *context.Context {
+		stageCtx := *ctx
+		stageCtx.DirWriter = stage
+		stageCtx.Bundle = nil
+		stageCtx.Sealer = nil
+		stageCtx.Opener = nil
+		return &stageCtx
+	}
+
+	return RunSequence(
+		fmt.Sprintf("\x1b[31m%v\x1b[0m", appName),
+		appName,
+		StepWithProgressBar(
+			func(ctx *context.Context, r Result) (Result, error) {
+				return loadSourceApp(ctx, orgName, spaceName, appName)
+			},
+			"Loading Cache",
+		),
+		StepWithProgressBar(
+			func(ctx *context.Context, r Result) (Result, error) {
+				stageCtx := stageContext(ctx)
+				if err := ctx.ManifestExporter.ExportAppManifest(stageCtx, r.GetOrg(), r.GetSpace(), r.GetApp(), exportDir); err != nil {
+					return nil, err
+				}
+				if err := ctx.AutoScalerExporter.ExportAutoScalerRules(stageCtx, r.GetOrg(), r.GetSpace(), r.GetApp(), exportDir); err != nil {
+					return nil, err
+				}
+				if err := ctx.AutoScalerExporter.ExportAutoScalerInstances(stageCtx, r.GetOrg(), r.GetSpace(), r.GetApp(), exportDir); err != nil {
+					return nil, err
+				}
+				if err := ctx.AutoScalerExporter.ExportAutoScalerSchedules(stageCtx, r.GetOrg(), r.GetSpace(), r.GetApp(), exportDir); err != nil {
+					return nil, err
+				}
+				return r, nil
+			},
+			"Exporting Manifest",
+		),
+		StepWithProgressBar(
+			func(ctx *context.Context, r Result) (Result, error) {
+				stageCtx := stageContext(ctx)
+				var err error
+				if importer.AppName, err = importer.getAppNameFromManifest(stageCtx); err != nil {
+					return nil, err
+				}
+				if err = importer.createApp(stageCtx); err != nil {
+					return nil, err
+				}
+				return r, nil
+			},
+			"Creating app",
+		),
+		StepWithProgressBar(
+			func(ctx *context.Context, r Result) (Result, error) {
+				if err := importer.moveServiceData(ctx, sourceBindingCredentials(ctx, r.GetApp())); err != nil {
+					return nil, err
+				}
+				return r, nil
+			},
+			"Moving service data",
+		),
+		StepWithProgressBar(
+			func(ctx *context.Context, r Result) (Result, error) {
+				if err := streamPackages(ctx, r, importer, exportDir); err != nil {
+					return nil, err
+				}
+				return r, nil
+			},
+			"Streaming blob",
+		),
+		StepWithProgressBar(
+			func(ctx *context.Context, r Result) (Result, error) {
+				stageCtx := stageContext(ctx)
+				if err := importer.applyAutoscalerRules(stageCtx); err != nil {
+					return nil, err
+				}
+				if err := importer.applyAutoscalerInstances(stageCtx); err != nil {
+					return nil, err
+				}
+				if err := importer.applyAutoscalerSchedules(stageCtx); err != nil {
+					return nil, err
+				}
+				return r, nil
+			},
+			"Applying AutoScaler settings",
+		),
+		StepWithProgressBar(
+			func(ctx *context.Context, r Result) (Result, error) {
+				if !ctx.TeeExport {
+					return r, nil
+				}
+				if err := copyStaged(ctx, stage, exportDir); err != nil {
+					return nil, err
+				}
+				return r, nil
+			},
+			"Copying to export dir",
+		),
+	)
+}
+
+// streamPackages streams the package and then the droplet of the app from the source to the target foundation, or
+// stages the package there when the app is restaged
+func streamPackages(ctx *context.Context, r Result, importer *ImportApp, exportDir string) error {
+	app := r.GetApp()
+
+	// Don't migrate packages if app is a Docker image
+	if app.DockerImage != "" {
+		return nil
+	}
+
+	numOfPackages, err := ctx.DropletExporter.NumberOfPackages(ctx, app)
+	if err != nil {
+		ctx.Logger.Errorf("Error getting packages: %s", err)
+		return err
+	}
+
+	if numOfPackages == 0 {
+		ctx.Logger.Warnf("App %s/%s/%s has no packages with bits to download, so it will be skipped", r.GetOrg().Name, r.GetSpace().Name, app.Name)
+		return nil
+	}
+
+	ctx.Logger.Infof("Streaming bits for app %s/%s/%s", r.GetOrg().Name, r.GetSpace().Name, app.Name)
+	err = stream(ctx,
+		func() (io.ReadCloser, int64, error) {
+			return ctx.DropletExporter.OpenPackage(ctx, app)
+		},
+		func(body io.Reader, size int64) error {
+			return cf.StreamAppBits(ctx.ImportCFClient, importer.appGUID, body, size)
+		},
+		path.Join(exportDir, getAppFileName(app.Name)+".zip"),
+	)
+	if err != nil {
+		return err
+	}
+
+	if importer.restage(ctx, nil) {
+		ctx.Logger.Infof("Staging app %s/%s/%s on the target foundation", r.GetOrg().Name, r.GetSpace().Name, app.Name)
+		return importer.putDroplet(ctx, func() error {
+			return importer.stageApp(ctx)
+		})
+	}
+
+	ctx.Logger.Infof("Streaming droplet for app %s/%s/%s", r.GetOrg().Name, r.GetSpace().Name, app.Name)
+	return importer.putDroplet(ctx, func() error {
+		return stream(ctx,
+			func() (io.ReadCloser, int64, error) {
+				return ctx.DropletExporter.OpenDroplet(ctx, app)
+			},
+			func(body io.Reader, size int64) error {
+				return cf.StreamDroplet(ctx.ImportCFClient, importer.appGUID, body, size)
+			},
+			path.Join(exportDir, getAppFileName(app.Name)+".tgz"),
+		)
+	})
+}
+
+// stream copies a download into an upload through a buffer of ctx.StreamBufferSize bytes, so that neither waits
+// on the other for every read. The download is also written to teeName in the export dir when ctx.TeeExport is set.
+func stream(ctx *context.Context, download func() (io.ReadCloser, int64, error), upload func(io.Reader, int64) error, teeName string) error {
+	body, size, err := download()
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = body
+	var tee io.WriteCloser
+	if ctx.TeeExport {
+		tee, err = aio.PutFile(ctx.DirWriter, teeName, 0644, ctx.Sealer)
+		if err != nil {
+			body.Close()
+			return err
+		}
+		src = io.TeeReader(body, tee)
+	}
+
+	pr, pw := aio.NewBufferedPipe(ctx.StreamBufferSize)
+	downloaded := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(pw, src)
+		pw.CloseWithError(err)
+		downloaded <- err
+	}()
+
+	err = upload(pr, size)
+	// stop the download if the upload gave up early
+	pr.CloseWithError(err)
+	body.Close()
+	downloadErr := <-downloaded
+	if err == nil && downloadErr != nil {
+		err = downloadErr
+	}
+
+	if tee != nil {
+		if closeErr := tee.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = ctx.DirWriter.Delete(teeName)
+		}
+	}
+
+	return err
+}
+
+// copyStaged writes the files staged for an app to the export dir, as export would have written them
+func copyStaged(ctx *context.Context, stage *storage.Memory, exportDir string) error {
+	entries, err := stage.List(exportDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err = copyFile(ctx, stage, path.Join(exportDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(ctx *context.Context, stage *storage.Memory, name string) error {
+	r, err := stage.Get(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := aio.PutFile(ctx.DirWriter, name, 0644, ctx.Sealer)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err = io.Copy(w, r); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
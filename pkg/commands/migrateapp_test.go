@@ -0,0 +1,188 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
+)
+
+// uploadRecorder serves the target foundation and keeps the files uploaded to it
+type uploadRecorder struct {
+	http.Handler
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func newUploadRecorder(t *testing.T) *uploadRecorder {
+	u := &uploadRecorder{files: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.Handle("/", ImportTestHandler(t))
+	record := func(field string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mr, err := r.MultipartReader()
+			require.NoError(t, err)
+			for {
+				part, err := mr.NextPart()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				if part.FormName() == field {
+					data, err := io.ReadAll(part)
+					require.NoError(t, err)
+					u.mu.Lock()
+					u.files[field] = string(data)
+					u.mu.Unlock()
+				}
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"metadata":{}}`)
+		}
+	}
+	mux.Handle("/v2/apps/6064d98a-95e6-400b-bc03-be65e6d59622/bits", record("application"))
+	mux.Handle("/v2/apps/6064d98a-95e6-400b-bc03-be65e6d59622/droplet/upload", record("droplet"))
+	u.Handler = mux
+	return u
+}
+
+func (u *uploadRecorder) file(field string) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.files[field]
+}
+
+func TestMigrateApp_Run(t *testing.T) {
+	tests := []struct {
+		name      string
+		tee       bool
+		wantFiles []string
+	}{
+		{
+			name: "streams the app without an export dir",
+		},
+		{
+			name: "tees the app to the export dir",
+			tee:  true,
+			wantFiles: []string{
+				"my_org/my_space/my_app.tgz",
+				"my_org/my_space/my_app.zip",
+				"my_org/my_space/my_app_manifest.yml",
+				"my_org/my_space/my_app_autoscale_rules.json",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+			source := httptest.NewServer(ExportTestHandler(t))
+			defer source.Close()
+			target := newUploadRecorder(t)
+			ts := httptest.NewServer(target)
+			defer ts.Close()
+
+			exportDir := storage.NewLocal(t.TempDir())
+			out := &bytes.Buffer{}
+			logger := log.New()
+			logger.SetOutput(out)
+			ctx := &context.Context{
+				Logger:             logger,
+				DirWriter:          exportDir,
+				Metadata:           metadata.NewMetadata(),
+				Summary:            report.NewSummary(&bytes.Buffer{}),
+				ExportCFClient:     NewTestCFClient(t, source),
+				ImportCFClient:     NewTestCFClient(t, ts),
+				AutoScalerExporter: export.NewAutoScalerExporter(),
+				DropletExporter:    export.NewDropletExporter(),
+				ManifestExporter:   export.NewManifestExporter(),
+				OnConflict:         ConflictUpdate,
+				UpdateUnownedApps:  true,
+				StreamBufferSize:   4,
+				TeeExport:          tt.tee,
+			}
+
+			migrateApp := &MigrateApp{AppName: "my_app"}
+			require.NoError(t, migrateApp.Run(ctx, "my_org", "my_space"), out.String())
+
+			assert.Equal(t, "app bits data", target.file("application"))
+			assert.Equal(t, "droplet data", target.file("droplet"))
+			assert.Equal(t, 1, ctx.Summary.AppSuccessCount())
+
+			files, err := fs.Glob(storage.NewFS(exportDir), "my_org/my_space/*")
+			require.NoError(t, err)
+			for _, f := range tt.wantFiles {
+				assert.Contains(t, files, f)
+			}
+			if !tt.tee {
+				assert.Empty(t, files)
+			}
+		})
+	}
+}
+
+func TestStream_UploadFails(t *testing.T) {
+	exportDir := storage.NewLocal(t.TempDir())
+	ctx := &context.Context{
+		DirWriter:        exportDir,
+		StreamBufferSize: 2,
+		TeeExport:        true,
+	}
+
+	var closed bool
+	err := stream(ctx,
+		func() (io.ReadCloser, int64, error) {
+			return readCloser{Reader: bytes.NewReader(bytes.Repeat([]byte("x"), 1024)), close: func() { closed = true }}, 1024, nil
+		},
+		func(r io.Reader, size int64) error {
+			_, err := multipart.NewReader(r, "boundary").NextPart()
+			return err
+		},
+		"my_org/my_space/my_app.zip",
+	)
+	require.Error(t, err)
+	assert.True(t, closed)
+
+	_, err = exportDir.Stat("my_org/my_space/my_app.zip")
+	assert.ErrorIs(t, err, fs.ErrNotExist, "a partial copy is not left in the export dir")
+}
+
+type readCloser struct {
+	io.Reader
+	close func()
+}
+
+func (r readCloser) Close() error {
+	r.close()
+	return nil
+}
@@ -0,0 +1,55 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+)
+
+type MigrateOrg struct {
+	Org string `help:"the org to migrate" short:"o" env:"CF_ORG"`
+}
+
+// Run migrates the apps of every space in the org, the spaces missing on the target foundation are skipped
+func (m *MigrateOrg) Run(ctx *context.Context, orgName string) error {
+	org, err := cache.GetCache(ctx.ExportCFClient).GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+
+	runSteps(ctx, "migrating", mapping.Display(orgName, ctx.NameMapping.Org(orgName)), "",
+		step{"roles", func() error { return migrateOrgRoles(ctx, org) }},
+		step{"isolation segments", func() error { return migrateOrgIsolationSegments(ctx, org) }},
+	)
+
+	return forEachSourceSpace(ctx, orgName, func(space cfclient.Space) {
+		migrateSpaceCmd := &MigrateSpace{
+			MigrateOrg: MigrateOrg{
+				Org: orgName,
+			},
+			Space: space.Name,
+		}
+
+		if err := migrateSpaceCmd.Run(ctx, orgName, space.Name); err != nil {
+			log.Errorf("Error migrating space %s/%s: %v", orgName, space.Name, err)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+)
+
+type MigrateSpace struct {
+	MigrateOrg
+	Space string `help:"the space to migrate" short:"s" env:"CF_SPACE"`
+}
+
+// Run migrates all the apps of a space, which must already exist on the target foundation
+func (m *MigrateSpace) Run(ctx *context.Context, orgName, spaceName string) error {
+	c := cache.GetCache(ctx.ExportCFClient)
+
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+
+	space, err := c.GetSpaceByName(spaceName, org.Guid)
+	if err != nil {
+		return err
+	}
+
+	targetOrg := ctx.NameMapping.Org(orgName)
+	targetSpace := ctx.NameMapping.Space(orgName, spaceName)
+	if err = targetSpaceExists(ctx, targetOrg, targetSpace); err != nil {
+		return err
+	}
+
+	runSteps(ctx, "migrating", mapping.Display(orgName, targetOrg), mapping.Display(spaceName, targetSpace),
+		step{"roles", func() error { return migrateSpaceRoles(ctx, org, space) }},
+		step{"security groups", func() error { return migrateSpaceSecurityGroups(ctx, org, space) }},
+		step{"isolation segment", func() error { return migrateSpaceIsolationSegment(ctx, org, space) }},
+		step{"service instances", func() error { return migrateSpaceServiceInstances(ctx, org, space) }},
+		step{"service keys", func() error { return migrateSpaceServiceKeys(ctx, org, space) }},
+	)
+
+	migrateApp := func(ctx *context.Context, r context.QueryResult) context.ProcessResult {
+		appMigrator := &MigrateApp{
+			MigrateSpace: *m,
+			AppName:      fmt.Sprintf("%v", r.Value),
+		}
+		err := appMigrator.Run(ctx, orgName, spaceName)
+		return context.ProcessResult{Value: r.Value, Err: err}
+	}
+
+	results, err := ctx.SpaceExporter.ExportSpace(ctx, space, migrateApp)
+	if err != nil {
+		return err
+	}
+
+	for r := range results {
+		if r.Err != nil {
+			appPath := strings.Join([]string{orgName, spaceName, fmt.Sprintf("%v", r.Value)}, "/")
+			ctx.Summary.AddFailedApp(mapping.Display(orgName, targetOrg), mapping.Display(spaceName, targetSpace), appPath, r.Err)
+		}
+	}
+
+	return nil
+}
+
+// targetSpaceExists checks that apps can be migrated to the space on the target foundation
+func targetSpaceExists(ctx *context.Context, orgName, spaceName string) error {
+	c := cache.GetCache(ctx.ImportCFClient)
+
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.GetSpaceByName(spaceName, org.Guid)
+	return err
+}
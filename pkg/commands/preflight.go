@@ -0,0 +1,412 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"gopkg.in/yaml.v2"
+)
+
+// Kinds of resources checked by the preflight
+const (
+	CheckBuildpack  = "buildpack"
+	CheckStack      = "stack"
+	CheckOrgQuota   = "org quota"
+	CheckSpaceQuota = "space quota"
+)
+
+type Preflight struct {
+	buildpacks map[string][]cf.V3Buildpack
+	stacks     map[string]bool
+	usage      map[string]*quotaUsage // source org, or org/space -> what the exported apps of it need
+}
+
+// quotaUsage is the memory and instances the exported apps of an org or space need
+type quotaUsage struct {
+	memory    int
+	instances int
+}
+
+// preflightApp is an exported app and why it cannot be imported, if it cannot
+type preflightApp struct {
+	org, space, name string
+	problems         []string
+}
+
+// Run checks that the target foundation provides what the exported apps depend on, it reports every resource they
+// reference and marks the apps that cannot be imported as failed
+func (p *Preflight) Run(ctx *context.Context) error {
+	if err := p.loadTarget(ctx); err != nil {
+		return err
+	}
+
+	manifests, err := fs.Glob(ctx.ExportFS(), path.Join("*", "*", "*_manifest.yml"))
+	if err != nil {
+		return err
+	}
+
+	instances, err := p.checkServiceInstances(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.usage = make(map[string]*quotaUsage)
+	var apps []preflightApp
+	for _, manifestPath := range manifests {
+		org, space := path.Split(path.Dir(manifestPath))
+		org = path.Clean(org)
+		if isOrgExcluded(ctx, org) || !isOrgIncluded(ctx, org) {
+			continue
+		}
+
+		appName := strings.TrimSuffix(path.Base(manifestPath), "_manifest.yml")
+		app, err := readManifest(ctx, manifestPath)
+		if err != nil {
+			ctx.Summary.AddFailedApp(org, space, appName, err)
+			continue
+		}
+
+		p.addUsage(org, space, app)
+		problems := p.checkApp(ctx, app)
+		for _, s := range app.Services {
+			if problem, ok := instances[path.Join(org, space, s.Name)]; ok {
+				problems = append(problems, problem)
+			}
+		}
+		apps = append(apps, preflightApp{org: org, space: space, name: appName, problems: problems})
+	}
+
+	quotas := p.checkQuotas(ctx)
+	for _, app := range apps {
+		for _, check := range append(quotas[app.org], quotas[path.Join(app.org, app.space)]...) {
+			ctx.Preflight.Add(check)
+			if check.Failed {
+				app.problems = append(app.problems, fmt.Sprintf("%s %s %s", check.Kind, check.Target, check.Status))
+			}
+		}
+
+		if len(app.problems) > 0 {
+			ctx.Summary.AddFailedApp(app.org, app.space, app.name, errors.New(strings.Join(app.problems, ", ")))
+			continue
+		}
+		ctx.Summary.AddSuccessfulApp(app.org, app.space, app.name)
+	}
+
+	ctx.Preflight.Display()
+	if ctx.ServicePlanChecks != nil {
+		ctx.ServicePlanChecks.Display()
+	}
+
+	return nil
+}
+
+// loadTarget lists the buildpacks and stacks of the target foundation
+func (p *Preflight) loadTarget(ctx *context.Context) error {
+	buildpacks, err := cf.ListBuildpacks(ctx.ImportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the buildpacks of the target foundation: %w", err)
+	}
+	p.buildpacks = make(map[string][]cf.V3Buildpack)
+	for _, b := range buildpacks {
+		p.buildpacks[b.Name] = append(p.buildpacks[b.Name], b)
+	}
+
+	stacks, err := cf.ListStacks(ctx.ImportCFClient)
+	if err != nil {
+		return fmt.Errorf("error listing the stacks of the target foundation: %w", err)
+	}
+	p.stacks = make(map[string]bool)
+	for _, s := range stacks {
+		p.stacks[s.Name] = true
+	}
+
+	return nil
+}
+
+// checkApp records the buildpacks and stack of the app in the preflight report, and returns why the app cannot be
+// imported, if it cannot
+func (p *Preflight) checkApp(ctx *context.Context, app export.Application) []string {
+	if app.Docker.Image != "" {
+		return nil
+	}
+
+	var problems []string
+	stack := app.Stack
+	if target, ok := ctx.StackMappings[stack]; ok {
+		stack = target
+	}
+	if stack != "" {
+		check := report.Check{Kind: CheckStack, Name: app.Stack, Target: stack, Status: "ok"}
+		if !p.stacks[stack] {
+			check.Status = "missing"
+			check.Failed = ctx.ImportStrategy == "" || ctx.ImportStrategy == ImportDroplet
+			if !check.Failed {
+				check.Status = "missing, restaged on the default stack"
+				stack = ""
+			}
+		}
+		ctx.Preflight.Add(check)
+		if check.Failed {
+			problems = append(problems, fmt.Sprintf("stack %s %s", check.Target, check.Status))
+		}
+	}
+
+	targets := mapBuildpacks(ctx.BuildpackMappings, app.Buildpacks)
+	for idx, name := range app.Buildpacks {
+		check := report.Check{Kind: CheckBuildpack, Name: name, Target: targets[idx]}
+		check.Status, check.Failed = p.buildpackStatus(check.Target, stack)
+		ctx.Preflight.Add(check)
+		if check.Failed {
+			problems = append(problems, fmt.Sprintf("buildpack %s %s", check.Target, check.Status))
+		}
+	}
+
+	return problems
+}
+
+// checkServiceInstances records in the service plan report whether the target foundation has a service plan, visible
+// to the target org, for each exported managed service instance. It returns why the service instances without one
+// cannot be created, by org/space/service instance.
+func (p *Preflight) checkServiceInstances(ctx *context.Context) (map[string]string, error) {
+	files, err := fs.Glob(ctx.ExportFS(), path.Join("*", "*", export.ServiceInstancesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make(map[string]string)
+	c := cache.GetCache(ctx.ImportCFClient)
+	for _, f := range files {
+		org, space := path.Split(path.Dir(f))
+		org = path.Clean(org)
+		if isOrgExcluded(ctx, org) || !isOrgIncluded(ctx, org) {
+			continue
+		}
+
+		instances, err := export.ReadSpaceServiceInstances(ctx, org, space)
+		if err != nil {
+			ctx.Resources.AddFailed("service instances", org, space, "", err)
+			continue
+		}
+
+		targetOrg, orgErr := c.GetOrgByName(ctx.NameMapping.Org(org))
+		var existing map[string]bool
+		if orgErr == nil {
+			existing = p.targetServiceInstances(ctx, targetOrg.Guid, ctx.NameMapping.Space(org, space))
+		}
+		for _, si := range instances {
+			source := sourceServicePlan(si)
+			target := ctx.ServiceMappings.Map(source)
+			check := report.ServicePlanCheck{Org: org, Space: space, ServiceInstance: si.Name, Source: source.String(), Target: target.String()}
+			switch {
+			case orgErr != nil:
+				check.Status, check.Failed = "org missing", true
+			case existing[si.Name]:
+				check.Status = "exists in the target space"
+			default:
+				plan, status, err := findTargetServicePlan(ctx, targetOrg.Guid, target)
+				if err != nil {
+					status = fmt.Sprintf("error listing service plans, %s", err)
+				}
+				check.Status, check.Failed = status, plan == nil
+			}
+
+			if ctx.ServicePlanChecks != nil {
+				ctx.ServicePlanChecks.Add(check)
+			}
+			if check.Failed {
+				problems[path.Join(org, space, si.Name)] = fmt.Sprintf("service plan %s %s", check.Target, check.Status)
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// targetServiceInstances returns the names of the service instances of the target space, which are not created again,
+// none when the space does not exist yet
+func (p *Preflight) targetServiceInstances(ctx *context.Context, orgGUID, spaceName string) map[string]bool {
+	names := make(map[string]bool)
+	space, err := cache.GetCache(ctx.ImportCFClient).GetSpaceByName(spaceName, orgGUID)
+	if err != nil {
+		return names
+	}
+
+	var instances []cf.ServiceInstance
+	err = withRetry(ctx, func() (err error) {
+		instances, err = cf.ListSpaceServiceInstances(ctx.ImportCFClient, space.Guid)
+		return err
+	})
+	if err != nil {
+		ctx.Logger.Warnf("Could not list the service instances of space %s, they are all checked for a service plan: %s", spaceName, err)
+	}
+	for _, si := range instances {
+		names[si.Name] = true
+	}
+
+	return names
+}
+
+// addUsage adds the memory and instances of the app to what its org and space need
+func (p *Preflight) addUsage(org, space string, app export.Application) {
+	memory := 0
+	if app.Memory != "" {
+		memory = getSizeFromString(app.Memory)
+	}
+	if memory < 0 {
+		memory = 0
+	}
+
+	for _, key := range []string{org, path.Join(org, space)} {
+		u, ok := p.usage[key]
+		if !ok {
+			u = &quotaUsage{}
+			p.usage[key] = u
+		}
+		u.memory += memory * int(app.Instances)
+		u.instances += int(app.Instances)
+	}
+}
+
+// checkQuotas compares what the exported apps of every org and space need with the quotas of the target org and
+// space, it returns the checks to record for the apps of each org and org/space
+func (p *Preflight) checkQuotas(ctx *context.Context) map[string][]report.Check {
+	checks := make(map[string][]report.Check)
+	c := cache.GetCache(ctx.ImportCFClient)
+
+	for key, u := range p.usage {
+		org, space := path.Split(key)
+		if org == "" {
+			targetOrg := ctx.NameMapping.Org(space)
+			o, err := c.GetOrgByName(targetOrg)
+			if err != nil {
+				checks[key] = []report.Check{{Kind: CheckOrgQuota, Name: space, Target: targetOrg, Status: "org missing", Failed: true}}
+				continue
+			}
+			checks[key] = p.checkQuota(ctx, CheckOrgQuota, space, targetOrg, o.QuotaDefinitionGuid, cf.GetOrgQuota, u)
+			continue
+		}
+
+		org = path.Clean(org)
+		targetOrg := ctx.NameMapping.Org(org)
+		targetSpace := ctx.NameMapping.Space(org, space)
+		o, err := c.GetOrgByName(targetOrg)
+		if err != nil {
+			// reported with the org quota
+			continue
+		}
+		s, err := c.GetSpaceByName(targetSpace, o.Guid)
+		if err != nil {
+			checks[key] = []report.Check{{Kind: CheckSpaceQuota, Name: key, Target: path.Join(targetOrg, targetSpace), Status: "space missing", Failed: true}}
+			continue
+		}
+		checks[key] = p.checkQuota(ctx, CheckSpaceQuota, key, path.Join(targetOrg, targetSpace), s.QuotaDefinitionGuid, cf.GetSpaceQuota, u)
+	}
+
+	return checks
+}
+
+// checkQuota compares what the exported apps need with the quota with the given guid, orgs and spaces without a
+// quota are unlimited
+func (p *Preflight) checkQuota(
+	ctx *context.Context,
+	kind, name, target, guid string,
+	get func(cf.Client, string) (cf.Quota, error),
+	u *quotaUsage,
+) []report.Check {
+	check := report.Check{Kind: kind, Name: name, Target: target, Status: "ok"}
+	if guid == "" {
+		return []report.Check{check}
+	}
+
+	var q cf.Quota
+	err := withRetry(ctx, func() error {
+		var err error
+		q, err = get(ctx.ImportCFClient, guid)
+		return err
+	})
+	if err != nil {
+		check.Status, check.Failed = fmt.Sprintf("error reading quota, %s", err), true
+		return []report.Check{check}
+	}
+
+	var checks []report.Check
+	if limit := q.Apps.TotalMemoryInMB; limit != nil && *limit >= 0 && u.memory > *limit {
+		check.Status, check.Failed = fmt.Sprintf("needs %d MB of memory, quota %s allows %d MB", u.memory, q.Name, *limit), true
+		checks = append(checks, check)
+	}
+	if limit := q.Apps.TotalInstances; limit != nil && *limit >= 0 && u.instances > *limit {
+		check.Status, check.Failed = fmt.Sprintf("needs %d instances, quota %s allows %d", u.instances, q.Name, *limit), true
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 {
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// buildpackStatus tells whether the buildpack can be used to stage apps on the stack, any stack when stack is empty
+func (p *Preflight) buildpackStatus(name, stack string) (string, bool) {
+	if isCustomBuildpack(name) {
+		return "custom, must be allowed on the target", false
+	}
+
+	candidates := p.buildpacks[name]
+	if len(candidates) == 0 {
+		return "missing", true
+	}
+
+	status := "disabled"
+	for _, b := range candidates {
+		if !b.Enabled || b.State != cf.BuildpackReady {
+			continue
+		}
+		if stack == "" || b.Stack == "" || b.Stack == stack {
+			return "ok", false
+		}
+		status = fmt.Sprintf("not available for stack %s", stack)
+	}
+	return status, true
+}
+
+// readManifest reads the exported manifest of an app
+func readManifest(ctx *context.Context, manifestPath string) (export.Application, error) {
+	f, err := aio.OpenFS(ctx.ExportFS(), manifestPath, ctx.Opener)
+	if err != nil {
+		return export.Application{}, err
+	}
+	defer f.Close()
+
+	var manifest export.AppManifest
+	if err = yaml.NewDecoder(f).Decode(&manifest); err != nil {
+		return export.Application{}, fmt.Errorf("error reading %s: %w", manifestPath, err)
+	}
+	if len(manifest.Applications) == 0 {
+		return export.Application{}, fmt.Errorf("%s has no application", manifestPath)
+	}
+
+	return manifest.Applications[0], nil
+}
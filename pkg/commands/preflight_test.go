@@ -0,0 +1,327 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+)
+
+const (
+	preflightBuildpacks = `{
+  "pagination": {"next": {"href": "https://api.example.com/v3/buildpacks?order_by=position&page=2"}},
+  "resources": [
+    {"guid": "bp-1", "name": "java_buildpack", "stack": "cflinuxfs4", "state": "READY", "enabled": true},
+    {"guid": "bp-2", "name": "go_buildpack", "stack": "cflinuxfs3", "state": "READY", "enabled": true}
+  ]
+}`
+	preflightBuildpacksPage2 = `{
+  "pagination": {"next": null},
+  "resources": [
+    {"guid": "bp-3", "name": "ruby_buildpack", "stack": "cflinuxfs4", "state": "READY", "enabled": false}
+  ]
+}`
+	preflightStacks = `{
+  "pagination": {"next": null},
+  "resources": [
+    {"guid": "stack-1", "name": "cflinuxfs4"}
+  ]
+}`
+)
+
+func TestPreflight_Run(t *testing.T) {
+	tests := []struct {
+		name            string
+		manifests       map[string]string
+		ctx             context.Context
+		successfulCount int
+		failedCount     int
+		wantChecks      []report.Check
+	}{
+		{
+			name: "reports the missing buildpacks and stacks",
+			manifests: map[string]string{
+				"my_org/my_space/java_app_manifest.yml":   "applications:\n- name: java_app\n  buildpacks: [java_buildpack_offline]\n  stack: cflinuxfs3\n",
+				"my_org/my_space/go_app_manifest.yml":     "applications:\n- name: go_app\n  buildpacks: [go_buildpack]\n  stack: cflinuxfs4\n",
+				"my_org/my_space/ruby_app_manifest.yml":   "applications:\n- name: ruby_app\n  buildpacks: [ruby_buildpack, https://github.com/example/buildpack.git]\n",
+				"my_org/my_space/docker_app_manifest.yml": "applications:\n- name: docker_app\n  docker:\n    image: example/app\n  stack: cflinuxfs3\n",
+			},
+			ctx:             context.Context{ImportStrategy: ImportDroplet},
+			successfulCount: 1,
+			failedCount:     3,
+			wantChecks: []report.Check{
+				{Kind: CheckBuildpack, Name: "go_buildpack", Target: "go_buildpack", Status: "not available for stack cflinuxfs4", Failed: true, Apps: 1},
+				{Kind: CheckBuildpack, Name: "https://github.com/example/buildpack.git", Target: "https://github.com/example/buildpack.git", Status: "custom, must be allowed on the target", Apps: 1},
+				{Kind: CheckBuildpack, Name: "java_buildpack_offline", Target: "java_buildpack_offline", Status: "missing", Failed: true, Apps: 1},
+				{Kind: CheckBuildpack, Name: "ruby_buildpack", Target: "ruby_buildpack", Status: "disabled", Failed: true, Apps: 1},
+				{Kind: CheckOrgQuota, Name: "my_org", Target: "my_org", Status: "ok", Apps: 4},
+				{Kind: CheckSpaceQuota, Name: "my_org/my_space", Target: "my_org/my_space", Status: "ok", Apps: 4},
+				{Kind: CheckStack, Name: "cflinuxfs3", Target: "cflinuxfs3", Status: "missing", Failed: true, Apps: 1},
+				{Kind: CheckStack, Name: "cflinuxfs4", Target: "cflinuxfs4", Status: "ok", Apps: 1},
+			},
+		},
+		{
+			name: "applies the buildpack and stack mappings",
+			manifests: map[string]string{
+				"my_org/my_space/java_app_manifest.yml":  "applications:\n- name: java_app\n  buildpacks: [java_buildpack_offline]\n  stack: cflinuxfs3\n",
+				"my_org/my_space/other_app_manifest.yml": "applications:\n- name: other_app\n  buildpacks: [java_buildpack]\n  stack: cflinuxfs3\n",
+			},
+			ctx: context.Context{
+				ImportStrategy:    ImportDroplet,
+				StackMappings:     map[string]string{"cflinuxfs3": "cflinuxfs4"},
+				BuildpackMappings: map[string]string{"java_buildpack_offline": "java_buildpack"},
+			},
+			successfulCount: 2,
+			wantChecks: []report.Check{
+				{Kind: CheckBuildpack, Name: "java_buildpack", Target: "java_buildpack", Status: "ok", Apps: 1},
+				{Kind: CheckBuildpack, Name: "java_buildpack_offline", Target: "java_buildpack", Status: "ok", Apps: 1},
+				{Kind: CheckOrgQuota, Name: "my_org", Target: "my_org", Status: "ok", Apps: 2},
+				{Kind: CheckSpaceQuota, Name: "my_org/my_space", Target: "my_org/my_space", Status: "ok", Apps: 2},
+				{Kind: CheckStack, Name: "cflinuxfs3", Target: "cflinuxfs4", Status: "ok", Apps: 2},
+			},
+		},
+		{
+			name: "accepts missing stacks when the apps are restaged",
+			manifests: map[string]string{
+				"my_org/my_space/java_app_manifest.yml": "applications:\n- name: java_app\n  buildpacks: [java_buildpack]\n  stack: cflinuxfs3\n",
+			},
+			ctx:             context.Context{ImportStrategy: ImportAuto},
+			successfulCount: 1,
+			wantChecks: []report.Check{
+				{Kind: CheckBuildpack, Name: "java_buildpack", Target: "java_buildpack", Status: "ok", Apps: 1},
+				{Kind: CheckOrgQuota, Name: "my_org", Target: "my_org", Status: "ok", Apps: 1},
+				{Kind: CheckSpaceQuota, Name: "my_org/my_space", Target: "my_org/my_space", Status: "ok", Apps: 1},
+				{Kind: CheckStack, Name: "cflinuxfs3", Target: "cflinuxfs3", Status: "missing, restaged on the default stack", Apps: 1},
+			},
+		},
+		{
+			name: "skips the excluded orgs",
+			manifests: map[string]string{
+				"system/my_space/java_app_manifest.yml": "applications:\n- name: java_app\n  buildpacks: [java_buildpack_offline]\n",
+			},
+			ctx: context.Context{ImportStrategy: ImportDroplet, ExcludedOrgs: []string{"^system$"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+			exportDir := t.TempDir()
+			for name, content := range tt.manifests {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(exportDir, name)), 0700))
+				require.NoError(t, os.WriteFile(filepath.Join(exportDir, name), []byte(content), 0600))
+			}
+
+			client := &fakes.FakeClient{
+				GetStub: func(url string) ([]byte, error) {
+					switch url {
+					case "/v3/buildpacks?order_by=position":
+						return []byte(preflightBuildpacks), nil
+					case "/v3/buildpacks?order_by=position&page=2":
+						return []byte(preflightBuildpacksPage2), nil
+					case "/v3/stacks":
+						return []byte(preflightStacks), nil
+					}
+					return nil, errors.New("unexpected request " + url)
+				},
+			}
+
+			ctx := tt.ctx
+			ctx.ExportDir = exportDir
+			ctx.Logger = log.New()
+			ctx.ImportCFClient = client
+			ctx.Summary = report.NewSummary(&bytes.Buffer{})
+			ctx.Preflight = report.NewPreflight(&bytes.Buffer{})
+
+			p := &Preflight{}
+			require.NoError(t, p.Run(&ctx))
+			assert.Equal(t, tt.successfulCount, ctx.Summary.AppSuccessCount())
+			assert.Equal(t, tt.failedCount, ctx.Summary.AppFailureCount())
+			assert.Equal(t, tt.wantChecks, ctx.Preflight.Checks())
+		})
+	}
+}
+
+func TestPreflight_RunQuotas(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	exportDir := t.TempDir()
+	manifests := map[string]string{
+		"my_org/dev/big_app_manifest.yml":    "applications:\n- name: big_app\n  instances: 2\n  memory: 512M\n",
+		"my_org/test/small_app_manifest.yml": "applications:\n- name: small_app\n  instances: 1\n  memory: 256M\n",
+		"gone/dev/other_app_manifest.yml":    "applications:\n- name: other_app\n  instances: 1\n  memory: 1G\n",
+	}
+	for name, content := range manifests {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(exportDir, name)), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(exportDir, name), []byte(content), 0600))
+	}
+
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(url string) ([]byte, error) {
+			switch url {
+			case "/v3/buildpacks?order_by=position&page=2":
+				return []byte(preflightBuildpacksPage2), nil
+			case "/v3/buildpacks?order_by=position":
+				return []byte(preflightBuildpacks), nil
+			case "/v3/stacks":
+				return []byte(preflightStacks), nil
+			case "/v3/organization_quotas/org-quota":
+				return []byte(`{"guid": "org-quota", "name": "small", "apps": {"total_memory_in_mb": 1024, "total_instances": 10}}`), nil
+			case "/v3/space_quotas/space-quota":
+				return []byte(`{"guid": "space-quota", "name": "tiny", "apps": {"total_memory_in_mb": 512, "total_instances": null}}`), nil
+			}
+			return nil, errors.New("unexpected request " + url)
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			if name != "my_org" {
+				return cfclient.Org{}, errors.New("org not found")
+			}
+			return cfclient.Org{Guid: "org-guid", Name: name, QuotaDefinitionGuid: "org-quota"}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			space := cfclient.Space{Guid: name + "-guid", Name: name, OrganizationGuid: orgGUID}
+			if name == "dev" {
+				space.QuotaDefinitionGuid = "space-quota"
+			}
+			return space, nil
+		},
+	}
+
+	ctx := &context.Context{
+		ExportDir:      exportDir,
+		Logger:         log.New(),
+		ImportCFClient: client,
+		Summary:        report.NewSummary(&bytes.Buffer{}),
+		Preflight:      report.NewPreflight(&bytes.Buffer{}),
+	}
+
+	require.NoError(t, (&Preflight{}).Run(ctx))
+	assert.Equal(t, 0, ctx.Summary.AppSuccessCount())
+	assert.Equal(t, 3, ctx.Summary.AppFailureCount())
+	assert.Equal(t, []report.Check{
+		{Kind: CheckOrgQuota, Name: "gone", Target: "gone", Status: "org missing", Failed: true, Apps: 1},
+		{Kind: CheckOrgQuota, Name: "my_org", Target: "my_org", Status: "needs 1280 MB of memory, quota small allows 1024 MB", Failed: true, Apps: 2},
+		{Kind: CheckSpaceQuota, Name: "my_org/dev", Target: "my_org/dev", Status: "needs 1024 MB of memory, quota tiny allows 512 MB", Failed: true, Apps: 1},
+		{Kind: CheckSpaceQuota, Name: "my_org/test", Target: "my_org/test", Status: "ok", Apps: 1},
+	}, ctx.Preflight.Checks())
+}
+
+func TestPreflight_RunServiceInstances(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	exportDir := t.TempDir()
+	files := map[string]string{
+		"my_org/my_space/web_manifest.yml": "applications:\n- name: web\n  services: [my-db, my-cache]\n",
+		"my_org/my_space/api_manifest.yml": "applications:\n- name: api\n  services: [my-db, my-queue]\n",
+		"my_org/my_space/service_instances.json": `[
+  {"name": "my-cache", "offering": "p-redis", "plan": "shared", "broker": "redis-broker"},
+  {"name": "my-db", "offering": "p.mysql", "plan": "db-small", "broker": "mysql-broker"},
+  {"name": "my-queue", "offering": "p.rabbitmq", "plan": "single-node"}
+]`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(exportDir, name)), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(exportDir, name), []byte(content), 0600))
+	}
+
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(url string) ([]byte, error) {
+			switch url {
+			case "/v3/buildpacks?order_by=position&page=2":
+				return []byte(preflightBuildpacksPage2), nil
+			case "/v3/buildpacks?order_by=position":
+				return []byte(preflightBuildpacks), nil
+			case "/v3/stacks":
+				return []byte(preflightStacks), nil
+			case "/v3/service_instances?space_guids=my_space-guid":
+				return []byte(`{"pagination": {}, "resources": [{"guid": "queue-guid", "name": "my-queue", "type": "managed"}]}`), nil
+			case "/v3/service_plans?names=small&organization_guids=org-guid&service_offering_names=p.mysql":
+				return []byte(`{"pagination": {}, "resources": [{"guid": "small-guid", "name": "small", "available": true}]}`), nil
+			case "/v3/service_plans?names=shared&organization_guids=org-guid&service_offering_names=p-redis":
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/service_plans?names=shared&service_offering_names=p-redis":
+				return []byte(`{"pagination": {}, "resources": [{"guid": "shared-guid", "name": "shared", "available": true}]}`), nil
+			}
+			return nil, errors.New("unexpected request " + url)
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "org-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: name + "-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		ExportDir:         exportDir,
+		Logger:            log.New(),
+		ImportCFClient:    client,
+		ServiceMappings:   mapping.Services{{Source: mapping.ServicePlan{Offering: "p.mysql", Plan: "db-small"}, Target: mapping.ServicePlan{Plan: "small"}}},
+		Summary:           report.NewSummary(&bytes.Buffer{}),
+		Preflight:         report.NewPreflight(&bytes.Buffer{}),
+		ServicePlanChecks: report.NewServicePlanChecks(&bytes.Buffer{}),
+	}
+
+	require.NoError(t, (&Preflight{}).Run(ctx))
+	assert.Equal(t, 1, ctx.Summary.AppSuccessCount())
+	assert.Equal(t, 1, ctx.Summary.AppFailureCount())
+	assert.Equal(t, []report.ServicePlanCheck{
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-cache", Source: "p-redis/shared (redis-broker)", Target: "p-redis/shared", Status: "not visible to the target org", Failed: true},
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-db", Source: "p.mysql/db-small (mysql-broker)", Target: "p.mysql/small", Status: "ok"},
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-queue", Source: "p.rabbitmq/single-node", Target: "p.rabbitmq/single-node", Status: "exists in the target space"},
+	}, ctx.ServicePlanChecks.Checks())
+}
+
+func TestPreflight_RunListError(t *testing.T) {
+	client := &fakes.FakeClient{
+		GetStub: func(url string) ([]byte, error) {
+			return nil, errors.New("unavailable")
+		},
+	}
+	ctx := &context.Context{
+		ExportDir:      t.TempDir(),
+		ImportCFClient: client,
+		Summary:        report.NewSummary(&bytes.Buffer{}),
+		Preflight:      report.NewPreflight(&bytes.Buffer{}),
+	}
+
+	err := (&Preflight{}).Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error listing the buildpacks of the target foundation")
+}
@@ -0,0 +1,243 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+)
+
+// exportOrgRoles writes the roles of the users of the org on the source foundation to the export
+func exportOrgRoles(ctx *context.Context, org cfclient.Org) error {
+	roles, err := cf.ListOrgRoles(ctx.ExportCFClient, org.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the roles of org %s: %w", org.Name, err)
+	}
+
+	return export.WriteOrgRoles(ctx, org.Name, roles)
+}
+
+// exportSpaceRoles writes the roles of the users of the space on the source foundation to the export
+func exportSpaceRoles(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	roles, err := cf.ListSpaceRoles(ctx.ExportCFClient, space.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the roles of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return export.WriteSpaceRoles(ctx, org.Name, space.Name, roles)
+}
+
+// migrateOrgRoles gives the users their roles of the org on the source foundation in the org of the target foundation
+func migrateOrgRoles(ctx *context.Context, org cfclient.Org) error {
+	roles, err := cf.ListOrgRoles(ctx.ExportCFClient, org.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the roles of org %s: %w", org.Name, err)
+	}
+
+	return grantOrgRoles(ctx, ctx.NameMapping.Org(org.Name), roles)
+}
+
+// migrateSpaceRoles gives the users their roles of the space on the source foundation in the space of the target
+// foundation
+func migrateSpaceRoles(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	roles, err := cf.ListSpaceRoles(ctx.ExportCFClient, space.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the roles of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return grantSpaceRoles(ctx, ctx.NameMapping.Org(org.Name), ctx.NameMapping.Space(org.Name, space.Name), roles)
+}
+
+// importOrgRoles gives the users the exported roles of the org in the target org. Exports made before roles were
+// exported have none to import.
+func importOrgRoles(ctx *context.Context, sourceOrg string) error {
+	roles, err := export.ReadOrgRoles(ctx, sourceOrg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return grantOrgRoles(ctx, ctx.NameMapping.Org(sourceOrg), roles)
+}
+
+// importSpaceRoles gives the users the exported roles of the space in the target space. Exports made before roles
+// were exported have none to import.
+func importSpaceRoles(ctx *context.Context, sourceOrg, sourceSpace string) error {
+	roles, err := export.ReadSpaceRoles(ctx, sourceOrg, sourceSpace)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return grantSpaceRoles(ctx, ctx.NameMapping.Org(sourceOrg), ctx.NameMapping.Space(sourceOrg, sourceSpace), roles)
+}
+
+// grantOrgRoles gives the users the roles they do not have yet in the org of the target foundation. Users that do
+// not exist on the target foundation are added to the missing users report.
+func grantOrgRoles(ctx *context.Context, orgName string, roles []cf.UserRole) error {
+	org, err := cache.GetCache(ctx.ImportCFClient).GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+
+	existing, err := cf.ListOrgRoles(ctx.ImportCFClient, org.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the roles of org %s: %w", orgName, err)
+	}
+
+	var errs []string
+	for _, r := range roles {
+		r.Origin = mapOrigin(ctx, r.Origin)
+		if hasRole(existing, r) {
+			continue
+		}
+
+		ctx.Logger.Infof("Giving %s of %s the role %s in org %s", r.Username, r.Origin, r.Type, orgName)
+		err := withRetry(ctx, func() error {
+			guid, err := cf.CreateOrgRole(ctx.ImportCFClient, org.Guid, r)
+			if err != nil {
+				return err
+			}
+			return recordResource(ctx, journal.Entry{Action: journal.CreateRole, Org: orgName, Name: roleName(r), GUID: guid}, nil)
+		})
+		switch {
+		case cf.IsUserNotFound(err):
+			ctx.MissingUsers.Add(r.Username, r.Origin, fmt.Sprintf("%s in %s", r.Type, orgName))
+		case err != nil:
+			errs = append(errs, err.Error())
+		default:
+			existing = append(existing, r)
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// grantSpaceRoles gives the users the roles they do not have yet in the space of the target foundation. Users need
+// a role in the org of the space, those who have none are made org users first. Users that do not exist on the target
+// foundation are added to the missing users report.
+func grantSpaceRoles(ctx *context.Context, orgName, spaceName string, roles []cf.UserRole) error {
+	c := cache.GetCache(ctx.ImportCFClient)
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+	space, err := c.GetSpaceByName(spaceName, org.Guid)
+	if err != nil {
+		return err
+	}
+
+	orgRoles, err := cf.ListOrgRoles(ctx.ImportCFClient, org.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the roles of org %s: %w", orgName, err)
+	}
+	existing, err := cf.ListSpaceRoles(ctx.ImportCFClient, space.Guid)
+	if err != nil {
+		return fmt.Errorf("error listing the roles of space %s/%s: %w", orgName, spaceName, err)
+	}
+
+	var errs []string
+	for _, r := range roles {
+		r.Origin = mapOrigin(ctx, r.Origin)
+		if hasRole(existing, r) {
+			continue
+		}
+
+		ctx.Logger.Infof("Giving %s of %s the role %s in space %s/%s", r.Username, r.Origin, r.Type, orgName, spaceName)
+		err := withRetry(ctx, func() error {
+			if !hasUser(orgRoles, r) {
+				orgUser := cf.UserRole{Type: cf.OrgUser, Username: r.Username, Origin: r.Origin}
+				guid, err := cf.CreateOrgRole(ctx.ImportCFClient, org.Guid, orgUser)
+				if err != nil {
+					return err
+				}
+				orgRoles = append(orgRoles, orgUser)
+				if err = recordResource(ctx, journal.Entry{Action: journal.CreateRole, Org: orgName, Name: roleName(orgUser), GUID: guid}, nil); err != nil {
+					return err
+				}
+			}
+			guid, err := cf.CreateSpaceRole(ctx.ImportCFClient, space.Guid, r)
+			if err != nil {
+				return err
+			}
+			return recordResource(ctx, journal.Entry{Action: journal.CreateRole, Org: orgName, Space: spaceName, Name: roleName(r), GUID: guid}, nil)
+		})
+		switch {
+		case cf.IsUserNotFound(err):
+			ctx.MissingUsers.Add(r.Username, r.Origin, fmt.Sprintf("%s in %s", r.Type, path.Join(orgName, spaceName)))
+		case err != nil:
+			errs = append(errs, err.Error())
+		default:
+			existing = append(existing, r)
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// roleName names the role of the user in the journal
+func roleName(r cf.UserRole) string {
+	return fmt.Sprintf("%s of %s (%s)", r.Type, r.Username, r.Origin)
+}
+
+// mapOrigin returns the identity provider origin of the target foundation for an origin of the source foundation
+func mapOrigin(ctx *context.Context, origin string) string {
+	if target, ok := ctx.OriginMappings[origin]; ok {
+		return target
+	}
+	return origin
+}
+
+// hasRole tells whether the user already has the role
+func hasRole(roles []cf.UserRole, role cf.UserRole) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUser tells whether the user has any of the roles
+func hasUser(roles []cf.UserRole, role cf.UserRole) bool {
+	for _, r := range roles {
+		if r.Username == role.Username && r.Origin == role.Origin {
+			return true
+		}
+	}
+	return false
+}
+
+// joinErrors returns the errors as a single one, or nil when there are none
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, ", "))
+}
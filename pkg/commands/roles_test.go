@@ -0,0 +1,144 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestExportSpaceRoles(t *testing.T) {
+	client := &fakes.FakeClient{
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/roles?" + url.Values{"space_guids": []string{"space-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "role-1", "type": "space_developer", "relationships": {"user": {"data": {"guid": "jane-guid"}}, "space": {"data": {"guid": "space-guid"}}}},
+  {"guid": "role-2", "type": "space_manager", "relationships": {"user": {"data": {"guid": "jane-guid"}}, "space": {"data": {"guid": "space-guid"}}}},
+  {"guid": "role-3", "type": "space_auditor", "relationships": {"user": {"data": {"guid": "client-guid"}}, "space": {"data": {"guid": "space-guid"}}}}
+]}`), nil
+			case "/v3/users?" + url.Values{"guids": []string{"jane-guid,client-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "jane-guid", "username": "jane", "origin": "ldap"},
+  {"guid": "client-guid", "username": null, "origin": null}
+]}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{DirWriter: storage.NewMemory(), ExportCFClient: client}
+
+	err := exportSpaceRoles(ctx, cfclient.Org{Name: "my_org"}, cfclient.Space{Guid: "space-guid", Name: "my_space"})
+	require.NoError(t, err)
+
+	roles, err := export.ReadSpaceRoles(ctx, "my_org", "my_space")
+	require.NoError(t, err)
+	assert.Equal(t, []cf.UserRole{
+		{Type: cf.SpaceDeveloper, Username: "jane", Origin: "ldap"},
+		{Type: cf.SpaceManager, Username: "jane", Origin: "ldap"},
+	}, roles)
+}
+
+func TestImportSpaceRoles(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var requests []string
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "org-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch {
+			case strings.HasPrefix(path, "/v3/roles?organization_guids=org-guid"):
+				return []byte(`{"pagination": {}, "resources": [
+  {"type": "organization_user", "relationships": {"user": {"data": {"guid": "jane-guid"}}}}
+]}`), nil
+			case strings.HasPrefix(path, "/v3/roles?space_guids=space-guid"):
+				return []byte(`{"pagination": {}, "resources": [
+  {"type": "space_manager", "relationships": {"user": {"data": {"guid": "jane-guid"}}}}
+]}`), nil
+			case strings.HasPrefix(path, "/v3/users?"):
+				return []byte(`{"pagination": {}, "resources": [{"guid": "jane-guid", "username": "jane", "origin": "okta"}]}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			requests = append(requests, method+" "+path+" "+string(data))
+			return &cfclient.Request{}
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			if strings.Contains(requests[len(requests)-1], `"username":"ghost"`) {
+				return nil, cfclient.CloudFoundryError{Code: 10008, Description: "No user exists with the username 'ghost' and origin 'okta'."}
+			}
+			return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ImportCFClient: client,
+		OriginMappings: map[string]string{"ldap": "okta"},
+		MissingUsers:   report.NewMissingUsers(&bytes.Buffer{}),
+	}
+	require.NoError(t, export.WriteSpaceRoles(ctx, "my_org", "my_space", []cf.UserRole{
+		{Type: cf.SpaceManager, Username: "jane", Origin: "ldap"},
+		{Type: cf.SpaceDeveloper, Username: "jane", Origin: "ldap"},
+		{Type: cf.SpaceAuditor, Username: "bob", Origin: "uaa"},
+		{Type: cf.SpaceDeveloper, Username: "ghost", Origin: "ldap"},
+	}))
+
+	require.NoError(t, importSpaceRoles(ctx, "my_org", "my_space"))
+	assert.Equal(t, []string{
+		`POST /v3/roles {"type":"space_developer","relationships":{"user":{"data":{"username":"jane","origin":"okta"}},"space":{"data":{"guid":"space-guid"}}}}`,
+		`POST /v3/roles {"type":"organization_user","relationships":{"user":{"data":{"username":"bob","origin":"uaa"}},"organization":{"data":{"guid":"org-guid"}}}}`,
+		`POST /v3/roles {"type":"space_auditor","relationships":{"user":{"data":{"username":"bob","origin":"uaa"}},"space":{"data":{"guid":"space-guid"}}}}`,
+		`POST /v3/roles {"type":"organization_user","relationships":{"user":{"data":{"username":"ghost","origin":"okta"}},"organization":{"data":{"guid":"org-guid"}}}}`,
+	}, requests)
+	assert.Equal(t, []report.MissingUser{
+		{Username: "ghost", Origin: "okta", Roles: []string{"space_developer in my_org/my_space"}},
+	}, ctx.MissingUsers.Users())
+}
+
+func TestImportOrgRolesWithoutRolesFile(t *testing.T) {
+	ctx := &context.Context{DirWriter: storage.NewMemory(), ImportCFClient: &fakes.FakeClient{}}
+	require.NoError(t, importOrgRoles(ctx, "my_org"))
+}
@@ -0,0 +1,518 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+type Rollback struct {
+	RunID string
+}
+
+// errNothingToUndo is returned by undo when an action left nothing to undo, e.g. the resource was already deleted
+var errNothingToUndo = errors.New("nothing to undo")
+
+// rollbackOutcome tallies the undos of the actions taken on an app or on another resource
+type rollbackOutcome struct {
+	undone int
+	err    error
+}
+
+// Run replays the journal of an import run in reverse, undoing every action it recorded. Apps with an action that
+// could not be undone are reported as failed, and apps none of whose actions had anything left to undo as skipped.
+// The other resources, e.g. domains or quotas, are reported the same way in the report of the resources.
+func (r *Rollback) Run(ctx *context.Context) error {
+	if r.RunID == "" {
+		return errors.New("the id of the run to roll back is required")
+	}
+
+	opener, err := journalOpener(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries, err := journal.Load(storage.LocalDir(ctx.ExportDir), r.RunID, opener)
+	if err != nil {
+		return err
+	}
+
+	createdApps := make(map[string]bool)
+	for _, e := range entries {
+		if e.Action == journal.CreateApp {
+			createdApps[e.GUID] = true
+		}
+	}
+
+	var rolledBack []journal.Entry
+	outcomes := make(map[string]*rollbackOutcome)
+	for idx := len(entries) - 1; idx >= 0; idx-- {
+		e := entries[idx]
+		key := rollbackKey(e)
+		outcome, seen := outcomes[key]
+		if !seen {
+			outcome = &rollbackOutcome{}
+			outcomes[key] = outcome
+			rolledBack = append(rolledBack, e)
+		}
+
+		// deleting an app also removes its route mappings, service bindings, droplets and autoscaler settings
+		if e.App != "" && createdApps[e.AppGUID] && removedWithApp(e.Action) {
+			ctx.Logger.Debugf("Skipping %s for %s, it will be deleted", e.Action, key)
+			continue
+		}
+
+		ctx.Logger.Infof("Rolling back %s %s for %s", e.Action, e.GUID, key)
+		err = r.undo(ctx, e)
+		switch {
+		case errors.Is(err, errNothingToUndo):
+			ctx.Logger.Infof("Nothing to roll back for %s %s of %s", e.Action, e.GUID, key)
+		case err != nil:
+			ctx.Logger.Errorf("Error rolling back %s %s for %s: %s", e.Action, e.GUID, key, err)
+			if outcome.err == nil {
+				outcome.err = fmt.Errorf("%s: %w", e.Action, err)
+			}
+		default:
+			outcome.undone++
+		}
+	}
+
+	for _, e := range rolledBack {
+		outcome := outcomes[rollbackKey(e)]
+		if e.App == "" {
+			kind := resourceKind(e.Action)
+			switch {
+			case outcome.err != nil:
+				ctx.Resources.AddFailed(kind, e.Org, e.Space, e.Name, outcome.err)
+			case outcome.undone == 0:
+				ctx.Resources.AddSkipped(kind, e.Org, e.Space, e.Name, "nothing to undo")
+			default:
+				ctx.Resources.AddSucceeded(kind, e.Org, e.Space, e.Name)
+			}
+			continue
+		}
+
+		switch {
+		case outcome.err != nil:
+			ctx.Summary.AddFailedApp(e.Org, e.Space, e.App, outcome.err)
+		case outcome.undone == 0:
+			ctx.Summary.AddSkippedApp(e.Org, e.Space, e.App, "nothing to undo")
+		default:
+			ctx.Summary.AddSuccessfulApp(e.Org, e.Space, e.App)
+		}
+	}
+
+	return nil
+}
+
+// rollbackKey identifies the app or other resource the actions of which are rolled back and reported together
+func rollbackKey(e journal.Entry) string {
+	if e.App == "" {
+		return fmt.Sprintf("%s %s", resourceKind(e.Action), strings.Join([]string{e.Org, e.Space, e.Name}, "/"))
+	}
+	return fmt.Sprintf("app %s", strings.Join([]string{e.Org, e.Space, e.App}, "/"))
+}
+
+// resourceKind is what the resource of an action that is not taken on an app is, in the rollback report
+func resourceKind(action journal.Action) string {
+	switch action {
+	case journal.CreateServiceInstance:
+		return "service instance"
+	case journal.CreateServiceKey:
+		return "service key"
+	case journal.CreateDomain, journal.ShareDomain:
+		return "domain"
+	case journal.CreateSecurityGroup, journal.UpdateSecurityGroup, journal.BindRunningSecurityGroup, journal.BindStagingSecurityGroup:
+		return "security group"
+	case journal.CreateOrgQuota, journal.UpdateOrgQuota, journal.ApplyOrgQuota:
+		return "org quota"
+	case journal.CreateSpaceQuota, journal.UpdateSpaceQuota, journal.ApplySpaceQuota:
+		return "space quota"
+	case journal.CreateRole:
+		return "role"
+	case journal.CreateNetworkPolicy:
+		return "network policy"
+	case journal.EntitleIsolationSegment, journal.SetOrgIsolationSegment, journal.SetSpaceIsolationSegment:
+		return "isolation segment"
+	case journal.CreateBuildpack, journal.UpdateBuildpack, journal.UploadBuildpack:
+		return "buildpack"
+	}
+	return string(action)
+}
+
+// journalOpener opens the settings sealed in the journal, with the identity or key of the export or the key of the
+// secrets file, as they were sealed by the import
+func journalOpener(ctx *context.Context) (crypt.Opener, error) {
+	if ctx.Opener != nil {
+		return ctx.Opener, nil
+	}
+
+	key, err := crypt.NewKey(ctx.SecretsKeyFile)
+	if err != nil || key == nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func removedWithApp(action journal.Action) bool {
+	switch action {
+	case journal.CreateApp, journal.CreateRoute, journal.CreateRouteBinding:
+		return false
+	}
+	return true
+}
+
+func (r *Rollback) undo(ctx *context.Context, e journal.Entry) error {
+	var err error
+	switch e.Action {
+	case journal.CreateApp:
+		err = withRetry(ctx, func() error {
+			return ctx.ImportCFClient.DeleteApp(e.GUID)
+		})
+	case journal.UpdateApp:
+		var previous cfclient.AppUpdateResource
+		if err = json.Unmarshal(e.Previous, &previous); err != nil {
+			return err
+		}
+		err = withRetry(ctx, func() error {
+			_, err := ctx.ImportCFClient.UpdateApp(e.GUID, previous)
+			return err
+		})
+	case journal.UpdateAppLifecycle:
+		var previous cfclient.UpdateV3AppRequest
+		if err = json.Unmarshal(e.Previous, &previous); err != nil {
+			return err
+		}
+		err = withRetry(ctx, func() error {
+			_, err := ctx.ImportCFClient.UpdateV3App(e.GUID, previous)
+			return err
+		})
+	case journal.CreateRoute:
+		err = withRetry(ctx, func() error {
+			return ctx.ImportCFClient.DeleteRoute(e.GUID)
+		})
+	case journal.BindRoute:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v2/routes/%s/apps/%s", e.GUID, e.AppGUID), nil)
+	case journal.CreateServiceBinding:
+		err = withRetry(ctx, func() error {
+			return ctx.ImportCFClient.DeleteServiceBinding(e.GUID)
+		})
+	case journal.CreateRouteBinding:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/service_route_bindings/%s", e.GUID), nil)
+	case journal.UploadDroplet:
+		var previousGUID string
+		if err = json.Unmarshal(e.Previous, &previousGUID); err != nil {
+			return err
+		}
+		if previousGUID == "" {
+			ctx.Logger.Infof("App %s/%s/%s had no droplet before the import, nothing to restore", e.Org, e.Space, e.App)
+			return errNothingToUndo
+		}
+		body := map[string]interface{}{"data": map[string]string{"guid": previousGUID}}
+		err = r.request(ctx, http.MethodPatch, fmt.Sprintf("/v3/apps/%s/relationships/current_droplet", e.AppGUID), body)
+	case journal.UpdateAutoscalerRules:
+		err = r.restoreAutoscaler(ctx, e, "/rules", []byte("[]"))
+	case journal.UpdateAutoscalerInstances:
+		err = r.restoreAutoscaler(ctx, e, "", nil)
+	case journal.UpdateAutoscalerSchedules:
+		err = r.restoreAutoscaler(ctx, e, "/scheduled_limit_changes", []byte("[]"))
+	case journal.CreateServiceInstance:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/service_instances/%s", e.GUID), nil)
+	case journal.CreateServiceKey:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/service_credential_bindings/%s", e.GUID), nil)
+	case journal.CreateDomain:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/domains/%s", e.GUID), nil)
+	case journal.ShareDomain:
+		var orgGUIDs []string
+		if err = json.Unmarshal(e.Previous, &orgGUIDs); err != nil {
+			return err
+		}
+		// the domain is unshared from the orgs the import shared it with, an org already gone has nothing to undo
+		unshared := false
+		for _, orgGUID := range orgGUIDs {
+			err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/domains/%s/relationships/shared_organizations/%s", e.GUID, orgGUID), nil)
+			if isGone(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			unshared = true
+		}
+		if !unshared {
+			return errNothingToUndo
+		}
+	case journal.CreateSecurityGroup:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/security_groups/%s", e.GUID), nil)
+	case journal.UpdateSecurityGroup:
+		var rules []cf.SecurityGroupRule
+		if err = json.Unmarshal(e.Previous, &rules); err != nil {
+			return err
+		}
+		if rules == nil {
+			rules = []cf.SecurityGroupRule{}
+		}
+		err = withRetry(ctx, func() error {
+			return cf.UpdateSecurityGroupRules(ctx.ImportCFClient, e.GUID, rules)
+		})
+	case journal.BindRunningSecurityGroup:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/security_groups/%s/relationships/running_spaces/%s", e.GUID, e.RelatedGUID), nil)
+	case journal.BindStagingSecurityGroup:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/security_groups/%s/relationships/staging_spaces/%s", e.GUID, e.RelatedGUID), nil)
+	case journal.CreateOrgQuota:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/organization_quotas/%s", e.GUID), nil)
+	case journal.UpdateOrgQuota, journal.UpdateSpaceQuota:
+		var previous cf.Quota
+		if err = json.Unmarshal(e.Previous, &previous); err != nil {
+			return err
+		}
+		update := cf.UpdateOrgQuota
+		if e.Action == journal.UpdateSpaceQuota {
+			update = cf.UpdateSpaceQuota
+		}
+		err = withRetry(ctx, func() error {
+			_, err := update(ctx.ImportCFClient, e.GUID, previous)
+			return err
+		})
+	case journal.ApplyOrgQuota:
+		// every org has a quota, the one it had is assigned to it again
+		var previousGUID string
+		if err = json.Unmarshal(e.Previous, &previousGUID); err != nil {
+			return err
+		}
+		if previousGUID == "" {
+			return fmt.Errorf("the quota org %s had before the import is not known", e.RelatedGUID)
+		}
+		err = withRetry(ctx, func() error {
+			return cf.ApplyOrgQuota(ctx.ImportCFClient, previousGUID, []string{e.RelatedGUID})
+		})
+	case journal.CreateSpaceQuota:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/space_quotas/%s", e.GUID), nil)
+	case journal.ApplySpaceQuota:
+		var previousGUID string
+		if err = json.Unmarshal(e.Previous, &previousGUID); err != nil {
+			return err
+		}
+		if previousGUID == "" {
+			err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/space_quotas/%s/relationships/spaces/%s", e.GUID, e.RelatedGUID), nil)
+			break
+		}
+		err = withRetry(ctx, func() error {
+			return cf.ApplySpaceQuota(ctx.ImportCFClient, previousGUID, []string{e.RelatedGUID})
+		})
+	case journal.CreateRole:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/roles/%s", e.GUID), nil)
+	case journal.CreateNetworkPolicy:
+		var policy cf.NetworkPolicy
+		if err = json.Unmarshal(e.Previous, &policy); err != nil {
+			return err
+		}
+		err = r.request(ctx, http.MethodPost, "/networking/v1/external/policies/delete", map[string][]cf.NetworkPolicy{"policies": {policy}})
+	case journal.EntitleIsolationSegment:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/isolation_segments/%s/relationships/organizations/%s", e.GUID, e.RelatedGUID), nil)
+	case journal.SetOrgIsolationSegment:
+		err = r.restoreIsolationSegment(ctx, e, fmt.Sprintf("/v3/organizations/%s/relationships/default_isolation_segment", e.RelatedGUID))
+	case journal.SetSpaceIsolationSegment:
+		err = r.restoreIsolationSegment(ctx, e, fmt.Sprintf("/v3/spaces/%s/relationships/isolation_segment", e.RelatedGUID))
+	case journal.CreateBuildpack:
+		err = r.request(ctx, http.MethodDelete, fmt.Sprintf("/v3/buildpacks/%s", e.GUID), nil)
+	case journal.UpdateBuildpack:
+		var previous cf.BuildpackRequest
+		if err = json.Unmarshal(e.Previous, &previous); err != nil {
+			return err
+		}
+		err = withRetry(ctx, func() error {
+			_, err := cf.UpdateBuildpack(ctx.ImportCFClient, e.GUID, previous)
+			return err
+		})
+	case journal.UploadBuildpack:
+		return fmt.Errorf("the bits buildpack %s had before the import were not kept, they cannot be restored", e.Name)
+	default:
+		return fmt.Errorf("unknown journal action %q", e.Action)
+	}
+
+	if isGone(err) {
+		return errNothingToUndo
+	}
+
+	return err
+}
+
+// request sends a request undoing an action, a request accepted by the cloud controller, e.g. the deletion of a service
+// instance, is only done once the job it returned is complete
+func (r *Rollback) request(ctx *context.Context, method, path string, body interface{}) error {
+	var location string
+	err := withRetry(ctx, func() error {
+		var req *cfclient.Request
+		if body != nil {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return err
+			}
+			req = ctx.ImportCFClient.NewRequestWithBody(method, path, bytes.NewReader(data))
+		} else {
+			req = ctx.ImportCFClient.NewRequest(method, path)
+		}
+
+		resp, err := ctx.ImportCFClient.DoRequest(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		location = ""
+		if resp.StatusCode == http.StatusAccepted {
+			location = resp.Header.Get("Location")
+		}
+		return nil
+	})
+	if err != nil || location == "" {
+		return err
+	}
+
+	return waitForJob(ctx, location, fmt.Sprintf("%s %s", method, path))
+}
+
+// waitForJob waits for the asynchronous job at location to complete, and fails when the job fails
+func waitForJob(ctx *context.Context, location, what string) error {
+	return waitUntil(30*time.Minute, what, func() (bool, error) {
+		var job cf.Job
+		err := withRetry(ctx, func() (err error) {
+			job, err = cf.GetJob(ctx.ImportCFClient, location)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		switch job.State {
+		case cf.JobComplete:
+			return true, nil
+		case cf.JobFailed:
+			return false, fmt.Errorf("%s failed: %s", what, job.Error())
+		}
+		return false, nil
+	})
+}
+
+// restoreIsolationSegment assigns the isolation segment the org or space had before the import again, or unassigns
+// the segment when it had none
+func (r *Rollback) restoreIsolationSegment(ctx *context.Context, e journal.Entry, path string) error {
+	var previousGUID string
+	if err := json.Unmarshal(e.Previous, &previousGUID); err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{"data": nil}
+	if previousGUID != "" {
+		body["data"] = map[string]string{"guid": previousGUID}
+	}
+	return r.request(ctx, http.MethodPatch, path, body)
+}
+
+// restoreAutoscaler puts back the autoscaler settings an app had before the import, or empty if there were none
+func (r *Rollback) restoreAutoscaler(ctx *context.Context, e journal.Entry, path string, empty []byte) error {
+	previous := []byte(e.Previous)
+	if len(previous) == 0 || string(previous) == "null" {
+		if empty == nil {
+			ctx.Logger.Infof("App %s/%s/%s had no autoscaler settings before the import, nothing to restore", e.Org, e.Space, e.App)
+			return errNothingToUndo
+		}
+		previous = empty
+	}
+
+	autoscalerBase := strings.Replace(ctx.ImportCFClient.Target(), "/api.", "/autoscale.", 1)
+	autoscalerURL := fmt.Sprintf("%s/api/v2/apps/%s%s", autoscalerBase, e.AppGUID, path)
+
+	return withRetry(ctx, func() error {
+		req, err := http.NewRequest(http.MethodPut, autoscalerURL, bytes.NewReader(previous))
+		if err != nil {
+			return err
+		}
+
+		resp, err := ctx.ImportCFClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 500 && resp.StatusCode <= 599 {
+			return cf.ErrRetry
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("expected an HTTP 2xx code, got %d instead", resp.StatusCode)
+		}
+
+		return nil
+	})
+}
+
+// withRetry retries f when the cloud controller of the target foundation responds with a server error
+func withRetry(ctx *context.Context, f func() error) error {
+	return retryServerErrors(ctx.ImportCFClient, f)
+}
+
+// withSourceRetry retries f when the cloud controller of the source foundation responds with a server error
+func withSourceRetry(ctx *context.Context, f func() error) error {
+	return retryServerErrors(ctx.ExportCFClient, f)
+}
+
+// retryServerErrors retries f with the retries of c when the cloud controller responds with a server error
+func retryServerErrors(c cf.Client, f func() error) error {
+	return c.DoWithRetry(func() error {
+		err := f()
+		if err != nil {
+			cfErr := cfclient.CloudFoundryHTTPError{}
+			if errors.As(err, &cfErr) {
+				if cfErr.StatusCode >= 500 && cfErr.StatusCode <= 599 {
+					return cf.ErrRetry
+				}
+			}
+		}
+
+		return err
+	})
+}
+
+// isGone reports whether err is caused by the resource having already been deleted
+func isGone(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if cfclient.IsAppNotFoundError(err) ||
+		cfclient.IsRouteNotFoundError(err) ||
+		cfclient.IsServiceBindingNotFoundError(err) ||
+		cfclient.IsResourceNotFoundError(err) ||
+		cfclient.IsNotFoundError(err) {
+		return true
+	}
+
+	cfErr := cfclient.CloudFoundryHTTPError{}
+	return errors.As(err, &cfErr) && cfErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,358 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+)
+
+func TestRollback_Run(t *testing.T) {
+	previous, err := json.Marshal(cfclient.AppUpdateResource{Name: "existing_app", Memory: 512})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		entries         []journal.Entry
+		client          *fakes.FakeClient
+		wantErr         bool
+		successfulCount int
+		failedCount     int
+		skippedCount    int
+		afterFunc       func(*testing.T, *fakes.FakeClient)
+	}{
+		{
+			name: "deletes created apps and routes and skips what is removed with the app",
+			entries: []journal.Entry{
+				{Action: journal.CreateApp, Org: "my_org", Space: "my_space", App: "my_app", GUID: "app-guid", AppGUID: "app-guid"},
+				{Action: journal.CreateRoute, Org: "my_org", Space: "my_space", App: "my_app", GUID: "route-guid", AppGUID: "app-guid"},
+				{Action: journal.BindRoute, Org: "my_org", Space: "my_space", App: "my_app", GUID: "route-guid", AppGUID: "app-guid"},
+				{Action: journal.CreateServiceBinding, Org: "my_org", Space: "my_space", App: "my_app", GUID: "binding-guid", AppGUID: "app-guid"},
+			},
+			client:          &fakes.FakeClient{},
+			successfulCount: 1,
+			afterFunc: func(t *testing.T, client *fakes.FakeClient) {
+				require.Equal(t, 1, client.DeleteAppCallCount())
+				assert.Equal(t, "app-guid", client.DeleteAppArgsForCall(0))
+				require.Equal(t, 1, client.DeleteRouteCallCount())
+				assert.Equal(t, "route-guid", client.DeleteRouteArgsForCall(0))
+				assert.Equal(t, 0, client.DeleteServiceBindingCallCount())
+				assert.Equal(t, 0, client.DoRequestCallCount())
+			},
+		},
+		{
+			name: "restores updated apps and removes their bindings",
+			entries: []journal.Entry{
+				{Action: journal.UpdateApp, Org: "my_org", Space: "my_space", App: "existing_app", GUID: "existing-guid", AppGUID: "existing-guid", Previous: previous},
+				{Action: journal.CreateServiceBinding, Org: "my_org", Space: "my_space", App: "existing_app", GUID: "binding-guid", AppGUID: "existing-guid"},
+			},
+			client:          &fakes.FakeClient{},
+			successfulCount: 1,
+			afterFunc: func(t *testing.T, client *fakes.FakeClient) {
+				require.Equal(t, 1, client.DeleteServiceBindingCallCount())
+				assert.Equal(t, "binding-guid", client.DeleteServiceBindingArgsForCall(0))
+				require.Equal(t, 1, client.UpdateAppCallCount())
+				guid, req := client.UpdateAppArgsForCall(0)
+				assert.Equal(t, "existing-guid", guid)
+				assert.Equal(t, 512, req.Memory)
+			},
+		},
+		{
+			name: "unbinds route services from routes before deleting them",
+			entries: []journal.Entry{
+				{Action: journal.CreateApp, Org: "my_org", Space: "my_space", App: "my_app", GUID: "app-guid", AppGUID: "app-guid"},
+				{Action: journal.CreateRoute, Org: "my_org", Space: "my_space", App: "my_app", GUID: "route-guid", AppGUID: "app-guid"},
+				{Action: journal.CreateRouteBinding, Org: "my_org", Space: "my_space", App: "my_app", GUID: "route-binding-guid", AppGUID: "app-guid"},
+			},
+			client: &fakes.FakeClient{
+				DoRequestStub: func(*cfclient.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader(""))}, nil
+				},
+			},
+			successfulCount: 1,
+			afterFunc: func(t *testing.T, client *fakes.FakeClient) {
+				require.Equal(t, 1, client.NewRequestCallCount())
+				method, path := client.NewRequestArgsForCall(0)
+				assert.Equal(t, "DELETE /v3/service_route_bindings/route-binding-guid", method+" "+path)
+				assert.Equal(t, 1, client.DeleteRouteCallCount())
+				assert.Equal(t, 1, client.DeleteAppCallCount())
+			},
+		},
+		{
+			name: "ignores resources that are already gone",
+			entries: []journal.Entry{
+				{Action: journal.CreateApp, Org: "my_org", Space: "my_space", App: "my_app", GUID: "app-guid", AppGUID: "app-guid"},
+			},
+			client: &fakes.FakeClient{
+				DeleteAppStub: func(string) error {
+					return cfclient.CloudFoundryError{Code: 100004, ErrorCode: "CF-AppNotFound"}
+				},
+			},
+			skippedCount: 1,
+		},
+		{
+			name: "reports apps that could not be rolled back",
+			entries: []journal.Entry{
+				{Action: journal.CreateApp, Org: "my_org", Space: "my_space", App: "my_app", GUID: "app-guid", AppGUID: "app-guid"},
+			},
+			client: &fakes.FakeClient{
+				DeleteAppStub: func(string) error {
+					return errors.New("boom")
+				},
+			},
+			failedCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			j := journal.New(dir, nil)
+			for _, e := range tt.entries {
+				require.NoError(t, j.Record(e))
+			}
+			require.NoError(t, j.Close())
+
+			tt.client.DoWithRetryStub = func(f func() error) error {
+				return f()
+			}
+			ctx := &context.Context{
+				Logger:         log.New(),
+				ExportDir:      dir,
+				Summary:        report.NewSummary(&bytes.Buffer{}),
+				ImportCFClient: tt.client,
+			}
+
+			r := &Rollback{RunID: j.RunID}
+			if err := r.Run(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.successfulCount, ctx.Summary.AppSuccessCount())
+			assert.Equal(t, tt.failedCount, ctx.Summary.AppFailureCount())
+			assert.Equal(t, tt.skippedCount, ctx.Summary.AppSkippedCount())
+			if tt.afterFunc != nil {
+				tt.afterFunc(t, tt.client)
+			}
+		})
+	}
+}
+
+func TestRollback_RunResources(t *testing.T) {
+	shared, err := json.Marshal([]string{"org-guid"})
+	require.NoError(t, err)
+	noSegment, err := json.Marshal("")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		entries       []journal.Entry
+		jobState      string
+		wantRequests  []string
+		wantBody      string
+		succeeded     int
+		failed        int
+		skipped       int
+		wantFailedErr string
+	}{
+		{
+			name: "deletes created resources",
+			entries: []journal.Entry{
+				{Action: journal.CreateServiceInstance, Org: "my_org", Space: "my_space", Name: "my_db", GUID: "instance-guid"},
+				{Action: journal.CreateServiceKey, Org: "my_org", Space: "my_space", Name: "my_db/my_key", GUID: "key-guid"},
+				{Action: journal.CreateRole, Org: "my_org", Name: "organization_manager of my_user (uaa)", GUID: "role-guid"},
+			},
+			wantRequests: []string{
+				"DELETE /v3/roles/role-guid",
+				"DELETE /v3/service_credential_bindings/key-guid",
+				"DELETE /v3/service_instances/instance-guid",
+			},
+			succeeded: 3,
+		},
+		{
+			name: "waits for the broker to delete service instances",
+			entries: []journal.Entry{
+				{Action: journal.CreateServiceInstance, Org: "my_org", Space: "my_space", Name: "my_db", GUID: "instance-guid"},
+			},
+			jobState:     "COMPLETE",
+			wantRequests: []string{"DELETE /v3/service_instances/instance-guid"},
+			succeeded:    1,
+		},
+		{
+			name: "reports the service instances the broker failed to delete",
+			entries: []journal.Entry{
+				{Action: journal.CreateServiceInstance, Org: "my_org", Space: "my_space", Name: "my_db", GUID: "instance-guid"},
+			},
+			jobState:      "FAILED",
+			wantRequests:  []string{"DELETE /v3/service_instances/instance-guid"},
+			failed:        1,
+			wantFailedErr: "DELETE /v3/service_instances/instance-guid failed: the broker is down",
+		},
+		{
+			name: "unshares and deletes domains",
+			entries: []journal.Entry{
+				{Action: journal.CreateDomain, Name: "example.com", GUID: "domain-guid"},
+				{Action: journal.ShareDomain, Name: "example.com", GUID: "domain-guid", Previous: shared},
+			},
+			wantRequests: []string{
+				"DELETE /v3/domains/domain-guid/relationships/shared_organizations/org-guid",
+				"DELETE /v3/domains/domain-guid",
+			},
+			succeeded: 1,
+		},
+		{
+			name: "unbinds security groups from spaces",
+			entries: []journal.Entry{
+				{Action: journal.BindRunningSecurityGroup, Org: "my_org", Space: "my_space", Name: "public", GUID: "group-guid", RelatedGUID: "space-guid"},
+				{Action: journal.BindStagingSecurityGroup, Org: "my_org", Space: "my_space", Name: "public", GUID: "group-guid", RelatedGUID: "space-guid"},
+			},
+			wantRequests: []string{
+				"DELETE /v3/security_groups/group-guid/relationships/staging_spaces/space-guid",
+				"DELETE /v3/security_groups/group-guid/relationships/running_spaces/space-guid",
+			},
+			succeeded: 1,
+		},
+		{
+			name: "unassigns the isolation segment a space did not have",
+			entries: []journal.Entry{
+				{Action: journal.SetSpaceIsolationSegment, Org: "my_org", Space: "my_space", Name: "my_segment", GUID: "segment-guid", RelatedGUID: "space-guid", Previous: noSegment},
+			},
+			wantRequests: []string{"PATCH /v3/spaces/space-guid/relationships/isolation_segment"},
+			wantBody:     `{"data":null}`,
+			succeeded:    1,
+		},
+		{
+			name: "cannot restore the bits of a buildpack",
+			entries: []journal.Entry{
+				{Action: journal.UploadBuildpack, Name: "my_buildpack", GUID: "buildpack-guid"},
+			},
+			failed:        1,
+			wantFailedErr: "cannot be restored",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			j := journal.New(dir, nil)
+			for _, e := range tt.entries {
+				require.NoError(t, j.Record(e))
+			}
+			require.NoError(t, j.Close())
+
+			var requests []string
+			var body string
+			client := &fakes.FakeClient{
+				DoWithRetryStub: func(f func() error) error {
+					return f()
+				},
+				NewRequestStub: func(method, path string) *cfclient.Request {
+					requests = append(requests, method+" "+path)
+					return &cfclient.Request{}
+				},
+				NewRequestWithBodyStub: func(method, path string, r io.Reader) *cfclient.Request {
+					requests = append(requests, method+" "+path)
+					data, err := io.ReadAll(r)
+					require.NoError(t, err)
+					body = string(data)
+					return &cfclient.Request{}
+				},
+				DoRequestStub: func(*cfclient.Request) (*http.Response, error) {
+					if tt.jobState != "" {
+						return &http.Response{
+							StatusCode: http.StatusAccepted,
+							Header:     http.Header{"Location": []string{"https://api.example.com/v3/jobs/job-guid"}},
+							Body:       io.NopCloser(strings.NewReader("")),
+						}, nil
+					}
+					return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(strings.NewReader(""))}, nil
+				},
+				GetStub: func(path string) ([]byte, error) {
+					if path != "/v3/jobs/job-guid" {
+						return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+					}
+					return []byte(`{"guid": "job-guid", "state": "` + tt.jobState + `", "errors": [{"detail": "the broker is down"}]}`), nil
+				},
+			}
+			ctx := &context.Context{
+				Logger:         log.New(),
+				ExportDir:      dir,
+				Summary:        report.NewSummary(&bytes.Buffer{}),
+				Resources:      report.NewResources(&bytes.Buffer{}),
+				ImportCFClient: client,
+			}
+
+			r := &Rollback{RunID: j.RunID}
+			require.NoError(t, r.Run(ctx))
+			assert.Equal(t, tt.wantRequests, requests)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, body)
+			}
+			assert.Equal(t, tt.succeeded, ctx.Resources.Count(report.ResourceSucceeded))
+			assert.Equal(t, tt.failed, ctx.Resources.Count(report.ResourceFailed))
+			assert.Equal(t, tt.skipped, ctx.Resources.Count(report.ResourceSkipped))
+			assert.Equal(t, 0, ctx.Summary.AppSuccessCount()+ctx.Summary.AppFailureCount()+ctx.Summary.AppSkippedCount())
+			if tt.wantFailedErr != "" {
+				require.Len(t, ctx.Resources.Resources(), 1)
+				assert.Contains(t, ctx.Resources.Resources()[0].Message, tt.wantFailedErr)
+			}
+		})
+	}
+}
+
+func TestRollback_RunSealed(t *testing.T) {
+	key := crypt.NewPassphraseKey("passphrase")
+	previous, err := json.Marshal(cfclient.AppUpdateResource{Name: "existing_app", Environment: map[string]interface{}{"PASSWORD": "s3cr3t"}})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	j := journal.New(dir, key)
+	require.NoError(t, j.Record(journal.Entry{Action: journal.UpdateApp, Org: "my_org", Space: "my_space", App: "existing_app", GUID: "existing-guid", AppGUID: "existing-guid", Previous: previous}))
+	require.NoError(t, j.Close())
+
+	client := &fakes.FakeClient{}
+	client.DoWithRetryStub = func(f func() error) error {
+		return f()
+	}
+	ctx := &context.Context{
+		Logger:         log.New(),
+		ExportDir:      dir,
+		Summary:        report.NewSummary(&bytes.Buffer{}),
+		ImportCFClient: client,
+	}
+
+	r := &Rollback{RunID: j.RunID}
+	assert.ErrorContains(t, r.Run(ctx), "is sealed")
+	assert.Equal(t, 0, client.UpdateAppCallCount())
+
+	ctx.Opener = key
+	require.NoError(t, r.Run(ctx))
+	require.Equal(t, 1, client.UpdateAppCallCount())
+	_, update := client.UpdateAppArgsForCall(0)
+	assert.Equal(t, map[string]interface{}{"PASSWORD": "s3cr3t"}, update.Environment)
+	assert.Equal(t, 1, ctx.Summary.AppSuccessCount())
+}
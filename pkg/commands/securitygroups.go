@@ -0,0 +1,223 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+)
+
+// securityGroupsMutex keeps spaces imported concurrently from creating or updating the same security group at once
+var securityGroupsMutex sync.Mutex
+
+// exportSpaceSecurityGroups writes the security groups bound to the space on the source foundation to the export
+func exportSpaceSecurityGroups(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	groups, err := spaceSecurityGroups(ctx, space)
+	if err != nil {
+		return fmt.Errorf("error listing the security groups of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return export.WriteSpaceSecurityGroups(ctx, org.Name, space.Name, groups)
+}
+
+// migrateSpaceSecurityGroups binds the security groups bound to the space on the source foundation to the space of
+// the target foundation, when --security-groups is set
+func migrateSpaceSecurityGroups(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	if !ctx.SecurityGroups {
+		return nil
+	}
+
+	groups, err := spaceSecurityGroups(ctx, space)
+	if err != nil {
+		return fmt.Errorf("error listing the security groups of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return bindSecurityGroups(ctx, ctx.NameMapping.Org(org.Name), ctx.NameMapping.Space(org.Name, space.Name), groups)
+}
+
+// importSpaceSecurityGroups binds the exported security groups of the space to the target space, when
+// --security-groups is set. Exports made before security groups were exported have none to import.
+func importSpaceSecurityGroups(ctx *context.Context, sourceOrg, sourceSpace string) error {
+	if !ctx.SecurityGroups {
+		return nil
+	}
+
+	groups, err := export.ReadSpaceSecurityGroups(ctx, sourceOrg, sourceSpace)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return bindSecurityGroups(ctx, ctx.NameMapping.Org(sourceOrg), ctx.NameMapping.Space(sourceOrg, sourceSpace), groups)
+}
+
+// spaceSecurityGroups returns the security groups bound to the space on the source foundation, sorted by name
+func spaceSecurityGroups(ctx *context.Context, space cfclient.Space) ([]export.SecurityGroup, error) {
+	running, err := cf.ListRunningSecurityGroups(ctx.ExportCFClient, space.Guid)
+	if err != nil {
+		return nil, err
+	}
+	staging, err := cf.ListStagingSecurityGroups(ctx.ExportCFClient, space.Guid)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*export.SecurityGroup)
+	for _, g := range running {
+		byName[g.Name] = &export.SecurityGroup{Name: g.Name, Rules: g.Rules, Running: true}
+	}
+	for _, g := range staging {
+		if _, ok := byName[g.Name]; !ok {
+			byName[g.Name] = &export.SecurityGroup{Name: g.Name, Rules: g.Rules}
+		}
+		byName[g.Name].Staging = true
+	}
+
+	groups := make([]export.SecurityGroup, 0, len(byName))
+	for _, g := range byName {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups, nil
+}
+
+// bindSecurityGroups creates the security groups missing on the target foundation, gives the existing ones the rules
+// they have on the source foundation, and binds them to the space of the target foundation. Rules that change are
+// added to the security group diff report.
+func bindSecurityGroups(ctx *context.Context, orgName, spaceName string, groups []export.SecurityGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	c := cache.GetCache(ctx.ImportCFClient)
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+	space, err := c.GetSpaceByName(spaceName, org.Guid)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, g := range groups {
+		ctx.Logger.Infof("Binding security group %s to space %s/%s", g.Name, orgName, spaceName)
+		err := withRetry(ctx, func() error {
+			target, err := saveSecurityGroup(ctx, g)
+			if err != nil {
+				return err
+			}
+			entry := journal.Entry{Org: orgName, Space: spaceName, Name: g.Name, GUID: target.GUID, RelatedGUID: space.Guid}
+			if g.Running && !hasRelationship(&target.Relationships.RunningSpaces, space.Guid) {
+				if err = cf.BindRunningSecurityGroup(ctx.ImportCFClient, target.GUID, space.Guid); err != nil {
+					return err
+				}
+				entry.Action = journal.BindRunningSecurityGroup
+				if err = recordResource(ctx, entry, nil); err != nil {
+					return err
+				}
+			}
+			if g.Staging && !hasRelationship(&target.Relationships.StagingSpaces, space.Guid) {
+				if err = cf.BindStagingSecurityGroup(ctx.ImportCFClient, target.GUID, space.Guid); err != nil {
+					return err
+				}
+				entry.Action = journal.BindStagingSecurityGroup
+				return recordResource(ctx, entry, nil)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// saveSecurityGroup creates the security group on the target foundation, or replaces the rules of the group with the
+// same name when they differ
+func saveSecurityGroup(ctx *context.Context, g export.SecurityGroup) (cf.SecurityGroup, error) {
+	securityGroupsMutex.Lock()
+	defer securityGroupsMutex.Unlock()
+
+	current, err := cf.GetSecurityGroupByName(ctx.ImportCFClient, g.Name)
+	if err != nil {
+		return cf.SecurityGroup{}, err
+	}
+	if current == nil {
+		created, err := cf.CreateSecurityGroup(ctx.ImportCFClient, g.Name, g.Rules)
+		if err != nil {
+			return cf.SecurityGroup{}, err
+		}
+		return created, recordResource(ctx, journal.Entry{Action: journal.CreateSecurityGroup, Name: g.Name, GUID: created.GUID}, nil)
+	}
+
+	added, removed := diffRules(g.Rules, current.Rules)
+	if len(added) == 0 && len(removed) == 0 {
+		return *current, nil
+	}
+
+	ctx.Logger.Infof("Updating the rules of security group %s", g.Name)
+	if err = cf.UpdateSecurityGroupRules(ctx.ImportCFClient, current.GUID, g.Rules); err != nil {
+		return cf.SecurityGroup{}, err
+	}
+	err = recordResource(ctx, journal.Entry{Action: journal.UpdateSecurityGroup, Name: g.Name, GUID: current.GUID}, current.Rules)
+	if err != nil {
+		return cf.SecurityGroup{}, err
+	}
+	if ctx.SecurityGroupDiffs != nil {
+		ctx.SecurityGroupDiffs.Add(g.Name, added, removed)
+	}
+
+	return *current, nil
+}
+
+// diffRules returns the rules that are only in want and the rules that are only in have, ignoring their order
+func diffRules(want, have []cf.SecurityGroupRule) (added, removed []string) {
+	count := make(map[string]int)
+	for _, r := range have {
+		count[r.String()]++
+	}
+	for _, r := range want {
+		if count[r.String()] > 0 {
+			count[r.String()]--
+			continue
+		}
+		added = append(added, r.String())
+	}
+	for _, r := range have {
+		if count[r.String()] > 0 {
+			count[r.String()]--
+			removed = append(removed, r.String())
+		}
+	}
+
+	return added, removed
+}
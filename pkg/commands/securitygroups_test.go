@@ -0,0 +1,142 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestExportSpaceSecurityGroups(t *testing.T) {
+	client := &fakes.FakeClient{
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/security_groups?" + url.Values{"running_space_guids": []string{"space-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "postgres-guid", "name": "postgres", "rules": [{"protocol": "tcp", "destination": "10.0.1.0/24", "ports": "5432"}]},
+  {"guid": "dns-guid", "name": "dns", "rules": [{"protocol": "udp", "destination": "10.0.0.2", "ports": "53"}]}
+]}`), nil
+			case "/v3/security_groups?" + url.Values{"staging_space_guids": []string{"space-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "dns-guid", "name": "dns", "rules": [{"protocol": "udp", "destination": "10.0.0.2", "ports": "53"}]},
+  {"guid": "proxy-guid", "name": "proxy", "rules": [{"protocol": "tcp", "destination": "10.0.2.10", "ports": "3128", "log": true}]}
+]}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{DirWriter: storage.NewMemory(), ExportCFClient: client}
+
+	err := exportSpaceSecurityGroups(ctx, cfclient.Org{Name: "my_org"}, cfclient.Space{Guid: "space-guid", Name: "my_space"})
+	require.NoError(t, err)
+
+	groups, err := export.ReadSpaceSecurityGroups(ctx, "my_org", "my_space")
+	require.NoError(t, err)
+	assert.Equal(t, []export.SecurityGroup{
+		{Name: "dns", Rules: []cf.SecurityGroupRule{{Protocol: "udp", Destination: "10.0.0.2", Ports: "53"}}, Running: true, Staging: true},
+		{Name: "postgres", Rules: []cf.SecurityGroupRule{{Protocol: "tcp", Destination: "10.0.1.0/24", Ports: "5432"}}, Running: true},
+		{Name: "proxy", Rules: []cf.SecurityGroupRule{{Protocol: "tcp", Destination: "10.0.2.10", Ports: "3128", Log: true}}, Staging: true},
+	}, groups)
+}
+
+func TestImportSpaceSecurityGroups(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var requests []string
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "org-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/security_groups?names=postgres":
+				return []byte(`{"pagination": {}, "resources": [{
+  "guid": "postgres-guid", "name": "postgres", "rules": [{"protocol": "tcp", "destination": "10.0.0.0/24", "ports": "5432"}],
+  "relationships": {"running_spaces": {"data": [{"guid": "space-guid"}]}, "staging_spaces": {"data": []}}
+}]}`), nil
+			case "/v3/security_groups?names=dns":
+				return []byte(`{"pagination": {}, "resources": [{
+  "guid": "dns-guid", "name": "dns", "rules": [{"protocol": "udp", "destination": "10.0.0.2", "ports": "53"}],
+  "relationships": {"running_spaces": {"data": [{"guid": "other-space-guid"}]}, "staging_spaces": {"data": []}}
+}]}`), nil
+			}
+			return []byte(`{"pagination": {}, "resources": []}`), nil
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			requests = append(requests, method+" "+path+" "+string(data))
+			return &cfclient.Request{}
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			if strings.HasPrefix(requests[len(requests)-1], "POST /v3/security_groups {") {
+				return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{"guid": "proxy-guid", "name": "proxy"}`))}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		},
+	}
+
+	ctx := &context.Context{
+		Logger:             log.New(),
+		DirWriter:          storage.NewMemory(),
+		ImportCFClient:     client,
+		SecurityGroupDiffs: report.NewSecurityGroupDiffs(&bytes.Buffer{}),
+	}
+	require.NoError(t, export.WriteSpaceSecurityGroups(ctx, "my_org", "my_space", []export.SecurityGroup{
+		{Name: "dns", Rules: []cf.SecurityGroupRule{{Protocol: "udp", Destination: "10.0.0.2", Ports: "53"}}, Running: true, Staging: true},
+		{Name: "postgres", Rules: []cf.SecurityGroupRule{{Protocol: "tcp", Destination: "10.0.1.0/24", Ports: "5432"}}, Running: true},
+		{Name: "proxy", Rules: []cf.SecurityGroupRule{{Protocol: "tcp", Destination: "10.0.2.10", Ports: "3128", Log: true}}, Staging: true},
+	}))
+
+	require.NoError(t, importSpaceSecurityGroups(ctx, "my_org", "my_space"))
+	assert.Empty(t, requests, "security groups are only imported with --security-groups")
+
+	ctx.SecurityGroups = true
+	require.NoError(t, importSpaceSecurityGroups(ctx, "my_org", "my_space"))
+	assert.Equal(t, []string{
+		`POST /v3/security_groups/dns-guid/relationships/running_spaces {"data":[{"guid":"space-guid"}]}`,
+		`POST /v3/security_groups/dns-guid/relationships/staging_spaces {"data":[{"guid":"space-guid"}]}`,
+		`PATCH /v3/security_groups/postgres-guid {"rules":[{"protocol":"tcp","destination":"10.0.1.0/24","ports":"5432"}]}`,
+		`POST /v3/security_groups {"name":"proxy","rules":[{"protocol":"tcp","destination":"10.0.2.10","ports":"3128","log":true}]}`,
+		`POST /v3/security_groups/proxy-guid/relationships/staging_spaces {"data":[{"guid":"space-guid"}]}`,
+	}, requests)
+	assert.Equal(t, []report.SecurityGroupDiff{
+		{Name: "postgres", Added: []string{"tcp 10.0.1.0/24 5432"}, Removed: []string{"tcp 10.0.0.0/24 5432"}},
+	}, ctx.SecurityGroupDiffs.Diffs())
+}
@@ -0,0 +1,281 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+)
+
+// exportSpaceServiceInstances writes the managed service instances of the space on the source foundation to the
+// export
+func exportSpaceServiceInstances(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	instances, err := spaceServiceInstances(ctx, space)
+	if err != nil {
+		return fmt.Errorf("error listing the service instances of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return export.WriteSpaceServiceInstances(ctx, org.Name, space.Name, instances)
+}
+
+// migrateSpaceServiceInstances creates the managed service instances of the space on the source foundation in the
+// space of the target foundation
+func migrateSpaceServiceInstances(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	instances, err := spaceServiceInstances(ctx, space)
+	if err != nil {
+		return fmt.Errorf("error listing the service instances of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return createServiceInstances(ctx, ctx.NameMapping.Org(org.Name), ctx.NameMapping.Space(org.Name, space.Name), instances)
+}
+
+// importSpaceServiceInstances creates the exported managed service instances of the space in the target space.
+// Exports made before service instances were exported have none to import.
+func importSpaceServiceInstances(ctx *context.Context, sourceOrg, sourceSpace string) error {
+	instances, err := export.ReadSpaceServiceInstances(ctx, sourceOrg, sourceSpace)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return createServiceInstances(ctx, ctx.NameMapping.Org(sourceOrg), ctx.NameMapping.Space(sourceOrg, sourceSpace), instances)
+}
+
+// spaceServiceInstances returns the managed service instances of the space on the source foundation, with their
+// service plan and parameters. User-provided service instances are exported by the apps bound to them.
+func spaceServiceInstances(ctx *context.Context, space cfclient.Space) ([]export.ServiceInstance, error) {
+	instances, err := cf.ListSpaceServiceInstances(ctx.ExportCFClient, space.Guid)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name < instances[j].Name
+	})
+
+	exported := make([]export.ServiceInstance, 0)
+	for _, si := range instances {
+		if !si.IsManaged() {
+			continue
+		}
+
+		plan, err := cf.GetServicePlan(ctx.ExportCFClient, si.PlanGUID())
+		if err != nil {
+			return nil, err
+		}
+
+		e := export.ServiceInstance{Name: si.Name, Offering: plan.Offering, Plan: plan.Name, Broker: plan.Broker, Tags: si.Tags}
+		e.Parameters, err = cf.GetServiceInstanceParameters(ctx.ExportCFClient, si.GUID)
+		if err != nil {
+			ctx.Logger.Warnf("Could not get the parameters of service %s, they are not exported: %s", si.Name, err)
+		}
+		exported = append(exported, e)
+	}
+
+	return exported, nil
+}
+
+// createServiceInstances creates the managed service instances missing in the target space, with the service plan
+// the service mappings give their plan on the source foundation, and waits for their brokers to provision them
+func createServiceInstances(ctx *context.Context, orgName, spaceName string, instances []export.ServiceInstance) error {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	c := cache.GetCache(ctx.ImportCFClient)
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+	space, err := c.GetSpaceByName(spaceName, org.Guid)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, si := range instances {
+		if err = createServiceInstance(ctx, org, space, si); err != nil {
+			errs = append(errs, fmt.Sprintf("service instance %s: %s", si.Name, err))
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// createServiceInstance creates the managed service instance in the target space unless it already exists there
+func createServiceInstance(ctx *context.Context, org cfclient.Org, space cfclient.Space, instance export.ServiceInstance) error {
+	var existing *cf.ServiceInstance
+	err := withRetry(ctx, func() (err error) {
+		existing, err = cf.GetServiceInstanceByName(ctx.ImportCFClient, instance.Name, space.Guid)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		ctx.Logger.Debugf("Service instance %s already exists in space %s/%s", instance.Name, org.Name, space.Name)
+		return nil
+	}
+
+	target := ctx.ServiceMappings.Map(sourceServicePlan(instance))
+	plan, status, err := findTargetServicePlan(ctx, org.Guid, target)
+	if err != nil {
+		return err
+	}
+	if plan == nil {
+		return fmt.Errorf("service plan %s %s", target, status)
+	}
+
+	ctx.Logger.Infof("Creating service instance %s of service plan %s in space %s/%s", instance.Name, target, org.Name, space.Name)
+	si := cf.ServiceInstance{
+		Type:       "managed",
+		Name:       instance.Name,
+		Tags:       instance.Tags,
+		Parameters: instance.Parameters,
+		Relationships: cf.ServiceInstanceRelationships{
+			Space:       &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: space.Guid}},
+			ServicePlan: &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: plan.GUID}},
+		},
+	}
+	var guid string
+	err = withRetry(ctx, func() (err error) {
+		guid, err = cf.CreateServiceInstance(ctx.ImportCFClient, si)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = recordResource(ctx, journal.Entry{Action: journal.CreateServiceInstance, Org: org.Name, Space: space.Name, Name: instance.Name, GUID: guid}, nil)
+	if err != nil {
+		return err
+	}
+
+	return waitUntil(30*time.Minute, fmt.Sprintf("service instance %s to be created", instance.Name), func() (bool, error) {
+		created, err := cf.GetServiceInstance(ctx.ImportCFClient, guid)
+		if err != nil {
+			return false, err
+		}
+		if created.LastOperation == nil {
+			return true, nil
+		}
+		switch created.LastOperation.State {
+		case "succeeded":
+			return true, nil
+		case "failed":
+			return false, fmt.Errorf("creating service instance %s failed: %s", instance.Name, created.LastOperation.Description)
+		}
+		return false, nil
+	})
+}
+
+// createServiceCredentialBinding creates the app binding or service key and waits for the broker to complete it. The
+// guid of the binding is returned as soon as it is created, along with the error of the broker when it fails.
+func createServiceCredentialBinding(ctx *context.Context, b cf.ServiceCredentialBinding, what string) (string, error) {
+	var created cf.ServiceCredentialBinding
+	err := withRetry(ctx, func() (err error) {
+		created, err = cf.CreateServiceCredentialBinding(ctx.ImportCFClient, b)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	done := func(binding cf.ServiceCredentialBinding) (bool, error) {
+		if binding.LastOperation == nil {
+			return true, nil
+		}
+		switch binding.LastOperation.State {
+		case "succeeded":
+			return true, nil
+		case "failed":
+			return false, fmt.Errorf("creating %s failed: %s", what, binding.LastOperation.Description)
+		}
+		return false, nil
+	}
+	if ok, err := done(created); ok || err != nil {
+		return created.GUID, err
+	}
+
+	return created.GUID, waitUntil(15*time.Minute, what+" to be created", func() (bool, error) {
+		var current cf.ServiceCredentialBinding
+		err := withRetry(ctx, func() (err error) {
+			current, err = cf.GetServiceCredentialBinding(ctx.ImportCFClient, created.GUID)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		return done(current)
+	})
+}
+
+// findTargetServicePlan returns the service plan of the target foundation the service instances of the org can be
+// created with, or nil and the reason when there is none
+func findTargetServicePlan(ctx *context.Context, orgGUID string, target mapping.ServicePlan) (*cf.ServicePlan, string, error) {
+	var plans []cf.ServicePlan
+	err := withRetry(ctx, func() (err error) {
+		plans, err = cf.FindServicePlans(ctx.ImportCFClient, target.Offering, target.Plan, target.Broker, orgGUID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var available []cf.ServicePlan
+	for _, p := range plans {
+		if p.Available {
+			available = append(available, p)
+		}
+	}
+	switch {
+	case len(available) == 1:
+		return &available[0], "ok", nil
+	case len(available) > 1:
+		return nil, "provided by several brokers, set the broker of the target plan", nil
+	case len(plans) > 0:
+		return nil, "not available", nil
+	}
+
+	// the plan may exist without being visible to the org
+	err = withRetry(ctx, func() (err error) {
+		plans, err = cf.FindServicePlans(ctx.ImportCFClient, target.Offering, target.Plan, target.Broker, "")
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(plans) > 0 {
+		return nil, "not visible to the target org", nil
+	}
+
+	return nil, "missing", nil
+}
+
+// sourceServicePlan is the service plan of the exported service instance
+func sourceServicePlan(si export.ServiceInstance) mapping.ServicePlan {
+	return mapping.ServicePlan{Offering: si.Offering, Plan: si.Plan, Broker: si.Broker}
+}
@@ -0,0 +1,278 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestExportSpaceServiceInstances(t *testing.T) {
+	client := &fakes.FakeClient{
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_instances?space_guids=space-guid":
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "db-guid", "name": "my-db", "type": "managed", "tags": ["mysql"], "relationships": {"service_plan": {"data": {"guid": "db-plan-guid"}}}},
+  {"guid": "config-guid", "name": "my-config", "type": "user-provided"},
+  {"guid": "cache-guid", "name": "my-cache", "type": "managed", "relationships": {"service_plan": {"data": {"guid": "cache-plan-guid"}}}}
+]}`), nil
+			case "/v3/service_plans/db-plan-guid?fields%5Bservice_offering.service_broker%5D=name&include=service_offering":
+				return []byte(`{"guid": "db-plan-guid", "name": "db-small", "included": {"service_offerings": [{"name": "p.mysql"}], "service_brokers": [{"name": "mysql-broker"}]}}`), nil
+			case "/v3/service_plans/cache-plan-guid?fields%5Bservice_offering.service_broker%5D=name&include=service_offering":
+				return []byte(`{"guid": "cache-plan-guid", "name": "shared", "included": {"service_offerings": [{"name": "p-redis"}], "service_brokers": [{"name": "redis-broker"}]}}`), nil
+			case "/v3/service_instances/db-guid/parameters":
+				return []byte(`{"storage": 10}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ExportCFClient: client,
+	}
+
+	err := exportSpaceServiceInstances(ctx, cfclient.Org{Name: "my_org"}, cfclient.Space{Guid: "space-guid", Name: "my_space"})
+	require.NoError(t, err)
+
+	instances, err := export.ReadSpaceServiceInstances(ctx, "my_org", "my_space")
+	require.NoError(t, err)
+	assert.Equal(t, []export.ServiceInstance{
+		{Name: "my-cache", Offering: "p-redis", Plan: "shared", Broker: "redis-broker"},
+		{Name: "my-db", Offering: "p.mysql", Plan: "db-small", Broker: "mysql-broker", Parameters: map[string]interface{}{"storage": float64(10)}, Tags: []string{"mysql"}},
+	}, instances)
+}
+
+func TestImportSpaceServiceInstances(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var requests []string
+	created := false
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "org-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_instances?" + url.Values{"names": []string{"my-db"}, "space_guids": []string{"space-guid"}}.Encode():
+				if created {
+					return []byte(`{"pagination": {}, "resources": [{"guid": "new-db-guid", "name": "my-db", "type": "managed"}]}`), nil
+				}
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/service_instances?" + url.Values{"names": []string{"my-cache"}, "space_guids": []string{"space-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/service_instances?" + url.Values{"names": []string{"my-queue"}, "space_guids": []string{"space-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [{"guid": "queue-guid", "name": "my-queue", "type": "managed"}]}`), nil
+			case "/v3/service_plans?names=small&organization_guids=org-guid&service_offering_names=p.mysql":
+				return []byte(`{"pagination": {}, "resources": [{"guid": "small-guid", "name": "small", "available": true}]}`), nil
+			case "/v3/service_plans?names=shared&organization_guids=org-guid&service_offering_names=p-redis",
+				"/v3/service_plans?names=shared&service_offering_names=p-redis":
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/service_instances/new-db-guid":
+				return []byte(`{"guid": "new-db-guid", "name": "my-db", "type": "managed", "last_operation": {"type": "create", "state": "succeeded"}}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			requests = append(requests, method+" "+path+" "+string(data))
+			return &cfclient.Request{}
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			created = true
+			return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader(""))}, nil
+		},
+	}
+	ctx := &context.Context{
+		Logger:          log.New(),
+		DirWriter:       storage.NewMemory(),
+		ImportCFClient:  client,
+		ServiceMappings: mapping.Services{{Source: mapping.ServicePlan{Offering: "p.mysql", Plan: "db-small"}, Target: mapping.ServicePlan{Plan: "small"}}},
+	}
+	err := export.WriteSpaceServiceInstances(ctx, "my_org", "my_space", []export.ServiceInstance{
+		{Name: "my-cache", Offering: "p-redis", Plan: "shared"},
+		{Name: "my-db", Offering: "p.mysql", Plan: "db-small", Broker: "mysql-broker", Parameters: map[string]interface{}{"storage": 10}, Tags: []string{"mysql"}},
+		{Name: "my-queue", Offering: "p.rabbitmq", Plan: "single-node"},
+	})
+	require.NoError(t, err)
+
+	err = importSpaceServiceInstances(ctx, "my_org", "my_space")
+	assert.EqualError(t, err, "service instance my-cache: service plan p-redis/shared missing")
+	assert.Equal(t, []string{
+		`POST /v3/service_instances {"name":"my-db","type":"managed","tags":["mysql"],"parameters":{"storage":10},"relationships":{"space":{"data":{"guid":"space-guid"}},"service_plan":{"data":{"guid":"small-guid"}}}}`,
+	}, requests)
+}
+
+func TestImportSpaceServiceInstances_NotExported(t *testing.T) {
+	ctx := &context.Context{Logger: log.New(), DirWriter: storage.NewMemory()}
+	require.NoError(t, importSpaceServiceInstances(ctx, "my_org", "my_space"))
+}
+
+func TestCreateServiceCredentialBinding(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		state    string
+		wantGets []string
+		wantErr  string
+	}{
+		{
+			name:   "created synchronously",
+			status: http.StatusCreated,
+			body:   `{"guid": "binding-guid", "last_operation": {"type": "create", "state": "succeeded"}}`,
+		},
+		{
+			name:     "waits for the broker",
+			status:   http.StatusAccepted,
+			state:    `{"guid": "binding-guid", "last_operation": {"type": "create", "state": "succeeded"}}`,
+			wantGets: []string{"/v3/service_credential_bindings?app_guids=app-guid&service_instance_guids=si-guid&type=app", "/v3/service_credential_bindings/binding-guid"},
+		},
+		{
+			name:     "returns the error of the broker",
+			status:   http.StatusAccepted,
+			state:    `{"guid": "binding-guid", "last_operation": {"type": "create", "state": "failed", "description": "out of connections"}}`,
+			wantGets: []string{"/v3/service_credential_bindings?app_guids=app-guid&service_instance_guids=si-guid&type=app", "/v3/service_credential_bindings/binding-guid"},
+			wantErr:  "creating binding of service instance my-db failed: out of connections",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gets []string
+			client := &fakes.FakeClient{
+				DoWithRetryStub: func(f func() error) error {
+					return f()
+				},
+				NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+					return &cfclient.Request{}
+				},
+				DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}, nil
+				},
+				GetStub: func(path string) ([]byte, error) {
+					gets = append(gets, path)
+					if path == "/v3/service_credential_bindings/binding-guid" {
+						return []byte(tt.state), nil
+					}
+					return []byte(`{"pagination": {}, "resources": [{"guid": "binding-guid", "type": "app", "last_operation": {"type": "create", "state": "in progress"}}]}`), nil
+				},
+			}
+			ctx := &context.Context{Logger: log.New(), ImportCFClient: client}
+
+			guid, err := createServiceCredentialBinding(ctx, cf.ServiceCredentialBinding{
+				Type: "app",
+				Relationships: cf.ServiceCredentialBindingRelationships{
+					App:             &cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: "app-guid"}},
+					ServiceInstance: cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: "si-guid"}},
+				},
+			}, "binding of service instance my-db")
+			assert.Equal(t, "binding-guid", guid)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantGets, gets)
+		})
+	}
+}
+
+func TestImportApp_RunCreatesTheServiceInstancesOfItsSpace(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var requests []string
+	created := false
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "org-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_instances?" + url.Values{"names": []string{"my-db"}, "space_guids": []string{"space-guid"}}.Encode():
+				if created {
+					return []byte(`{"pagination": {}, "resources": [{"guid": "new-db-guid", "name": "my-db", "type": "managed"}]}`), nil
+				}
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/service_plans?names=db-small&organization_guids=org-guid&service_offering_names=p.mysql":
+				return []byte(`{"pagination": {}, "resources": [{"guid": "small-guid", "name": "db-small", "available": true}]}`), nil
+			case "/v3/service_instances/new-db-guid":
+				return []byte(`{"guid": "new-db-guid", "name": "my-db", "type": "managed", "last_operation": {"type": "create", "state": "succeeded"}}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			requests = append(requests, method+" "+path)
+			return &cfclient.Request{}
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			created = true
+			return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(strings.NewReader(""))}, nil
+		},
+	}
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewMemory(),
+		ImportCFClient: client,
+	}
+	err := export.WriteSpaceServiceInstances(ctx, "my_org", "my_space", []export.ServiceInstance{
+		{Name: "my-db", Offering: "p.mysql", Plan: "db-small"},
+	})
+	require.NoError(t, err)
+
+	var createdBeforeApp []string
+	i := &ImportApp{
+		ImportSpace: ImportSpace{ImportOrg: ImportOrg{Org: "my_org"}, Space: "my_space"},
+		AppName:     "my_app",
+		Sequence: StepFunc(func(*context.Context, Result) (Result, error) {
+			createdBeforeApp = append(createdBeforeApp, requests...)
+			return nil, nil
+		}),
+	}
+	require.NoError(t, i.Run(ctx))
+	assert.Equal(t, []string{"POST /v3/service_instances"}, createdBeforeApp)
+}
@@ -0,0 +1,273 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+)
+
+// exportSpaceServiceKeys writes the service keys of the service instances of the space on the source foundation to
+// the export. When the secrets are externalized their credentials are stored in the secrets file, to be compared on
+// import.
+func exportSpaceServiceKeys(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	keys, credentials, err := spaceServiceKeys(ctx, space, ctx.ExternalizeSecrets)
+	if err != nil {
+		return fmt.Errorf("error listing the service keys of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	if ctx.ExternalizeSecrets {
+		for _, si := range keys {
+			for i := range si.Keys {
+				key := secrets.Key{"key", org.Name, space.Name, si.ServiceInstance, si.Keys[i].Name}
+				if err = ctx.Secrets.Add(key, credentials[serviceKeyID(si.ServiceInstance, si.Keys[i].Name)]); err != nil {
+					return err
+				}
+				si.Keys[i].Credentials = key.Name()
+			}
+		}
+	}
+
+	return export.WriteSpaceServiceKeys(ctx, org.Name, space.Name, keys)
+}
+
+// migrateSpaceServiceKeys creates the service keys of the service instances of the space on the source foundation
+// in the space of the target foundation
+func migrateSpaceServiceKeys(ctx *context.Context, org cfclient.Org, space cfclient.Space) error {
+	keys, credentials, err := spaceServiceKeys(ctx, space, true)
+	if err != nil {
+		return fmt.Errorf("error listing the service keys of space %s/%s: %w", org.Name, space.Name, err)
+	}
+
+	return createServiceKeys(ctx, ctx.NameMapping.Org(org.Name), ctx.NameMapping.Space(org.Name, space.Name), keys, credentials)
+}
+
+// importSpaceServiceKeys creates the exported service keys of the space in the target space. The credentials of the
+// keys are compared when they are in the secrets file. Exports made before service keys were exported have none to
+// import.
+func importSpaceServiceKeys(ctx *context.Context, sourceOrg, sourceSpace string) error {
+	keys, err := export.ReadSpaceServiceKeys(ctx, sourceOrg, sourceSpace)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	credentials := make(map[string]string)
+	for _, si := range keys {
+		for _, k := range si.Keys {
+			if value, ok := ctx.Secrets.Value(k.Credentials); ok && k.Credentials != "" {
+				credentials[serviceKeyID(si.ServiceInstance, k.Name)] = value
+			}
+		}
+	}
+
+	return createServiceKeys(ctx, ctx.NameMapping.Org(sourceOrg), ctx.NameMapping.Space(sourceOrg, sourceSpace), keys, credentials)
+}
+
+// spaceServiceKeys returns the service keys of the managed service instances of the space on the source foundation,
+// user-provided service instances cannot have any. The credentials of the keys are returned by serviceKeyID when
+// withCredentials is set.
+func spaceServiceKeys(ctx *context.Context, space cfclient.Space, withCredentials bool) ([]export.ServiceInstanceKeys, map[string]string, error) {
+	instances, err := cf.ListSpaceServiceInstances(ctx.ExportCFClient, space.Guid)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name < instances[j].Name
+	})
+
+	keys := make([]export.ServiceInstanceKeys, 0)
+	credentials := make(map[string]string)
+	for _, si := range instances {
+		if !si.IsManaged() {
+			continue
+		}
+
+		siKeys, err := cf.ListServiceKeys(ctx.ExportCFClient, si.GUID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(siKeys) == 0 {
+			continue
+		}
+		sort.Slice(siKeys, func(i, j int) bool {
+			return siKeys[i].Name < siKeys[j].Name
+		})
+
+		exported := export.ServiceInstanceKeys{ServiceInstance: si.Name}
+		for _, k := range siKeys {
+			key := export.ServiceKey{Name: k.Name}
+			key.Parameters, err = cf.GetServiceCredentialBindingParameters(ctx.ExportCFClient, k.GUID)
+			if err != nil {
+				ctx.Logger.Warnf("Could not get the parameters of service key %s of service %s, they are not exported: %s", k.Name, si.Name, err)
+			}
+
+			if withCredentials {
+				value, err := serviceKeyCredentials(ctx.ExportCFClient, k.GUID)
+				if err != nil {
+					return nil, nil, err
+				}
+				credentials[serviceKeyID(si.Name, k.Name)] = value
+			}
+			exported.Keys = append(exported.Keys, key)
+		}
+		keys = append(keys, exported)
+	}
+
+	return keys, credentials, nil
+}
+
+// createServiceKeys creates the service keys missing on the service instances of the target space, with the
+// parameters they have on the source foundation. Created keys, and existing keys whose credentials differ from the
+// credentials of the source foundation, given by serviceKeyID when they are known, are added to the service key
+// report.
+func createServiceKeys(ctx *context.Context, orgName, spaceName string, keys []export.ServiceInstanceKeys, credentials map[string]string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	c := cache.GetCache(ctx.ImportCFClient)
+	org, err := c.GetOrgByName(orgName)
+	if err != nil {
+		return err
+	}
+	space, err := c.GetSpaceByName(spaceName, org.Guid)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, si := range keys {
+		for _, k := range si.Keys {
+			change, err := createServiceKey(ctx, org, space, si.ServiceInstance, k, credentials)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("service key %s of service %s: %s", k.Name, si.ServiceInstance, err))
+				continue
+			}
+			if change != "" && ctx.ServiceKeyChanges != nil {
+				ctx.ServiceKeyChanges.Add(orgName, spaceName, si.ServiceInstance, k.Name, change)
+			}
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// createServiceKey creates the service key on the service instance of the target space when it is missing, and
+// returns how its credentials changed, or nothing when they are the same or not known
+func createServiceKey(ctx *context.Context, org cfclient.Org, space cfclient.Space, instance string, k export.ServiceKey, credentials map[string]string) (string, error) {
+	var si *cf.ServiceInstance
+	err := withRetry(ctx, func() (err error) {
+		si, err = cf.GetServiceInstanceByName(ctx.ImportCFClient, instance, space.Guid)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if si == nil {
+		return "", fmt.Errorf("service instance %s does not exist in the target space", instance)
+	}
+
+	var existing []cf.ServiceCredentialBinding
+	err = withRetry(ctx, func() (err error) {
+		existing, err = cf.ListServiceKeys(ctx.ImportCFClient, si.GUID)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	guid := ""
+	for _, e := range existing {
+		if e.Name == k.Name {
+			guid = e.GUID
+			break
+		}
+	}
+
+	created := guid == ""
+	if created {
+		ctx.Logger.Infof("Creating service key %s of service %s in space %s", k.Name, instance, space.Name)
+		key := cf.ServiceCredentialBinding{
+			Type:       "key",
+			Name:       k.Name,
+			Parameters: k.Parameters,
+			Relationships: cf.ServiceCredentialBindingRelationships{
+				ServiceInstance: cfclient.V3ToOneRelationship{Data: cfclient.V3Relationship{GUID: si.GUID}},
+			},
+		}
+		guid, err = createServiceCredentialBinding(ctx, key, fmt.Sprintf("service key %s of service %s", k.Name, instance))
+		// a key the broker failed to complete is still recorded, so that rollback deletes it
+		if guid != "" {
+			entry := journal.Entry{Action: journal.CreateServiceKey, Org: org.Name, Space: space.Name, Name: serviceKeyID(instance, k.Name), GUID: guid}
+			if rerr := recordResource(ctx, entry, nil); rerr != nil {
+				return "", rerr
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	source, known := credentials[serviceKeyID(instance, k.Name)]
+	if !known {
+		if created {
+			return "created", nil
+		}
+		return "", nil
+	}
+
+	target, err := serviceKeyCredentials(ctx.ImportCFClient, guid)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case created && target != source:
+		return "created", nil
+	case target != source:
+		return "credentials differ", nil
+	}
+
+	return "", nil
+}
+
+// serviceKeyCredentials returns the credentials of the service key with the given guid as json, with sorted keys so
+// that the credentials can be compared
+func serviceKeyCredentials(c cf.Client, guid string) (string, error) {
+	credentials, err := cf.GetServiceCredentialBindingCredentials(c, guid)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(credentials)
+	return string(data), err
+}
+
+func serviceKeyID(instance, key string) string {
+	return instance + "/" + key
+}
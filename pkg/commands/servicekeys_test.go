@@ -0,0 +1,164 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+func TestExportSpaceServiceKeys(t *testing.T) {
+	client := &fakes.FakeClient{
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_instances?space_guids=space-guid":
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "db-guid", "name": "my-db", "type": "managed"},
+  {"guid": "config-guid", "name": "my-config", "type": "user-provided"},
+  {"guid": "cache-guid", "name": "my-cache", "type": "managed"}
+]}`), nil
+			case "/v3/service_credential_bindings?" + url.Values{"type": []string{"key"}, "service_instance_guids": []string{"db-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "db-ci-guid", "type": "key", "name": "ci", "relationships": {"service_instance": {"data": {"guid": "db-guid"}}}}
+]}`), nil
+			case "/v3/service_credential_bindings?" + url.Values{"type": []string{"key"}, "service_instance_guids": []string{"cache-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/service_credential_bindings/db-ci-guid/parameters":
+				return []byte(`{"role": "reader"}`), nil
+			case "/v3/service_credential_bindings/db-ci-guid/details":
+				return []byte(`{"credentials": {"username": "ci", "password": "hunter2"}}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{
+		Logger:             log.New(),
+		DirWriter:          storage.NewMemory(),
+		ExportCFClient:     client,
+		ExternalizeSecrets: true,
+		Secrets:            secrets.New(crypt.NewPassphraseKey("passphrase"), nil),
+	}
+
+	err := exportSpaceServiceKeys(ctx, cfclient.Org{Name: "my_org"}, cfclient.Space{Guid: "space-guid", Name: "my_space"})
+	require.NoError(t, err)
+
+	keys, err := export.ReadSpaceServiceKeys(ctx, "my_org", "my_space")
+	require.NoError(t, err)
+	assert.Equal(t, []export.ServiceInstanceKeys{
+		{ServiceInstance: "my-db", Keys: []export.ServiceKey{
+			{Name: "ci", Parameters: map[string]interface{}{"role": "reader"}, Credentials: secrets.Key{"key", "my_org", "my_space", "my-db", "ci"}.Name()},
+		}},
+	}, keys)
+
+	credentials, ok := ctx.Secrets.Value(secrets.Key{"key", "my_org", "my_space", "my-db", "ci"}.Name())
+	assert.True(t, ok)
+	assert.Equal(t, `{"password":"hunter2","username":"ci"}`, credentials)
+}
+
+func TestImportSpaceServiceKeys(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+
+	var requests []string
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetOrgByNameStub: func(name string) (cfclient.Org, error) {
+			return cfclient.Org{Guid: "org-guid", Name: name}, nil
+		},
+		GetSpaceByNameStub: func(name, orgGUID string) (cfclient.Space, error) {
+			return cfclient.Space{Guid: "space-guid", Name: name, OrganizationGuid: orgGUID}, nil
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_instances?" + url.Values{"names": []string{"my-db"}, "space_guids": []string{"space-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [{"guid": "db-guid", "name": "my-db", "type": "managed"}]}`), nil
+			case "/v3/service_instances?" + url.Values{"names": []string{"my-queue"}, "space_guids": []string{"space-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": []}`), nil
+			case "/v3/service_credential_bindings?" + url.Values{"type": []string{"key"}, "service_instance_guids": []string{"db-guid"}}.Encode():
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "db-ci-guid", "type": "key", "name": "ci", "relationships": {"service_instance": {"data": {"guid": "db-guid"}}}},
+  {"guid": "db-same-guid", "type": "key", "name": "same", "relationships": {"service_instance": {"data": {"guid": "db-guid"}}}}
+]}`), nil
+			case "/v3/service_credential_bindings/db-ci-guid/details":
+				return []byte(`{"credentials": {"username": "ci", "password": "rotated"}}`), nil
+			case "/v3/service_credential_bindings/db-same-guid/details":
+				return []byte(`{"credentials": {"username": "same", "password": "hunter2"}}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			requests = append(requests, method+" "+path+" "+string(data))
+			return &cfclient.Request{}
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(`{"guid": "new-key-guid"}`))}, nil
+		},
+	}
+
+	ci := secrets.Key{"key", "my_org", "my_space", "my-db", "ci"}
+	same := secrets.Key{"key", "my_org", "my_space", "my-db", "same"}
+	s := secrets.New(crypt.NewPassphraseKey("passphrase"), nil)
+	require.NoError(t, s.Add(ci, `{"password":"hunter2","username":"ci"}`))
+	require.NoError(t, s.Add(same, `{"password":"hunter2","username":"same"}`))
+	ctx := &context.Context{
+		Logger:            log.New(),
+		DirWriter:         storage.NewMemory(),
+		ImportCFClient:    client,
+		Secrets:           s,
+		ServiceKeyChanges: report.NewServiceKeyChanges(&bytes.Buffer{}),
+	}
+	require.NoError(t, export.WriteSpaceServiceKeys(ctx, "my_org", "my_space", []export.ServiceInstanceKeys{
+		{ServiceInstance: "my-db", Keys: []export.ServiceKey{
+			{Name: "ci", Credentials: ci.Name()},
+			{Name: "pipeline", Parameters: map[string]interface{}{"role": "writer"}},
+			{Name: "same", Credentials: same.Name()},
+		}},
+		{ServiceInstance: "my-queue", Keys: []export.ServiceKey{{Name: "consumer"}}},
+	}))
+
+	err := importSpaceServiceKeys(ctx, "my_org", "my_space")
+	assert.EqualError(t, err, "service key consumer of service my-queue: service instance my-queue does not exist in the target space")
+	require.NoError(t, importSpaceServiceKeys(ctx, "my_org", "space_without_export"))
+
+	assert.Equal(t, []string{
+		`POST /v3/service_credential_bindings {"type":"key","name":"pipeline","parameters":{"role":"writer"},"relationships":{"service_instance":{"data":{"guid":"db-guid"}}}}`,
+	}, requests)
+	assert.Equal(t, []report.ServiceKeyChange{
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-db", Name: "ci", Change: "credentials differ"},
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-db", Name: "pipeline", Change: "created"},
+	}, ctx.ServiceKeyChanges.Changes())
+}
@@ -0,0 +1,175 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+)
+
+// Strategies for giving an imported app a droplet
+const (
+	// ImportDroplet uploads the exported droplet
+	ImportDroplet = "droplet"
+	// ImportRestage uploads the exported package and stages it on the target foundation
+	ImportRestage = "restage"
+	// ImportAuto restages the apps whose stack was mapped to another one or is missing on the target foundation,
+	// and uploads the droplet of the others
+	ImportAuto = "auto"
+)
+
+// ImportStrategies lists every supported import strategy
+var ImportStrategies = []string{ImportDroplet, ImportRestage, ImportAuto}
+
+// IsValidImportStrategy tells whether strategy is one of ImportStrategies
+func IsValidImportStrategy(strategy string) bool {
+	for _, s := range ImportStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// StackNotFoundError is returned when the stack of an app does not exist on the target foundation and the app cannot
+// be restaged on another one
+type StackNotFoundError struct {
+	App   string
+	Stack string
+}
+
+func (e *StackNotFoundError) Error() string {
+	return fmt.Sprintf("stack %s of app %s does not exist on the target foundation, map it to another stack with --stack-mappings or restage the app with --import-strategy=%s", e.Stack, e.App, ImportAuto)
+}
+
+// resolveStack maps the exported stack of the app to the stack it gets on the target foundation, and returns the guid
+// of that stack. The guid is empty when the app gets the default stack of the target foundation, which is the case
+// when the stack is missing there and the app is restaged.
+func (i *ImportApp) resolveStack(ctx *appcontext.Context, stack string) (string, error) {
+	i.sourceStack = stack
+	i.stack = stack
+	if target, ok := ctx.StackMappings[stack]; ok {
+		i.stack = target
+	}
+	if i.stack == "" {
+		return "", nil
+	}
+
+	guid, err := cache.GetCache(ctx.ImportCFClient).GetStackGUIDByName(i.stack)
+	if err == nil || !cache.IsNotFound(err) {
+		return guid, err
+	}
+
+	if ctx.ImportStrategy == "" || ctx.ImportStrategy == ImportDroplet {
+		return "", &StackNotFoundError{App: i.AppName, Stack: i.stack}
+	}
+
+	ctx.Logger.Warnf("Stack %s does not exist on the target foundation, app %s/%s/%s is staged on the default stack", i.stack, i.Org, i.Space, i.AppName)
+	i.stack = ""
+	i.stackMissing = true
+	return "", nil
+}
+
+// restage tells whether the app is staged on the target foundation rather than imported with its droplet. info
+// describes the exported droplet, it is nil for exports that have no droplet info.
+func (i *ImportApp) restage(ctx *appcontext.Context, info *export.DropletInfo) bool {
+	switch ctx.ImportStrategy {
+	case ImportRestage:
+		return true
+	case ImportAuto:
+		dropletStack := i.sourceStack
+		if info != nil && info.Stack != "" {
+			dropletStack = info.Stack
+		}
+		return i.stackMissing || (dropletStack != "" && i.stack != dropletStack)
+	}
+	return false
+}
+
+// stageApp stages the latest package of the app and makes the resulting droplet the current one
+func (i *ImportApp) stageApp(c *appcontext.Context) error {
+	params := url.Values{
+		"states":   []string{"READY"},
+		"types":    []string{"bits"},
+		"order_by": []string{"-created_at"},
+	}
+	body, err := c.ImportCFClient.Get(fmt.Sprintf("/v3/apps/%s/packages?%s", i.appGUID, params.Encode()))
+	if err != nil {
+		return err
+	}
+
+	var packages struct {
+		Resources []cfclient.V3Package `json:"resources"`
+	}
+	if err = json.Unmarshal(body, &packages); err != nil {
+		return err
+	}
+	if len(packages.Resources) == 0 {
+		return fmt.Errorf("app %s has no package to stage", i.AppName)
+	}
+
+	var build cfclient.V3Build
+	err = c.ImportCFClient.DoWithRetry(func() error {
+		var err error
+		build, err = cf.CreateBuild(c.ImportCFClient, packages.Resources[0].GUID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = waitUntil(15*time.Minute, "app to be staged", func() (bool, error) {
+		build, err = cf.GetBuild(c.ImportCFClient, build.GUID)
+		if err != nil {
+			return false, err
+		}
+		if build.State == "FAILED" {
+			return false, fmt.Errorf("staging app %s failed: %s", i.AppName, build.Error)
+		}
+		return build.State == "STAGED", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.ImportCFClient.DoWithRetry(func() error {
+		return cf.SetCurrentDroplet(c.ImportCFClient, i.appGUID, build.Droplet.GUID)
+	})
+}
+
+// waitUntil polls done every 5 seconds until it returns true or an error, or until the timeout
+func waitUntil(timeout time.Duration, what string, done func() (bool, error)) error {
+	deadline := time.After(timeout)
+	for {
+		ok, err := done()
+		if err != nil || ok {
+			return err
+		}
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-deadline:
+			return fmt.Errorf("timed out waiting for %s", what)
+		}
+	}
+}
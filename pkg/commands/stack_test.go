@@ -0,0 +1,222 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/export"
+)
+
+func TestImportApp_resolveStack(t *testing.T) {
+	tests := []struct {
+		name         string
+		stack        string
+		mappings     map[string]string
+		strategy     string
+		wantGUID     string
+		wantStack    string
+		wantMissing  bool
+		wantNotFound bool
+	}{
+		{
+			name:      "keeps the exported stack",
+			stack:     "cflinuxfs3",
+			strategy:  ImportDroplet,
+			wantGUID:  "cflinuxfs3-guid",
+			wantStack: "cflinuxfs3",
+		},
+		{
+			name:      "maps the exported stack",
+			stack:     "cflinuxfs3",
+			mappings:  map[string]string{"cflinuxfs3": "cflinuxfs4"},
+			strategy:  ImportDroplet,
+			wantGUID:  "cflinuxfs4-guid",
+			wantStack: "cflinuxfs4",
+		},
+		{
+			name:         "fails when the stack is missing and the droplet is uploaded",
+			stack:        "windows2016",
+			strategy:     ImportDroplet,
+			wantStack:    "windows2016",
+			wantNotFound: true,
+		},
+		{
+			name:        "uses the default stack when the stack is missing and the app is restaged",
+			stack:       "windows2016",
+			strategy:    ImportAuto,
+			wantMissing: true,
+		},
+		{
+			name:     "leaves the stack to the target when the app has none",
+			strategy: ImportAuto,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				cache.Cache = nil
+			})
+			client := &fakes.FakeClient{
+				DoWithRetryStub: func(f func() error) error {
+					return f()
+				},
+				ListStacksByQueryStub: func(params url.Values) ([]cfclient.Stack, error) {
+					name := strings.TrimPrefix(params.Get("q"), "name:")
+					if strings.HasPrefix(name, "cflinuxfs") {
+						return []cfclient.Stack{{Guid: name + "-guid", Name: name}}, nil
+					}
+					return nil, nil
+				},
+			}
+			i := &ImportApp{AppName: "my_app"}
+			guid, err := i.resolveStack(&context.Context{
+				Logger:         log.New(),
+				ImportCFClient: client,
+				StackMappings:  tt.mappings,
+				ImportStrategy: tt.strategy,
+			}, tt.stack)
+
+			var notFound *StackNotFoundError
+			require.Equal(t, tt.wantNotFound, errors.As(err, &notFound), "unexpected error %v", err)
+			if !tt.wantNotFound {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantGUID, guid)
+			assert.Equal(t, tt.wantStack, i.stack)
+			assert.Equal(t, tt.stack, i.sourceStack)
+			assert.Equal(t, tt.wantMissing, i.stackMissing)
+		})
+	}
+}
+
+func TestImportApp_restage(t *testing.T) {
+	tests := []struct {
+		name     string
+		app      ImportApp
+		strategy string
+		info     *export.DropletInfo
+		want     bool
+	}{
+		{
+			name:     "uploads the droplet by default",
+			app:      ImportApp{sourceStack: "cflinuxfs3", stack: "cflinuxfs4"},
+			strategy: "",
+		},
+		{
+			name:     "uploads the droplet",
+			app:      ImportApp{sourceStack: "cflinuxfs3", stack: "cflinuxfs4"},
+			strategy: ImportDroplet,
+		},
+		{
+			name:     "always restages",
+			app:      ImportApp{sourceStack: "cflinuxfs4", stack: "cflinuxfs4"},
+			strategy: ImportRestage,
+			want:     true,
+		},
+		{
+			name:     "restages when the stack was mapped",
+			app:      ImportApp{sourceStack: "cflinuxfs3", stack: "cflinuxfs4"},
+			strategy: ImportAuto,
+			want:     true,
+		},
+		{
+			name:     "restages when the stack is missing",
+			app:      ImportApp{sourceStack: "windows2016", stackMissing: true},
+			strategy: ImportAuto,
+			want:     true,
+		},
+		{
+			name:     "restages when the droplet was built on another stack than the manifest says",
+			app:      ImportApp{sourceStack: "cflinuxfs4", stack: "cflinuxfs4"},
+			strategy: ImportAuto,
+			info:     &export.DropletInfo{Stack: "cflinuxfs3"},
+			want:     true,
+		},
+		{
+			name:     "uploads the droplet when the stack is unchanged",
+			app:      ImportApp{sourceStack: "cflinuxfs3", stack: "cflinuxfs3"},
+			strategy: ImportAuto,
+			info:     &export.DropletInfo{Stack: "cflinuxfs3"},
+		},
+		{
+			name:     "uploads the droplet when the stack is not known",
+			strategy: ImportAuto,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.app.restage(&context.Context{ImportStrategy: tt.strategy}, tt.info))
+		})
+	}
+}
+
+func TestImportApp_stageApp(t *testing.T) {
+	requests := map[*cfclient.Request]string{}
+	var staged, current string
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(url string) ([]byte, error) {
+			switch {
+			case strings.HasPrefix(url, "/v3/apps/6064d98a-95e6-400b-bc03-be65e6d59622/packages?"):
+				return []byte(`{"resources":[{"guid":"new-package","type":"bits","state":"READY"},{"guid":"old-package","type":"bits","state":"READY"}]}`), nil
+			case url == "/v3/builds/new-build":
+				return []byte(`{"guid":"new-build","state":"STAGED","droplet":{"guid":"new-droplet"}}`), nil
+			}
+			return nil, errors.New("unexpected request " + url)
+		},
+		NewRequestWithBodyStub: func(method, path string, body io.Reader) *cfclient.Request {
+			req := &cfclient.Request{}
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			requests[req] = method + " " + path
+			switch path {
+			case "/v3/builds":
+				staged = string(data)
+			case "/v3/apps/6064d98a-95e6-400b-bc03-be65e6d59622/relationships/current_droplet":
+				current = string(data)
+			}
+			return req
+		},
+		DoRequestStub: func(req *cfclient.Request) (*http.Response, error) {
+			if requests[req] == "POST /v3/builds" {
+				return &http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       io.NopCloser(strings.NewReader(`{"guid":"new-build","state":"STAGING"}`)),
+				}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		},
+	}
+
+	i := &ImportApp{AppName: "my_app", appGUID: "6064d98a-95e6-400b-bc03-be65e6d59622"}
+	require.NoError(t, i.stageApp(&context.Context{Logger: log.New(), ImportCFClient: client}))
+	assert.JSONEq(t, `{"package":{"guid":"new-package"}}`, staged)
+	assert.JSONEq(t, `{"data":{"guid":"new-droplet"}}`, current)
+}
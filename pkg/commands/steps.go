@@ -0,0 +1,43 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+// step moves one kind of resource of an org or space along with its apps, such as its roles
+type step struct {
+	kind string
+	run  func() error
+}
+
+// runSteps runs every step of the org, or of the space when it is given, in turn. The steps that fail are logged and
+// reported with the other resources, they do not keep the next steps or the apps from running.
+func runSteps(ctx *context.Context, verb, org, space string, steps ...step) {
+	for _, s := range steps {
+		err := s.run()
+		if err == nil {
+			continue
+		}
+
+		if space == "" {
+			ctx.Logger.Errorf("Error %s the %s of org %s: %s", verb, s.kind, org, err)
+		} else {
+			ctx.Logger.Errorf("Error %s the %s of space %s/%s: %s", verb, s.kind, org, space, err)
+		}
+		ctx.Resources.AddFailed(s.kind, org, space, "", err)
+	}
+}
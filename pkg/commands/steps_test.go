@@ -0,0 +1,50 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+)
+
+func TestRunSteps(t *testing.T) {
+	ctx := &context.Context{
+		Logger:    log.New(),
+		Resources: report.NewResources(&bytes.Buffer{}),
+	}
+
+	var ran []string
+	runSteps(ctx, "importing", "my_org", "my_space",
+		step{"roles", func() error {
+			ran = append(ran, "roles")
+			return errors.New("forbidden")
+		}},
+		step{"service keys", func() error {
+			ran = append(ran, "service keys")
+			return nil
+		}},
+	)
+
+	assert.Equal(t, []string{"roles", "service keys"}, ran)
+	assert.Equal(t, []report.Resource{
+		{Kind: "roles", Org: "my_org", Space: "my_space", Status: report.ResourceFailed, Message: "forbidden"},
+	}, ctx.Resources.Resources())
+}
@@ -15,14 +15,25 @@
 package context
 
 import (
+	"io"
+	"io/fs"
 	"os"
+	"time"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 	log "github.com/sirupsen/logrus"
 	"github.com/vbauerster/mpb/v7"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/bundle"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/datamover"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/journal"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/metadata"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/report"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/transform"
 )
 
 // You only need **one** of these per package
@@ -30,10 +41,17 @@ import (
 
 //counterfeiter:generate -o fakes . DirWriter
 
+// DirWriter stores the files of an export, names are slash separated and relative to the export dir
 type DirWriter interface {
 	Mkdir(dir string) error
 	MkdirAll(dir string, perm os.FileMode) error
 	IsEmpty(name string) (bool, error)
+	// Put creates or replaces the named file with everything written to the returned writer, which must be closed
+	Put(name string, perm os.FileMode) (io.WriteCloser, error)
+	Get(name string) (io.ReadCloser, error)
+	Stat(name string) (fs.FileInfo, error)
+	List(dir string) ([]fs.DirEntry, error)
+	Delete(name string) error
 }
 
 //counterfeiter:generate -o fakes . CommandRunner
@@ -86,8 +104,11 @@ type SpaceExporter interface {
 
 type DropletExporter interface {
 	NumberOfPackages(ctx *Context, app cfclient.App) (float64, error)
-	DownloadDroplet(ctx *Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error
-	DownloadPackages(ctx *Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error
+	// DownloadBlobs writes the current droplet of an app and the package it was staged from to the export
+	DownloadBlobs(ctx *Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error
+	// OpenDroplet and OpenPackage stream the blobs of an app, along with their size or -1 when it is not known
+	OpenDroplet(ctx *Context, app cfclient.App) (io.ReadCloser, int64, error)
+	OpenPackage(ctx *Context, app cfclient.App) (io.ReadCloser, int64, error)
 }
 
 //counterfeiter:generate -o fakes . ManifestExporter
@@ -141,9 +162,54 @@ type Context struct {
 	DomainsToAdd       []string
 	DomainsToReplace   map[string]string
 	DropletCountToKeep int
+	StreamBufferSize   int
+	TeeExport          bool
+	DedupeBlobs        bool
 	ConcurrencyLimit   int
+	OnConflict         string
+	ConflictSuffix     string
+	UpdateUnownedApps  bool
+	StackMappings      map[string]string
+	BuildpackMappings  map[string]string
+	OriginMappings     map[string]string
+	SegmentMappings    map[string]string
+	ServiceMappings    mapping.Services
+	DataMovers         map[string]string
+	MoveRunningAppData bool
+	DataMoverTimeout   time.Duration
+	MovedInstances     *datamover.Instances
+	SecurityGroups     bool
+	SharedDomains      bool
+	ImportStrategy     string
+	NameMappingFile    string
+	NameMapping        *mapping.Names
+	EnvTransformFile   string
+	VarsFiles          []string
+	EnvTransform       *transform.Env
+	ExternalizeSecrets bool
+	SecretKeyPatterns  []string
+	SecretMinEntropy   float64
+	SecretsKeyFile     string
+	SecretsFromEnv     bool
+	Secrets            *secrets.Secrets
+	EncryptExport      bool
+	EncryptionKeyFile  string
+	Recipient          string
+	IdentityFile       string
+	Sealer             crypt.Sealer
+	Opener             crypt.Opener
+	BundleFile         string
+	Bundle             *bundle.Archive
 	Metadata           *metadata.Metadata
+	Journal            *journal.Journal
 	Summary            *report.Summary
+	Resources          *report.Resources
+	Preflight          *report.Preflight
+	ServicePlanChecks  *report.ServicePlanChecks
+	MissingUsers       *report.MissingUsers
+	SecurityGroupDiffs *report.SecurityGroupDiffs
+	ServiceKeyChanges  *report.ServiceKeyChanges
+	DataMoves          *report.DataMoves
 	ExportCFClient     cf.Client
 	ImportCFClient     cf.Client
 	SpaceImporter      SpaceImporter
@@ -156,6 +222,17 @@ type Context struct {
 	DisplayProgress    bool
 }
 
+// ExportFS returns the files of the export being imported, read from the bundle when one was given
+func (ctx *Context) ExportFS() fs.FS {
+	if ctx.Bundle != nil {
+		return ctx.Bundle
+	}
+	if ctx.DirWriter != nil {
+		return storage.NewFS(ctx.DirWriter)
+	}
+	return os.DirFS(ctx.ExportDir)
+}
+
 func (ctx *Context) InitLogger() {
 	logger := log.New()
 	ctx.Logger = logger
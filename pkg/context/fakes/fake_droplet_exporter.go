@@ -16,6 +16,7 @@
 package fakes
 
 import (
+	"io"
 	"sync"
 
 	cfclient "github.com/cloudfoundry-community/go-cfclient"
@@ -23,34 +24,19 @@ import (
 )
 
 type FakeDropletExporter struct {
-	DownloadDropletStub        func(*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) error
-	downloadDropletMutex       sync.RWMutex
-	downloadDropletArgsForCall []struct {
+	DownloadBlobsStub        func(*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) error
+	downloadBlobsMutex       sync.RWMutex
+	downloadBlobsArgsForCall []struct {
 		arg1 *context.Context
 		arg2 cfclient.Org
 		arg3 cfclient.Space
 		arg4 cfclient.App
 		arg5 string
 	}
-	downloadDropletReturns struct {
+	downloadBlobsReturns struct {
 		result1 error
 	}
-	downloadDropletReturnsOnCall map[int]struct {
-		result1 error
-	}
-	DownloadPackagesStub        func(*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) error
-	downloadPackagesMutex       sync.RWMutex
-	downloadPackagesArgsForCall []struct {
-		arg1 *context.Context
-		arg2 cfclient.Org
-		arg3 cfclient.Space
-		arg4 cfclient.App
-		arg5 string
-	}
-	downloadPackagesReturns struct {
-		result1 error
-	}
-	downloadPackagesReturnsOnCall map[int]struct {
+	downloadBlobsReturnsOnCall map[int]struct {
 		result1 error
 	}
 	NumberOfPackagesStub        func(*context.Context, cfclient.App) (float64, error)
@@ -67,89 +53,56 @@ type FakeDropletExporter struct {
 		result1 float64
 		result2 error
 	}
-	invocations      map[string][][]interface{}
-	invocationsMutex sync.RWMutex
-}
-
-func (fake *FakeDropletExporter) DownloadDroplet(arg1 *context.Context, arg2 cfclient.Org, arg3 cfclient.Space, arg4 cfclient.App, arg5 string) error {
-	fake.downloadDropletMutex.Lock()
-	ret, specificReturn := fake.downloadDropletReturnsOnCall[len(fake.downloadDropletArgsForCall)]
-	fake.downloadDropletArgsForCall = append(fake.downloadDropletArgsForCall, struct {
+	OpenDropletStub        func(*context.Context, cfclient.App) (io.ReadCloser, int64, error)
+	openDropletMutex       sync.RWMutex
+	openDropletArgsForCall []struct {
 		arg1 *context.Context
-		arg2 cfclient.Org
-		arg3 cfclient.Space
-		arg4 cfclient.App
-		arg5 string
-	}{arg1, arg2, arg3, arg4, arg5})
-	stub := fake.DownloadDropletStub
-	fakeReturns := fake.downloadDropletReturns
-	fake.recordInvocation("DownloadDroplet", []interface{}{arg1, arg2, arg3, arg4, arg5})
-	fake.downloadDropletMutex.Unlock()
-	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4, arg5)
+		arg2 cfclient.App
 	}
-	if specificReturn {
-		return ret.result1
+	openDropletReturns struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
 	}
-	return fakeReturns.result1
-}
-
-func (fake *FakeDropletExporter) DownloadDropletCallCount() int {
-	fake.downloadDropletMutex.RLock()
-	defer fake.downloadDropletMutex.RUnlock()
-	return len(fake.downloadDropletArgsForCall)
-}
-
-func (fake *FakeDropletExporter) DownloadDropletCalls(stub func(*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) error) {
-	fake.downloadDropletMutex.Lock()
-	defer fake.downloadDropletMutex.Unlock()
-	fake.DownloadDropletStub = stub
-}
-
-func (fake *FakeDropletExporter) DownloadDropletArgsForCall(i int) (*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) {
-	fake.downloadDropletMutex.RLock()
-	defer fake.downloadDropletMutex.RUnlock()
-	argsForCall := fake.downloadDropletArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
-}
-
-func (fake *FakeDropletExporter) DownloadDropletReturns(result1 error) {
-	fake.downloadDropletMutex.Lock()
-	defer fake.downloadDropletMutex.Unlock()
-	fake.DownloadDropletStub = nil
-	fake.downloadDropletReturns = struct {
-		result1 error
-	}{result1}
-}
-
-func (fake *FakeDropletExporter) DownloadDropletReturnsOnCall(i int, result1 error) {
-	fake.downloadDropletMutex.Lock()
-	defer fake.downloadDropletMutex.Unlock()
-	fake.DownloadDropletStub = nil
-	if fake.downloadDropletReturnsOnCall == nil {
-		fake.downloadDropletReturnsOnCall = make(map[int]struct {
-			result1 error
-		})
+	openDropletReturnsOnCall map[int]struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
 	}
-	fake.downloadDropletReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+	OpenPackageStub        func(*context.Context, cfclient.App) (io.ReadCloser, int64, error)
+	openPackageMutex       sync.RWMutex
+	openPackageArgsForCall []struct {
+		arg1 *context.Context
+		arg2 cfclient.App
+	}
+	openPackageReturns struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
+	}
+	openPackageReturnsOnCall map[int]struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeDropletExporter) DownloadPackages(arg1 *context.Context, arg2 cfclient.Org, arg3 cfclient.Space, arg4 cfclient.App, arg5 string) error {
-	fake.downloadPackagesMutex.Lock()
-	ret, specificReturn := fake.downloadPackagesReturnsOnCall[len(fake.downloadPackagesArgsForCall)]
-	fake.downloadPackagesArgsForCall = append(fake.downloadPackagesArgsForCall, struct {
+func (fake *FakeDropletExporter) DownloadBlobs(arg1 *context.Context, arg2 cfclient.Org, arg3 cfclient.Space, arg4 cfclient.App, arg5 string) error {
+	fake.downloadBlobsMutex.Lock()
+	ret, specificReturn := fake.downloadBlobsReturnsOnCall[len(fake.downloadBlobsArgsForCall)]
+	fake.downloadBlobsArgsForCall = append(fake.downloadBlobsArgsForCall, struct {
 		arg1 *context.Context
 		arg2 cfclient.Org
 		arg3 cfclient.Space
 		arg4 cfclient.App
 		arg5 string
 	}{arg1, arg2, arg3, arg4, arg5})
-	stub := fake.DownloadPackagesStub
-	fakeReturns := fake.downloadPackagesReturns
-	fake.recordInvocation("DownloadPackages", []interface{}{arg1, arg2, arg3, arg4, arg5})
-	fake.downloadPackagesMutex.Unlock()
+	stub := fake.DownloadBlobsStub
+	fakeReturns := fake.downloadBlobsReturns
+	fake.recordInvocation("DownloadBlobs", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.downloadBlobsMutex.Unlock()
 	if stub != nil {
 		return stub(arg1, arg2, arg3, arg4, arg5)
 	}
@@ -159,44 +112,44 @@ func (fake *FakeDropletExporter) DownloadPackages(arg1 *context.Context, arg2 cf
 	return fakeReturns.result1
 }
 
-func (fake *FakeDropletExporter) DownloadPackagesCallCount() int {
-	fake.downloadPackagesMutex.RLock()
-	defer fake.downloadPackagesMutex.RUnlock()
-	return len(fake.downloadPackagesArgsForCall)
+func (fake *FakeDropletExporter) DownloadBlobsCallCount() int {
+	fake.downloadBlobsMutex.RLock()
+	defer fake.downloadBlobsMutex.RUnlock()
+	return len(fake.downloadBlobsArgsForCall)
 }
 
-func (fake *FakeDropletExporter) DownloadPackagesCalls(stub func(*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) error) {
-	fake.downloadPackagesMutex.Lock()
-	defer fake.downloadPackagesMutex.Unlock()
-	fake.DownloadPackagesStub = stub
+func (fake *FakeDropletExporter) DownloadBlobsCalls(stub func(*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) error) {
+	fake.downloadBlobsMutex.Lock()
+	defer fake.downloadBlobsMutex.Unlock()
+	fake.DownloadBlobsStub = stub
 }
 
-func (fake *FakeDropletExporter) DownloadPackagesArgsForCall(i int) (*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) {
-	fake.downloadPackagesMutex.RLock()
-	defer fake.downloadPackagesMutex.RUnlock()
-	argsForCall := fake.downloadPackagesArgsForCall[i]
+func (fake *FakeDropletExporter) DownloadBlobsArgsForCall(i int) (*context.Context, cfclient.Org, cfclient.Space, cfclient.App, string) {
+	fake.downloadBlobsMutex.RLock()
+	defer fake.downloadBlobsMutex.RUnlock()
+	argsForCall := fake.downloadBlobsArgsForCall[i]
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
-func (fake *FakeDropletExporter) DownloadPackagesReturns(result1 error) {
-	fake.downloadPackagesMutex.Lock()
-	defer fake.downloadPackagesMutex.Unlock()
-	fake.DownloadPackagesStub = nil
-	fake.downloadPackagesReturns = struct {
+func (fake *FakeDropletExporter) DownloadBlobsReturns(result1 error) {
+	fake.downloadBlobsMutex.Lock()
+	defer fake.downloadBlobsMutex.Unlock()
+	fake.DownloadBlobsStub = nil
+	fake.downloadBlobsReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeDropletExporter) DownloadPackagesReturnsOnCall(i int, result1 error) {
-	fake.downloadPackagesMutex.Lock()
-	defer fake.downloadPackagesMutex.Unlock()
-	fake.DownloadPackagesStub = nil
-	if fake.downloadPackagesReturnsOnCall == nil {
-		fake.downloadPackagesReturnsOnCall = make(map[int]struct {
+func (fake *FakeDropletExporter) DownloadBlobsReturnsOnCall(i int, result1 error) {
+	fake.downloadBlobsMutex.Lock()
+	defer fake.downloadBlobsMutex.Unlock()
+	fake.DownloadBlobsStub = nil
+	if fake.downloadBlobsReturnsOnCall == nil {
+		fake.downloadBlobsReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.downloadPackagesReturnsOnCall[i] = struct {
+	fake.downloadBlobsReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
@@ -266,15 +219,153 @@ func (fake *FakeDropletExporter) NumberOfPackagesReturnsOnCall(i int, result1 fl
 	}{result1, result2}
 }
 
+func (fake *FakeDropletExporter) OpenDroplet(arg1 *context.Context, arg2 cfclient.App) (io.ReadCloser, int64, error) {
+	fake.openDropletMutex.Lock()
+	ret, specificReturn := fake.openDropletReturnsOnCall[len(fake.openDropletArgsForCall)]
+	fake.openDropletArgsForCall = append(fake.openDropletArgsForCall, struct {
+		arg1 *context.Context
+		arg2 cfclient.App
+	}{arg1, arg2})
+	stub := fake.OpenDropletStub
+	fakeReturns := fake.openDropletReturns
+	fake.recordInvocation("OpenDroplet", []interface{}{arg1, arg2})
+	fake.openDropletMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeDropletExporter) OpenDropletCallCount() int {
+	fake.openDropletMutex.RLock()
+	defer fake.openDropletMutex.RUnlock()
+	return len(fake.openDropletArgsForCall)
+}
+
+func (fake *FakeDropletExporter) OpenDropletCalls(stub func(*context.Context, cfclient.App) (io.ReadCloser, int64, error)) {
+	fake.openDropletMutex.Lock()
+	defer fake.openDropletMutex.Unlock()
+	fake.OpenDropletStub = stub
+}
+
+func (fake *FakeDropletExporter) OpenDropletArgsForCall(i int) (*context.Context, cfclient.App) {
+	fake.openDropletMutex.RLock()
+	defer fake.openDropletMutex.RUnlock()
+	argsForCall := fake.openDropletArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDropletExporter) OpenDropletReturns(result1 io.ReadCloser, result2 int64, result3 error) {
+	fake.openDropletMutex.Lock()
+	defer fake.openDropletMutex.Unlock()
+	fake.OpenDropletStub = nil
+	fake.openDropletReturns = struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeDropletExporter) OpenDropletReturnsOnCall(i int, result1 io.ReadCloser, result2 int64, result3 error) {
+	fake.openDropletMutex.Lock()
+	defer fake.openDropletMutex.Unlock()
+	fake.OpenDropletStub = nil
+	if fake.openDropletReturnsOnCall == nil {
+		fake.openDropletReturnsOnCall = make(map[int]struct {
+			result1 io.ReadCloser
+			result2 int64
+			result3 error
+		})
+	}
+	fake.openDropletReturnsOnCall[i] = struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeDropletExporter) OpenPackage(arg1 *context.Context, arg2 cfclient.App) (io.ReadCloser, int64, error) {
+	fake.openPackageMutex.Lock()
+	ret, specificReturn := fake.openPackageReturnsOnCall[len(fake.openPackageArgsForCall)]
+	fake.openPackageArgsForCall = append(fake.openPackageArgsForCall, struct {
+		arg1 *context.Context
+		arg2 cfclient.App
+	}{arg1, arg2})
+	stub := fake.OpenPackageStub
+	fakeReturns := fake.openPackageReturns
+	fake.recordInvocation("OpenPackage", []interface{}{arg1, arg2})
+	fake.openPackageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeDropletExporter) OpenPackageCallCount() int {
+	fake.openPackageMutex.RLock()
+	defer fake.openPackageMutex.RUnlock()
+	return len(fake.openPackageArgsForCall)
+}
+
+func (fake *FakeDropletExporter) OpenPackageCalls(stub func(*context.Context, cfclient.App) (io.ReadCloser, int64, error)) {
+	fake.openPackageMutex.Lock()
+	defer fake.openPackageMutex.Unlock()
+	fake.OpenPackageStub = stub
+}
+
+func (fake *FakeDropletExporter) OpenPackageArgsForCall(i int) (*context.Context, cfclient.App) {
+	fake.openPackageMutex.RLock()
+	defer fake.openPackageMutex.RUnlock()
+	argsForCall := fake.openPackageArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDropletExporter) OpenPackageReturns(result1 io.ReadCloser, result2 int64, result3 error) {
+	fake.openPackageMutex.Lock()
+	defer fake.openPackageMutex.Unlock()
+	fake.OpenPackageStub = nil
+	fake.openPackageReturns = struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeDropletExporter) OpenPackageReturnsOnCall(i int, result1 io.ReadCloser, result2 int64, result3 error) {
+	fake.openPackageMutex.Lock()
+	defer fake.openPackageMutex.Unlock()
+	fake.OpenPackageStub = nil
+	if fake.openPackageReturnsOnCall == nil {
+		fake.openPackageReturnsOnCall = make(map[int]struct {
+			result1 io.ReadCloser
+			result2 int64
+			result3 error
+		})
+	}
+	fake.openPackageReturnsOnCall[i] = struct {
+		result1 io.ReadCloser
+		result2 int64
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeDropletExporter) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.downloadDropletMutex.RLock()
-	defer fake.downloadDropletMutex.RUnlock()
-	fake.downloadPackagesMutex.RLock()
-	defer fake.downloadPackagesMutex.RUnlock()
+	fake.downloadBlobsMutex.RLock()
+	defer fake.downloadBlobsMutex.RUnlock()
 	fake.numberOfPackagesMutex.RLock()
 	defer fake.numberOfPackagesMutex.RUnlock()
+	fake.openDropletMutex.RLock()
+	defer fake.openDropletMutex.RUnlock()
+	fake.openPackageMutex.RLock()
+	defer fake.openPackageMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,190 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv is the environment variable the passphrase is read from, so that it never appears on the command line
+const PassphraseEnv = "APP_MIGRATOR_PASSPHRASE"
+
+const (
+	modePassphrase byte = 1
+	modeKeyFile    byte = 2
+
+	keySize  = 32
+	saltSize = 16
+)
+
+var magic = []byte("AMC1")
+
+// ErrDecrypt is returned when data cannot be decrypted, because the key is wrong or the data was tampered with
+var ErrDecrypt = errors.New("failed to decrypt, the passphrase or key file is wrong or the data is corrupted")
+
+// Key encrypts and decrypts data with AES-256-GCM, using either a key derived from a passphrase
+// with scrypt, or a key derived from the contents of a key file
+type Key struct {
+	passphrase []byte
+	fileKey    []byte
+	salt       []byte
+	mutex      sync.Mutex
+}
+
+// NewPassphraseKey creates a key from a passphrase, a new salt is used every time data is encrypted
+func NewPassphraseKey(passphrase string) *Key {
+	return &Key{passphrase: []byte(passphrase)}
+}
+
+// NewKeyFromFile creates a key from the contents of a file, e.g. one created with `openssl rand -out key 32`
+func NewKeyFromFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) < keySize/2 {
+		return nil, fmt.Errorf("key file %s is too short, it must hold at least %d bytes", path, keySize/2)
+	}
+
+	key := sha256.Sum256(data)
+	return &Key{fileKey: key[:]}, nil
+}
+
+// NewKey returns the key from the key file when one is given, or from the passphrase in PassphraseEnv,
+// or nil when neither is set
+func NewKey(keyFile string) (*Key, error) {
+	if keyFile != "" {
+		return NewKeyFromFile(keyFile)
+	}
+
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok && passphrase != "" {
+		return NewPassphraseKey(passphrase), nil
+	}
+
+	return nil, nil
+}
+
+// Encrypt seals plaintext, the result holds everything needed to decrypt it apart from the key
+func (k *Key) Encrypt(plaintext []byte) ([]byte, error) {
+	header := append([]byte{}, magic...)
+	var salt []byte
+	if k.fileKey != nil {
+		header = append(header, modeKeyFile)
+	} else {
+		header = append(header, modePassphrase)
+		salt = make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+		header = append(header, salt...)
+	}
+
+	aead, err := k.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// Decrypt opens data sealed by Encrypt with the same passphrase or key file
+func (k *Key) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) || len(data) < len(magic)+1 {
+		return nil, errors.New("data was not encrypted by app-migrator")
+	}
+
+	offset := len(magic)
+	mode := data[offset]
+	offset++
+
+	var salt []byte
+	switch mode {
+	case modeKeyFile:
+		if k.fileKey == nil {
+			return nil, errors.New("data was encrypted with a key file, but a passphrase was given")
+		}
+	case modePassphrase:
+		if k.fileKey != nil {
+			return nil, errors.New("data was encrypted with a passphrase, but a key file was given")
+		}
+		if len(data) < offset+saltSize {
+			return nil, ErrDecrypt
+		}
+		salt = data[offset : offset+saltSize]
+		offset += saltSize
+	default:
+		return nil, fmt.Errorf("unsupported encryption mode %d", mode)
+	}
+
+	aead, err := k.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < offset+aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce := data[offset : offset+aead.NonceSize()]
+	offset += aead.NonceSize()
+
+	plaintext, err := aead.Open(nil, nonce, data[offset:], data[:offset])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
+
+// IsEncrypted reports whether data looks like it was sealed by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+func (k *Key) aead(salt []byte) (cipher.AEAD, error) {
+	key := k.fileKey
+	if key == nil {
+		var err error
+		key, err = scrypt.Key(k.passphrase, salt, 1<<15, 8, 1, keySize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
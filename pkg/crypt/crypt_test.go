@@ -0,0 +1,91 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package crypt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey_EncryptDecrypt(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef\n"), 0600))
+	fileKey, err := NewKeyFromFile(keyFile)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		key      *Key
+		wrongKey *Key
+	}{
+		{
+			name:     "passphrase",
+			key:      NewPassphraseKey("correct horse battery staple"),
+			wrongKey: NewPassphraseKey("wrong"),
+		},
+		{
+			name:     "key file",
+			key:      fileKey,
+			wrongKey: &Key{fileKey: make([]byte, keySize)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := []byte("my secret value")
+			sealed, err := tt.key.Encrypt(plaintext)
+			require.NoError(t, err)
+			assert.True(t, IsEncrypted(sealed))
+			assert.NotContains(t, string(sealed), string(plaintext))
+
+			opened, err := tt.key.Decrypt(sealed)
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, opened)
+
+			_, err = tt.wrongKey.Decrypt(sealed)
+			assert.ErrorIs(t, err, ErrDecrypt)
+
+			sealed[len(sealed)-1] ^= 0xff
+			_, err = tt.key.Decrypt(sealed)
+			assert.ErrorIs(t, err, ErrDecrypt)
+		})
+	}
+}
+
+func TestKey_DecryptWithOtherMode(t *testing.T) {
+	sealed, err := NewPassphraseKey("passphrase").Encrypt([]byte("value"))
+	require.NoError(t, err)
+
+	_, err = (&Key{fileKey: make([]byte, keySize)}).Decrypt(sealed)
+	assert.EqualError(t, err, "data was encrypted with a passphrase, but a key file was given")
+}
+
+func TestNewKey(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	key, err := NewKey("")
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	t.Setenv(PassphraseEnv, "passphrase")
+	key, err = NewKey("")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("passphrase"), key.passphrase)
+
+	_, err = NewKey(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,480 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Files are encrypted as a stream of chunks so that droplets and packages never have to fit in memory. The header holds
+// what is needed to derive the key of the file, and every chunk is sealed with AES-256-GCM using a nonce made of its
+// index and a flag marking the last chunk, so chunks cannot be reordered, dropped or truncated without being detected.
+const (
+	chunkSize = 64 * 1024
+
+	modeRecipient byte = 3
+
+	RecipientPrefix = "amr1"
+	IdentityPrefix  = "AMI1-"
+)
+
+var streamMagic = []byte("AMS1")
+
+// Sealer encrypts the files written to an export
+type Sealer interface {
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// Opener decrypts the files read from an export
+type Opener interface {
+	NewReader(r io.Reader) (io.Reader, error)
+}
+
+// IsEncryptedStream reports whether data starts like a file sealed by a Sealer
+func IsEncryptedStream(data []byte) bool {
+	return bytes.HasPrefix(data, streamMagic)
+}
+
+// StreamMagicSize is the number of bytes needed to recognize an encrypted file
+func StreamMagicSize() int {
+	return len(streamMagic)
+}
+
+// NewWriter encrypts everything written to the returned writer into w, Close must be called to write the last chunk
+func (k *Key) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	header := append([]byte{}, streamMagic...)
+	var master []byte
+	if k.fileKey != nil {
+		header = append(header, modeKeyFile)
+		master = k.fileKey
+	} else {
+		salt, key, err := k.streamPassphraseKey()
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, modePassphrase)
+		header = append(header, salt...)
+		master = key
+	}
+
+	return newStreamWriter(w, header, master)
+}
+
+// NewReader decrypts a file sealed with the same passphrase or key file
+func (k *Key) NewReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	mode, err := readStreamMode(br)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append([]byte{}, streamMagic...), mode)
+	var master []byte
+	switch mode {
+	case modeKeyFile:
+		if k.fileKey == nil {
+			return nil, errors.New("file was encrypted with a key file, but a passphrase was given")
+		}
+		master = k.fileKey
+	case modePassphrase:
+		if k.fileKey != nil {
+			return nil, errors.New("file was encrypted with a passphrase, but a key file was given")
+		}
+		salt := make([]byte, saltSize)
+		if _, err = io.ReadFull(br, salt); err != nil {
+			return nil, ErrDecrypt
+		}
+		header = append(header, salt...)
+		if master, err = k.derivePassphraseKey(salt); err != nil {
+			return nil, err
+		}
+	case modeRecipient:
+		return nil, errors.New("file was encrypted for a recipient, an identity file is needed to decrypt it")
+	default:
+		return nil, fmt.Errorf("unsupported encryption mode %d", mode)
+	}
+
+	return newStreamReader(br, header, master)
+}
+
+var passphraseKeys = struct {
+	sync.Mutex
+	keys map[string][]byte
+}{keys: make(map[string][]byte)}
+
+// streamPassphraseKey returns the salt and key used for every file written with this passphrase, deriving
+// a key with scrypt is slow on purpose so it is only done once per run
+func (k *Key) streamPassphraseKey() ([]byte, []byte, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, nil, err
+		}
+		k.salt = salt
+	}
+
+	key, err := k.derivePassphraseKey(k.salt)
+	return k.salt, key, err
+}
+
+func (k *Key) derivePassphraseKey(salt []byte) ([]byte, error) {
+	sum := sha256.Sum256(append(append([]byte{}, k.passphrase...), salt...))
+	cacheKey := string(sum[:])
+	passphraseKeys.Lock()
+	defer passphraseKeys.Unlock()
+	if key, ok := passphraseKeys.keys[cacheKey]; ok {
+		return key, nil
+	}
+
+	key, err := scrypt.Key(k.passphrase, salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, err
+	}
+	passphraseKeys.keys[cacheKey] = key
+
+	return key, nil
+}
+
+// Recipient is the public half of an identity, files encrypted for a recipient can only be decrypted with its identity
+type Recipient struct {
+	publicKey []byte
+}
+
+// Identity is an X25519 private key used to decrypt the files encrypted for its recipient
+type Identity struct {
+	privateKey []byte
+}
+
+// GenerateIdentity creates a new random identity
+func GenerateIdentity() (*Identity, error) {
+	key := make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return &Identity{privateKey: key}, nil
+}
+
+// LoadIdentity reads an identity file written by String, lines starting with # are ignored
+func LoadIdentity(path string) (*Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := decodeKey(line, IdentityPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity file %s: %w", path, err)
+		}
+		return &Identity{privateKey: key}, nil
+	}
+
+	return nil, fmt.Errorf("no identity found in %s", path)
+}
+
+// String encodes the identity so that it can be written to an identity file
+func (id *Identity) String() string {
+	return IdentityPrefix + base64.RawURLEncoding.EncodeToString(id.privateKey)
+}
+
+// Recipient returns the recipient files must be encrypted for so that this identity can decrypt them
+func (id *Identity) Recipient() (*Recipient, error) {
+	pub, err := curve25519.X25519(id.privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Recipient{publicKey: pub}, nil
+}
+
+// ParseRecipient decodes a recipient returned by Recipient.String
+func ParseRecipient(s string) (*Recipient, error) {
+	key, err := decodeKey(strings.TrimSpace(s), RecipientPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	return &Recipient{publicKey: key}, nil
+}
+
+// String encodes the recipient so that it can be shared
+func (r *Recipient) String() string {
+	return RecipientPrefix + base64.RawURLEncoding.EncodeToString(r.publicKey)
+}
+
+// NewWriter encrypts everything written to the returned writer for the recipient, using a new ephemeral key for every file
+func (r *Recipient) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	ephemeral := make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(rand.Reader, ephemeral); err != nil {
+		return nil, err
+	}
+	ephemeralPub, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := curve25519.X25519(ephemeral, r.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := recipientKey(shared, ephemeralPub, r.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, streamMagic...), modeRecipient), ephemeralPub...)
+	return newStreamWriter(w, header, master)
+}
+
+// NewReader decrypts a file encrypted for the recipient of this identity
+func (id *Identity) NewReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	mode, err := readStreamMode(br)
+	if err != nil {
+		return nil, err
+	}
+	if mode != modeRecipient {
+		return nil, errors.New("file was encrypted with a passphrase or key file, not for a recipient")
+	}
+
+	ephemeralPub := make([]byte, curve25519.PointSize)
+	if _, err = io.ReadFull(br, ephemeralPub); err != nil {
+		return nil, ErrDecrypt
+	}
+
+	recipient, err := id.Recipient()
+	if err != nil {
+		return nil, err
+	}
+	shared, err := curve25519.X25519(id.privateKey, ephemeralPub)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	master, err := recipientKey(shared, ephemeralPub, recipient.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, streamMagic...), modeRecipient), ephemeralPub...)
+	return newStreamReader(br, header, master)
+}
+
+func recipientKey(shared, ephemeralPub, recipientPub []byte) ([]byte, error) {
+	key := make([]byte, keySize)
+	salt := append(append([]byte{}, ephemeralPub...), recipientPub...)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte("app-migrator recipient")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func decodeKey(s, prefix string) ([]byte, error) {
+	if !strings.HasPrefix(s, prefix) {
+		return nil, fmt.Errorf("expected a key starting with %s", prefix)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("expected a key of %d bytes, got %d", curve25519.ScalarSize, len(key))
+	}
+	return key, nil
+}
+
+func readStreamMode(r io.Reader) (byte, error) {
+	prefix := make([]byte, len(streamMagic)+1)
+	if _, err := io.ReadFull(r, prefix); err != nil || !IsEncryptedStream(prefix) {
+		return 0, errors.New("file was not encrypted by app-migrator")
+	}
+	return prefix[len(streamMagic)], nil
+}
+
+// fileAEAD derives a key unique to the file from the master key, the header and a random nonce
+func fileAEAD(master, header, fileNonce []byte) (cipher.AEAD, error) {
+	key := make([]byte, keySize)
+	info := append([]byte("app-migrator stream"), header...)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, fileNonce, info), key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(aead cipher.AEAD, index uint64, last bool) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce, index)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+type streamWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	buf   []byte
+	index uint64
+	err   error
+}
+
+func newStreamWriter(w io.Writer, header, master []byte) (io.WriteCloser, error) {
+	fileNonce := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, fileNonce); err != nil {
+		return nil, err
+	}
+
+	aead, err := fileAEAD(master, header, fileNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(append(header, fileNonce...)); err != nil {
+		return nil, err
+	}
+
+	return &streamWriter{w: w, aead: aead, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	n := 0
+	for len(p) > 0 {
+		// a full chunk is only flushed once more data arrives, so that the last chunk is always sealed by Close
+		if len(s.buf) == chunkSize {
+			if s.err = s.flush(false); s.err != nil {
+				return n, s.err
+			}
+		}
+		c := copy(s.buf[len(s.buf):chunkSize], p)
+		s.buf = s.buf[:len(s.buf)+c]
+		p = p[c:]
+		n += c
+	}
+
+	return n, nil
+}
+
+func (s *streamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.err = s.flush(true)
+	if s.err != nil {
+		return s.err
+	}
+	s.err = errors.New("write to closed encrypted stream")
+	return nil
+}
+
+func (s *streamWriter) flush(last bool) error {
+	sealed := s.aead.Seal(nil, chunkNonce(s.aead, s.index, last), s.buf, nil)
+	s.index++
+	s.buf = s.buf[:0]
+	_, err := s.w.Write(sealed)
+	return err
+}
+
+type streamReader struct {
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	chunk []byte
+	buf   []byte
+	index uint64
+	done  bool
+}
+
+func newStreamReader(r *bufio.Reader, header, master []byte) (io.Reader, error) {
+	fileNonce := make([]byte, saltSize)
+	if _, err := io.ReadFull(r, fileNonce); err != nil {
+		return nil, ErrDecrypt
+	}
+
+	aead, err := fileAEAD(master, header, fileNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamReader{r: r, aead: aead, chunk: make([]byte, chunkSize+aead.Overhead())}, nil
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *streamReader) next() error {
+	n, err := io.ReadFull(s.r, s.chunk)
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		// a short chunk must be the last one
+		s.done = true
+	case err != nil:
+		return err
+	default:
+		// a full chunk is the last one when nothing follows it
+		if _, peekErr := s.r.Peek(1); peekErr == io.EOF {
+			s.done = true
+		}
+	}
+
+	plaintext, err := s.aead.Open(nil, chunkNonce(s.aead, s.index, s.done), s.chunk[:n], nil)
+	if err != nil {
+		return ErrDecrypt
+	}
+	s.index++
+	s.buf = plaintext
+
+	return nil
+}
@@ -0,0 +1,146 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream_RoundTrip(t *testing.T) {
+	identity, err := GenerateIdentity()
+	require.NoError(t, err)
+	recipient, err := identity.Recipient()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		sealer Sealer
+		opener Opener
+	}{
+		{name: "passphrase", sealer: NewPassphraseKey("passphrase"), opener: NewPassphraseKey("passphrase")},
+		{name: "key file", sealer: &Key{fileKey: bytes.Repeat([]byte{1}, keySize)}, opener: &Key{fileKey: bytes.Repeat([]byte{1}, keySize)}},
+		{name: "recipient", sealer: recipient, opener: identity},
+	}
+	sizes := []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 17}
+
+	for _, tt := range tests {
+		for _, size := range sizes {
+			plaintext := make([]byte, size)
+			_, err := rand.Read(plaintext)
+			require.NoError(t, err)
+
+			sealed := seal(t, tt.sealer, plaintext)
+			assert.True(t, IsEncryptedStream(sealed), tt.name)
+
+			opened, err := open(tt.opener, sealed)
+			require.NoError(t, err, "%s with %d bytes", tt.name, size)
+			assert.Equal(t, plaintext, opened, "%s with %d bytes", tt.name, size)
+		}
+	}
+}
+
+func TestStream_DetectsTampering(t *testing.T) {
+	key := NewPassphraseKey("passphrase")
+	plaintext := bytes.Repeat([]byte("droplet"), chunkSize)
+	sealed := seal(t, key, plaintext)
+
+	tests := []struct {
+		name   string
+		sealed []byte
+	}{
+		{name: "truncated at a chunk boundary", sealed: sealed[:len(sealed)-(chunkSize+16)]},
+		{name: "truncated in a chunk", sealed: sealed[:len(sealed)-10]},
+		{name: "modified", sealed: func() []byte {
+			modified := append([]byte{}, sealed...)
+			modified[len(modified)/2] ^= 1
+			return modified
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := open(key, tt.sealed)
+			assert.ErrorIs(t, err, ErrDecrypt)
+		})
+	}
+
+	_, err := open(NewPassphraseKey("wrong"), sealed)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestStream_WrongOpener(t *testing.T) {
+	identity, err := GenerateIdentity()
+	require.NoError(t, err)
+	recipient, err := identity.Recipient()
+	require.NoError(t, err)
+
+	_, err = open(NewPassphraseKey("passphrase"), seal(t, recipient, []byte("data")))
+	assert.EqualError(t, err, "file was encrypted for a recipient, an identity file is needed to decrypt it")
+
+	_, err = open(identity, seal(t, NewPassphraseKey("passphrase"), []byte("data")))
+	assert.EqualError(t, err, "file was encrypted with a passphrase or key file, not for a recipient")
+
+	other, err := GenerateIdentity()
+	require.NoError(t, err)
+	_, err = open(other, seal(t, recipient, []byte("data")))
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestIdentity_SaveLoad(t *testing.T) {
+	identity, err := GenerateIdentity()
+	require.NoError(t, err)
+	recipient, err := identity.Recipient()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "identity")
+	require.NoError(t, os.WriteFile(path, []byte("# recipient: "+recipient.String()+"\n"+identity.String()+"\n"), 0600))
+
+	loaded, err := LoadIdentity(path)
+	require.NoError(t, err)
+	assert.Equal(t, identity.privateKey, loaded.privateKey)
+
+	parsed, err := ParseRecipient(recipient.String())
+	require.NoError(t, err)
+	assert.Equal(t, recipient.publicKey, parsed.publicKey)
+
+	_, err = ParseRecipient("age1notarecipient")
+	assert.EqualError(t, err, "invalid recipient: expected a key starting with amr1")
+}
+
+func seal(t *testing.T, sealer Sealer, plaintext []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := sealer.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write(plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func open(opener Opener, sealed []byte) ([]byte, error) {
+	r, err := opener.NewReader(bytes.NewReader(sealed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,146 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package datamover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
+)
+
+const (
+	// Succeeded is the status of a data mover that moved the data of the service instance
+	Succeeded = "succeeded"
+	// Failed is the status of a data mover that could not move the data of the service instance
+	Failed = "failed"
+	// Skipped is the status of a data mover that was not run, e.g. for an app that was already running on the target
+	Skipped = "skipped"
+)
+
+// Binding is the binding of a service instance to an app on one of the foundations, its credentials give the data
+// mover access to the data of the service instance
+type Binding struct {
+	Org             string                 `json:"org"`
+	Space           string                 `json:"space"`
+	App             string                 `json:"app"`
+	ServiceInstance string                 `json:"service_instance"`
+	Credentials     map[string]interface{} `json:"credentials"`
+}
+
+// Request is written as json to the stdin of a data mover, it moves the data of the service instance of the source
+// binding to the service instance of the target binding
+type Request struct {
+	Offering string  `json:"offering"`
+	Plan     string  `json:"plan"`
+	Source   Binding `json:"source"`
+	Target   Binding `json:"target"`
+}
+
+// Result is read as json from the stdout of a data mover once it exits
+type Result struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Run runs the data mover executable with the request on its stdin and waits for it to exit, it is killed when it
+// does not exit within timeout, unless timeout is 0. An error is returned when it exits with a non zero status or is
+// killed, when its stdout is not a result or when the result is not Succeeded.
+func Run(executable string, req Request, timeout time.Duration) (Result, error) {
+	var result Result
+	input, err := json.Marshal(req)
+	if err != nil {
+		return result, err
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, executable)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("data mover %s did not exit within %s", executable, timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return result, fmt.Errorf("data mover %s exited with status %d: %s", executable, exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return result, fmt.Errorf("could not run data mover %s: %w", executable, err)
+	}
+
+	if err = json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return result, fmt.Errorf("data mover %s did not write a result: %w", executable, err)
+	}
+	if result.Status != Succeeded {
+		return result, fmt.Errorf("data mover %s %s: %s", executable, result.Status, result.Message)
+	}
+
+	return result, nil
+}
+
+// Instances tracks the service instances the data of which is moved, so that the data of an instance bound to several
+// apps is only moved once. It is safe for concurrent use, and a nil Instances lets every app move the data.
+type Instances struct {
+	apps  map[string]string
+	mutex sync.Mutex
+}
+
+// NewInstances creates an empty set of service instances
+func NewInstances() *Instances {
+	return &Instances{
+		apps: make(map[string]string),
+	}
+}
+
+// Claim claims the service instance of the target foundation for the app. It returns false, and the app that claimed
+// the instance, when another app did first.
+func (m *Instances) Claim(org, space, serviceInstance, app string) (string, bool) {
+	if m == nil {
+		return app, true
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := strings.Join([]string{org, space, serviceInstance}, "/")
+	if claimedBy, ok := m.apps[key]; ok && claimedBy != app {
+		return claimedBy, false
+	}
+	m.apps[key] = app
+	return app, true
+}
+
+// CredentialsSecret is the key of the secret holding the credentials of the binding of the service instance to the
+// app on the source foundation
+func CredentialsSecret(org, space, app, serviceInstance string) secrets.Key {
+	return secrets.Key{"binding", org, space, app, serviceInstance}
+}
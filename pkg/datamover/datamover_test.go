@@ -0,0 +1,143 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package datamover
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mover writes a data mover script to a temp dir, it saves its request next to itself
+func mover(t *testing.T, script string) (string, string) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "mover")
+	request := filepath.Join(dir, "request.json")
+	err := os.WriteFile(executable, []byte("#!/bin/sh\ncat > "+request+"\n"+script+"\n"), 0o755)
+	require.NoError(t, err)
+	return executable, request
+}
+
+func TestRun(t *testing.T) {
+	req := Request{
+		Offering: "p.mysql",
+		Plan:     "db-small",
+		Source: Binding{
+			Org:             "org",
+			Space:           "space",
+			App:             "app",
+			ServiceInstance: "db",
+			Credentials:     map[string]interface{}{"uri": "mysql://source"},
+		},
+		Target: Binding{
+			Org:             "org",
+			Space:           "space",
+			App:             "app",
+			ServiceInstance: "db",
+			Credentials:     map[string]interface{}{"uri": "mysql://target"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		script  string
+		want    Result
+		wantErr string
+	}{
+		{
+			name:   "returns the result of the data mover",
+			script: `echo '{"status": "succeeded", "message": "copied 3 tables"}'`,
+			want:   Result{Status: Succeeded, Message: "copied 3 tables"},
+		},
+		{
+			name:    "fails when the data mover fails",
+			script:  `echo '{"status": "failed", "message": "table locked"}'`,
+			want:    Result{Status: Failed, Message: "table locked"},
+			wantErr: "failed: table locked",
+		},
+		{
+			name:    "fails with the stderr of a data mover exiting with a non zero status",
+			script:  "echo 'connection refused' >&2; exit 3",
+			wantErr: "exited with status 3: connection refused",
+		},
+		{
+			name:    "fails when the data mover writes no result",
+			script:  "echo done",
+			wantErr: "did not write a result",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executable, request := mover(t, tt.script)
+
+			got, err := Run(executable, req, time.Minute)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+
+			data, err := os.ReadFile(request)
+			require.NoError(t, err)
+			var gotReq Request
+			require.NoError(t, json.Unmarshal(data, &gotReq))
+			assert.Equal(t, req, gotReq)
+		})
+	}
+}
+
+func TestRun_MissingExecutable(t *testing.T) {
+	_, err := Run(filepath.Join(t.TempDir(), "missing"), Request{}, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "could not run data mover")
+}
+
+func TestRun_Timeout(t *testing.T) {
+	executable, _ := mover(t, "exec sleep 10")
+
+	start := time.Now()
+	_, err := Run(executable, Request{}, 100*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did not exit within 100ms")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestInstances_Claim(t *testing.T) {
+	instances := NewInstances()
+
+	claimedBy, ok := instances.Claim("my_org", "my_space", "my-db", "app_a")
+	assert.True(t, ok)
+	assert.Equal(t, "app_a", claimedBy)
+
+	claimedBy, ok = instances.Claim("my_org", "my_space", "my-db", "app_b")
+	assert.False(t, ok)
+	assert.Equal(t, "app_a", claimedBy)
+
+	_, ok = instances.Claim("my_org", "my_space", "my-db", "app_a")
+	assert.True(t, ok, "the app that claimed the instance can claim it again")
+	_, ok = instances.Claim("my_org", "other_space", "my-db", "app_b")
+	assert.True(t, ok)
+
+	var untracked *Instances
+	_, ok = untracked.Claim("my_org", "my_space", "my-db", "app_b")
+	assert.True(t, ok)
+}
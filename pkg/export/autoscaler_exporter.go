@@ -19,11 +19,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
 
@@ -147,8 +147,8 @@ func (e *DefaultAutoScalerExporter) ExportAutoScalerRules(ctx *context.Context,
 		}
 	}
 
-	rulesJSONFile := filepath.Join(exportDir, getAppFileName(app.Name)+"_autoscale_rules.json")
-	file, err := os.Create(rulesJSONFile)
+	rulesJSONFile := path.Join(exportDir, getAppFileName(app.Name)+"_autoscale_rules.json")
+	file, err := aio.PutFile(ctx.DirWriter, rulesJSONFile, 0644, ctx.Sealer)
 	if err != nil {
 		return err
 	}
@@ -158,7 +158,7 @@ func (e *DefaultAutoScalerExporter) ExportAutoScalerRules(ctx *context.Context,
 		return err
 	}
 
-	return nil
+	return file.Close()
 }
 
 func (e *DefaultAutoScalerExporter) ExportAutoScalerInstances(ctx *context.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
@@ -207,14 +207,18 @@ func (e *DefaultAutoScalerExporter) ExportAutoScalerInstances(ctx *context.Conte
 			return err
 		}
 
-		instanceFileName := filepath.Join(exportDir, getAppFileName(app.Name)+"_autoscale_instances.json")
-		file, err := os.Create(instanceFileName)
+		instanceFileName := path.Join(exportDir, getAppFileName(app.Name)+"_autoscale_instances.json")
+		file, err := aio.PutFile(ctx.DirWriter, instanceFileName, 0644, ctx.Sealer)
 		if err != nil {
 			return err
 		}
 		defer file.Close()
 
-		return json.NewEncoder(file).Encode(respObj)
+		if err = json.NewEncoder(file).Encode(respObj); err != nil {
+			return err
+		}
+
+		return file.Close()
 	}
 
 	return nil
@@ -290,7 +294,7 @@ func (e *DefaultAutoScalerExporter) ExportAutoScalerSchedules(ctx *context.Conte
 			}
 		}
 
-		file, err := os.Create(filepath.Join(exportDir, strings.ReplaceAll(app.Name, "/", "_")+"_autoscale_schedules.json"))
+		file, err := aio.PutFile(ctx.DirWriter, path.Join(exportDir, strings.ReplaceAll(app.Name, "/", "_")+"_autoscale_schedules.json"), 0644, ctx.Sealer)
 		if err != nil {
 			return err
 		}
@@ -299,6 +303,8 @@ func (e *DefaultAutoScalerExporter) ExportAutoScalerSchedules(ctx *context.Conte
 		if err = json.NewEncoder(file).Encode(schedules); err != nil {
 			return err
 		}
+
+		return file.Close()
 	}
 
 	return nil
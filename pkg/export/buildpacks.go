@@ -0,0 +1,101 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+)
+
+const (
+	// BuildpacksDir is the directory of the export holding the admin buildpacks
+	BuildpacksDir = aio.ReservedDir + "/buildpacks"
+	// BuildpacksFile lists the exported buildpacks with their settings
+	BuildpacksFile = BuildpacksDir + "/buildpacks.json"
+)
+
+// Buildpack is an admin buildpack exported with its settings, its bits are stored in BuildpacksDir
+type Buildpack struct {
+	Name     string `json:"name"`
+	Stack    string `json:"stack,omitempty"`
+	Position int    `json:"position"`
+	Enabled  bool   `json:"enabled"`
+	Locked   bool   `json:"locked"`
+	// Filename is the name the bits were uploaded with on the source foundation
+	Filename string `json:"filename"`
+	// Bits is the path of the exported bits in the export dir
+	Bits     string   `json:"bits"`
+	Checksum Checksum `json:"checksum"`
+}
+
+// String names the buildpack along with its stack, as buildpacks with the same name can exist for several stacks
+func (b Buildpack) String() string {
+	if b.Stack == "" {
+		return b.Name
+	}
+	return fmt.Sprintf("%s (%s)", b.Name, b.Stack)
+}
+
+// ExportBuildpack downloads the bits of a buildpack of the source foundation to the export dir
+func ExportBuildpack(ctx *appcontext.Context, b cf.V3Buildpack) (Buildpack, error) {
+	exported := Buildpack{
+		Name:     b.Name,
+		Stack:    b.Stack,
+		Position: b.Position,
+		Enabled:  b.Enabled,
+		Locked:   b.Locked,
+		Filename: b.Filename,
+		Bits:     path.Join(BuildpacksDir, b.GUID+".zip"),
+	}
+
+	r, _, err := cf.DownloadBuildpack(ctx.ExportCFClient, b.GUID)
+	if err != nil {
+		return exported, err
+	}
+	defer r.Close()
+
+	file, err := aio.PutFile(ctx.DirWriter, exported.Bits, 0644, ctx.Sealer)
+	if err != nil {
+		return exported, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(file, io.TeeReader(r, h)); err != nil {
+		return exported, err
+	}
+	exported.Checksum = Checksum{Type: "sha256", Value: hex.EncodeToString(h.Sum(nil))}
+
+	return exported, file.Close()
+}
+
+// WriteBuildpacks writes the list of exported buildpacks to BuildpacksFile
+func WriteBuildpacks(ctx *appcontext.Context, buildpacks []Buildpack) error {
+	return writeJSON(ctx, BuildpacksFile, buildpacks)
+}
+
+// ReadBuildpacks reads the list of exported buildpacks from BuildpacksFile
+func ReadBuildpacks(ctx *appcontext.Context) ([]Buildpack, error) {
+	var buildpacks []Buildpack
+	err := readJSON(ctx, BuildpacksFile, &buildpacks)
+	return buildpacks, err
+}
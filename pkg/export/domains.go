@@ -0,0 +1,59 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+)
+
+const (
+	// DomainsDir is the directory of the export holding the domains
+	DomainsDir = aio.ReservedDir + "/domains"
+	// DomainsFile lists the exported private and shared domains
+	DomainsFile = DomainsDir + "/domains.json"
+)
+
+// PrivateDomain is a private domain exported with the name of the org owning it and of the orgs it is shared with
+type PrivateDomain struct {
+	Name       string   `json:"name"`
+	Internal   bool     `json:"internal,omitempty"`
+	Org        string   `json:"org"`
+	SharedOrgs []string `json:"shared_orgs,omitempty"`
+}
+
+// SharedDomain is a shared domain used by the routes of the exported apps
+type SharedDomain struct {
+	Name     string `json:"name"`
+	Internal bool   `json:"internal,omitempty"`
+}
+
+// Domains holds the domains the exported orgs and apps use
+type Domains struct {
+	PrivateDomains []PrivateDomain `json:"private_domains"`
+	SharedDomains  []SharedDomain  `json:"shared_domains"`
+}
+
+// WriteDomains writes the exported domains to DomainsFile
+func WriteDomains(ctx *appcontext.Context, domains Domains) error {
+	return writeJSON(ctx, DomainsFile, domains)
+}
+
+// ReadDomains reads the exported domains from DomainsFile
+func ReadDomains(ctx *appcontext.Context) (Domains, error) {
+	var domains Domains
+	err := readJSON(ctx, DomainsFile, &domains)
+	return domains, err
+}
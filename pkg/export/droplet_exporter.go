@@ -15,20 +15,19 @@
 package export
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"path"
 	"time"
 
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/blobstore"
 	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
 
@@ -36,11 +35,11 @@ import (
 )
 
 type PackageDownloader interface {
-	downloadPackages(c *appcontext.Context, packageGUID string) ([]byte, error)
+	downloadPackages(c *appcontext.Context, packageGUID string) (io.ReadCloser, int64, error)
 }
 
 type PackageRetriever interface {
-	getPackages(c *appcontext.Context, appGUID string) (string, error)
+	getPackages(c *appcontext.Context, appGUID string) (cfclient.V3Package, error)
 }
 
 type DefaultDropletExporter struct {
@@ -78,34 +77,181 @@ func (d *DefaultDropletExporter) NumberOfPackages(ctx *appcontext.Context, app c
 	return resp.Pagination.TotalResults, nil
 }
 
-func (d *DefaultDropletExporter) DownloadDroplet(ctx *appcontext.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
+// DownloadBlobs downloads the current droplet of the app and the package it was staged from, or its latest ready
+// package when that one is gone. The droplet and its package are only looked up once for both downloads.
+func (d *DefaultDropletExporter) DownloadBlobs(ctx *appcontext.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
+	droplet, err := d.currentDroplet(ctx, app)
+	if err != nil {
+		return err
+	}
+
+	pkg, ok, err := d.stagedPackage(ctx, droplet)
+	if err != nil {
+		return err
+	}
+
+	if err = d.downloadDropletTo(ctx, org, space, app, exportDir, droplet, pkg, ok); err != nil {
+		return err
+	}
+
+	if !ok {
+		if pkg, err = d.otherPackage(ctx, app); err != nil {
+			return err
+		}
+	}
+
+	return d.downloadPackageTo(ctx, org, space, app, exportDir, pkg)
+}
+
+// downloadDropletTo writes the droplet and its info to the export, staged tells whether pkg is the package the
+// droplet was staged from
+func (d *DefaultDropletExporter) downloadDropletTo(ctx *appcontext.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string, droplet cfclient.V3Droplet, pkg cfclient.V3Package, staged bool) error {
 	ctx.Logger.Infof("Downloading %s/%s/%s droplet", org.Name, space.Name, app.Name)
 
-	body, err := ctx.ExportCFClient.Get(path.Join("/v2", "apps", app.Guid, "droplet", "download"))
+	info := DropletInfo{
+		GUID:              droplet.GUID,
+		Stack:             droplet.Stack,
+		Buildpacks:        droplet.Buildpacks,
+		ProcessTypes:      droplet.ProcessTypes,
+		ExecutionMetadata: droplet.ExecutionMetadata,
+		Checksum:          Checksum{Type: droplet.Checksum.Type, Value: droplet.Checksum.Value},
+	}
+	if staged {
+		info.Package = &PackageInfo{GUID: pkg.GUID, Checksum: packageChecksum(pkg)}
+	}
+	if err := writeDropletInfo(ctx, path.Join(exportDir, getAppFileName(app.Name)+DropletInfoSuffix), info); err != nil {
+		return err
+	}
+
+	dropletFileName := path.Join(exportDir, getAppFileName(app.Name)+".tgz")
+	if ctx.DedupeBlobs {
+		reused, err := blobstore.New(ctx.DirWriter, ctx.Sealer).Put(dropletFileName, info.Checksum.sha256(), func() (io.ReadCloser, error) {
+			body, _, err := d.downloadDroplet(ctx, droplet.GUID)
+			return body, err
+		})
+		if reused {
+			ctx.Logger.Infof("Droplet of %s/%s/%s is already in the export", org.Name, space.Name, app.Name)
+		}
+		return err
+	}
+
+	body, _, err := d.downloadDroplet(ctx, droplet.GUID)
 	if err != nil {
 		return err
 	}
+	defer body.Close()
 
-	var dropletFile *os.File
-	dropletFile, err = os.Create(path.Join(exportDir, getAppFileName(app.Name)+".tgz"))
+	var dropletFile io.WriteCloser
+	dropletFile, err = aio.PutFile(ctx.DirWriter, dropletFileName, 0644, ctx.Sealer)
 	if err != nil {
 		return err
 	}
 	defer dropletFile.Close()
 
-	_, err = io.Copy(dropletFile, bytes.NewReader(body))
+	if _, err = io.Copy(dropletFile, body); err != nil {
+		return err
+	}
 
-	return err
+	return dropletFile.Close()
 }
 
-func (d *DefaultDropletExporter) DownloadPackages(c *appcontext.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string) error {
+// OpenDroplet starts downloading the current droplet of the app, along with its size or -1 when it is not known.
+// The caller must close the returned reader.
+func (d *DefaultDropletExporter) OpenDroplet(ctx *appcontext.Context, app cfclient.App) (io.ReadCloser, int64, error) {
+	droplet, err := d.currentDroplet(ctx, app)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return d.downloadDroplet(ctx, droplet.GUID)
+}
+
+// downloadPackageTo writes the bits of the package to the export
+func (d *DefaultDropletExporter) downloadPackageTo(c *appcontext.Context, org cfclient.Org, space cfclient.Space, app cfclient.App, exportDir string, pkg cfclient.V3Package) error {
 	c.Logger.Infof("Downloading %s/%s/%s bits\n", org.Name, space.Name, app.Name)
 
+	zipFileName := fmt.Sprintf("%s/%s.zip", exportDir, getAppFileName(app.Name))
+	if c.DedupeBlobs {
+		reused, err := blobstore.New(c.DirWriter, c.Sealer).Put(zipFileName, packageChecksum(pkg).sha256(), func() (io.ReadCloser, error) {
+			body, _, err := d.downloadPackages(c, pkg.GUID)
+			return body, err
+		})
+		if reused {
+			c.Logger.Infof("Bits of %s/%s/%s are already in the export", org.Name, space.Name, app.Name)
+		}
+		return err
+	}
+
+	body, _, err := d.downloadPackages(c, pkg.GUID)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var zipFile io.WriteCloser
+	zipFile, err = aio.PutFile(c.DirWriter, zipFileName, 0644, c.Sealer)
+	if err != nil {
+		return fmt.Errorf("error creating zip file: %w", err)
+	}
+	defer zipFile.Close()
+
+	if written, err := io.Copy(zipFile, body); err != nil {
+		fmt.Printf("Wrote %d bytes", written)
+		return fmt.Errorf("error writing zip file: %w", err)
+	}
+
+	if err = zipFile.Close(); err != nil {
+		return fmt.Errorf("error writing zip file: %w", err)
+	}
+
+	return nil
+}
+
+// OpenPackage starts downloading the package the current droplet of the app was staged from, along with its size or
+// -1 when it is not known. The caller must close the returned reader.
+func (d *DefaultDropletExporter) OpenPackage(c *appcontext.Context, app cfclient.App) (io.ReadCloser, int64, error) {
+	pkg, err := d.appPackage(c, app)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return d.downloadPackages(c, pkg.GUID)
+}
+
+// appPackage returns the package the current droplet of the app was staged from, or its latest ready package when
+// that one is gone or the droplet was uploaded rather than staged
+func (d *DefaultDropletExporter) appPackage(c *appcontext.Context, app cfclient.App) (cfclient.V3Package, error) {
+	droplet, err := d.currentDroplet(c, app)
+	if err != nil && !cfclient.IsResourceNotFoundError(err) {
+		return cfclient.V3Package{}, err
+	}
+
+	if err == nil {
+		pkg, ok, err := d.stagedPackage(c, droplet)
+		if err != nil {
+			return pkg, err
+		}
+		if ok {
+			return pkg, nil
+		}
+	}
+
+	return d.otherPackage(c, app)
+}
+
+// otherPackage returns the latest ready package of the app, for when the one its current droplet was staged from is
+// not available
+func (d *DefaultDropletExporter) otherPackage(c *appcontext.Context, app cfclient.App) (cfclient.V3Package, error) {
+	c.Logger.Warnf("The package the current droplet of %s was staged from is not available, the latest package is exported instead", app.Name)
+	return d.latestPackage(c, app)
+}
+
+func (d *DefaultDropletExporter) latestPackage(c *appcontext.Context, app cfclient.App) (cfclient.V3Package, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
 	for {
-		packageGUID, err := d.getPackages(c, app.Guid)
+		pkg, err := d.getPackages(c, app.Guid)
 		if err != nil {
 			cfErr := cfclient.CloudFoundryError{}
 
@@ -115,41 +261,68 @@ func (d *DefaultDropletExporter) DownloadPackages(c *appcontext.Context, org cfc
 					case <-time.After(10 * time.Second):
 						continue
 					case <-ctx.Done():
-						return err
+						return pkg, err
 					}
 				}
 			}
 
-			return err
+			return pkg, err
 		}
 
-		var body []byte
-		body, err = d.downloadPackages(c, packageGUID)
-		if err != nil {
-			return err
-		}
+		return pkg, nil
+	}
+}
 
-		zipFileName := fmt.Sprintf("%s/%s.zip", exportDir, getAppFileName(app.Name))
-		var zipFile io.WriteCloser
-		zipFile, err = os.Create(zipFileName)
-		if err != nil {
-			return fmt.Errorf("error creating zip file: %w", err)
-		}
-		defer zipFile.Close()
+// currentDroplet returns the droplet the app is running with
+func (d *DefaultDropletExporter) currentDroplet(c *appcontext.Context, app cfclient.App) (cfclient.V3Droplet, error) {
+	var droplet cfclient.V3Droplet
+	body, err := c.ExportCFClient.Get(fmt.Sprintf("/v3/apps/%s/droplets/current", app.Guid))
+	if err != nil {
+		return droplet, fmt.Errorf("error getting the current droplet of %s: %w", app.Name, err)
+	}
+
+	if err = json.Unmarshal(body, &droplet); err != nil {
+		return droplet, fmt.Errorf("error reading the current droplet of %s: %w", app.Name, err)
+	}
+
+	return droplet, nil
+}
 
-		if written, err := io.Copy(zipFile, bytes.NewReader(body)); err != nil {
-			fmt.Printf("Wrote %d bytes", written)
-			return fmt.Errorf("error writing zip file: %w", err)
+// stagedPackage returns the package the droplet was staged from, if it is still ready to download. Droplets uploaded
+// rather than staged have no package.
+func (d *DefaultDropletExporter) stagedPackage(c *appcontext.Context, droplet cfclient.V3Droplet) (cfclient.V3Package, bool, error) {
+	var pkg cfclient.V3Package
+	link, ok := droplet.Links["package"]
+	if !ok || link.Href == "" {
+		return pkg, false, nil
+	}
+
+	body, err := c.ExportCFClient.Get(path.Join("/v3/packages", path.Base(link.Href)))
+	if err != nil {
+		if cfclient.IsResourceNotFoundError(err) {
+			return pkg, false, nil
 		}
+		return pkg, false, err
+	}
 
-		break
+	if err = json.Unmarshal(body, &pkg); err != nil {
+		return pkg, false, err
 	}
 
-	return nil
+	return pkg, pkg.State == "READY", nil
+}
+
+// packageChecksum returns the checksum the Cloud Controller reports for a bits package, if any
+func packageChecksum(pkg cfclient.V3Package) Checksum {
+	bits, err := pkg.BitsData()
+	if err != nil {
+		return Checksum{}
+	}
+	return Checksum{Type: bits.Checksum.Type, Value: bits.Checksum.Value}
 }
 
-func (d *DefaultPackageRetriever) getPackages(c *appcontext.Context, appGUID string) (string, error) {
-	var packageGUID string
+func (d *DefaultPackageRetriever) getPackages(c *appcontext.Context, appGUID string) (cfclient.V3Package, error) {
+	var pkg cfclient.V3Package
 	err := c.ExportCFClient.DoWithRetry(func() error {
 		params := url.Values{
 			"states":   []string{"READY"},
@@ -179,60 +352,22 @@ func (d *DefaultPackageRetriever) getPackages(c *appcontext.Context, appGUID str
 			return fmt.Errorf("expected at least 1 package, but found %d", len(resp.Resources))
 		}
 
-		packageGUID = resp.Resources[0].GUID
+		pkg = resp.Resources[0]
 		return nil
 	})
 
-	return packageGUID, err
+	return pkg, err
 }
 
-func (d *DefaultPackageDownloader) downloadPackages(c *appcontext.Context, packageGUID string) ([]byte, error) {
-	var httpResp *http.Response
-	var body []byte
-	var err error
-
-	err = c.ExportCFClient.DoWithRetry(func() error {
-		var err error
-		req := c.ExportCFClient.NewRequest(http.MethodGet, fmt.Sprintf("/v3/packages/%s/download", packageGUID))
-		httpResp, err = c.ExportCFClient.DoRequest(req)
-		if err == nil {
-			defer httpResp.Body.Close()
-			if httpResp.StatusCode >= 500 && httpResp.StatusCode <= 599 {
-				return cf.ErrRetry
-			}
-			body, err = ioutil.ReadAll(httpResp.Body)
-			if err != nil {
-				return err
-			}
-		}
-		return err
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	if httpResp.StatusCode == http.StatusFound {
-		locationURL := httpResp.Header.Get("location")
-		err = c.ExportCFClient.DoWithRetry(func() error {
-			client := c.ExportCFClient.HTTPClient()
-			resp, err := client.Get(locationURL)
-			if err == nil {
-				defer resp.Body.Close()
-				if resp.StatusCode >= 500 && resp.StatusCode <= 599 {
-					return cf.ErrRetry
-				}
-				body, err = ioutil.ReadAll(resp.Body)
-				if err != nil {
-					return err
-				}
-			}
-			return err
-		})
+func (d *DefaultPackageDownloader) downloadPackages(c *appcontext.Context, packageGUID string) (io.ReadCloser, int64, error) {
+	return openDownload(c, fmt.Sprintf("/v3/packages/%s/download", packageGUID))
+}
 
-		if err != nil {
-			return body, err
-		}
-	}
+func (d *DefaultDropletExporter) downloadDroplet(c *appcontext.Context, dropletGUID string) (io.ReadCloser, int64, error) {
+	return openDownload(c, fmt.Sprintf("/v3/droplets/%s/download", dropletGUID))
+}
 
-	return body, err
+// openDownload starts a download from the source api, see cf.Download
+func openDownload(c *appcontext.Context, url string) (io.ReadCloser, int64, error) {
+	return cf.Download(c.ExportCFClient, url)
 }
@@ -15,21 +15,32 @@
 package export
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/blobstore"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cache"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
 	. "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 )
 
-func TestDefaultDropletExporter_DownloadDroplet(t *testing.T) {
+func TestDefaultDropletExporter_DownloadBlobs(t *testing.T) {
 	type args struct {
 		ctx       *context.Context
 		org       cfclient.Org
@@ -38,26 +49,97 @@ func TestDefaultDropletExporter_DownloadDroplet(t *testing.T) {
 		exportDir string
 	}
 	logger := log.New()
+	exportDir := t.TempDir()
+	newClient := func(resp *http.Response, err error) *fakes.FakeClient {
+		client := &fakes.FakeClient{}
+		client.DoWithRetryStub = func(f func() error) error {
+			return f()
+		}
+		client.GetStub = func(url string) ([]byte, error) {
+			switch url {
+			case "/v3/apps/6064d98a-95e6-400b-bc03-be65e6d59622/droplets/current":
+				return []byte(`{
+					"guid": "585bc3c1-3743-497d-88b0-403ad6b56d16",
+					"state": "STAGED",
+					"stack": "cflinuxfs3",
+					"buildpacks": [{"name": "ruby_buildpack", "buildpack_name": "ruby", "version": "1.1.1", "detect_output": "ruby 1.6.14"}],
+					"process_types": {"web": "bundle exec rackup"},
+					"execution_metadata": "",
+					"checksum": {"type": "sha256", "value": "d8c0a6d4e8dbd3d6e4b3c5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6"},
+					"links": {"package": {"href": "https://api.example.org/v3/packages/8222f76a-9e09-4360-b3aa-1ed329945e92"}}
+				}`), nil
+			case "/v3/packages/8222f76a-9e09-4360-b3aa-1ed329945e92":
+				return []byte(`{
+					"guid": "8222f76a-9e09-4360-b3aa-1ed329945e92",
+					"type": "bits",
+					"state": "READY",
+					"data": {"checksum": {"type": "sha256", "value": "a2b8b2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1"}}
+				}`), nil
+			}
+			return nil, cfclient.CloudFoundryError{Code: 10010, ErrorCode: "CF-ResourceNotFound"}
+		}
+		client.NewRequestReturns(&cfclient.Request{})
+		client.DoRequestReturns(resp, err)
+		return client
+	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name     string
+		args     args
+		wantData string
+		wantErr  bool
 	}{
 		{
-			name: "download droplet returns a result",
+			name: "download blobs returns a result",
 			args: args{
 				ctx: &context.Context{
-					Logger: logger,
-					ExportCFClient: StubClient{
-						FakeClient: &fakes.FakeClient{},
-						GetFunc: func(string) ([]byte, error) {
-							return []byte(`{}`), nil
-						},
-					},
+					Logger:    logger,
+					DirWriter: storage.NewLocal(exportDir),
+					ExportCFClient: newClient(&http.Response{
+						StatusCode:    http.StatusOK,
+						Body:          io.NopCloser(strings.NewReader("droplet data")),
+						ContentLength: 12,
+					}, nil),
+				},
+				app: cfclient.App{
+					Guid: "6064d98a-95e6-400b-bc03-be65e6d59622",
+					Name: "my_app",
 				},
-				exportDir: "/tmp",
+				exportDir: "my_org/my_space",
 			},
-			wantErr: false,
+			wantData: "droplet data",
+			wantErr:  false,
+		},
+		{
+			name: "app has no current droplet",
+			args: args{
+				ctx: &context.Context{
+					Logger:         logger,
+					DirWriter:      storage.NewLocal(exportDir),
+					ExportCFClient: newClient(nil, errors.New("droplet not found")),
+				},
+				app: cfclient.App{
+					Guid: "unknown-guid",
+					Name: "unknown_app",
+				},
+				exportDir: "my_org/my_space",
+			},
+			wantErr: true,
+		},
+		{
+			name: "download droplet returns an error",
+			args: args{
+				ctx: &context.Context{
+					Logger:         logger,
+					DirWriter:      storage.NewLocal(exportDir),
+					ExportCFClient: newClient(nil, errors.New("blobstore unavailable")),
+				},
+				app: cfclient.App{
+					Guid: "6064d98a-95e6-400b-bc03-be65e6d59622",
+					Name: "failing_app",
+				},
+				exportDir: "my_org/my_space",
+			},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
@@ -65,15 +147,90 @@ func TestDefaultDropletExporter_DownloadDroplet(t *testing.T) {
 			cache.Cache = nil
 		})
 		t.Run(tt.name, func(t *testing.T) {
-			d := &DefaultDropletExporter{}
-			if err := d.DownloadDroplet(tt.args.ctx, tt.args.org, tt.args.space, tt.args.app, tt.args.exportDir); (err != nil) != tt.wantErr {
-				t.Errorf("DownloadDroplet() error = %v, wantErr %v", err, tt.wantErr)
+			d := &DefaultDropletExporter{
+				PackageDownloader: stubPackageDownloader{DownloadPackages: func(c *context.Context, packageGUID string) (io.ReadCloser, int64, error) {
+					require.Equal(t, "8222f76a-9e09-4360-b3aa-1ed329945e92", packageGUID)
+					return io.NopCloser(strings.NewReader("package data")), 12, nil
+				}},
+			}
+			if err := d.DownloadBlobs(tt.args.ctx, tt.args.org, tt.args.space, tt.args.app, tt.args.exportDir); (err != nil) != tt.wantErr {
+				t.Errorf("DownloadBlobs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
 			}
+			require.Equal(t, 2, tt.args.ctx.ExportCFClient.(*fakes.FakeClient).GetCallCount(), "the droplet and its package are looked up once")
+
+			data, err := os.ReadFile(filepath.Join(exportDir, "my_org", "my_space", tt.args.app.Name+".tgz"))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantData, string(data))
+
+			data, err = os.ReadFile(filepath.Join(exportDir, "my_org", "my_space", tt.args.app.Name+".zip"))
+			require.NoError(t, err)
+			require.Equal(t, "package data", string(data))
+
+			data, err = os.ReadFile(filepath.Join(exportDir, "my_org", "my_space", tt.args.app.Name+DropletInfoSuffix))
+			require.NoError(t, err)
+			var info DropletInfo
+			require.NoError(t, json.Unmarshal(data, &info))
+			require.Equal(t, "585bc3c1-3743-497d-88b0-403ad6b56d16", info.GUID)
+			require.Equal(t, "cflinuxfs3", info.Stack)
+			require.Equal(t, map[string]string{"web": "bundle exec rackup"}, info.ProcessTypes)
+			require.Equal(t, "ruby_buildpack", info.Buildpacks[0].Name)
+			require.Equal(t, &PackageInfo{
+				GUID:     "8222f76a-9e09-4360-b3aa-1ed329945e92",
+				Checksum: Checksum{Type: "sha256", Value: "a2b8b2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1"},
+			}, info.Package)
 		})
 	}
 }
 
-func TestDefaultDropletExporter_DownloadPackages(t *testing.T) {
+func TestDefaultDropletExporter_DownloadBlobsLatestPackage(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+	exportDir := t.TempDir()
+	client := &fakes.FakeClient{}
+	client.DoWithRetryStub = func(f func() error) error {
+		return f()
+	}
+	client.GetReturns([]byte(`{"guid": "585bc3c1-3743-497d-88b0-403ad6b56d16", "state": "STAGED"}`), nil)
+	client.NewRequestReturns(&cfclient.Request{})
+	client.DoRequestReturns(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("droplet data")),
+	}, nil)
+	ctx := &context.Context{
+		Logger:         log.New(),
+		DirWriter:      storage.NewLocal(exportDir),
+		ExportCFClient: client,
+	}
+	d := &DefaultDropletExporter{
+		PackageRetriever: stubPackageRetriever{GetPackages: func(c *context.Context, appGUID string) (cfclient.V3Package, error) {
+			return cfclient.V3Package{GUID: "latest-package"}, nil
+		}},
+		PackageDownloader: stubPackageDownloader{DownloadPackages: func(c *context.Context, packageGUID string) (io.ReadCloser, int64, error) {
+			require.Equal(t, "latest-package", packageGUID)
+			return io.NopCloser(strings.NewReader("package data")), 12, nil
+		}},
+	}
+
+	err := d.DownloadBlobs(ctx, cfclient.Org{Name: "my_org"}, cfclient.Space{Name: "my_space"}, cfclient.App{Guid: "6064d98a-95e6-400b-bc03-be65e6d59622", Name: "my_app"}, "my_org/my_space")
+	require.NoError(t, err)
+	require.Equal(t, 1, client.GetCallCount())
+
+	data, err := os.ReadFile(filepath.Join(exportDir, "my_org", "my_space", "my_app"+DropletInfoSuffix))
+	require.NoError(t, err)
+	var info DropletInfo
+	require.NoError(t, json.Unmarshal(data, &info))
+	require.Nil(t, info.Package)
+
+	data, err = os.ReadFile(filepath.Join(exportDir, "my_org", "my_space", "my_app.zip"))
+	require.NoError(t, err)
+	require.Equal(t, "package data", string(data))
+}
+
+func TestDefaultDropletExporter_DownloadPackageTo(t *testing.T) {
 	type args struct {
 		c         *context.Context
 		org       cfclient.Org
@@ -82,6 +239,7 @@ func TestDefaultDropletExporter_DownloadPackages(t *testing.T) {
 		exportDir string
 	}
 	logger := log.New()
+	exportDir := t.TempDir()
 	tests := []struct {
 		name    string
 		args    args
@@ -91,7 +249,8 @@ func TestDefaultDropletExporter_DownloadPackages(t *testing.T) {
 			name: "download packages returns a result",
 			args: args{
 				c: &context.Context{
-					Logger: logger,
+					Logger:    logger,
+					DirWriter: storage.NewLocal(exportDir),
 					ExportCFClient: StubClient{
 						FakeClient: &fakes.FakeClient{},
 						GetFunc: func(string) ([]byte, error) {
@@ -108,7 +267,7 @@ func TestDefaultDropletExporter_DownloadPackages(t *testing.T) {
 				app: cfclient.App{
 					Name: "my_app",
 				},
-				exportDir: "/tmp",
+				exportDir: "my_org/my_space",
 			},
 			wantErr: false,
 		},
@@ -119,26 +278,69 @@ func TestDefaultDropletExporter_DownloadPackages(t *testing.T) {
 				cache.Cache = nil
 			})
 			d := &DefaultDropletExporter{
-				PackageRetriever: stubPackageRetriever{GetPackages: func(c *context.Context, appGUID string) (string, error) {
-					return "some-guid", nil
-				}},
-				PackageDownloader: stubPackageDownloader{DownloadPackages: func(c *context.Context, packageGUID string) ([]byte, error) {
-					return []byte(`{}`), nil
+				PackageDownloader: stubPackageDownloader{DownloadPackages: func(c *context.Context, packageGUID string) (io.ReadCloser, int64, error) {
+					return io.NopCloser(strings.NewReader(`{}`)), 2, nil
 				}},
 			}
-			if err := d.DownloadPackages(tt.args.c, tt.args.org, tt.args.space, tt.args.app, tt.args.exportDir); (err != nil) != tt.wantErr {
-				t.Errorf("DownloadPackages() error = %v, wantErr %v", err, tt.wantErr)
+			if err := d.downloadPackageTo(tt.args.c, tt.args.org, tt.args.space, tt.args.app, tt.args.exportDir, cfclient.V3Package{GUID: "some-guid"}); (err != nil) != tt.wantErr {
+				t.Errorf("downloadPackageTo() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
+func TestDefaultDropletExporter_DownloadPackageToDedupe(t *testing.T) {
+	t.Cleanup(func() {
+		cache.Cache = nil
+	})
+	exportDir := t.TempDir()
+	ctx := &context.Context{
+		Logger:    log.New(),
+		DirWriter: storage.NewLocal(exportDir),
+		ExportCFClient: StubClient{
+			FakeClient: &fakes.FakeClient{},
+			GetFunc: func(string) ([]byte, error) {
+				return []byte(`{}`), nil
+			},
+		},
+		DedupeBlobs: true,
+	}
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte("app bits")))
+	var downloads int
+	d := &DefaultDropletExporter{
+		PackageDownloader: stubPackageDownloader{DownloadPackages: func(c *context.Context, packageGUID string) (io.ReadCloser, int64, error) {
+			downloads++
+			return io.NopCloser(strings.NewReader("app bits")), 8, nil
+		}},
+	}
+
+	for _, name := range []string{"app_a", "app_b"} {
+		pkg := cfclient.V3Package{
+			GUID: name + "-package",
+			Type: "bits",
+			Data: json.RawMessage(fmt.Sprintf(`{"checksum":{"type":"sha256","value":%q}}`, digest)),
+		}
+		err := d.downloadPackageTo(ctx, cfclient.Org{Name: "my_org"}, cfclient.Space{Name: "my_space"}, cfclient.App{Guid: name, Name: name}, "my_org/my_space", pkg)
+		require.NoError(t, err)
+
+		ref, err := os.ReadFile(filepath.Join(exportDir, "my_org", "my_space", name+".zip"+blobstore.RefSuffix))
+		require.NoError(t, err)
+		require.Equal(t, digest, strings.TrimSpace(string(ref)))
+	}
+
+	require.Equal(t, 1, downloads)
+	data, err := os.ReadFile(filepath.Join(exportDir, filepath.FromSlash(blobstore.Name(digest))))
+	require.NoError(t, err)
+	require.Equal(t, "app bits", string(data))
+}
+
 func TestDefaultDropletExporter_NumberOfPackages(t *testing.T) {
 	type args struct {
 		ctx *context.Context
 		app cfclient.App
 	}
 	logger := log.New()
+	exportDir := t.TempDir()
 	tests := []struct {
 		name    string
 		args    args
@@ -149,7 +351,8 @@ func TestDefaultDropletExporter_NumberOfPackages(t *testing.T) {
 			name: "get packages returns error",
 			args: args{
 				ctx: &context.Context{
-					Logger: logger,
+					Logger:    logger,
+					DirWriter: storage.NewLocal(exportDir),
 					ExportCFClient: StubClient{
 						GetFunc: func(url string) ([]byte, error) {
 							return nil, errors.New("some error")
@@ -167,7 +370,8 @@ func TestDefaultDropletExporter_NumberOfPackages(t *testing.T) {
 			name: "get packages returns result",
 			args: args{
 				ctx: &context.Context{
-					Logger: logger,
+					Logger:    logger,
+					DirWriter: storage.NewLocal(exportDir),
 					ExportCFClient: StubClient{
 						FakeClient: &fakes.FakeClient{},
 						GetFunc: func(string) ([]byte, error) {
@@ -223,17 +427,17 @@ func TestNewDropletExporter(t *testing.T) {
 }
 
 type stubPackageDownloader struct {
-	DownloadPackages func(c *context.Context, packageGUID string) ([]byte, error)
+	DownloadPackages func(c *context.Context, packageGUID string) (io.ReadCloser, int64, error)
 }
 
-func (s stubPackageDownloader) downloadPackages(ctx *context.Context, packageGUID string) ([]byte, error) {
+func (s stubPackageDownloader) downloadPackages(ctx *context.Context, packageGUID string) (io.ReadCloser, int64, error) {
 	return s.DownloadPackages(ctx, packageGUID)
 }
 
 type stubPackageRetriever struct {
-	GetPackages func(c *context.Context, appGUID string) (string, error)
+	GetPackages func(c *context.Context, appGUID string) (cfclient.V3Package, error)
 }
 
-func (s stubPackageRetriever) getPackages(ctx *context.Context, appGUID string) (string, error) {
+func (s stubPackageRetriever) getPackages(ctx *context.Context, appGUID string) (cfclient.V3Package, error) {
 	return s.GetPackages(ctx, appGUID)
 }
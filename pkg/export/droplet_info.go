@@ -0,0 +1,74 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"encoding/json"
+	"strings"
+
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+)
+
+// DropletInfoSuffix is appended to the name of an app to name the file describing its exported droplet
+const DropletInfoSuffix = "_droplet.json"
+
+// Checksum is the digest of a droplet or package, as reported by the Cloud Controller
+type Checksum struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+func (c Checksum) sha256() string {
+	if !strings.EqualFold(c.Type, "sha256") {
+		return ""
+	}
+	return c.Value
+}
+
+// DropletInfo describes the droplet an app was exported with
+type DropletInfo struct {
+	GUID              string                         `json:"guid"`
+	Stack             string                         `json:"stack,omitempty"`
+	Buildpacks        []cfclient.V3DetectedBuildpack `json:"buildpacks,omitempty"`
+	ProcessTypes      map[string]string              `json:"process_types,omitempty"`
+	ExecutionMetadata string                         `json:"execution_metadata,omitempty"`
+	Checksum          Checksum                       `json:"checksum"`
+	// Package is the package the droplet was staged from, it is nil when the droplet was uploaded or the package is
+	// gone, the latest package of the app is then exported instead
+	Package *PackageInfo `json:"package,omitempty"`
+}
+
+// PackageInfo describes the package a droplet was staged from
+type PackageInfo struct {
+	GUID     string   `json:"guid"`
+	Checksum Checksum `json:"checksum"`
+}
+
+func writeDropletInfo(ctx *appcontext.Context, name string, info DropletInfo) error {
+	file, err := aio.PutFile(ctx.DirWriter, name, 0644, ctx.Sealer)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = json.NewEncoder(file).Encode(info); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
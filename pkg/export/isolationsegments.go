@@ -0,0 +1,61 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"path"
+
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+// IsolationSegmentsFile holds the isolation segments of an org, or of a space, in the directory of the org or space
+const IsolationSegmentsFile = "isolation_segments.json"
+
+// OrgIsolationSegments are the names of the isolation segments an org is entitled to, and of the one its apps run on
+// by default, empty for the shared segment
+type OrgIsolationSegments struct {
+	Entitled []string `json:"entitled"`
+	Default  string   `json:"default,omitempty"`
+}
+
+// SpaceIsolationSegment is the name of the isolation segment assigned to a space, empty when its apps run on the
+// default segment of its org
+type SpaceIsolationSegment struct {
+	Name string `json:"name,omitempty"`
+}
+
+// WriteOrgIsolationSegments writes the isolation segments of the org to the IsolationSegmentsFile of its directory
+func WriteOrgIsolationSegments(ctx *appcontext.Context, org string, segments OrgIsolationSegments) error {
+	return writeJSON(ctx, path.Join(org, IsolationSegmentsFile), segments)
+}
+
+// ReadOrgIsolationSegments reads the isolation segments of the org from the IsolationSegmentsFile of its directory
+func ReadOrgIsolationSegments(ctx *appcontext.Context, org string) (OrgIsolationSegments, error) {
+	var segments OrgIsolationSegments
+	err := readJSON(ctx, path.Join(org, IsolationSegmentsFile), &segments)
+	return segments, err
+}
+
+// WriteSpaceIsolationSegment writes the isolation segment of the space to the IsolationSegmentsFile of its directory
+func WriteSpaceIsolationSegment(ctx *appcontext.Context, org, space string, segment SpaceIsolationSegment) error {
+	return writeJSON(ctx, path.Join(org, space, IsolationSegmentsFile), segment)
+}
+
+// ReadSpaceIsolationSegment reads the isolation segment of the space from the IsolationSegmentsFile of its directory
+func ReadSpaceIsolationSegment(ctx *appcontext.Context, org, space string) (SpaceIsolationSegment, error) {
+	var segment SpaceIsolationSegment
+	err := readJSON(ctx, path.Join(org, space, IsolationSegmentsFile), &segment)
+	return segment, err
+}
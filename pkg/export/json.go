@@ -0,0 +1,55 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+)
+
+// writeJSON writes v to the named file of the export dir, indented for the files operators are expected to read
+func writeJSON(ctx *appcontext.Context, name string, v interface{}) error {
+	file, err := aio.PutFile(ctx.DirWriter, name, 0644, ctx.Sealer)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(v); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// readJSON reads the named file of the export into v
+func readJSON(ctx *appcontext.Context, name string, v interface{}) error {
+	file, err := aio.OpenFS(ctx.ExportFS(), name, ctx.Opener)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = json.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("error reading %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -19,10 +19,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"path"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/datamover"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/mapping"
 
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
 
@@ -52,12 +54,47 @@ type Application struct {
 	Instances               int64                  `yaml:"instances"`
 	Memory                  string                 `yaml:"memory"`
 	NoRoute                 bool                   `yaml:"no-route,omitempty"`
-	Routes                  []struct {
-		Route string `yaml:"route,omitempty"`
-	} `yaml:"routes,omitempty"`
-	Services []string `yaml:"services"`
-	Stack    string   `yaml:"stack"`
-	Timeout  int64    `yaml:"timeout,omitempty"`
+	Routes                  []Route                `yaml:"routes,omitempty"`
+	Services                []Service              `yaml:"services"`
+	Stack                   string                 `yaml:"stack"`
+	Timeout                 int64                  `yaml:"timeout,omitempty"`
+}
+
+// Service is a service instance bound to an application, with the name and the parameters of the binding. It is
+// written as just the name of the service instance when the binding has neither.
+type Service struct {
+	Name        string                 `yaml:"name"`
+	BindingName string                 `yaml:"binding_name,omitempty"`
+	Parameters  map[string]interface{} `yaml:"parameters,omitempty"`
+}
+
+// service has the fields of Service without its yaml methods
+type service Service
+
+func (s Service) MarshalYAML() (interface{}, error) {
+	if s.BindingName == "" && len(s.Parameters) == 0 {
+		return s.Name, nil
+	}
+	return service(s), nil
+}
+
+func (s *Service) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&s.Name); err == nil {
+		return nil
+	}
+	return unmarshal((*service)(s))
+}
+
+// Route is a route of an application, with the route service bound to it if there is one
+type Route struct {
+	Route        string        `yaml:"route,omitempty"`
+	RouteService *RouteService `yaml:"route-service,omitempty"`
+}
+
+// RouteService is the service instance a route is bound to, with the parameters of the binding
+type RouteService struct {
+	Name       string                 `yaml:"name"`
+	Parameters map[string]interface{} `yaml:"parameters,omitempty"`
 }
 
 func NewManifestExporter() *DefaultManifestExporter {
@@ -70,6 +107,13 @@ func (m *DefaultManifestExporter) ExportAppManifest(ctx *context.Context, org cf
 	manifestApp := Application{}
 	manifestApp.Name = app.Name
 	manifestApp.Env = app.Environment
+	if ctx.ExternalizeSecrets {
+		env, err := ctx.Secrets.Externalize(org.Name, space.Name, app.Name, app.Environment)
+		if err != nil {
+			return err
+		}
+		manifestApp.Env = env
+	}
 	manifestApp.HealthCheckType = app.HealthCheckType
 	manifestApp.HealthCheckHTTPEndpoint = app.HealthCheckHttpEndpoint
 	manifestApp.Instances = int64(app.Instances)
@@ -144,7 +188,8 @@ func (m *DefaultManifestExporter) ExportAppManifest(ctx *context.Context, org cf
 
 	var routesResponse = struct {
 		Resources []struct {
-			URL string `json:"url"`
+			GUID string `json:"guid"`
+			URL  string `json:"url"`
 		} `json:"resources"`
 	}{}
 
@@ -153,87 +198,159 @@ func (m *DefaultManifestExporter) ExportAppManifest(ctx *context.Context, org cf
 	}
 
 	manifestApp.NoRoute = len(routesResponse.Resources) == 0
-	manifestApp.Routes = make([]struct {
-		Route string `yaml:"route,omitempty"`
-	}, 0)
+	manifestApp.Routes = make([]Route, 0)
 
-	var routes []string
+	var (
+		routes     []string
+		routeGUIDs []string
+	)
 	for _, route := range routesResponse.Resources {
 		routes = append(routes, route.URL)
+		routeGUIDs = append(routeGUIDs, route.GUID)
 	}
 	routeMapper := &routeMapper{
 		DomainsToAdd:     ctx.DomainsToAdd,
 		DomainsToReplace: ctx.DomainsToReplace,
 	}
+
+	routeServices := make(map[string]*RouteService)
+	if len(routeGUIDs) > 0 {
+		services, err := getRouteServices(ctx, app, routeGUIDs)
+		if err != nil {
+			return err
+		}
+		for _, route := range routesResponse.Resources {
+			if rs, ok := services[route.GUID]; ok {
+				routeServices[routeMapper.adjustRoute(route.URL)] = rs
+			}
+		}
+	}
+
 	adjustedRoutes := routeMapper.AdjustRoutes(routes)
 	for _, adjustedRoute := range adjustedRoutes {
-		manifestApp.Routes = append(manifestApp.Routes, struct {
-			Route string `yaml:"route,omitempty"`
-		}{adjustedRoute})
+		manifestApp.Routes = append(manifestApp.Routes, Route{Route: adjustedRoute, RouteService: routeServices[adjustedRoute]})
 	}
 
-	var sbResp *http.Response
-	err = ctx.ExportCFClient.DoWithRetry(func() error {
-		req := ctx.ExportCFClient.NewRequest(http.MethodGet, fmt.Sprintf("/v2/apps/%s/service_bindings", app.Guid))
-		sbResp, err = ctx.ExportCFClient.DoRequest(req)
-		if err == nil {
-			if sbResp.StatusCode >= 500 && sbResp.StatusCode <= 599 {
-				defer sbResp.Body.Close()
-				return cf.ErrRetry
-			}
-		}
+	manifestApp.Services, err = getServices(ctx, org.Name, space.Name, app)
+	if err != nil {
 		return err
-	})
+	}
+
+	manifestFilePath := path.Join(appExportDir, getAppFileName(app.Name)+"_manifest.yml")
+	manifestFile, err := aio.PutFile(ctx.DirWriter, manifestFilePath, 0644, ctx.Sealer)
 	if err != nil {
 		return err
 	}
-	defer sbResp.Body.Close()
+	defer manifestFile.Close()
 
-	var bindings cfclient.ServiceBindingsResponse
-	if err = json.NewDecoder(sbResp.Body).Decode(&bindings); err != nil {
+	if err = yaml.NewEncoder(manifestFile).Encode(AppManifest{Applications: []Application{manifestApp}}); err != nil {
 		return err
 	}
 
-	manifestApp.Services = make([]string, 0, len(bindings.Resources))
-	for _, binding := range bindings.Resources {
-		var siResp *http.Response
-		err := ctx.ExportCFClient.DoWithRetry(func() error {
-			req := ctx.ExportCFClient.NewRequest(http.MethodGet, binding.Entity.ServiceInstanceUrl)
-			siResp, err = ctx.ExportCFClient.DoRequest(req)
-			if err == nil {
-				if siResp.StatusCode >= 500 && siResp.StatusCode <= 599 {
-					defer siResp.Body.Close()
-					return cf.ErrRetry
-				}
-			}
+	return manifestFile.Close()
+}
+
+// getServices returns the service instances the app is bound to, with the name and the parameters of each binding
+func getServices(ctx *context.Context, org, space string, app cfclient.App) ([]Service, error) {
+	var bindings []cf.ServiceCredentialBinding
+	err := withRetry(ctx.ExportCFClient, func() (err error) {
+		bindings, err = cf.ListAppServiceBindings(ctx.ExportCFClient, app.Guid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]Service, 0, len(bindings))
+	for _, binding := range bindings {
+		var si cf.ServiceInstance
+		err := withRetry(ctx.ExportCFClient, func() (err error) {
+			si, err = cf.GetServiceInstance(ctx.ExportCFClient, binding.Relationships.ServiceInstance.Data.GUID)
 			return err
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
-		defer siResp.Body.Close()
 
-		var si cfclient.ServiceInstanceResource
-		if err = json.NewDecoder(siResp.Body).Decode(&si); err != nil {
-			if siResp != nil && siResp.Body != nil {
-				siResp.Body.Close()
+		s := Service{Name: si.Name, BindingName: binding.Name}
+		if si.IsManaged() {
+			s.Parameters, err = cf.GetServiceCredentialBindingParameters(ctx.ExportCFClient, binding.GUID)
+			if err != nil {
+				ctx.Logger.Warnf("Could not get the parameters of the binding of service %s to app %s, they are not exported: %s", si.Name, app.Name, err)
+			}
+			if ctx.ExternalizeSecrets {
+				if err = exportBindingCredentials(ctx, org, space, app.Name, si, binding.GUID); err != nil {
+					return nil, err
+				}
 			}
-
-			return err
 		}
-		siResp.Body.Close()
+		services = append(services, s)
+	}
 
-		manifestApp.Services = append(manifestApp.Services, si.Entity.Name)
+	return services, nil
+}
+
+// exportBindingCredentials stores the credentials of the binding of the service instance to the app in the secrets
+// file when the offering the service instance gets on the target foundation has a data mover, which is given them to
+// move the data on import
+func exportBindingCredentials(ctx *context.Context, org, space, app string, si cf.ServiceInstance, bindingGUID string) error {
+	if len(ctx.DataMovers) == 0 {
+		return nil
 	}
 
-	manifestFilePath := path.Join(appExportDir, getAppFileName(app.Name)+"_manifest.yml")
-	manifestFile, err := os.Create(manifestFilePath)
+	plan, err := cf.GetServicePlan(ctx.ExportCFClient, si.PlanGUID())
 	if err != nil {
 		return err
 	}
-	defer manifestFile.Close()
+	target := ctx.ServiceMappings.Map(mapping.ServicePlan{Offering: plan.Offering, Plan: plan.Name, Broker: plan.Broker})
+	if _, ok := ctx.DataMovers[target.Offering]; !ok {
+		return nil
+	}
+
+	credentials, err := cf.GetServiceCredentialBindingCredentials(ctx.ExportCFClient, bindingGUID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+	return ctx.Secrets.Add(datamover.CredentialsSecret(org, space, app, si.Name), string(data))
+}
 
-	return yaml.NewEncoder(manifestFile).Encode(AppManifest{Applications: []Application{manifestApp}})
+// getRouteServices returns the route services the routes of the app are bound to, by route guid
+func getRouteServices(ctx *context.Context, app cfclient.App, routeGUIDs []string) (map[string]*RouteService, error) {
+	var bindings []cf.RouteBinding
+	err := withRetry(ctx.ExportCFClient, func() (err error) {
+		bindings, err = cf.ListRouteBindings(ctx.ExportCFClient, routeGUIDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*RouteService, len(bindings))
+	for _, binding := range bindings {
+		var si cf.ServiceInstance
+		err := withRetry(ctx.ExportCFClient, func() (err error) {
+			si, err = cf.GetServiceInstance(ctx.ExportCFClient, binding.Relationships.ServiceInstance.Data.GUID)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		rs := &RouteService{Name: si.Name}
+		if si.IsManaged() {
+			rs.Parameters, err = cf.GetRouteBindingParameters(ctx.ExportCFClient, binding.GUID)
+			if err != nil {
+				ctx.Logger.Warnf("Could not get the parameters of the route service %s bound to a route of app %s, they are not exported: %s", si.Name, app.Name, err)
+			}
+		}
+		services[binding.Relationships.Route.Data.GUID] = rs
+	}
+
+	return services, nil
 }
 
 func getSizeString(size int64) string {
@@ -245,3 +362,15 @@ func getSizeString(size int64) string {
 
 	return fmt.Sprintf("%d%s", size, suffix)
 }
+
+// withRetry calls f until it succeeds or fails with something else than a server error of the Cloud Controller
+func withRetry(c cf.Client, f func() error) error {
+	return c.DoWithRetry(func() error {
+		err := f()
+		cfErr := cfclient.CloudFoundryHTTPError{}
+		if errors.As(err, &cfErr) && cfErr.StatusCode >= 500 && cfErr.StatusCode <= 599 {
+			return cf.ErrRetry
+		}
+		return err
+	})
+}
@@ -15,12 +15,23 @@
 package export
 
 import (
+	"errors"
+	"net/http"
 	"reflect"
 	"testing"
 
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf/fakes"
 	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/datamover"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/secrets"
 
 	"github.com/cloudfoundry-community/go-cfclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestDefaultManifestExporter_ExportAppManifest(t *testing.T) {
@@ -63,3 +74,171 @@ func TestNewManifestExporter(t *testing.T) {
 		})
 	}
 }
+
+func TestService_YAML(t *testing.T) {
+	services := []Service{
+		{Name: "my-db"},
+		{Name: "my-bucket", BindingName: "uploads", Parameters: map[string]interface{}{"prefix": "uploads/"}},
+	}
+	want := `- my-db
+- name: my-bucket
+  binding_name: uploads
+  parameters:
+    prefix: uploads/
+`
+
+	data, err := yaml.Marshal(services)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(data))
+
+	var got []Service
+	require.NoError(t, yaml.Unmarshal(data, &got))
+	assert.Equal(t, services, got)
+}
+
+func TestGetServices(t *testing.T) {
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_credential_bindings?app_guids=app-guid&type=app":
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "binding-1-guid", "type": "app", "name": "uploads", "relationships": {"app": {"data": {"guid": "app-guid"}}, "service_instance": {"data": {"guid": "bucket-guid"}}}},
+  {"guid": "binding-2-guid", "type": "app", "name": null, "relationships": {"app": {"data": {"guid": "app-guid"}}, "service_instance": {"data": {"guid": "config-guid"}}}}
+]}`), nil
+			case "/v3/service_instances/bucket-guid":
+				return []byte(`{"guid": "bucket-guid", "name": "my-bucket", "type": "managed"}`), nil
+			case "/v3/service_instances/config-guid":
+				return []byte(`{"guid": "config-guid", "name": "my-config", "type": "user-provided"}`), nil
+			case "/v3/service_credential_bindings/binding-1-guid/parameters":
+				return []byte(`{"prefix": "uploads/"}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{Logger: log.New(), ExportCFClient: client}
+
+	services, err := getServices(ctx, "my_org", "my_space", cfclient.App{Guid: "app-guid", Name: "my_app"})
+	require.NoError(t, err)
+	assert.Equal(t, []Service{
+		{Name: "my-bucket", BindingName: "uploads", Parameters: map[string]interface{}{"prefix": "uploads/"}},
+		{Name: "my-config"},
+	}, services)
+}
+
+func TestGetRouteServices(t *testing.T) {
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_route_bindings?route_guids=route-a-guid%2Croute-b-guid%2Croute-c-guid":
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "binding-a-guid", "relationships": {"route": {"data": {"guid": "route-a-guid"}}, "service_instance": {"data": {"guid": "gateway-guid"}}}},
+  {"guid": "binding-b-guid", "relationships": {"route": {"data": {"guid": "route-b-guid"}}, "service_instance": {"data": {"guid": "proxy-guid"}}}}
+]}`), nil
+			case "/v3/service_instances/gateway-guid":
+				return []byte(`{"guid": "gateway-guid", "name": "gateway", "type": "managed"}`), nil
+			case "/v3/service_instances/proxy-guid":
+				return []byte(`{"guid": "proxy-guid", "name": "proxy", "type": "user-provided"}`), nil
+			case "/v3/service_route_bindings/binding-a-guid/parameters":
+				return []byte(`{"rate": 100}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{Logger: log.New(), ExportCFClient: client}
+
+	services, err := getRouteServices(ctx, cfclient.App{Name: "my_app"}, []string{"route-a-guid", "route-b-guid", "route-c-guid"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*RouteService{
+		"route-a-guid": {Name: "gateway", Parameters: map[string]interface{}{"rate": float64(100)}},
+		"route-b-guid": {Name: "proxy"},
+	}, services)
+}
+
+func TestGetServices_DataMoverCredentials(t *testing.T) {
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			return f()
+		},
+		GetStub: func(path string) ([]byte, error) {
+			switch path {
+			case "/v3/service_credential_bindings?app_guids=app-guid&type=app":
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "binding-1-guid", "type": "app", "relationships": {"app": {"data": {"guid": "app-guid"}}, "service_instance": {"data": {"guid": "db-guid"}}}},
+  {"guid": "binding-2-guid", "type": "app", "relationships": {"app": {"data": {"guid": "app-guid"}}, "service_instance": {"data": {"guid": "cache-guid"}}}}
+]}`), nil
+			case "/v3/service_instances/db-guid":
+				return []byte(`{"guid": "db-guid", "name": "my-db", "type": "managed", "relationships": {"service_plan": {"data": {"guid": "db-plan-guid"}}}}`), nil
+			case "/v3/service_instances/cache-guid":
+				return []byte(`{"guid": "cache-guid", "name": "my-cache", "type": "managed", "relationships": {"service_plan": {"data": {"guid": "cache-plan-guid"}}}}`), nil
+			case "/v3/service_plans/db-plan-guid?fields%5Bservice_offering.service_broker%5D=name&include=service_offering":
+				return []byte(`{"guid": "db-plan-guid", "name": "db-small", "included": {"service_offerings": [{"name": "p.mysql"}]}}`), nil
+			case "/v3/service_plans/cache-plan-guid?fields%5Bservice_offering.service_broker%5D=name&include=service_offering":
+				return []byte(`{"guid": "cache-plan-guid", "name": "cache-small", "included": {"service_offerings": [{"name": "p.redis"}]}}`), nil
+			case "/v3/service_credential_bindings/binding-1-guid/details":
+				return []byte(`{"credentials": {"uri": "mysql://source"}}`), nil
+			case "/v3/service_credential_bindings/binding-1-guid/parameters", "/v3/service_credential_bindings/binding-2-guid/parameters":
+				return []byte(`{}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{
+		Logger:             log.New(),
+		ExportCFClient:     client,
+		ExternalizeSecrets: true,
+		Secrets:            secrets.New(crypt.NewPassphraseKey("passphrase"), nil),
+		DataMovers:         map[string]string{"p.mysql": "mysql-mover"},
+	}
+
+	_, err := getServices(ctx, "my_org", "my_space", cfclient.App{Guid: "app-guid", Name: "my_app"})
+	require.NoError(t, err)
+
+	credentials, ok := ctx.Secrets.Value(datamover.CredentialsSecret("my_org", "my_space", "my_app", "my-db").Name())
+	assert.True(t, ok)
+	assert.Equal(t, `{"uri":"mysql://source"}`, credentials)
+	_, ok = ctx.Secrets.Value(datamover.CredentialsSecret("my_org", "my_space", "my_app", "my-cache").Name())
+	assert.False(t, ok)
+}
+
+func TestGetServices_RetriesServerErrors(t *testing.T) {
+	calls := make(map[string]int)
+	client := &fakes.FakeClient{
+		DoWithRetryStub: func(f func() error) error {
+			for {
+				if err := f(); !errors.Is(err, cf.ErrRetry) {
+					return err
+				}
+			}
+		},
+		GetStub: func(path string) ([]byte, error) {
+			calls[path]++
+			if calls[path] == 1 {
+				return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusBadGateway}
+			}
+			switch path {
+			case "/v3/service_credential_bindings?app_guids=app-guid&type=app":
+				return []byte(`{"pagination": {}, "resources": [
+  {"guid": "binding-guid", "type": "app", "relationships": {"app": {"data": {"guid": "app-guid"}}, "service_instance": {"data": {"guid": "config-guid"}}}}
+]}`), nil
+			case "/v3/service_instances/config-guid":
+				return []byte(`{"guid": "config-guid", "name": "my-config", "type": "user-provided"}`), nil
+			}
+			return nil, cfclient.CloudFoundryHTTPError{StatusCode: http.StatusNotFound}
+		},
+	}
+	ctx := &context.Context{Logger: log.New(), ExportCFClient: client}
+
+	services, err := getServices(ctx, "my_org", "my_space", cfclient.App{Guid: "app-guid", Name: "my_app"})
+	require.NoError(t, err)
+	assert.Equal(t, []Service{{Name: "my-config"}}, services)
+	assert.Equal(t, map[string]int{
+		"/v3/service_credential_bindings?app_guids=app-guid&type=app": 2,
+		"/v3/service_instances/config-guid":                           2,
+	}, calls)
+}
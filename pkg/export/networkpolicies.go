@@ -0,0 +1,70 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+)
+
+const (
+	// NetworkPoliciesDir is the directory of the export holding the network policies
+	NetworkPoliciesDir = aio.ReservedDir + "/network_policies"
+	// NetworkPoliciesFile lists the exported network policies between apps
+	NetworkPoliciesFile = NetworkPoliciesDir + "/policies.json"
+)
+
+// AppRef identifies an app by name, as guids differ between foundations
+type AppRef struct {
+	Org   string `json:"org"`
+	Space string `json:"space"`
+	App   string `json:"app"`
+}
+
+func (a AppRef) String() string {
+	return path.Join(a.Org, a.Space, a.App)
+}
+
+// NetworkPolicy lets the source app connect to the destination app over the container network
+type NetworkPolicy struct {
+	Source      AppRef         `json:"source"`
+	Destination AppRef         `json:"destination"`
+	Protocol    string         `json:"protocol"`
+	Ports       cf.PolicyPorts `json:"ports"`
+}
+
+func (p NetworkPolicy) String() string {
+	ports := fmt.Sprintf("%d", p.Ports.Start)
+	if p.Ports.End != p.Ports.Start {
+		ports = fmt.Sprintf("%d-%d", p.Ports.Start, p.Ports.End)
+	}
+	return fmt.Sprintf("%s -> %s %s %s", p.Source, p.Destination, p.Protocol, ports)
+}
+
+// WriteNetworkPolicies writes the exported network policies to NetworkPoliciesFile
+func WriteNetworkPolicies(ctx *appcontext.Context, policies []NetworkPolicy) error {
+	return writeJSON(ctx, NetworkPoliciesFile, policies)
+}
+
+// ReadNetworkPolicies reads the exported network policies from NetworkPoliciesFile
+func ReadNetworkPolicies(ctx *appcontext.Context) ([]NetworkPolicy, error) {
+	var policies []NetworkPolicy
+	err := readJSON(ctx, NetworkPoliciesFile, &policies)
+	return policies, err
+}
@@ -0,0 +1,66 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+	aio "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/io"
+)
+
+const (
+	// QuotasDir is the directory of the export holding the quota definitions
+	QuotasDir = aio.ReservedDir + "/quotas"
+	// QuotasFile lists the exported org and space quotas with the orgs and spaces they are assigned to
+	QuotasFile = QuotasDir + "/quotas.json"
+)
+
+// OrgQuota is an organization quota exported with the names of the orgs it is assigned to
+type OrgQuota struct {
+	Name     string           `json:"name"`
+	Apps     cf.QuotaApps     `json:"apps"`
+	Services cf.QuotaServices `json:"services"`
+	Routes   cf.QuotaRoutes   `json:"routes"`
+	Domains  cf.QuotaDomains  `json:"domains"`
+	Orgs     []string         `json:"orgs,omitempty"`
+}
+
+// SpaceQuota is a space quota exported with the name of its org and the names of the spaces it is assigned to
+type SpaceQuota struct {
+	Name     string           `json:"name"`
+	Org      string           `json:"org"`
+	Apps     cf.QuotaApps     `json:"apps"`
+	Services cf.QuotaServices `json:"services"`
+	Routes   cf.QuotaRoutes   `json:"routes"`
+	Spaces   []string         `json:"spaces,omitempty"`
+}
+
+// Quotas holds the quota definitions of a foundation
+type Quotas struct {
+	OrgQuotas   []OrgQuota   `json:"org_quotas"`
+	SpaceQuotas []SpaceQuota `json:"space_quotas"`
+}
+
+// WriteQuotas writes the exported quotas to QuotasFile
+func WriteQuotas(ctx *appcontext.Context, quotas Quotas) error {
+	return writeJSON(ctx, QuotasFile, quotas)
+}
+
+// ReadQuotas reads the exported quotas from QuotasFile
+func ReadQuotas(ctx *appcontext.Context) (Quotas, error) {
+	var quotas Quotas
+	err := readJSON(ctx, QuotasFile, &quotas)
+	return quotas, err
+}
@@ -0,0 +1,49 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"path"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+// RolesFile lists the roles of the users of an org, or of a space, in the directory of the org or space
+const RolesFile = "roles.json"
+
+// WriteOrgRoles writes the roles of the users of the org to the RolesFile of its directory
+func WriteOrgRoles(ctx *appcontext.Context, org string, roles []cf.UserRole) error {
+	return writeJSON(ctx, path.Join(org, RolesFile), roles)
+}
+
+// ReadOrgRoles reads the roles of the users of the org from the RolesFile of its directory
+func ReadOrgRoles(ctx *appcontext.Context, org string) ([]cf.UserRole, error) {
+	var roles []cf.UserRole
+	err := readJSON(ctx, path.Join(org, RolesFile), &roles)
+	return roles, err
+}
+
+// WriteSpaceRoles writes the roles of the users of the space to the RolesFile of its directory
+func WriteSpaceRoles(ctx *appcontext.Context, org, space string, roles []cf.UserRole) error {
+	return writeJSON(ctx, path.Join(org, space, RolesFile), roles)
+}
+
+// ReadSpaceRoles reads the roles of the users of the space from the RolesFile of its directory
+func ReadSpaceRoles(ctx *appcontext.Context, org, space string) ([]cf.UserRole, error) {
+	var roles []cf.UserRole
+	err := readJSON(ctx, path.Join(org, space, RolesFile), &roles)
+	return roles, err
+}
@@ -47,19 +47,19 @@ func (r *routeMapper) AdjustRoutes(existingRoutes []string) []string {
 	*/
 
 	for _, route := range existingRoutes {
-		found := false
-		for oldRouteToBeReplaced, newRouteToBeUsed := range r.DomainsToReplace {
-			if strings.Contains(route, oldRouteToBeReplaced) {
-				// we found an old route in need of replacement
-				adjustedRoutes = append(adjustedRoutes, strings.Replace(route, oldRouteToBeReplaced, newRouteToBeUsed, 1))
-				found = true
-				break
-			}
-		}
-		if !found {
-			adjustedRoutes = append(adjustedRoutes, route)
-		}
+		adjustedRoutes = append(adjustedRoutes, r.adjustRoute(route))
 	}
 
 	return adjustedRoutes
 }
+
+// adjustRoute returns the route with the first domain replacement rule it qualifies for applied
+func (r *routeMapper) adjustRoute(route string) string {
+	for oldRouteToBeReplaced, newRouteToBeUsed := range r.DomainsToReplace {
+		if strings.Contains(route, oldRouteToBeReplaced) {
+			// we found an old route in need of replacement
+			return strings.Replace(route, oldRouteToBeReplaced, newRouteToBeUsed, 1)
+		}
+	}
+	return route
+}
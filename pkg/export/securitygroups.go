@@ -0,0 +1,45 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"path"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/cf"
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+// SecurityGroupsFile lists the security groups bound to a space, in the directory of the space
+const SecurityGroupsFile = "security_groups.json"
+
+// SecurityGroup is a security group bound to a space, for its running apps, for staging them, or both
+type SecurityGroup struct {
+	Name    string                 `json:"name"`
+	Rules   []cf.SecurityGroupRule `json:"rules"`
+	Running bool                   `json:"running"`
+	Staging bool                   `json:"staging"`
+}
+
+// WriteSpaceSecurityGroups writes the security groups bound to the space to the SecurityGroupsFile of its directory
+func WriteSpaceSecurityGroups(ctx *appcontext.Context, org, space string, groups []SecurityGroup) error {
+	return writeJSON(ctx, path.Join(org, space, SecurityGroupsFile), groups)
+}
+
+// ReadSpaceSecurityGroups reads the security groups bound to the space from the SecurityGroupsFile of its directory
+func ReadSpaceSecurityGroups(ctx *appcontext.Context, org, space string) ([]SecurityGroup, error) {
+	var groups []SecurityGroup
+	err := readJSON(ctx, path.Join(org, space, SecurityGroupsFile), &groups)
+	return groups, err
+}
@@ -0,0 +1,49 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"path"
+
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+// ServiceInstancesFile lists the managed service instances of a space, in the directory of the space
+const ServiceInstancesFile = "service_instances.json"
+
+// ServiceInstance is a managed service instance, with the names of its service offering, plan and broker on the
+// source foundation
+type ServiceInstance struct {
+	Name       string                 `json:"name"`
+	Offering   string                 `json:"offering"`
+	Plan       string                 `json:"plan"`
+	Broker     string                 `json:"broker,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Tags       []string               `json:"tags,omitempty"`
+}
+
+// WriteSpaceServiceInstances writes the managed service instances of the space to the ServiceInstancesFile of its
+// directory
+func WriteSpaceServiceInstances(ctx *appcontext.Context, org, space string, instances []ServiceInstance) error {
+	return writeJSON(ctx, path.Join(org, space, ServiceInstancesFile), instances)
+}
+
+// ReadSpaceServiceInstances reads the managed service instances of the space from the ServiceInstancesFile of its
+// directory
+func ReadSpaceServiceInstances(ctx *appcontext.Context, org, space string) ([]ServiceInstance, error) {
+	var instances []ServiceInstance
+	err := readJSON(ctx, path.Join(org, space, ServiceInstancesFile), &instances)
+	return instances, err
+}
@@ -0,0 +1,53 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package export
+
+import (
+	"path"
+
+	appcontext "github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/context"
+)
+
+// ServiceKeysFile lists the service keys of the service instances of a space, in the directory of the space
+const ServiceKeysFile = "service_keys.json"
+
+// ServiceKey is a service key of a service instance, used by consumers outside the foundation
+type ServiceKey struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Credentials is the name of the secret holding the credentials of the key, only set when the secrets are
+	// externalized, so that they can be compared with the credentials of the key on the target foundation
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// ServiceInstanceKeys are the service keys of a service instance of a space
+type ServiceInstanceKeys struct {
+	ServiceInstance string       `json:"service_instance"`
+	Keys            []ServiceKey `json:"keys"`
+}
+
+// WriteSpaceServiceKeys writes the service keys of the service instances of the space to the ServiceKeysFile of its
+// directory
+func WriteSpaceServiceKeys(ctx *appcontext.Context, org, space string, keys []ServiceInstanceKeys) error {
+	return writeJSON(ctx, path.Join(org, space, ServiceKeysFile), keys)
+}
+
+// ReadSpaceServiceKeys reads the service keys of the service instances of the space from the ServiceKeysFile of its
+// directory
+func ReadSpaceServiceKeys(ctx *appcontext.Context, org, space string) ([]ServiceInstanceKeys, error) {
+	var keys []ServiceInstanceKeys
+	err := readJSON(ctx, path.Join(org, space, ServiceKeysFile), &keys)
+	return keys, err
+}
@@ -15,9 +15,12 @@
 package io
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -33,6 +36,10 @@ type FileDescriptor struct {
 
 const pathSep = string(os.PathSeparator)
 
+// ReservedDir is the directory at the top of an export holding everything that is not the apps of an org, such as the
+// blob stores, buildpacks, quotas, domains and network policies
+const ReservedDir = ".app-migrator"
+
 var supportedExtensions = []string{".yml", ".yaml"}
 
 type InvalidFileExtensionError struct {
@@ -78,47 +85,6 @@ func GetOrgSpace(path string) (string, string) {
 	return orgSpace[0], orgSpace[1]
 }
 
-type FlatDir struct {
-}
-
-func NewDirWriter() FlatDir {
-	return FlatDir{}
-}
-
-func (fd FlatDir) Mkdir(dir string) error {
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (fd FlatDir) MkdirAll(dir string, perm os.FileMode) error {
-	return os.MkdirAll(dir, perm)
-}
-
-func (fd FlatDir) IsEmpty(name string) (bool, error) {
-	if _, err := os.Stat(name); os.IsNotExist(err) {
-		return true, nil
-	}
-	f, err := os.Open(name)
-	if err != nil {
-		return false, err
-	}
-	defer func(f *os.File) {
-		_ = f.Close()
-	}(f)
-
-	_, err = f.Readdirnames(1)
-	if err == io.EOF {
-		return true, nil
-	}
-
-	return false, err
-}
-
 func CreateFileIfNotExist(f string) (*os.File, error) {
 	var file *os.File
 	var err error
@@ -154,3 +120,120 @@ func CopyToTempFile(src io.Reader) (*os.File, error) {
 
 	return file, nil
 }
+
+type createdFile struct {
+	io.Writer
+	sealer io.Closer
+	file   io.Closer
+	closed bool
+}
+
+// Close seals the final chunk when encrypting and closes the file, it is safe to call more than once
+func (f *createdFile) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	var err error
+	if f.sealer != nil {
+		err = f.sealer.Close()
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type openedFile struct {
+	io.Reader
+	file io.Closer
+}
+
+func (f *openedFile) Close() error {
+	return f.file.Close()
+}
+
+// CreateFile creates the named file, encrypting everything written to it when sealer is not nil.
+// The returned writer must be closed for the file to be complete.
+func CreateFile(name string, sealer crypt.Sealer) (io.WriteCloser, error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return seal(name, file, sealer)
+}
+
+// Putter stores named files, such as the storage of an export
+type Putter interface {
+	Put(name string, perm os.FileMode) (io.WriteCloser, error)
+}
+
+// PutFile creates the named file in p, encrypting everything written to it when sealer is not nil.
+// The returned writer must be closed for the file to be complete.
+func PutFile(p Putter, name string, perm os.FileMode, sealer crypt.Sealer) (io.WriteCloser, error) {
+	file, err := p.Put(name, perm)
+	if err != nil {
+		return nil, err
+	}
+	return seal(name, file, sealer)
+}
+
+func seal(name string, file io.WriteCloser, sealer crypt.Sealer) (io.WriteCloser, error) {
+	if sealer == nil {
+		return &createdFile{Writer: file, file: file}, nil
+	}
+	w, err := sealer.NewWriter(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrapf(err, "failed to encrypt %s", name)
+	}
+	return &createdFile{Writer: w, sealer: w, file: file}, nil
+}
+
+// OpenFile opens the named file for reading, transparently decrypting it when it was written encrypted
+func OpenFile(name string, opener crypt.Opener) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(name, file, opener)
+}
+
+// OpenFS opens the named file of fsys for reading, transparently decrypting it when it was written encrypted
+func OpenFS(fsys fs.FS, name string, opener crypt.Opener) (io.ReadCloser, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(name, file, opener)
+}
+
+func decrypt(name string, file io.ReadCloser, opener crypt.Opener) (io.ReadCloser, error) {
+	br := bufio.NewReader(file)
+	magic, err := br.Peek(crypt.StreamMagicSize())
+	if err != nil && err != io.EOF {
+		_ = file.Close()
+		return nil, err
+	}
+	if !crypt.IsEncryptedStream(magic) {
+		return &openedFile{Reader: br, file: file}, nil
+	}
+	if opener == nil {
+		_ = file.Close()
+		return nil, &EncryptedFileError{Name: name}
+	}
+	r, err := opener.NewReader(br)
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrapf(err, "failed to decrypt %s", name)
+	}
+	return &openedFile{Reader: r, file: file}, nil
+}
+
+type EncryptedFileError struct {
+	Name string
+}
+
+func (e *EncryptedFileError) Error() string {
+	return fmt.Sprintf("%s is encrypted, pass --encryption-key-file or --identity-file, or set %s", e.Name, crypt.PassphraseEnv)
+}
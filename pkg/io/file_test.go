@@ -16,9 +16,9 @@ package io
 
 import (
 	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
 	"io"
 	"os"
-	"path"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -130,37 +130,6 @@ func TestGetOrgSpace(t *testing.T) {
 	}
 }
 
-func TestMkdir(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "")
-	require.NoError(t, err)
-	dir := path.Join(tmpDir, "org", "spacey")
-	type args struct {
-		dir string
-	}
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			name: "creates temp dir",
-			args: args{
-				dir: dir,
-			},
-			wantErr: false,
-		},
-	}
-	for _, tt := range tests {
-		fd := FlatDir{}
-		t.Run(tt.name, func(t *testing.T) {
-			if err := fd.Mkdir(tt.args.dir); (err != nil) != tt.wantErr {
-				t.Errorf("Mkdir() error = %v, wantErr %v", err, tt.wantErr)
-			}
-			require.DirExists(t, dir, "directory exists")
-		})
-	}
-}
-
 func TestNewFileDescriptor(t *testing.T) {
 	pwd, err := os.Getwd()
 	require.NoError(t, err)
@@ -207,3 +176,72 @@ func TestNewFileDescriptor(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateFileOpenFile(t *testing.T) {
+	key := crypt.NewPassphraseKey("correct horse battery staple")
+	otherKey := crypt.NewPassphraseKey("another passphrase")
+	tests := []struct {
+		name      string
+		sealer    crypt.Sealer
+		opener    crypt.Opener
+		wantPlain bool
+		wantErr   string
+	}{
+		{
+			name:      "plain file is read as is",
+			wantPlain: true,
+		},
+		{
+			name:      "plain file is read as is when an opener is given",
+			opener:    key,
+			wantPlain: true,
+		},
+		{
+			name:   "encrypted file is decrypted",
+			sealer: key,
+			opener: key,
+		},
+		{
+			name:    "encrypted file without an opener fails",
+			sealer:  key,
+			wantErr: "is encrypted",
+		},
+		{
+			name:    "encrypted file with the wrong key fails",
+			sealer:  key,
+			opener:  otherKey,
+			wantErr: "decrypt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "manifest.yml")
+			content := "applications:\n- name: my-app\n"
+
+			w, err := CreateFile(name, tt.sealer)
+			require.NoError(t, err)
+			_, err = io.WriteString(w, content)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			require.NoError(t, w.Close())
+
+			raw, err := os.ReadFile(name)
+			require.NoError(t, err)
+			require.Equal(t, tt.sealer == nil, string(raw) == content)
+
+			r, err := OpenFile(name, tt.opener)
+			var got []byte
+			if err == nil {
+				defer r.Close()
+				got, err = io.ReadAll(r)
+			}
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, content, string(got))
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package io
+
+import (
+	"io"
+	"sync"
+)
+
+// DefaultPipeSize is the number of bytes a buffered pipe holds when no size is given
+const DefaultPipeSize = 4 << 20
+
+// NewBufferedPipe creates an in-memory pipe like io.Pipe, except that the writer may run
+// up to size bytes ahead of the reader before it blocks
+func NewBufferedPipe(size int) (*PipeReader, *PipeWriter) {
+	if size <= 0 {
+		size = DefaultPipeSize
+	}
+	p := &pipe{buf: make([]byte, size)}
+	p.cond = sync.NewCond(&p.mu)
+	return &PipeReader{p}, &PipeWriter{p}
+}
+
+type pipe struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	buf   []byte
+	start int
+	n     int
+	rerr  error
+	werr  error
+}
+
+func (p *pipe) read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.n == 0 && p.rerr == nil && p.werr == nil {
+		p.cond.Wait()
+	}
+	if p.rerr != nil {
+		return 0, io.ErrClosedPipe
+	}
+	if p.n == 0 {
+		return 0, p.werr
+	}
+
+	read := 0
+	for read < len(b) && p.n > 0 {
+		end := p.start + p.n
+		if end > len(p.buf) {
+			end = len(p.buf)
+		}
+		c := copy(b[read:], p.buf[p.start:end])
+		read += c
+		p.n -= c
+		p.start = (p.start + c) % len(p.buf)
+	}
+	p.cond.Broadcast()
+	return read, nil
+}
+
+func (p *pipe) write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	written := 0
+	for written < len(b) {
+		for p.n == len(p.buf) && p.rerr == nil && p.werr == nil {
+			p.cond.Wait()
+		}
+		if p.rerr != nil {
+			return written, p.rerr
+		}
+		if p.werr != nil {
+			return written, io.ErrClosedPipe
+		}
+
+		for written < len(b) && p.n < len(p.buf) {
+			end := (p.start + p.n) % len(p.buf)
+			limit := len(p.buf)
+			if end < p.start {
+				limit = p.start
+			}
+			c := copy(p.buf[end:limit], b[written:])
+			written += c
+			p.n += c
+		}
+		p.cond.Broadcast()
+	}
+	return written, nil
+}
+
+func (p *pipe) closeRead(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	if p.rerr == nil {
+		p.rerr = err
+	}
+	p.cond.Broadcast()
+}
+
+func (p *pipe) closeWrite(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		err = io.EOF
+	}
+	if p.werr == nil {
+		p.werr = err
+	}
+	p.cond.Broadcast()
+}
+
+// PipeReader is the read half of a buffered pipe
+type PipeReader struct {
+	p *pipe
+}
+
+// Read reads buffered data, blocking until data is written or the writer is closed.
+// Once everything written has been read, it returns the error the writer was closed with, io.EOF by default.
+func (r *PipeReader) Read(b []byte) (int, error) {
+	return r.p.read(b)
+}
+
+func (r *PipeReader) Close() error {
+	return r.CloseWithError(nil)
+}
+
+// CloseWithError closes the reader, so that subsequent writes return err, io.ErrClosedPipe by default
+func (r *PipeReader) CloseWithError(err error) error {
+	r.p.closeRead(err)
+	return nil
+}
+
+// PipeWriter is the write half of a buffered pipe
+type PipeWriter struct {
+	p *pipe
+}
+
+// Write copies b into the buffer, blocking while the buffer is full
+func (w *PipeWriter) Write(b []byte) (int, error) {
+	return w.p.write(b)
+}
+
+func (w *PipeWriter) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError closes the writer, so that reads return err once the buffer is drained, io.EOF by default
+func (w *PipeWriter) CloseWithError(err error) error {
+	w.p.closeWrite(err)
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package io
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedPipe(t *testing.T) {
+	data := make([]byte, 64<<10)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	r, w := NewBufferedPipe(7)
+	go func() {
+		_, err := io.Copy(w, bytes.NewReader(data))
+		w.CloseWithError(err)
+	}()
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+}
+
+func TestBufferedPipe_WriterError(t *testing.T) {
+	r, w := NewBufferedPipe(16)
+	wantErr := errors.New("download failed")
+	go func() {
+		_, _ = w.Write([]byte("partial"))
+		w.CloseWithError(wantErr)
+	}()
+
+	got, err := io.ReadAll(r)
+	require.ErrorIs(t, err, wantErr)
+	require.Equal(t, "partial", string(got))
+}
+
+func TestBufferedPipe_ReaderClosed(t *testing.T) {
+	r, w := NewBufferedPipe(4)
+	wantErr := errors.New("upload failed")
+	done := make(chan error)
+	go func() {
+		_, err := w.Write([]byte("more than four bytes"))
+		done <- err
+	}()
+
+	r.CloseWithError(wantErr)
+	require.ErrorIs(t, <-done, wantErr)
+
+	_, err := r.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
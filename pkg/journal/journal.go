@@ -0,0 +1,278 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+)
+
+// Action identifies a mutating call the importer made against the target foundation
+type Action string
+
+const (
+	CreateApp                 Action = "create-app"
+	UpdateApp                 Action = "update-app"
+	UpdateAppLifecycle        Action = "update-app-lifecycle"
+	CreateRoute               Action = "create-route"
+	BindRoute                 Action = "bind-route"
+	CreateServiceBinding      Action = "create-service-binding"
+	CreateRouteBinding        Action = "create-route-binding"
+	UploadDroplet             Action = "upload-droplet"
+	UpdateAutoscalerRules     Action = "update-autoscaler-rules"
+	UpdateAutoscalerInstances Action = "update-autoscaler-instances"
+	UpdateAutoscalerSchedules Action = "update-autoscaler-schedules"
+
+	// the actions taken on the resources that are not apps, their entries name the resource instead of an app
+	CreateServiceInstance    Action = "create-service-instance"
+	CreateServiceKey         Action = "create-service-key"
+	CreateDomain             Action = "create-domain"
+	ShareDomain              Action = "share-domain"
+	CreateSecurityGroup      Action = "create-security-group"
+	UpdateSecurityGroup      Action = "update-security-group"
+	BindRunningSecurityGroup Action = "bind-running-security-group"
+	BindStagingSecurityGroup Action = "bind-staging-security-group"
+	CreateOrgQuota           Action = "create-org-quota"
+	UpdateOrgQuota           Action = "update-org-quota"
+	ApplyOrgQuota            Action = "apply-org-quota"
+	CreateSpaceQuota         Action = "create-space-quota"
+	UpdateSpaceQuota         Action = "update-space-quota"
+	ApplySpaceQuota          Action = "apply-space-quota"
+	CreateRole               Action = "create-role"
+	CreateNetworkPolicy      Action = "create-network-policy"
+	EntitleIsolationSegment  Action = "entitle-isolation-segment"
+	SetOrgIsolationSegment   Action = "set-org-isolation-segment"
+	SetSpaceIsolationSegment Action = "set-space-isolation-segment"
+	CreateBuildpack          Action = "create-buildpack"
+	UpdateBuildpack          Action = "update-buildpack"
+	UploadBuildpack          Action = "upload-buildpack"
+)
+
+const runIDFormat = "20060102T150405Z"
+
+// Entry is a single journaled action. GUID is the resource that was created or changed,
+// and Previous holds the state it had before an update so that it can be restored.
+// Previous can hold the env vars of an app, it is written to the journal file sealed when the journal has a sealer.
+// The entries of the actions taken on resources that are not apps have no App but the Name of the resource, and the
+// RelatedGUID of the org, space or other resource it was bound, shared or assigned to.
+type Entry struct {
+	Action      Action          `json:"action"`
+	Org         string          `json:"org"`
+	Space       string          `json:"space"`
+	App         string          `json:"app"`
+	Name        string          `json:"name,omitempty"`
+	GUID        string          `json:"guid"`
+	AppGUID     string          `json:"app_guid,omitempty"`
+	RelatedGUID string          `json:"related_guid,omitempty"`
+	Previous    json.RawMessage `json:"previous,omitempty"`
+	Sealed      []byte          `json:"sealed,omitempty"`
+	Time        string          `json:"time"`
+}
+
+// Journal is a thread safe, append only record of every mutating action taken during a single run
+type Journal struct {
+	RunID  string
+	dir    string
+	sealer crypt.Sealer
+	file   *os.File
+	count  int
+	mutex  sync.Mutex
+}
+
+// New creates a journal for a new run, the journal file is only created in dir once the first entry is recorded.
+// The previous state of the entries is sealed with sealer, unless it is nil.
+func New(dir string, sealer crypt.Sealer) *Journal {
+	return &Journal{
+		RunID:  newRunID(),
+		dir:    dir,
+		sealer: sealer,
+	}
+}
+
+// newRunID is the time of the run followed by a random suffix, so that runs started in the same second get their
+// own journal
+func newRunID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format(runIDFormat) + "-" + hex.EncodeToString(suffix)
+}
+
+// Sealed reports whether the previous state of the entries is sealed before being written to the journal file
+func (j *Journal) Sealed() bool {
+	return j != nil && j.sealer != nil
+}
+
+// FileName returns the name of the journal file for a given run
+func FileName(runID string) string {
+	return fmt.Sprintf("journal-%s.jsonl", runID)
+}
+
+// Path returns the location of the journal file
+func (j *Journal) Path() string {
+	return filepath.Join(j.dir, FileName(j.RunID))
+}
+
+// Count is the number of entries recorded so far
+func (j *Journal) Count() int {
+	if j == nil {
+		return 0
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.count
+}
+
+// Record appends an entry to the journal and syncs it to disk, it is a no-op on a nil journal
+func (j *Journal) Record(e Entry) error {
+	if j == nil {
+		return nil
+	}
+
+	if e.Time == "" {
+		e.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	if j.sealer != nil && len(e.Previous) > 0 {
+		sealed, err := seal(j.sealer, e.Previous)
+		if err != nil {
+			return fmt.Errorf("failed to seal the previous state of %s %s: %w", e.Action, e.GUID, err)
+		}
+		e.Previous, e.Sealed = nil, sealed
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		if err = os.MkdirAll(j.dir, 0755); err != nil {
+			return err
+		}
+		// the journal file of another run is never appended to, the run gets a new id if its file already exists
+		for {
+			j.file, err = os.OpenFile(j.Path(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if !errors.Is(err, fs.ErrExist) {
+				break
+			}
+			j.RunID = newRunID()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open journal %s: %w", j.Path(), err)
+		}
+	}
+
+	if _, err = j.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	j.count++
+
+	return j.file.Sync()
+}
+
+// Close closes the journal file if one was created
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// Load reads all the entries recorded for a given run in the order they were recorded, opening their sealed
+// previous state with opener
+func Load(dir, runID string, opener crypt.Opener) ([]Entry, error) {
+	file, err := os.Open(filepath.Join(dir, FileName(runID)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no journal found for run %s in %s", runID, dir)
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err = json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("failed to parse journal %s: %w", file.Name(), err)
+		}
+		if len(e.Sealed) > 0 {
+			if opener == nil {
+				return nil, fmt.Errorf("journal %s is sealed, the key or identity file of the run is required to open it", file.Name())
+			}
+			if e.Previous, err = open(opener, e.Sealed); err != nil {
+				return nil, fmt.Errorf("failed to open the previous state of %s %s in journal %s: %w", e.Action, e.GUID, file.Name(), err)
+			}
+			e.Sealed = nil
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+func seal(sealer crypt.Sealer, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := sealer.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func open(opener crypt.Opener, data []byte) ([]byte, error) {
+	r, err := opener.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
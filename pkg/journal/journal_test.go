@@ -0,0 +1,113 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+)
+
+func TestJournal_RecordAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	j := New(dir, nil)
+
+	_, err := os.Stat(j.Path())
+	assert.True(t, os.IsNotExist(err), "journal file should not be created until something is recorded")
+
+	require.NoError(t, j.Record(Entry{Action: CreateApp, Org: "org", Space: "space", App: "app", GUID: "app-guid"}))
+	require.NoError(t, j.Record(Entry{Action: UpdateApp, Org: "org", Space: "space", App: "other-app", GUID: "other-guid", Previous: json.RawMessage(`{"name":"other-app"}`)}))
+	require.NoError(t, j.Close())
+	assert.Equal(t, 2, j.Count())
+	assert.Equal(t, filepath.Join(dir, "journal-"+j.RunID+".jsonl"), j.Path())
+
+	entries, err := Load(dir, j.RunID, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, CreateApp, entries[0].Action)
+	assert.Equal(t, "app-guid", entries[0].GUID)
+	assert.NotEmpty(t, entries[0].Time)
+	assert.Equal(t, UpdateApp, entries[1].Action)
+	assert.JSONEq(t, `{"name":"other-app"}`, string(entries[1].Previous))
+}
+
+func TestJournal_RunsDoNotShareJournals(t *testing.T) {
+	dir := t.TempDir()
+	first, second := New(dir, nil), New(dir, nil)
+	assert.NotEqual(t, first.RunID, second.RunID)
+
+	// a run whose id is taken gets a new one instead of appending to the journal of the other run
+	second.RunID = first.RunID
+	require.NoError(t, first.Record(Entry{Action: CreateApp, App: "first", GUID: "first-guid"}))
+	require.NoError(t, second.Record(Entry{Action: CreateApp, App: "second", GUID: "second-guid"}))
+	require.NoError(t, first.Close())
+	require.NoError(t, second.Close())
+	assert.NotEqual(t, first.RunID, second.RunID)
+
+	entries, err := Load(dir, first.RunID, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "first-guid", entries[0].GUID)
+
+	entries, err = Load(dir, second.RunID, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "second-guid", entries[0].GUID)
+}
+
+func TestJournal_RecordSealed(t *testing.T) {
+	dir := t.TempDir()
+	key := crypt.NewPassphraseKey("passphrase")
+	j := New(dir, key)
+	assert.True(t, j.Sealed())
+
+	require.NoError(t, j.Record(Entry{Action: UpdateApp, Org: "org", Space: "space", App: "app", GUID: "app-guid", Previous: json.RawMessage(`{"environment_json":{"PASSWORD":"s3cr3t"}}`)}))
+	require.NoError(t, j.Close())
+
+	data, err := os.ReadFile(j.Path())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.NotContains(t, string(data), "previous")
+
+	_, err = Load(dir, j.RunID, nil)
+	assert.EqualError(t, err, "journal "+j.Path()+" is sealed, the key or identity file of the run is required to open it")
+
+	entries, err := Load(dir, j.RunID, key)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.JSONEq(t, `{"environment_json":{"PASSWORD":"s3cr3t"}}`, string(entries[0].Previous))
+	assert.Empty(t, entries[0].Sealed)
+
+	_, err = Load(dir, j.RunID, crypt.NewPassphraseKey("wrong"))
+	assert.Error(t, err)
+}
+
+func TestJournal_Nil(t *testing.T) {
+	var j *Journal
+	assert.NoError(t, j.Record(Entry{Action: CreateApp}))
+	assert.NoError(t, j.Close())
+	assert.Equal(t, 0, j.Count())
+}
+
+func TestLoad_MissingRun(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Load(dir, "does-not-exist", nil)
+	assert.EqualError(t, err, "no journal found for run does-not-exist in "+dir)
+}
@@ -0,0 +1,127 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package mapping
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rule renames every name matching a regular expression, Replace can reference the groups captured by Match
+type Rule struct {
+	Match   string `yaml:"match"`
+	Replace string `yaml:"replace"`
+	re      *regexp.Regexp
+}
+
+// Mapping translates names from the source foundation to the target foundation. Exact names
+// take precedence over rules, and rules are tried in order until one matches.
+type Mapping struct {
+	Names map[string]string `yaml:"names"`
+	Rules []Rule            `yaml:"rules"`
+}
+
+// Names holds the org, space and app name mappings used by the import commands. Spaces can
+// also be listed by org/space, and apps by org/space/app, to rename them in a single org or space.
+type Names struct {
+	Orgs   Mapping `yaml:"orgs"`
+	Spaces Mapping `yaml:"spaces"`
+	Apps   Mapping `yaml:"apps"`
+}
+
+// Load reads a name mapping file
+func Load(path string) (*Names, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := &Names{}
+	if err = yaml.UnmarshalStrict(data, names); err != nil {
+		return nil, fmt.Errorf("failed to parse name mapping %s: %w", path, err)
+	}
+
+	for _, m := range []*Mapping{&names.Orgs, &names.Spaces, &names.Apps} {
+		if err = m.compile(); err != nil {
+			return nil, fmt.Errorf("failed to parse name mapping %s: %w", path, err)
+		}
+	}
+
+	return names, nil
+}
+
+// Org returns the target name of a source org
+func (n *Names) Org(org string) string {
+	if n == nil {
+		return org
+	}
+	return n.Orgs.translate(org)
+}
+
+// Space returns the target name of a space in a source org
+func (n *Names) Space(org, space string) string {
+	if n == nil {
+		return space
+	}
+	return n.Spaces.translate(space, org+"/"+space)
+}
+
+// App returns the target name of an app in a source org and space
+func (n *Names) App(org, space, app string) string {
+	if n == nil {
+		return app
+	}
+	return n.Apps.translate(app, strings.Join([]string{org, space, app}, "/"))
+}
+
+// Display shows how a name is translated, e.g. "source -> target", or only the name if it is unchanged
+func Display(source, target string) string {
+	if source == target {
+		return source
+	}
+	return source + " -> " + target
+}
+
+func (m *Mapping) compile() error {
+	for idx := range m.Rules {
+		re, err := regexp.Compile(m.Rules[idx].Match)
+		if err != nil {
+			return fmt.Errorf("invalid rule %q: %w", m.Rules[idx].Match, err)
+		}
+		m.Rules[idx].re = re
+	}
+	return nil
+}
+
+// translate looks up the qualified keys first, most specific first, then the plain name
+func (m Mapping) translate(name string, qualified ...string) string {
+	for _, key := range append(qualified, name) {
+		if target, ok := m.Names[key]; ok {
+			return target
+		}
+	}
+
+	for _, r := range m.Rules {
+		if r.re != nil && r.re.MatchString(name) {
+			return r.re.ReplaceAllString(name, r.Replace)
+		}
+	}
+
+	return name
+}
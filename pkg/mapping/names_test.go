@@ -0,0 +1,65 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNames(t *testing.T) {
+	names, err := Load("testdata/name_mapping.yml")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "maps an org by exact name", got: names.Org("legacy-org"), want: "platform"},
+		{name: "maps an org by rule", got: names.Org("team-payments"), want: "tenant-payments"},
+		{name: "keeps an unmapped org", got: names.Org("other-org"), want: "other-org"},
+		{name: "maps a space qualified by its org", got: names.Space("legacy-org", "dev"), want: "development"},
+		{name: "does not map a space in another org", got: names.Space("other-org", "dev"), want: "dev"},
+		{name: "maps a space by name in any org", got: names.Space("other-org", "staging"), want: "stage"},
+		{name: "maps an app qualified by its org and space", got: names.App("legacy-org", "dev", "api"), want: "api-v2"},
+		{name: "maps an app by rule", got: names.App("other-org", "dev", "web-blue"), want: "web"},
+		{name: "keeps an unmapped app", got: names.App("other-org", "dev", "api"), want: "api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.got)
+		})
+	}
+}
+
+func TestNilNames(t *testing.T) {
+	var names *Names
+	assert.Equal(t, "org", names.Org("org"))
+	assert.Equal(t, "space", names.Space("org", "space"))
+	assert.Equal(t, "app", names.App("org", "space", "app"))
+}
+
+func TestLoadInvalidRule(t *testing.T) {
+	_, err := Load("testdata/invalid_rule.yml")
+	assert.ErrorContains(t, err, `failed to parse name mapping testdata/invalid_rule.yml: invalid rule "^team-(.*$"`)
+}
+
+func TestDisplay(t *testing.T) {
+	assert.Equal(t, "my-org", Display("my-org", "my-org"))
+	assert.Equal(t, "my-org -> new-org", Display("my-org", "new-org"))
+}
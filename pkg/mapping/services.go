@@ -0,0 +1,70 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package mapping
+
+// ServicePlan is a service plan named by its service offering and plan, and by its service broker when several brokers
+// provide an offering of the same name
+type ServicePlan struct {
+	Offering string `mapstructure:"offering"`
+	Plan     string `mapstructure:"plan"`
+	Broker   string `mapstructure:"broker"`
+}
+
+// ServiceRule maps the service plans matching Source to Target. Empty fields of Source match any name, while empty
+// offering and plan of Target keep the ones of the source plan. The broker of the source plan is never kept, brokers
+// seldom have the same name on both foundations, so the target plan is of any broker unless Target has one.
+type ServiceRule struct {
+	Source ServicePlan `mapstructure:"source"`
+	Target ServicePlan `mapstructure:"target"`
+}
+
+// Services maps the service plans of the source foundation to the service plans of the target foundation, rules are
+// tried in order until one matches
+type Services []ServiceRule
+
+// Map returns the target service plan of a source service plan
+func (s Services) Map(source ServicePlan) ServicePlan {
+	target := ServicePlan{Offering: source.Offering, Plan: source.Plan}
+	for _, r := range s {
+		if !r.Source.matches(source) {
+			continue
+		}
+		if r.Target.Offering != "" {
+			target.Offering = r.Target.Offering
+		}
+		if r.Target.Plan != "" {
+			target.Plan = r.Target.Plan
+		}
+		target.Broker = r.Target.Broker
+		break
+	}
+
+	return target
+}
+
+// String shows the service plan as offering/plan, followed by its broker when it has one
+func (p ServicePlan) String() string {
+	s := p.Offering + "/" + p.Plan
+	if p.Broker != "" {
+		s += " (" + p.Broker + ")"
+	}
+	return s
+}
+
+func (p ServicePlan) matches(source ServicePlan) bool {
+	return (p.Offering == "" || p.Offering == source.Offering) &&
+		(p.Plan == "" || p.Plan == source.Plan) &&
+		(p.Broker == "" || p.Broker == source.Broker)
+}
@@ -0,0 +1,66 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServices_Map(t *testing.T) {
+	services := Services{
+		{Source: ServicePlan{Offering: "p.mysql", Plan: "db-small", Broker: "legacy-broker"}, Target: ServicePlan{Offering: "mysql", Plan: "tiny", Broker: "mysql-broker"}},
+		{Source: ServicePlan{Offering: "p.mysql", Plan: "db-small"}, Target: ServicePlan{Plan: "small"}},
+		{Source: ServicePlan{Offering: "p-redis"}, Target: ServicePlan{Offering: "p.redis"}},
+	}
+
+	tests := []struct {
+		name   string
+		source ServicePlan
+		want   ServicePlan
+	}{
+		{
+			name:   "maps a plan of a broker",
+			source: ServicePlan{Offering: "p.mysql", Plan: "db-small", Broker: "legacy-broker"},
+			want:   ServicePlan{Offering: "mysql", Plan: "tiny", Broker: "mysql-broker"},
+		},
+		{
+			name:   "maps a plan of any broker, keeping the offering",
+			source: ServicePlan{Offering: "p.mysql", Plan: "db-small", Broker: "mysql-broker"},
+			want:   ServicePlan{Offering: "p.mysql", Plan: "small"},
+		},
+		{
+			name:   "maps every plan of an offering, keeping the plan",
+			source: ServicePlan{Offering: "p-redis", Plan: "shared-vm", Broker: "redis-broker"},
+			want:   ServicePlan{Offering: "p.redis", Plan: "shared-vm"},
+		},
+		{
+			name:   "keeps an unmapped plan without its broker",
+			source: ServicePlan{Offering: "p.rabbitmq", Plan: "single-node", Broker: "rabbitmq-broker"},
+			want:   ServicePlan{Offering: "p.rabbitmq", Plan: "single-node"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, services.Map(tt.source))
+		})
+	}
+}
+
+func TestServicePlan_String(t *testing.T) {
+	assert.Equal(t, "p.mysql/db-small", ServicePlan{Offering: "p.mysql", Plan: "db-small"}.String())
+	assert.Equal(t, "p.mysql/db-small (mysql-broker)", ServicePlan{Offering: "p.mysql", Plan: "db-small", Broker: "mysql-broker"}.String())
+}
@@ -0,0 +1,117 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// DataMove is the run of the data mover of the offering of a service instance bound to an imported app, the org,
+// space and app are the ones of the target foundation
+type DataMove struct {
+	Org             string
+	Space           string
+	App             string
+	ServiceInstance string
+	Offering        string
+	// Status is the status the data mover returned, failed when it did not return one, or skipped when it was not run
+	Status  string
+	Message string
+}
+
+// DataMoves is a thread safe sink of the data mover runs
+type DataMoves struct {
+	moves       []DataMove
+	mutex       sync.Mutex
+	TableWriter io.Writer
+}
+
+// NewDataMoves creates a new initialized data mover report
+func NewDataMoves(w io.Writer) *DataMoves {
+	return &DataMoves{
+		TableWriter: w,
+	}
+}
+
+// Add records the result of the data mover run for the service instance bound to the app
+func (d *DataMoves) Add(org, space, app, serviceInstance, offering, status, message string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.moves = append(d.moves, DataMove{
+		Org:             org,
+		Space:           space,
+		App:             app,
+		ServiceInstance: serviceInstance,
+		Offering:        offering,
+		Status:          status,
+		Message:         message,
+	})
+}
+
+// Moves returns a copy of all the data mover runs, sorted by org, space, app and service instance
+func (d *DataMoves) Moves() []DataMove {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	moves := append([]DataMove(nil), d.moves...)
+	sort.Slice(moves, func(i, j int) bool {
+		a, b := moves[i], moves[j]
+		if a.Org != b.Org {
+			return a.Org < b.Org
+		}
+		if a.Space != b.Space {
+			return a.Space < b.Space
+		}
+		if a.App != b.App {
+			return a.App < b.App
+		}
+		return a.ServiceInstance < b.ServiceInstance
+	})
+
+	return moves
+}
+
+// Display prints the data mover runs, it prints nothing when no data mover ran or was skipped
+func (d *DataMoves) Display() {
+	moves := d.Moves()
+	if len(moves) == 0 {
+		return
+	}
+
+	failed, skipped := 0, 0
+	for _, m := range moves {
+		switch m.Status {
+		case "succeeded":
+		case "skipped":
+			skipped++
+		default:
+			failed++
+		}
+	}
+
+	tw := tabwriter.NewWriter(d.TableWriter, 10, 2, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Data movers: %d data movers ran, %d failed, %d skipped, the apps of the failed ones were not fully imported.\n\n", len(moves)-skipped, failed, skipped)
+	_, _ = fmt.Fprintln(tw, "Org\tSpace\tApp\tService instance\tOffering\tStatus\tMessage")
+	for _, m := range moves {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", m.Org, m.Space, m.App, m.ServiceInstance, m.Offering, m.Status, m.Message)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(d.TableWriter)
+}
@@ -0,0 +1,56 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataMoves_Add(t *testing.T) {
+	d := NewDataMoves(&bytes.Buffer{})
+	d.Add("my_org", "my_space", "web", "my-db", "p.mysql", "succeeded", "")
+	d.Add("my_org", "my_space", "api", "my-db", "p.mysql", "failed", "table locked")
+	d.Add("my_org", "dev", "web", "my-db", "p.mysql", "succeeded", "")
+
+	assert.Equal(t, []DataMove{
+		{Org: "my_org", Space: "dev", App: "web", ServiceInstance: "my-db", Offering: "p.mysql", Status: "succeeded"},
+		{Org: "my_org", Space: "my_space", App: "api", ServiceInstance: "my-db", Offering: "p.mysql", Status: "failed", Message: "table locked"},
+		{Org: "my_org", Space: "my_space", App: "web", ServiceInstance: "my-db", Offering: "p.mysql", Status: "succeeded"},
+	}, d.Moves())
+}
+
+func TestDataMoves_Display(t *testing.T) {
+	out := &bytes.Buffer{}
+	d := NewDataMoves(out)
+
+	d.Display()
+	assert.Empty(t, out.String())
+
+	d.Add("my_org", "my_space", "web", "my-db", "p.mysql", "succeeded", "copied 3 tables")
+	d.Add("my_org", "my_space", "api", "my-cache", "p.redis", "failed", "timed out")
+	d.Add("my_org", "my_space", "worker", "my-db", "p.mysql", "skipped", "the app is running")
+	d.Display()
+	assert.Equal(t, `Data movers: 2 data movers ran, 1 failed, 1 skipped, the apps of the failed ones were not fully imported.
+
+Org       Space     App       Service instance  Offering  Status     Message
+my_org    my_space  api       my-cache          p.redis   failed     timed out
+my_org    my_space  web       my-db             p.mysql   succeeded  copied 3 tables
+my_org    my_space  worker    my-db             p.mysql   skipped    the app is running
+
+`, out.String())
+}
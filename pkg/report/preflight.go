@@ -0,0 +1,118 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// Check is a resource the exported apps depend on, and whether the target foundation provides it
+type Check struct {
+	// Kind is the type of resource, e.g. buildpack or stack
+	Kind string
+	// Name is the name of the resource on the source foundation
+	Name string
+	// Target is the name of the resource on the target foundation, once mapped
+	Target string
+	// Status tells whether the target foundation provides the resource, or why it does not
+	Status string
+	// Failed is set when the apps depending on the resource cannot be imported as they are
+	Failed bool
+	// Apps is the number of apps depending on the resource
+	Apps int
+}
+
+// Preflight is a thread safe sink of the checks run against the target foundation before an import
+type Preflight struct {
+	checks      map[string]*Check
+	mutex       sync.Mutex
+	TableWriter io.Writer
+}
+
+// NewPreflight creates a new initialized preflight report
+func NewPreflight(w io.Writer) *Preflight {
+	return &Preflight{
+		checks:      make(map[string]*Check),
+		TableWriter: w,
+	}
+}
+
+// Add records that an app depends on a resource, checks with the same kind, names and status are counted once
+func (p *Preflight) Add(c Check) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := strings.Join([]string{c.Kind, c.Name, c.Target, c.Status}, "\x00")
+	if existing, ok := p.checks[key]; ok {
+		existing.Apps++
+		return
+	}
+	c.Apps = 1
+	p.checks[key] = &c
+}
+
+// Checks returns a copy of all the checks, sorted by kind and name
+func (p *Preflight) Checks() []Check {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var checks []Check
+	for _, c := range p.checks {
+		checks = append(checks, *c)
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].Kind != checks[j].Kind {
+			return checks[i].Kind < checks[j].Kind
+		}
+		if checks[i].Name != checks[j].Name {
+			return checks[i].Name < checks[j].Name
+		}
+		return checks[i].Status < checks[j].Status
+	})
+
+	return checks
+}
+
+// FailureCount is the number of checks that failed
+func (p *Preflight) FailureCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	count := 0
+	for _, c := range p.checks {
+		if c.Failed {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *Preflight) Display() {
+	tw := tabwriter.NewWriter(p.TableWriter, 10, 2, 2, ' ', 0)
+
+	checks := p.Checks()
+	_, _ = fmt.Fprintf(tw, "Preflight: %d checks, %d failed.\n\n", len(checks), p.FailureCount())
+	_, _ = fmt.Fprintln(tw, "Kind\tName\tTarget\tStatus\tApps")
+	for _, c := range checks {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", c.Kind, c.Name, c.Target, c.Status, c.Apps)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(p.TableWriter)
+}
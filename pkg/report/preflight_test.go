@@ -0,0 +1,50 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreflight_Add(t *testing.T) {
+	p := NewPreflight(&bytes.Buffer{})
+	p.Add(Check{Kind: "stack", Name: "cflinuxfs3", Target: "cflinuxfs4", Status: "ok"})
+	p.Add(Check{Kind: "buildpack", Name: "java_buildpack_offline", Target: "java_buildpack_offline", Status: "missing", Failed: true})
+	p.Add(Check{Kind: "stack", Name: "cflinuxfs3", Target: "cflinuxfs4", Status: "ok"})
+
+	assert.Equal(t, []Check{
+		{Kind: "buildpack", Name: "java_buildpack_offline", Target: "java_buildpack_offline", Status: "missing", Failed: true, Apps: 1},
+		{Kind: "stack", Name: "cflinuxfs3", Target: "cflinuxfs4", Status: "ok", Apps: 2},
+	}, p.Checks())
+	assert.Equal(t, 1, p.FailureCount())
+}
+
+func TestPreflight_Display(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := NewPreflight(out)
+	p.Add(Check{Kind: "buildpack", Name: "java_buildpack_offline", Target: "java_buildpack", Status: "ok"})
+
+	p.Display()
+
+	assert.Equal(t, `Preflight: 1 checks, 0 failed.
+
+Kind       Name                    Target          Status    Apps
+buildpack  java_buildpack_offline  java_buildpack  ok        1
+
+`, out.String())
+}
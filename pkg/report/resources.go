@@ -0,0 +1,140 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+const (
+	ResourceSucceeded = "succeeded"
+	ResourceFailed    = "failed"
+	ResourceSkipped   = "skipped"
+)
+
+// Resource is the result of exporting, importing or rolling back something else than an app, such as a buildpack, a
+// quota, a domain, a network policy, or the roles, security groups and service instances of an org or space
+type Resource struct {
+	// Kind is what the resource is, e.g. buildpack or roles
+	Kind  string
+	Org   string
+	Space string
+	// Name is the name of the resource, it is empty for the roles, security groups and such of the org or space
+	Name string
+	// Status is one of ResourceSucceeded, ResourceFailed or ResourceSkipped
+	Status  string
+	Message string
+}
+
+// Resources is a thread safe sink of the results of everything that is not an app, the results of the apps go to
+// the Summary, all the methods do nothing on a nil Resources
+type Resources struct {
+	resources   []Resource
+	mutex       sync.Mutex
+	TableWriter io.Writer
+}
+
+// NewResources creates a new initialized report of the resources that are not apps
+func NewResources(w io.Writer) *Resources {
+	return &Resources{
+		TableWriter: w,
+	}
+}
+
+// AddSucceeded records a resource that was exported, imported or rolled back
+func (r *Resources) AddSucceeded(kind, org, space, name string) {
+	r.add(Resource{Kind: kind, Org: org, Space: space, Name: name, Status: ResourceSucceeded})
+}
+
+// AddFailed records a resource that failed along with its error
+func (r *Resources) AddFailed(kind, org, space, name string, err error) {
+	r.add(Resource{Kind: kind, Org: org, Space: space, Name: name, Status: ResourceFailed, Message: err.Error()})
+}
+
+// AddSkipped records a resource that was deliberately left untouched along with the reason why
+func (r *Resources) AddSkipped(kind, org, space, name, reason string) {
+	r.add(Resource{Kind: kind, Org: org, Space: space, Name: name, Status: ResourceSkipped, Message: reason})
+}
+
+func (r *Resources) add(resource Resource) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.resources = append(r.resources, resource)
+}
+
+// Resources returns a copy of all the results, sorted by kind, org, space and name
+func (r *Resources) Resources() []Resource {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	resources := append([]Resource(nil), r.resources...)
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Org != b.Org {
+			return a.Org < b.Org
+		}
+		if a.Space != b.Space {
+			return a.Space < b.Space
+		}
+		return a.Name < b.Name
+	})
+
+	return resources
+}
+
+// Count is the number of results with the status
+func (r *Resources) Count(status string) int {
+	count := 0
+	for _, resource := range r.Resources() {
+		if resource.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Display prints the results, it prints nothing when there are none
+func (r *Resources) Display() {
+	resources := r.Resources()
+	if len(resources) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(r.TableWriter, 10, 2, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Other resources: %d successes, %d errors, %d skipped.\n\n", r.Count(ResourceSucceeded), r.Count(ResourceFailed), r.Count(ResourceSkipped))
+	_, _ = fmt.Fprintln(tw, "Kind\tOrg\tSpace\tName\tResult\tMessage")
+	for _, resource := range resources {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", resource.Kind, resource.Org, resource.Space, resource.Name, resource.Status, resource.Message)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(r.TableWriter)
+}
@@ -0,0 +1,60 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResources_Add(t *testing.T) {
+	r := NewResources(&bytes.Buffer{})
+	r.AddSucceeded("quota", "my_org", "", "small")
+	r.AddFailed("roles", "my_org", "my_space", "", errors.New("forbidden"))
+	r.AddSkipped("buildpack", "", "", "java_buildpack", "unchanged")
+
+	assert.Equal(t, []Resource{
+		{Kind: "buildpack", Name: "java_buildpack", Status: ResourceSkipped, Message: "unchanged"},
+		{Kind: "quota", Org: "my_org", Name: "small", Status: ResourceSucceeded},
+		{Kind: "roles", Org: "my_org", Space: "my_space", Status: ResourceFailed, Message: "forbidden"},
+	}, r.Resources())
+	assert.Equal(t, 1, r.Count(ResourceFailed))
+
+	var none *Resources
+	none.AddSucceeded("quota", "my_org", "", "small")
+	assert.Empty(t, none.Resources())
+}
+
+func TestResources_Display(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewResources(out)
+
+	r.Display()
+	assert.Empty(t, out.String())
+
+	r.AddSkipped("buildpack", "", "", "java_buildpack", "unchanged")
+	r.AddFailed("security groups", "my_org", "my_space", "", errors.New("forbidden"))
+	r.Display()
+	assert.Equal(t, `Other resources: 0 successes, 1 errors, 1 skipped.
+
+Kind             Org       Space     Name            Result    Message
+buildpack                            java_buildpack  skipped   unchanged
+security groups  my_org    my_space                  failed    forbidden
+
+`, out.String())
+}
@@ -0,0 +1,104 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// SecurityGroupDiff is a security group that already existed on the target foundation with other rules than on the
+// source foundation
+type SecurityGroupDiff struct {
+	Name string
+	// Added are the rules of the source foundation the group did not have on the target foundation
+	Added []string
+	// Removed are the rules the group had on the target foundation but not on the source foundation
+	Removed []string
+}
+
+// SecurityGroupDiffs is a thread safe sink of the security groups whose rules were changed on the target foundation
+type SecurityGroupDiffs struct {
+	diffs       map[string]SecurityGroupDiff
+	mutex       sync.Mutex
+	TableWriter io.Writer
+}
+
+// NewSecurityGroupDiffs creates a new initialized security group diff report
+func NewSecurityGroupDiffs(w io.Writer) *SecurityGroupDiffs {
+	return &SecurityGroupDiffs{
+		diffs:       make(map[string]SecurityGroupDiff),
+		TableWriter: w,
+	}
+}
+
+// Add records how the rules of the security group differed, a group bound to several spaces is recorded once
+func (s *SecurityGroupDiffs) Add(name string, added, removed []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.diffs[name]; ok {
+		return
+	}
+	s.diffs[name] = SecurityGroupDiff{
+		Name:    name,
+		Added:   append([]string(nil), added...),
+		Removed: append([]string(nil), removed...),
+	}
+}
+
+// Diffs returns a copy of all the security group diffs, sorted by name
+func (s *SecurityGroupDiffs) Diffs() []SecurityGroupDiff {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var diffs []SecurityGroupDiff
+	for _, d := range s.diffs {
+		diff := d
+		diff.Added = append([]string(nil), d.Added...)
+		diff.Removed = append([]string(nil), d.Removed...)
+		diffs = append(diffs, diff)
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs
+}
+
+// Display prints the rules that changed, it prints nothing when no existing security group had other rules
+func (s *SecurityGroupDiffs) Display() {
+	diffs := s.Diffs()
+	if len(diffs) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(s.TableWriter, 10, 2, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Security groups: %d security groups had different rules on the target foundation and were updated.\n\n", len(diffs))
+	_, _ = fmt.Fprintln(tw, "Name\tChange\tRule")
+	for _, d := range diffs {
+		for _, r := range d.Added {
+			_, _ = fmt.Fprintf(tw, "%s\tadded\t%s\n", d.Name, r)
+		}
+		for _, r := range d.Removed {
+			_, _ = fmt.Fprintf(tw, "%s\tremoved\t%s\n", d.Name, r)
+		}
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(s.TableWriter)
+}
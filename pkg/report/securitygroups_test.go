@@ -0,0 +1,52 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityGroupDiffs_Add(t *testing.T) {
+	s := NewSecurityGroupDiffs(&bytes.Buffer{})
+	s.Add("postgres", []string{"tcp 10.0.1.0/24 5432"}, []string{"tcp 10.0.0.0/24 5432"})
+	s.Add("dns", []string{"udp 10.0.0.2 53"}, nil)
+	s.Add("postgres", nil, nil)
+
+	assert.Equal(t, []SecurityGroupDiff{
+		{Name: "dns", Added: []string{"udp 10.0.0.2 53"}},
+		{Name: "postgres", Added: []string{"tcp 10.0.1.0/24 5432"}, Removed: []string{"tcp 10.0.0.0/24 5432"}},
+	}, s.Diffs())
+}
+
+func TestSecurityGroupDiffs_Display(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := NewSecurityGroupDiffs(out)
+
+	s.Display()
+	assert.Empty(t, out.String())
+
+	s.Add("postgres", []string{"tcp 10.0.1.0/24 5432"}, []string{"tcp 10.0.0.0/24 5432"})
+	s.Display()
+	assert.Equal(t, `Security groups: 1 security groups had different rules on the target foundation and were updated.
+
+Name      Change    Rule
+postgres  added     tcp 10.0.1.0/24 5432
+postgres  removed   tcp 10.0.0.0/24 5432
+
+`, out.String())
+}
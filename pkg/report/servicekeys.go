@@ -0,0 +1,102 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// ServiceKeyChange is a service key whose credentials are not the same on the target foundation as on the source
+// foundation, the consumers of the key have to be given the new ones
+type ServiceKeyChange struct {
+	Org             string
+	Space           string
+	ServiceInstance string
+	Name            string
+	// Change is why the credentials changed, e.g. the key was created
+	Change string
+}
+
+// ServiceKeyChanges is a thread safe sink of the service keys whose credentials changed
+type ServiceKeyChanges struct {
+	changes     []ServiceKeyChange
+	mutex       sync.Mutex
+	TableWriter io.Writer
+}
+
+// NewServiceKeyChanges creates a new initialized service key change report
+func NewServiceKeyChanges(w io.Writer) *ServiceKeyChanges {
+	return &ServiceKeyChanges{
+		TableWriter: w,
+	}
+}
+
+// Add records that the credentials of the service key changed
+func (s *ServiceKeyChanges) Add(org, space, serviceInstance, name, change string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.changes = append(s.changes, ServiceKeyChange{
+		Org:             org,
+		Space:           space,
+		ServiceInstance: serviceInstance,
+		Name:            name,
+		Change:          change,
+	})
+}
+
+// Changes returns a copy of all the service key changes, sorted by org, space, service instance and key
+func (s *ServiceKeyChanges) Changes() []ServiceKeyChange {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	changes := append([]ServiceKeyChange(nil), s.changes...)
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Org != b.Org {
+			return a.Org < b.Org
+		}
+		if a.Space != b.Space {
+			return a.Space < b.Space
+		}
+		if a.ServiceInstance != b.ServiceInstance {
+			return a.ServiceInstance < b.ServiceInstance
+		}
+		return a.Name < b.Name
+	})
+
+	return changes
+}
+
+// Display prints the service keys whose credentials changed, it prints nothing when there are none
+func (s *ServiceKeyChanges) Display() {
+	changes := s.Changes()
+	if len(changes) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(s.TableWriter, 10, 2, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Service keys: %d service keys have other credentials on the target foundation, give them to their consumers.\n\n", len(changes))
+	_, _ = fmt.Fprintln(tw, "Org\tSpace\tService instance\tKey\tChange")
+	for _, c := range changes {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Org, c.Space, c.ServiceInstance, c.Name, c.Change)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(s.TableWriter)
+}
@@ -0,0 +1,52 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceKeyChanges_Add(t *testing.T) {
+	s := NewServiceKeyChanges(&bytes.Buffer{})
+	s.Add("my_org", "my_space", "my-db", "ci", "created")
+	s.Add("my_org", "my_space", "my-bucket", "exporter", "credentials differ")
+	s.Add("my_org", "dev", "my-db", "ci", "created")
+
+	assert.Equal(t, []ServiceKeyChange{
+		{Org: "my_org", Space: "dev", ServiceInstance: "my-db", Name: "ci", Change: "created"},
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-bucket", Name: "exporter", Change: "credentials differ"},
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-db", Name: "ci", Change: "created"},
+	}, s.Changes())
+}
+
+func TestServiceKeyChanges_Display(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := NewServiceKeyChanges(out)
+
+	s.Display()
+	assert.Empty(t, out.String())
+
+	s.Add("my_org", "my_space", "my-db", "ci", "created")
+	s.Display()
+	assert.Equal(t, `Service keys: 1 service keys have other credentials on the target foundation, give them to their consumers.
+
+Org       Space     Service instance  Key       Change
+my_org    my_space  my-db             ci        created
+
+`, out.String())
+}
@@ -0,0 +1,111 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// ServicePlanCheck is an exported managed service instance, and whether the target foundation has a service plan to
+// create it with
+type ServicePlanCheck struct {
+	Org             string
+	Space           string
+	ServiceInstance string
+	// Source is the service plan of the service instance on the source foundation, and Target the one it maps to
+	Source string
+	Target string
+	// Status tells whether the target plan exists and is visible to the target org, or why it is not
+	Status string
+	// Failed is set when the service instance cannot be created on the target foundation
+	Failed bool
+}
+
+// ServicePlanChecks is a thread safe sink of the service plan checks of the exported service instances
+type ServicePlanChecks struct {
+	checks      []ServicePlanCheck
+	mutex       sync.Mutex
+	TableWriter io.Writer
+}
+
+// NewServicePlanChecks creates a new initialized service plan report
+func NewServicePlanChecks(w io.Writer) *ServicePlanChecks {
+	return &ServicePlanChecks{
+		TableWriter: w,
+	}
+}
+
+// Add records the service plan check of a service instance
+func (s *ServicePlanChecks) Add(c ServicePlanCheck) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.checks = append(s.checks, c)
+}
+
+// Checks returns a copy of all the service plan checks, sorted by org, space and service instance
+func (s *ServicePlanChecks) Checks() []ServicePlanCheck {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	checks := append([]ServicePlanCheck(nil), s.checks...)
+	sort.Slice(checks, func(i, j int) bool {
+		a, b := checks[i], checks[j]
+		if a.Org != b.Org {
+			return a.Org < b.Org
+		}
+		if a.Space != b.Space {
+			return a.Space < b.Space
+		}
+		return a.ServiceInstance < b.ServiceInstance
+	})
+
+	return checks
+}
+
+// FailureCount is the number of service instances without a service plan on the target foundation
+func (s *ServicePlanChecks) FailureCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+	for _, c := range s.checks {
+		if c.Failed {
+			count++
+		}
+	}
+	return count
+}
+
+// Display prints the service plan checks, it prints nothing when no service instance was exported
+func (s *ServicePlanChecks) Display() {
+	checks := s.Checks()
+	if len(checks) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(s.TableWriter, 10, 2, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Service instances: %d service instances, %d without a service plan on the target foundation.\n\n", len(checks), s.FailureCount())
+	_, _ = fmt.Fprintln(tw, "Org\tSpace\tService instance\tSource plan\tTarget plan\tStatus")
+	for _, c := range checks {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.Org, c.Space, c.ServiceInstance, c.Source, c.Target, c.Status)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(s.TableWriter)
+}
@@ -0,0 +1,55 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServicePlanChecks_Add(t *testing.T) {
+	s := NewServicePlanChecks(&bytes.Buffer{})
+	s.Add(ServicePlanCheck{Org: "my_org", Space: "my_space", ServiceInstance: "my-db", Source: "p.mysql/db-small", Target: "p.mysql/small", Status: "ok"})
+	s.Add(ServicePlanCheck{Org: "my_org", Space: "my_space", ServiceInstance: "my-cache", Source: "p-redis/shared", Target: "p-redis/shared", Status: "missing", Failed: true})
+	s.Add(ServicePlanCheck{Org: "my_org", Space: "dev", ServiceInstance: "my-db", Source: "p.mysql/db-small", Target: "p.mysql/small", Status: "ok"})
+
+	assert.Equal(t, []ServicePlanCheck{
+		{Org: "my_org", Space: "dev", ServiceInstance: "my-db", Source: "p.mysql/db-small", Target: "p.mysql/small", Status: "ok"},
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-cache", Source: "p-redis/shared", Target: "p-redis/shared", Status: "missing", Failed: true},
+		{Org: "my_org", Space: "my_space", ServiceInstance: "my-db", Source: "p.mysql/db-small", Target: "p.mysql/small", Status: "ok"},
+	}, s.Checks())
+	assert.Equal(t, 1, s.FailureCount())
+}
+
+func TestServicePlanChecks_Display(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := NewServicePlanChecks(out)
+
+	s.Display()
+	assert.Empty(t, out.String())
+
+	s.Add(ServicePlanCheck{Org: "my_org", Space: "my_space", ServiceInstance: "my-db", Source: "p.mysql/db-small", Target: "p.mysql/small", Status: "ok"})
+	s.Add(ServicePlanCheck{Org: "my_org", Space: "my_space", ServiceInstance: "my-cache", Source: "p-redis/shared", Target: "p-redis/shared", Status: "not visible to the target org", Failed: true})
+	s.Display()
+	assert.Equal(t, `Service instances: 2 service instances, 1 without a service plan on the target foundation.
+
+Org       Space     Service instance  Source plan       Target plan     Status
+my_org    my_space  my-cache          p-redis/shared    p-redis/shared  not visible to the target org
+my_org    my_space  my-db             p.mysql/db-small  p.mysql/small   ok
+
+`, out.String())
+}
@@ -24,10 +24,6 @@ import (
 	"time"
 )
 
-const (
-	keyFormat string = "%s.%s.%s"
-)
-
 // Result is a specific app migration result: success or failure
 type Result struct {
 	OrgName   string
@@ -36,14 +32,21 @@ type Result struct {
 	Message   string
 }
 
+// resultKey identifies the app a result is for, names can hold any character
+type resultKey struct {
+	org, space, app string
+}
+
 // Summary is a thread safe sink of execution results for app migrations
 type Summary struct {
-	results      map[string]string
+	results      map[resultKey]string
 	successCount int
 	failureCount int
+	skipCount    int
 	resMutex     sync.RWMutex
 	sucMutex     sync.RWMutex
 	errMutex     sync.RWMutex
+	skipMutex    sync.RWMutex
 	TableWriter  io.Writer
 	duration     time.Duration
 }
@@ -51,7 +54,7 @@ type Summary struct {
 // NewSummary creates a new initialized summary instance
 func NewSummary(w io.Writer) *Summary {
 	return &Summary{
-		results:     make(map[string]string),
+		results:     make(map[resultKey]string),
 		TableWriter: w,
 	}
 }
@@ -70,18 +73,24 @@ func (s *Summary) AppSuccessCount() int {
 	return s.successCount
 }
 
+// AppSkippedCount is the number of total apps that were left untouched
+func (s *Summary) AppSkippedCount() int {
+	s.skipMutex.Lock()
+	defer s.skipMutex.Unlock()
+	return s.skipCount
+}
+
 // Results returns a copy of all the app migrations that have occurred
 func (s *Summary) Results() []Result {
 	s.resMutex.Lock()
 	defer s.resMutex.Unlock()
 
 	var r []Result
-	for a, m := range s.results {
-		app := strings.Split(a, ".")
+	for k, m := range s.results {
 		r = append(r, Result{
-			OrgName:   app[0],
-			SpaceName: app[1],
-			AppName:   app[2],
+			OrgName:   k.org,
+			SpaceName: k.space,
+			AppName:   k.app,
 			Message:   m,
 		})
 	}
@@ -123,7 +132,7 @@ func (s *Summary) AddFailedApp(org, space, app string, err error) {
 	s.resMutex.Lock()
 	defer s.resMutex.Unlock()
 
-	s.results[fmt.Sprintf(keyFormat, org, space, app)] = err.Error()
+	s.results[resultKey{org: org, space: space, app: app}] = err.Error()
 }
 
 // AddSuccessfulApp adds a successful app and increments the count of successful apps
@@ -135,14 +144,30 @@ func (s *Summary) AddSuccessfulApp(org, space, app string) {
 	s.resMutex.Lock()
 	defer s.resMutex.Unlock()
 
-	s.results[fmt.Sprintf(keyFormat, org, space, app)] = "successful"
+	s.results[resultKey{org: org, space: space, app: app}] = "successful"
+}
+
+// AddSkippedApp adds an app that was deliberately left untouched along with the reason why
+func (s *Summary) AddSkippedApp(org, space, app, reason string) {
+	s.skipMutex.Lock()
+	defer s.skipMutex.Unlock()
+	s.skipCount++
+
+	s.resMutex.Lock()
+	defer s.resMutex.Unlock()
+
+	s.results[resultKey{org: org, space: space, app: app}] = "skipped: " + reason
 }
 
 func (s *Summary) Display() {
 	tw := tabwriter.NewWriter(s.TableWriter, 10, 2, 2, ' ', 0)
 
 	// Summary
-	_, _ = fmt.Fprintf(tw, "Migration took %v\nSummary: %d successes, %d errors.\n", s.Duration(), s.AppSuccessCount(), s.AppFailureCount())
+	_, _ = fmt.Fprintf(tw, "Migration took %v\nSummary: %d successes, %d errors", s.Duration(), s.AppSuccessCount(), s.AppFailureCount())
+	if skipped := s.AppSkippedCount(); skipped > 0 {
+		_, _ = fmt.Fprintf(tw, ", %d skipped", skipped)
+	}
+	_, _ = fmt.Fprintln(tw, ".")
 	fmt.Println()
 
 	// Header
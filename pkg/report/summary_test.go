@@ -143,3 +143,31 @@ red       dev       my-bad-app        this is an example error
 		})
 	}
 }
+
+func TestSummary_DisplaySkippedApps(t *testing.T) {
+	output := &bytes.Buffer{}
+	s := NewSummary(output)
+	s.AddSuccessfulApp("blue", "dev", "my-good-app")
+	s.AddSkippedApp("blue", "dev", "their-app", "app already exists")
+	s.Display()
+
+	assert.Equal(t, 1, s.AppSkippedCount())
+	assert.Equal(t, 1, s.AppSuccessCount())
+	assert.Equal(t, `Migration took 0s
+Summary: 1 successes, 0 errors, 1 skipped.
+Org       Space     App          Result
+blue      dev       my-good-app  successful
+blue      dev       their-app    skipped
+`, output.String())
+}
+
+func TestSummary_ResultsWithDottedNames(t *testing.T) {
+	s := NewSummary(&bytes.Buffer{})
+	s.AddSuccessfulApp("my.org", "", "apps.example.com")
+	s.AddFailedApp("my.org", "my.space", "my.app", errAppError)
+
+	assert.Equal(t, []Result{
+		{OrgName: "my.org", AppName: "apps.example.com", Message: "successful"},
+		{OrgName: "my.org", SpaceName: "my.space", AppName: "my.app", Message: errAppError.Error()},
+	}, s.Results())
+}
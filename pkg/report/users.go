@@ -0,0 +1,100 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// MissingUser is a user that has roles on the source foundation but does not exist on the target foundation
+type MissingUser struct {
+	Username string
+	Origin   string
+	// Roles are the roles the user could not be given, e.g. space_developer in my-org/my-space
+	Roles []string
+}
+
+// MissingUsers is a thread safe sink of the users whose roles could not be recreated on the target foundation
+type MissingUsers struct {
+	users       map[string]*MissingUser
+	mutex       sync.Mutex
+	TableWriter io.Writer
+}
+
+// NewMissingUsers creates a new initialized missing users report
+func NewMissingUsers(w io.Writer) *MissingUsers {
+	return &MissingUsers{
+		users:       make(map[string]*MissingUser),
+		TableWriter: w,
+	}
+}
+
+// Add records that the user with the given username and origin could not be given the role
+func (m *MissingUsers) Add(username, origin, role string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := username + "\x00" + origin
+	user, ok := m.users[key]
+	if !ok {
+		user = &MissingUser{Username: username, Origin: origin}
+		m.users[key] = user
+	}
+	user.Roles = append(user.Roles, role)
+}
+
+// Users returns a copy of all the missing users, sorted by origin and username
+func (m *MissingUsers) Users() []MissingUser {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var users []MissingUser
+	for _, u := range m.users {
+		user := *u
+		user.Roles = append([]string(nil), u.Roles...)
+		sort.Strings(user.Roles)
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Origin != users[j].Origin {
+			return users[i].Origin < users[j].Origin
+		}
+		return users[i].Username < users[j].Username
+	})
+
+	return users
+}
+
+// Display prints the missing users, it prints nothing when every user exists on the target foundation
+func (m *MissingUsers) Display() {
+	users := m.Users()
+	if len(users) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(m.TableWriter, 10, 2, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Missing users: %d users do not exist on the target foundation.\n\n", len(users))
+	_, _ = fmt.Fprintln(tw, "Username\tOrigin\tRoles")
+	for _, u := range users {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", u.Username, u.Origin, strings.Join(u.Roles, ", "))
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(m.TableWriter)
+}
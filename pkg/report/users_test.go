@@ -0,0 +1,51 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingUsers_Add(t *testing.T) {
+	m := NewMissingUsers(&bytes.Buffer{})
+	m.Add("jane@example.com", "okta", "space_developer in my-org/dev")
+	m.Add("bob", "uaa", "organization_manager in my-org")
+	m.Add("jane@example.com", "okta", "organization_user in my-org")
+
+	assert.Equal(t, []MissingUser{
+		{Username: "jane@example.com", Origin: "okta", Roles: []string{"organization_user in my-org", "space_developer in my-org/dev"}},
+		{Username: "bob", Origin: "uaa", Roles: []string{"organization_manager in my-org"}},
+	}, m.Users())
+}
+
+func TestMissingUsers_Display(t *testing.T) {
+	out := &bytes.Buffer{}
+	m := NewMissingUsers(out)
+
+	m.Display()
+	assert.Empty(t, out.String())
+
+	m.Add("bob", "uaa", "space_auditor in my-org/dev")
+	m.Display()
+	assert.Equal(t, `Missing users: 1 users do not exist on the target foundation.
+
+Username  Origin    Roles
+bob       uaa       space_auditor in my-org/dev
+
+`, out.String())
+}
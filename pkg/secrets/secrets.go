@@ -0,0 +1,335 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/crypt"
+)
+
+// FileName is the name of the encrypted secrets file written at the top of the export dir
+const FileName = "secrets.enc"
+
+// DefaultKeyPatterns match the names of env vars that usually hold credentials
+var DefaultKeyPatterns = []string{
+	`(?i)pass(word|wd)?`,
+	`(?i)secret`,
+	`(?i)token`,
+	`(?i)(api|access|private|secret)_?key`,
+	`(?i)credential`,
+}
+
+var placeholderPattern = regexp.MustCompile(`\(\(([A-Za-z0-9_.\-/]+)\)\)`)
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// generatedName matches the names Key.Name gives secrets, only the placeholders so named are resolved on import
+var generatedName = regexp.MustCompile(`^[A-Za-z0-9_]+_[0-9a-f]{8}$`)
+
+// Detector decides which env vars are sensitive, either because their name matches one of the key patterns
+// or because their value looks random, i.e. it is at least MinLength long and its entropy is at least MinEntropy
+// bits per character. Entropy detection is disabled when MinEntropy is 0.
+type Detector struct {
+	KeyPatterns []*regexp.Regexp
+	MinEntropy  float64
+	MinLength   int
+}
+
+// NewDetector creates a detector from a list of regular expressions matched against env var names
+func NewDetector(keyPatterns []string, minEntropy float64) (*Detector, error) {
+	d := &Detector{
+		MinEntropy: minEntropy,
+		MinLength:  20,
+	}
+	for _, p := range keyPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret key pattern %q: %w", p, err)
+		}
+		d.KeyPatterns = append(d.KeyPatterns, re)
+	}
+	return d, nil
+}
+
+// IsSensitive reports whether an env var should be externalized
+func (d *Detector) IsSensitive(key string, value interface{}) bool {
+	s, ok := value.(string)
+	if !ok || s == "" || placeholderPattern.MatchString(s) {
+		return false
+	}
+
+	for _, re := range d.KeyPatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+
+	return d.MinEntropy > 0 && len(s) >= d.MinLength && !strings.ContainsAny(s, " \t\n") && entropy(s) >= d.MinEntropy
+}
+
+// Key identifies a secret by the names it belongs to, e.g. the org, space and app of an env var and the name of the env var
+type Key []string
+
+// Name is the name of the secret, it can also be used as the name of an environment variable. The parts of the key
+// are made readable and followed by a short hash of their raw values, so that keys only differing by characters that
+// are not allowed in a name, or by where their parts start and end, get different names.
+func (k Key) Name() string {
+	sum := sha256.Sum256([]byte(k.id()))
+	return invalidNameChars.ReplaceAllString(strings.Join(k, "_"), "_") + "_" + hex.EncodeToString(sum[:4])
+}
+
+// id is an unambiguous encoding of the parts of the key
+func (k Key) id() string {
+	var b strings.Builder
+	for _, part := range k {
+		fmt.Fprintf(&b, "%d:%s", len(part), part)
+	}
+	return b.String()
+}
+
+// Secrets holds the values of the externalized env vars keyed by their placeholder name. It is safe for concurrent use.
+// LookupEnv allows the placeholders missing from the secrets file to be resolved from the environment.
+type Secrets struct {
+	Detector  *Detector
+	LookupEnv bool
+	key       *crypt.Key
+	values    map[string]string
+	keys      map[string]Key
+	loaded    bool
+	changed   bool
+	mutex     sync.RWMutex
+}
+
+// New creates an empty set of secrets encrypted with key
+func New(key *crypt.Key, detector *Detector) *Secrets {
+	return &Secrets{
+		Detector: detector,
+		key:      key,
+		values:   make(map[string]string),
+		keys:     make(map[string]Key),
+	}
+}
+
+// LoadFS reads the secrets stored in the named encrypted secrets file of fsys, the set is empty if the file does not exist
+func LoadFS(fsys fs.FS, path string, key *crypt.Key, detector *Detector) (*Secrets, error) {
+	s := New(key, detector)
+
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	plaintext, err := key.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file %s: %w", path, err)
+	}
+
+	if err = json.Unmarshal(plaintext, &s.values); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file %s: %w", path, err)
+	}
+	s.loaded = true
+
+	return s, nil
+}
+
+// Changed reports whether secrets were added since the set was loaded
+func (s *Secrets) Changed() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.changed
+}
+
+// Encrypt returns the content of the encrypted secrets file
+func (s *Secrets) Encrypt() ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	plaintext, err := json.Marshal(s.values)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.key.Encrypt(plaintext)
+}
+
+// Count is the number of secrets held
+func (s *Secrets) Count() int {
+	if s == nil {
+		return 0
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.values)
+}
+
+// Externalize returns a copy of the env vars of an app where the value of every sensitive env var
+// is replaced with a ((placeholder)), and keeps the real values
+func (s *Secrets) Externalize(org, space, app string, env map[string]interface{}) (map[string]interface{}, error) {
+	if s == nil || env == nil {
+		return env, nil
+	}
+
+	result := make(map[string]interface{}, len(env))
+	for k, v := range env {
+		result[k] = v
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, k := range keys {
+		if !s.Detector.IsSensitive(k, env[k]) {
+			continue
+		}
+		name, err := s.set(Key{org, space, app, k}, env[k].(string))
+		if err != nil {
+			return nil, err
+		}
+		result[k] = "((" + name + "))"
+	}
+
+	return result, nil
+}
+
+// Add keeps value as the secret with the given key, e.g. credentials that are only kept to be compared on import.
+// It fails when a secret with another key that has the same name was added.
+func (s *Secrets) Add(key Key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err := s.set(key, value)
+	return err
+}
+
+func (s *Secrets) set(key Key, value string) (string, error) {
+	name := key.Name()
+	if other, ok := s.keys[name]; ok && other.id() != key.id() {
+		return "", fmt.Errorf("secrets %q and %q have the same name %s", strings.Join(other, "/"), strings.Join(key, "/"), name)
+	}
+	s.keys[name] = key
+
+	if s.values[name] != value {
+		s.values[name] = value
+		s.changed = true
+	}
+
+	return name, nil
+}
+
+// Value returns the secret with the given name, unlike placeholders it is not looked up in the environment
+func (s *Secrets) Value(name string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// Resolve returns a copy of the env vars with every ((placeholder)) of an externalized secret replaced with its value,
+// or with the value of the environment variable of the same name when there is no such secret and LookupEnv is set.
+// Nothing is resolved unless a secrets file was loaded or LookupEnv is set, and only the placeholders named like the
+// export names the secrets are, the other values are left as they are.
+func (s *Secrets) Resolve(env map[string]interface{}) (map[string]interface{}, error) {
+	if s == nil || env == nil || (!s.loaded && !s.LookupEnv) {
+		return env, nil
+	}
+
+	result := make(map[string]interface{}, len(env))
+	var missing []string
+	for k, v := range env {
+		str, ok := v.(string)
+		if !ok {
+			result[k] = v
+			continue
+		}
+		result[k] = placeholderPattern.ReplaceAllStringFunc(str, func(p string) string {
+			name := placeholderPattern.FindStringSubmatch(p)[1]
+			if !generatedName.MatchString(name) {
+				return p
+			}
+			if value, ok := s.lookup(name); ok {
+				return value
+			}
+			missing = append(missing, name)
+			return p
+		})
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		if s.LookupEnv {
+			return nil, fmt.Errorf("no value found for placeholders %s, add them to the secrets file or set them as environment variables", strings.Join(missing, ", "))
+		}
+		return nil, fmt.Errorf("no value found for placeholders %s, add them to the secrets file or pass --secrets-from-env to set them as environment variables", strings.Join(missing, ", "))
+	}
+
+	return result, nil
+}
+
+func (s *Secrets) lookup(name string) (string, bool) {
+	s.mutex.RLock()
+	value, ok := s.values[name]
+	s.mutex.RUnlock()
+	if ok || !s.LookupEnv {
+		return value, ok
+	}
+	return os.LookupEnv(name)
+}
+
+// PlaceholderName is the name of the placeholder of an env var, it can also be used as the name of an environment variable
+func PlaceholderName(org, space, app, key string) string {
+	return Key{org, space, app, key}.Name()
+}
+
+// entropy is the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+
+	var e float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		e -= p * math.Log2(p)
+	}
+	return e
+}
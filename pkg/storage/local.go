@@ -0,0 +1,90 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Local holds an export in a directory of the local file system
+type Local struct {
+	Root string
+}
+
+func NewLocal(root string) *Local {
+	return &Local{Root: root}
+}
+
+// Path returns the local path of the named file
+func (l *Local) Path(name string) string {
+	return filepath.Join(l.Root, filepath.FromSlash(name))
+}
+
+func (l *Local) Mkdir(dir string) error {
+	if _, err := os.Stat(l.Path(dir)); os.IsNotExist(err) {
+		return os.MkdirAll(l.Path(dir), 0755)
+	}
+	return nil
+}
+
+func (l *Local) MkdirAll(dir string, perm os.FileMode) error {
+	return os.MkdirAll(l.Path(dir), perm)
+}
+
+func (l *Local) IsEmpty(name string) (bool, error) {
+	f, err := os.Open(l.Path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	_, err = f.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// Put creates or truncates the named file and its parent directories
+func (l *Local) Put(name string, perm os.FileMode) (io.WriteCloser, error) {
+	p := l.Path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func (l *Local) Get(name string) (io.ReadCloser, error) {
+	return os.Open(l.Path(name))
+}
+
+func (l *Local) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(l.Path(name))
+}
+
+func (l *Local) List(dir string) ([]fs.DirEntry, error) {
+	return os.ReadDir(l.Path(dir))
+}
+
+func (l *Local) Delete(name string) error {
+	return os.Remove(l.Path(name))
+}
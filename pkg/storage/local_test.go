@@ -0,0 +1,88 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalMkdir(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr bool
+	}{
+		{
+			name: "creates nested dir",
+			dir:  "org/spacey",
+		},
+		{
+			name: "accepts existing dir",
+			dir:  ".",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			l := NewLocal(root)
+			if err := l.Mkdir(tt.dir); (err != nil) != tt.wantErr {
+				t.Errorf("Mkdir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			require.DirExists(t, filepath.Join(root, filepath.FromSlash(tt.dir)))
+		})
+	}
+}
+
+func TestLocal(t *testing.T) {
+	root := t.TempDir()
+	l := NewLocal(root)
+
+	empty, err := l.IsEmpty(".")
+	require.NoError(t, err)
+	require.True(t, empty)
+
+	w, err := l.Put("org/space/app_manifest.yml", 0600)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "applications: []")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	info, err := os.Stat(filepath.Join(root, "org", "space", "app_manifest.yml"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	empty, err = l.IsEmpty(".")
+	require.NoError(t, err)
+	require.False(t, empty)
+
+	data, err := fs.ReadFile(NewFS(l), "org/space/app_manifest.yml")
+	require.NoError(t, err)
+	require.Equal(t, "applications: []", string(data))
+
+	entries, err := l.List("org")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.True(t, entries[0].IsDir())
+
+	require.NoError(t, l.Delete("org/space/app_manifest.yml"))
+	_, err = l.Stat("org/space/app_manifest.yml")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,183 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory holds an export in memory, it is meant for the small files of a single app rather than whole exports
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+	dirs  map[string]bool
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		files: map[string]memoryFile{},
+		dirs:  map[string]bool{},
+	}
+}
+
+func (m *Memory) Mkdir(dir string) error {
+	return m.MkdirAll(dir, 0755)
+}
+
+func (m *Memory) MkdirAll(dir string, _ os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for dir = path.Clean(dir); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		m.dirs[dir] = true
+	}
+	return nil
+}
+
+func (m *Memory) IsEmpty(name string) (bool, error) {
+	entries, err := m.List(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+// Put stores everything written to the returned writer under the named file once it is closed
+func (m *Memory) Put(name string, _ os.FileMode) (io.WriteCloser, error) {
+	return &memoryWriter{memory: m, name: path.Clean(name)}, nil
+}
+
+func (m *Memory) Get(name string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, notExist("open", name)
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func (m *Memory) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = path.Clean(name)
+	if f, ok := m.files[name]; ok {
+		return fileInfo{name: name, size: int64(len(f.data)), modTime: f.modTime}, nil
+	}
+	if name == "." || m.dirs[name] {
+		return fileInfo{name: name, dir: true}, nil
+	}
+	return nil, notExist("stat", name)
+}
+
+func (m *Memory) List(dir string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dir = path.Clean(dir)
+	if dir != "." && !m.dirs[dir] {
+		return nil, notExist("readdir", dir)
+	}
+
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	for name, f := range m.files {
+		if child, ok := childOf(dir, name); ok && !seen[child] {
+			seen[child] = true
+			if path.Join(dir, child) == name {
+				entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: name, size: int64(len(f.data)), modTime: f.modTime}))
+			} else {
+				entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: path.Join(dir, child), dir: true}))
+			}
+		}
+	}
+	for name := range m.dirs {
+		if child, ok := childOf(dir, name); ok && !seen[child] {
+			seen[child] = true
+			entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: path.Join(dir, child), dir: true}))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (m *Memory) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+	if _, ok := m.files[name]; !ok {
+		return notExist("remove", name)
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// childOf returns the first element of name below dir
+func childOf(dir, name string) (string, bool) {
+	if dir != "." {
+		if !strings.HasPrefix(name, dir+"/") {
+			return "", false
+		}
+		name = strings.TrimPrefix(name, dir+"/")
+	}
+	child := strings.SplitN(name, "/", 2)[0]
+	return child, child != ""
+}
+
+type memoryWriter struct {
+	bytes.Buffer
+	memory *Memory
+	name   string
+	closed bool
+}
+
+func (w *memoryWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.memory.MkdirAll(path.Dir(w.name), 0755); err != nil {
+		return err
+	}
+
+	w.memory.mu.Lock()
+	defer w.memory.mu.Unlock()
+	w.memory.files[w.name] = memoryFile{data: w.Bytes(), modTime: time.Now()}
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+
+	empty, err := m.IsEmpty(".")
+	require.NoError(t, err)
+	require.True(t, empty)
+
+	require.NoError(t, m.Mkdir("org/space"))
+	_, err = m.Stat("org")
+	require.NoError(t, err)
+
+	w, err := m.Put("org/space/app_manifest.yml", 0600)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "applications: []")
+	require.NoError(t, err)
+
+	_, err = m.Stat("org/space/app_manifest.yml")
+	require.ErrorIs(t, err, fs.ErrNotExist, "files are stored once the writer is closed")
+	require.NoError(t, w.Close())
+
+	w, err = m.Put("org/space/app_autoscale_rules.json", 0600)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	empty, err = m.IsEmpty(".")
+	require.NoError(t, err)
+	require.False(t, empty)
+
+	data, err := fs.ReadFile(NewFS(m), "org/space/app_manifest.yml")
+	require.NoError(t, err)
+	require.Equal(t, "applications: []", string(data))
+
+	entries, err := m.List("org")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.True(t, entries[0].IsDir())
+
+	require.NoError(t, fstest.TestFS(NewFS(m), "org/space/app_manifest.yml", "org/space/app_autoscale_rules.json"))
+
+	require.NoError(t, m.Delete("org/space/app_manifest.yml"))
+	_, err = m.Stat("org/space/app_manifest.yml")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,320 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	// S3EndpointEnv overrides the endpoint of the object store, e.g. http://localhost:9000 for a local MinIO
+	S3EndpointEnv = "APP_MIGRATOR_S3_ENDPOINT"
+
+	// DefaultPartSize is the size of the parts objects are uploaded in, it bounds the memory used by an upload
+	DefaultPartSize = 8 << 20
+)
+
+// S3 holds an export in a bucket of an S3-compatible object store
+type S3 struct {
+	Endpoint *url.URL
+	Region   string
+	Bucket   string
+	Prefix   string
+	// PathStyle addresses the bucket in the path rather than in the host name, as most S3-compatible stores expect
+	PathStyle bool
+	// PartSize is the size of the parts large objects are uploaded in, at least 5 MiB
+	PartSize int
+	client   *minio.Client
+	pageSize int
+}
+
+// NewS3 creates the storage for an export dir like s3://bucket/prefix. The credentials and region are read from the
+// usual AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION environment variables, and the
+// endpoint from APP_MIGRATOR_S3_ENDPOINT when the bucket is not on AWS.
+func NewS3(exportDir string) (*S3, error) {
+	if !IsRemote(exportDir) {
+		return nil, fmt.Errorf("%s is not an s3:// url", exportDir)
+	}
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(exportDir, S3Scheme), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("no bucket in %s", exportDir)
+	}
+
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	accessKeyID, secretAccessKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required to use an s3:// export dir")
+	}
+
+	endpoint, pathStyle := os.Getenv(S3EndpointEnv), true
+	if endpoint == "" {
+		endpoint, pathStyle = fmt.Sprintf("https://s3.%s.amazonaws.com", region), false
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", S3EndpointEnv, err)
+	}
+
+	creds := credentials.NewStaticV4(accessKeyID, secretAccessKey, os.Getenv("AWS_SESSION_TOKEN"))
+	return newS3(u, region, bucket, prefix, creds, pathStyle)
+}
+
+func newS3(endpoint *url.URL, region, bucket, prefix string, creds *credentials.Credentials, pathStyle bool) (*S3, error) {
+	if endpoint.Host == "" || strings.Trim(endpoint.Path, "/") != "" {
+		return nil, fmt.Errorf("invalid %s %s, it must be a url like http://host:port", S3EndpointEnv, endpoint)
+	}
+
+	lookup := minio.BucketLookupDNS
+	if pathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:        creds,
+		Secure:       endpoint.Scheme == "https",
+		Region:       region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    bucket,
+		Prefix:    strings.Trim(prefix, "/"),
+		PathStyle: pathStyle,
+		client:    client,
+	}, nil
+}
+
+func firstEnv(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Mkdir does nothing, directories only exist as the common prefix of object keys
+func (s *S3) Mkdir(string) error {
+	return nil
+}
+
+// MkdirAll does nothing, directories only exist as the common prefix of object keys
+func (s *S3) MkdirAll(string, os.FileMode) error {
+	return nil
+}
+
+func (s *S3) IsEmpty(name string) (bool, error) {
+	entries, err := s.List(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+// Put uploads everything written to the returned writer to the named object once it is closed.
+// Large objects are uploaded in parts while they are written, so at most a couple of parts are held in memory.
+func (s *S3) Put(name string, _ os.FileMode) (io.WriteCloser, error) {
+	partSize := s.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	return &s3Writer{s3: s, key: s.key(name), partSize: partSize}, nil
+}
+
+func (s *S3) Get(name string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(context.Background(), s.Bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// the object is only requested once it is read or stat'ed
+	if _, err = obj.Stat(); err != nil {
+		obj.Close()
+		if isNoSuchKey(err) {
+			return nil, notExist("open", name)
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3) Stat(name string) (fs.FileInfo, error) {
+	key := s.key(name)
+	if name != "." {
+		info, err := s.client.StatObject(context.Background(), s.Bucket, key, minio.StatObjectOptions{})
+		if err == nil {
+			return fileInfo{name: name, size: info.Size, modTime: info.LastModified}, nil
+		}
+		if !isNoSuchKey(err) {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for o := range s.client.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Prefix: dirPrefix(key), MaxKeys: 1}) {
+		if o.Err != nil {
+			return nil, o.Err
+		}
+		return fileInfo{name: name, dir: true}, nil
+	}
+	if name != "." {
+		return nil, notExist("stat", name)
+	}
+	return fileInfo{name: name, dir: true}, nil
+}
+
+func (s *S3) List(dir string) ([]fs.DirEntry, error) {
+	prefix := dirPrefix(s.key(dir))
+	var entries []fs.DirEntry
+	for o := range s.client.ListObjects(context.Background(), s.Bucket, minio.ListObjectsOptions{Prefix: prefix, MaxKeys: s.pageSize}) {
+		if o.Err != nil {
+			return nil, o.Err
+		}
+		if o.Key == prefix {
+			continue
+		}
+		name := path.Join(dir, strings.TrimSuffix(strings.TrimPrefix(o.Key, prefix), "/"))
+		if strings.HasSuffix(o.Key, "/") {
+			entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: name, dir: true}))
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: name, size: o.Size, modTime: o.LastModified}))
+	}
+
+	if dir != "." && len(entries) == 0 {
+		return nil, notExist("readdir", dir)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+func (s *S3) Delete(name string) error {
+	return s.client.RemoveObject(context.Background(), s.Bucket, s.key(name), minio.RemoveObjectOptions{})
+}
+
+func (s *S3) key(name string) string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if s.Prefix == "" {
+		return name
+	}
+	return strings.Trim(s.Prefix+"/"+name, "/")
+}
+
+func dirPrefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+func isNoSuchKey(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	return resp.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound
+}
+
+// s3Writer holds what is written in memory until a part is filled, the object is then uploaded in parts
+// while it is written. Objects smaller than a part are uploaded with a single request.
+type s3Writer struct {
+	s3       *S3
+	key      string
+	partSize int
+	buf      bytes.Buffer
+	pipe     *io.PipeWriter
+	done     chan error
+	err      error
+	closed   bool
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.closed {
+		return 0, errors.New("write to closed s3 object")
+	}
+
+	if w.pipe != nil {
+		// the pipe is closed with the error of the upload when it fails
+		n, err := w.pipe.Write(p)
+		w.err = err
+		return n, err
+	}
+
+	n, _ := w.buf.Write(p)
+	if w.buf.Len() >= w.partSize {
+		w.upload()
+	}
+	return n, nil
+}
+
+// upload starts uploading the object in parts, from what was written so far and what is written to the pipe
+func (w *s3Writer) upload() {
+	r, pipe := io.Pipe()
+	w.pipe, w.done = pipe, make(chan error, 1)
+	go func() {
+		_, err := w.s3.client.PutObject(context.Background(), w.s3.Bucket, w.key, io.MultiReader(&w.buf, r), -1,
+			minio.PutObjectOptions{PartSize: uint64(w.partSize)})
+		_ = r.CloseWithError(err)
+		w.done <- err
+	}()
+}
+
+// Close uploads what is left, the object only exists once Close succeeds
+func (w *s3Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.pipe == nil {
+		_, w.err = w.s3.client.PutObject(context.Background(), w.s3.Bucket, w.key, &w.buf, int64(w.buf.Len()),
+			minio.PutObjectOptions{})
+		return w.err
+	}
+
+	_ = w.pipe.Close()
+	w.err = <-w.done
+	return w.err
+}
@@ -0,0 +1,209 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/testsupport"
+)
+
+func TestNewS3(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "some-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "some-secret")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+
+	t.Setenv(S3EndpointEnv, "")
+	s, err := NewS3("s3://my-bucket/exports/prod/")
+	require.NoError(t, err)
+	assert.Equal(t, "my-bucket", s.Bucket)
+	assert.Equal(t, "exports/prod", s.Prefix)
+	assert.Equal(t, "eu-west-1", s.Region)
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com", s.Endpoint.String())
+	assert.False(t, s.PathStyle)
+
+	t.Setenv(S3EndpointEnv, "http://localhost:9000")
+	s, err = NewS3("s3://my-bucket")
+	require.NoError(t, err)
+	assert.Equal(t, "", s.Prefix)
+	assert.True(t, s.PathStyle)
+
+	_, err = NewS3("s3:///prefix")
+	assert.Error(t, err)
+
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	_, err = NewS3("s3://my-bucket")
+	assert.ErrorContains(t, err, "AWS_SECRET_ACCESS_KEY")
+}
+
+func newTestS3(t *testing.T, prefix string) (*S3, *testsupport.FakeS3) {
+	t.Helper()
+	fake := testsupport.NewFakeS3(t, "exports")
+	endpoint, err := url.Parse(fake.URL)
+	require.NoError(t, err)
+	s, err := newS3(endpoint, "us-east-1", "exports", prefix, credentials.NewStaticV4("some-key", "some-secret", ""), true)
+	require.NoError(t, err)
+	return s, fake
+}
+
+func putObject(t *testing.T, s *S3, name, content string) {
+	t.Helper()
+	w, err := s.Put(name, 0644)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func TestS3(t *testing.T) {
+	s, fake := newTestS3(t, "prod")
+
+	empty, err := s.IsEmpty(".")
+	require.NoError(t, err)
+	assert.True(t, empty)
+
+	putObject(t, s, "metadata.json", `{}`)
+	putObject(t, s, "my_org/my_space/my_app_manifest.yml", "applications: []")
+	putObject(t, s, "my_org/my_space/my_app.tgz", "droplet")
+	putObject(t, s, "my_org/other_space/other_app_manifest.yml", "applications: []")
+	assert.Equal(t, []string{
+		"prod/metadata.json",
+		"prod/my_org/my_space/my_app.tgz",
+		"prod/my_org/my_space/my_app_manifest.yml",
+		"prod/my_org/other_space/other_app_manifest.yml",
+	}, fake.Keys())
+
+	empty, err = s.IsEmpty(".")
+	require.NoError(t, err)
+	assert.False(t, empty)
+
+	info, err := s.Stat("my_org/my_space/my_app.tgz")
+	require.NoError(t, err)
+	assert.Equal(t, "my_app.tgz", info.Name())
+	assert.Equal(t, int64(7), info.Size())
+	assert.False(t, info.IsDir())
+
+	info, err = s.Stat("my_org")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	_, err = s.Stat("missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = s.Get("missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	entries, err := s.List("my_org")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "my_space", entries[0].Name())
+	assert.True(t, entries[0].IsDir())
+	assert.Equal(t, "other_space", entries[1].Name())
+
+	require.NoError(t, fstest.TestFS(NewFS(s),
+		"metadata.json",
+		"my_org/my_space/my_app_manifest.yml",
+		"my_org/my_space/my_app.tgz",
+		"my_org/other_space/other_app_manifest.yml",
+	))
+
+	require.NoError(t, s.Delete("my_org/my_space/my_app.tgz"))
+	_, err = s.Stat("my_org/my_space/my_app.tgz")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestS3ListPaginates(t *testing.T) {
+	s, fake := newTestS3(t, "")
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		putObject(t, s, "dir/"+name, name)
+	}
+
+	s.pageSize = 2
+
+	entries, err := s.List("dir")
+	require.NoError(t, err)
+	assert.Len(t, entries, 5)
+	assert.Equal(t, 3, fake.Requests["GET"])
+}
+
+func TestS3PutMultipart(t *testing.T) {
+	s, fake := newTestS3(t, "")
+	s.PartSize = 5 << 20
+
+	content := strings.Repeat("0123456789", 1<<20) + "abc"
+	w, err := s.Put("big.tgz", 0644)
+	require.NoError(t, err)
+	_, err = io.Copy(w, bytes.NewBufferString(content))
+	require.NoError(t, err)
+
+	_, ok := fake.Object("big.tgz")
+	assert.False(t, ok, "the object is not visible before close")
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+
+	data, ok := fake.Object("big.tgz")
+	require.True(t, ok)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, 1, fake.Requests["POST ?uploads"])
+	assert.Equal(t, 3, fake.Requests["PUT ?partNumber"])
+	assert.Equal(t, 0, fake.Uploads())
+
+	putObject(t, s, "small.txt", "1234")
+	assert.Equal(t, 1, fake.Requests["PUT"])
+	data, ok = fake.Object("small.txt")
+	require.True(t, ok)
+	assert.Equal(t, "1234", string(data))
+}
+
+func TestS3PutAbortsFailedUpload(t *testing.T) {
+	maxRetry := minio.MaxRetry
+	minio.MaxRetry = 1
+	t.Cleanup(func() {
+		minio.MaxRetry = maxRetry
+	})
+
+	s, fake := newTestS3(t, "")
+	s.PartSize = 5 << 20
+	fake.Fail = func(req *http.Request) bool {
+		return req.URL.Query().Get("partNumber") == "2"
+	}
+
+	w, err := s.Put("big.tgz", 0644)
+	require.NoError(t, err)
+	chunk := []byte(strings.Repeat("0123456789", 1<<17))
+	for i := 0; i < 20 && err == nil; i++ {
+		_, err = w.Write(chunk)
+	}
+	require.Error(t, err)
+	assert.Equal(t, "InternalError", minio.ToErrorResponse(err).Code)
+	assert.Equal(t, err, w.Close())
+
+	assert.Equal(t, 1, fake.Requests["DELETE ?uploadId"])
+	assert.Equal(t, 0, fake.Uploads())
+	_, ok := fake.Object("big.tgz")
+	assert.False(t, ok)
+}
@@ -0,0 +1,168 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+// Package storage holds the files of an export, either in a local directory or in an S3-compatible object store.
+//
+// Names are slash separated and relative to the root of the export, as with io/fs.
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Scheme is the scheme of export dirs held in an S3-compatible bucket, e.g. s3://bucket/prefix
+const S3Scheme = "s3://"
+
+// Store reads the files of an export
+type Store interface {
+	Get(name string) (io.ReadCloser, error)
+	Stat(name string) (fs.FileInfo, error)
+	List(dir string) ([]fs.DirEntry, error)
+}
+
+// IsRemote reports whether the export dir is held in an object store rather than on the local file system
+func IsRemote(exportDir string) bool {
+	return strings.HasPrefix(exportDir, S3Scheme)
+}
+
+// LocalDir is the local directory for the files that cannot be kept with a remote export, such as import journals
+func LocalDir(exportDir string) string {
+	if IsRemote(exportDir) {
+		return "."
+	}
+	return exportDir
+}
+
+// NewFS returns a read only view of the export held by s
+func NewFS(s Store) fs.FS {
+	return storeFS{store: s}
+}
+
+type storeFS struct {
+	store Store
+}
+
+func (s storeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := s.store.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		entries, err := s.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &dirFile{info: info, entries: entries}, nil
+	}
+
+	r, err := s.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return &file{ReadCloser: r, info: info}, nil
+}
+
+func (s storeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := s.store.List(name)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (s storeFS) Stat(name string) (fs.FileInfo, error) {
+	return s.store.Stat(name)
+}
+
+type file struct {
+	io.ReadCloser
+	info fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+type dirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *dirFile) Close() error {
+	return nil
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// fileInfo describes an object, or a common prefix of objects for a directory
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi fileInfo) Name() string       { return path.Base(fi.name) }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+func notExist(op, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
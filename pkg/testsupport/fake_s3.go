@@ -0,0 +1,302 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package testsupport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// FakeS3 is an in-process S3-compatible object store with a single bucket, addressed path-style
+type FakeS3 struct {
+	*httptest.Server
+	Bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	// Fail makes the requests it returns true for fail with an internal error
+	Fail func(req *http.Request) bool
+	// Requests counts the requests by method and sub-resource, e.g. "PUT", "PUT ?partNumber", "POST ?uploads"
+	Requests map[string]int
+}
+
+// NewFakeS3 starts a fake object store, it is closed when the test ends
+func NewFakeS3(t *testing.T, bucket string) *FakeS3 {
+	f := &FakeS3{
+		Bucket:   bucket,
+		objects:  map[string][]byte{},
+		uploads:  map[string]map[int][]byte{},
+		Requests: map[string]int{},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Object returns the content of the object with the given key
+func (f *FakeS3) Object(key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[key]
+	return data, ok
+}
+
+// Keys returns the sorted keys of all objects
+func (f *FakeS3) Keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Uploads returns the number of multipart uploads that were neither completed nor aborted
+func (f *FakeS3) Uploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.uploads)
+}
+
+func (f *FakeS3) serve(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		s3Error(w, http.StatusForbidden, "AccessDenied", "missing signature")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if bucket != f.Bucket {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket", "no such bucket")
+		return
+	}
+
+	query := req.URL.Query()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Requests[requestName(req.Method, query)]++
+	if f.Fail != nil && f.Fail(req) {
+		s3Error(w, http.StatusInternalServerError, "InternalError", "injected failure")
+		return
+	}
+
+	switch {
+	case key == "" && req.Method == http.MethodGet:
+		f.list(w, query)
+	case req.Method == http.MethodPost && query.Has("uploads"):
+		id := strconv.Itoa(len(f.Requests)) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+		f.uploads[id] = map[int][]byte{}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			UploadID string   `xml:"UploadId"`
+		}{UploadID: id})
+	case req.Method == http.MethodPut && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchUpload", "no such upload")
+			return
+		}
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		parts[n] = readBody(req)
+		w.Header().Set("ETag", fmt.Sprintf("%q", "etag-"+strconv.Itoa(n)))
+	case req.Method == http.MethodPost && query.Has("uploadId"):
+		f.complete(w, req, key, query.Get("uploadId"))
+	case req.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodPut:
+		f.objects[key] = readBody(req)
+	case req.Method == http.MethodGet, req.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey", "no such key")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case req.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", req.Method)
+	}
+}
+
+// readBody returns the content of a request, decoding the chunks of a streaming signed upload
+func readBody(req *http.Request) []byte {
+	data, _ := io.ReadAll(req.Body)
+	if !strings.HasPrefix(req.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return data
+	}
+
+	var content []byte
+	for {
+		header, rest, ok := strings.Cut(string(data), "\r\n")
+		if !ok {
+			return content
+		}
+		sizeHex, _, _ := strings.Cut(header, ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 || int(size) > len(rest) {
+			return content
+		}
+		content = append(content, rest[:size]...)
+		data = []byte(strings.TrimPrefix(rest[size:], "\r\n"))
+	}
+}
+
+func requestName(method string, query map[string][]string) string {
+	for _, sub := range []string{"uploads", "partNumber", "uploadId"} {
+		if _, ok := query[sub]; ok {
+			return method + " ?" + sub
+		}
+	}
+	return method
+}
+
+func (f *FakeS3) complete(w http.ResponseWriter, req *http.Request, key, id string) {
+	parts, ok := f.uploads[id]
+	if !ok {
+		s3Error(w, http.StatusNotFound, "NoSuchUpload", "no such upload")
+		return
+	}
+	var body struct {
+		Parts []struct {
+			PartNumber int `xml:"PartNumber"`
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(req.Body).Decode(&body); err != nil {
+		s3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	var data []byte
+	for _, p := range body.Parts {
+		data = append(data, parts[p.PartNumber]...)
+	}
+	f.objects[key] = data
+	delete(f.uploads, id)
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string   `xml:"Bucket"`
+		Key     string   `xml:"Key"`
+		ETag    string   `xml:"ETag"`
+	}{Bucket: f.Bucket, Key: key, ETag: fmt.Sprintf("%q", "etag-"+id)})
+}
+
+type fakeS3Object struct {
+	Key          string `xml:"Key"`
+	Size         int    `xml:"Size"`
+	LastModified string `xml:"LastModified"`
+}
+
+type fakeS3Prefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+func (f *FakeS3) list(w http.ResponseWriter, query map[string][]string) {
+	get := func(name string) string {
+		if v := query[name]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	prefix, delimiter, after := get("prefix"), get("delimiter"), get("continuation-token")
+	maxKeys, err := strconv.Atoi(get("max-keys"))
+	if err != nil || maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var contents []fakeS3Object
+	var prefixes []fakeS3Prefix
+	seen := map[string]bool{}
+	last := ""
+	truncated := false
+	for _, k := range keys {
+		entry := k
+		if delimiter != "" {
+			if i := strings.Index(k[len(prefix):], delimiter); i >= 0 {
+				entry = k[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if entry <= after || seen[entry] {
+			continue
+		}
+		if len(contents)+len(prefixes) == maxKeys {
+			truncated = true
+			break
+		}
+		seen[entry] = true
+		last = entry
+		if entry != k {
+			prefixes = append(prefixes, fakeS3Prefix{Prefix: entry})
+		} else {
+			contents = append(contents, fakeS3Object{
+				Key:          k,
+				Size:         len(f.objects[k]),
+				LastModified: time.Now().UTC().Format(time.RFC3339),
+			})
+		}
+	}
+
+	result := struct {
+		XMLName               xml.Name       `xml:"ListBucketResult"`
+		IsTruncated           bool           `xml:"IsTruncated"`
+		NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+		Contents              []fakeS3Object `xml:"Contents"`
+		CommonPrefixes        []fakeS3Prefix `xml:"CommonPrefixes"`
+	}{IsTruncated: truncated, Contents: contents, CommonPrefixes: prefixes}
+	if truncated {
+		result.NextContinuationToken = last
+	}
+	writeXML(w, result)
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func s3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
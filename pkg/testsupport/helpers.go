@@ -204,7 +204,9 @@ func ExportTestHandler(t *testing.T) http.Handler {
 				assert.NoError(t, err)
 			}
 		}),
-		WithTestHandler(t, "/v2/apps/6064d98a-95e6-400b-bc03-be65e6d59622/droplet/download", func(t *testing.T) http.HandlerFunc {
+		WithTestHandler(t, "/v3/apps/6064d98a-95e6-400b-bc03-be65e6d59622/droplets/current", V3DropletTestHandler),
+		WithTestHandler(t, "/v3/packages/752edab0-2147-4f58-9c25-cd72ad8c3561", V3PackageTestHandler),
+		WithTestHandler(t, "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16/download", func(t *testing.T) http.HandlerFunc {
 			return func(w http.ResponseWriter, r *http.Request) {
 				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 				w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -222,14 +224,47 @@ func ExportTestHandler(t *testing.T) http.Handler {
 		WithTestHandler(t, "/v2/spaces/5489e195-c42b-4e61-bf30-323c331ecc01", SpaceTestHandler),
 		WithTestHandler(t, "/v2/spaces/5489e195-c42b-4e61-bf30-323c331ecc01/apps", AppsTestHandler),
 		WithTestHandler(t, "/v3/apps/6064d98a-95e6-400b-bc03-be65e6d59622/routes", V3RoutesTestHandler),
-		WithTestHandler(t, "/v2/apps/6064d98a-95e6-400b-bc03-be65e6d59622/service_bindings", ServiceBindingsTestHandler),
-		WithTestHandler(t, "/v2/service_instances/92f0f510-dbb1-4c04-aa7c-28a8dc0797b4", ServiceInstancesTestHandler),
+		WithTestHandler(t, "/v3/service_credential_bindings", ServiceCredentialBindingsTestHandler),
+		WithTestHandler(t, "/v3/service_credential_bindings/0b6e8fe9-b173-4845-a7aa-e093f1081c94/parameters", EmptyObjectTestHandler),
+		WithTestHandler(t, "/v3/service_instances/92f0f510-dbb1-4c04-aa7c-28a8dc0797b4", V3ServiceInstanceTestHandler),
 		WithTestHandler(t, "/api/v2/apps/6064d98a-95e6-400b-bc03-be65e6d59622/rules", AutoScalerRulesTestHandler),
 		WithTestHandler(t, "/api/v2/apps/6064d98a-95e6-400b-bc03-be65e6d59622", AutoScalerAppInstancesTestHandler),
 		WithTestHandler(t, "/api/v2/apps/6064d98a-95e6-400b-bc03-be65e6d59622/scheduled_limit_changes", AutoScalerSchedulesTestHandler),
+		WithTestHandler(t, "/v3/roles", EmptyListTestHandler),
+		WithTestHandler(t, "/v3/security_groups", EmptyListTestHandler),
+		WithTestHandler(t, "/v3/service_route_bindings", EmptyListTestHandler),
+		WithTestHandler(t, "/v3/service_instances", EmptyListTestHandler),
+		WithTestHandler(t, "/v3/spaces/5489e195-c42b-4e61-bf30-323c331ecc01/relationships/isolation_segment", UnsetRelationshipTestHandler),
 	)
 }
 
+// EmptyListTestHandler serves a v3 list without any resources, e.g. the roles of a space without users
+func EmptyListTestHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := io.WriteString(w, `{"pagination": {}, "resources": []}`)
+		assert.NoError(t, err)
+	}
+}
+
+// EmptyObjectTestHandler serves an empty json object, e.g. the parameters of a binding created without any
+func EmptyObjectTestHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := io.WriteString(w, `{}`)
+		assert.NoError(t, err)
+	}
+}
+
+// UnsetRelationshipTestHandler serves a v3 to-one relationship that is not set, e.g. a space without isolation segment
+func UnsetRelationshipTestHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := io.WriteString(w, `{"data": null}`)
+		assert.NoError(t, err)
+	}
+}
+
 func InfoTestHandler(t *testing.T) http.HandlerFunc {
 	return JSONTestHandler(t, "testdata/info.json")
 }
@@ -250,6 +285,14 @@ func SpacesTestHandler(t *testing.T) http.HandlerFunc {
 	return JSONTestHandler(t, "testdata/spaces.json")
 }
 
+func V3DropletTestHandler(t *testing.T) http.HandlerFunc {
+	return JSONTestHandler(t, "testdata/v3droplet.json")
+}
+
+func V3PackageTestHandler(t *testing.T) http.HandlerFunc {
+	return JSONTestHandler(t, "testdata/v3package.json")
+}
+
 func AppTestHandler(t *testing.T) http.HandlerFunc {
 	return JSONTestHandler(t, "testdata/app.json")
 }
@@ -266,14 +309,18 @@ func V3RoutesTestHandler(t *testing.T) http.HandlerFunc {
 	return JSONTestHandler(t, "testdata/v3routes.json")
 }
 
-func ServiceBindingsTestHandler(t *testing.T) http.HandlerFunc {
-	return JSONTestHandler(t, "testdata/service_bindings.json")
+func ServiceCredentialBindingsTestHandler(t *testing.T) http.HandlerFunc {
+	return JSONTestHandler(t, "testdata/v3service_credential_bindings.json")
 }
 
 func ServiceInstancesTestHandler(t *testing.T) http.HandlerFunc {
 	return JSONTestHandler(t, "testdata/service_instances.json")
 }
 
+func V3ServiceInstanceTestHandler(t *testing.T) http.HandlerFunc {
+	return JSONTestHandler(t, "testdata/v3service_instance.json")
+}
+
 func AutoScalerRulesTestHandler(t *testing.T) http.HandlerFunc {
 	return JSONTestHandler(t, "testdata/autoscaler_rules.json")
 }
@@ -0,0 +1,194 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Replacement replaces every match of a regular expression in env var values, when Keys is set
+// only the values of the env vars whose name matches it are changed
+type Replacement struct {
+	Match   string `yaml:"match"`
+	Replace string `yaml:"replace"`
+	Keys    string `yaml:"keys,omitempty"`
+	re      *regexp.Regexp
+	keysRe  *regexp.Regexp
+}
+
+// Rules are the changes made to the env vars of an app: replacements first, then the
+// values to set, then the env vars to remove
+type Rules struct {
+	Replace []Replacement     `yaml:"replace"`
+	Set     map[string]string `yaml:"set"`
+	Remove  []string          `yaml:"remove"`
+}
+
+// Env transforms the env vars of the apps being imported. Global rules apply to every app, followed
+// by the rules of its org, of its space (keyed by org/space), and then of the app (keyed by org/space/app).
+type Env struct {
+	Global Rules            `yaml:"global"`
+	Orgs   map[string]Rules `yaml:"orgs"`
+	Spaces map[string]Rules `yaml:"spaces"`
+	Apps   map[string]Rules `yaml:"apps"`
+}
+
+// Load reads an env transformation file. The values it sets and its replacement strings are Go templates
+// expanded with the variables from the vars files, a variable in a later file overrides one in an earlier file.
+func Load(path string, varsFiles []string) (*Env, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Env{}
+	if err = yaml.UnmarshalStrict(data, e); err != nil {
+		return nil, fmt.Errorf("failed to parse env transformation %s: %w", path, err)
+	}
+
+	vars, err := loadVars(varsFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = e.Global.compile(vars); err != nil {
+		return nil, fmt.Errorf("failed to parse env transformation %s: %w", path, err)
+	}
+	for _, scope := range []map[string]Rules{e.Orgs, e.Spaces, e.Apps} {
+		for k, r := range scope {
+			if err = r.compile(vars); err != nil {
+				return nil, fmt.Errorf("failed to parse env transformation %s, %s: %w", path, k, err)
+			}
+			scope[k] = r
+		}
+	}
+
+	return e, nil
+}
+
+// Apply returns a copy of the env vars of an app in a source org and space with all the rules applied. The values of
+// the env vars are not templates, only the rules were expanded by Load.
+func (e *Env) Apply(org, space, app string, env map[string]interface{}) map[string]interface{} {
+	if e == nil {
+		return env
+	}
+
+	result := make(map[string]interface{}, len(env))
+	for k, v := range env {
+		result[k] = v
+	}
+
+	scoped := []Rules{
+		e.Global,
+		e.Orgs[org],
+		e.Spaces[org+"/"+space],
+		e.Apps[strings.Join([]string{org, space, app}, "/")],
+	}
+	for _, r := range scoped {
+		r.apply(result)
+	}
+
+	return result
+}
+
+func (r *Rules) compile(vars map[string]interface{}) error {
+	var err error
+	for idx := range r.Replace {
+		rep := &r.Replace[idx]
+		if rep.re, err = regexp.Compile(rep.Match); err != nil {
+			return fmt.Errorf("invalid replacement %q: %w", rep.Match, err)
+		}
+		if rep.Keys != "" {
+			if rep.keysRe, err = regexp.Compile(rep.Keys); err != nil {
+				return fmt.Errorf("invalid keys %q: %w", rep.Keys, err)
+			}
+		}
+		if rep.Replace, err = expand(rep.Replace, vars); err != nil {
+			return err
+		}
+	}
+
+	for k, v := range r.Set {
+		if r.Set[k], err = expand(v, vars); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+func (r Rules) apply(env map[string]interface{}) {
+	for _, rep := range r.Replace {
+		for k, v := range env {
+			s, ok := v.(string)
+			if !ok || (rep.keysRe != nil && !rep.keysRe.MatchString(k)) {
+				continue
+			}
+			env[k] = rep.re.ReplaceAllString(s, rep.Replace)
+		}
+	}
+
+	for k, v := range r.Set {
+		env[k] = v
+	}
+
+	for _, k := range r.Remove {
+		delete(env, k)
+	}
+}
+
+func expand(text string, vars map[string]interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func loadVars(files []string) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		fileVars := make(map[string]interface{})
+		if err = yaml.Unmarshal(data, &fileVars); err != nil {
+			return nil, fmt.Errorf("failed to parse vars file %s: %w", f, err)
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,99 @@
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnv_Apply(t *testing.T) {
+	env, err := Load("testdata/env_transform.yml", []string{"testdata/vars.yml", "testdata/vars_override.yml"})
+	require.NoError(t, err)
+
+	source := map[string]interface{}{
+		"API_URL":      "https://api.sys.old.example.com",
+		"DATABASE_URL": "postgres://db.old.internal/orders",
+		"BACKUP_URL":   "postgres://db.old.internal/backup",
+		"LEGACY_FLAG":  "true",
+		"PORT_COUNT":   2,
+	}
+
+	tests := []struct {
+		name  string
+		org   string
+		space string
+		app   string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "applies global rules",
+			org:   "other-org",
+			space: "dev",
+			app:   "api",
+			want: map[string]interface{}{
+				"API_URL":      "https://api.sys.new.example.com",
+				"DATABASE_URL": "postgres://db.old.internal/orders",
+				"BACKUP_URL":   "postgres://db.old.internal/backup",
+				"PORT_COUNT":   2,
+			},
+		},
+		{
+			name:  "applies org and space rules after global rules",
+			org:   "my-org",
+			space: "prod",
+			app:   "web",
+			want: map[string]interface{}{
+				"API_URL":      "https://api.sys.new.example.com",
+				"DATABASE_URL": "postgres://db.new.internal/orders",
+				"BACKUP_URL":   "postgres://db.old.internal/backup",
+				"PORT_COUNT":   2,
+				"LOG_LEVEL":    "info",
+			},
+		},
+		{
+			name:  "app rules override org rules",
+			org:   "my-org",
+			space: "prod",
+			app:   "api",
+			want: map[string]interface{}{
+				"API_URL":      "https://api.sys.new.example.com",
+				"DATABASE_URL": "postgres://db.new.internal/orders",
+				"BACKUP_URL":   "postgres://db.old.internal/backup",
+				"PORT_COUNT":   2,
+				"LOG_LEVEL":    "debug",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, env.Apply(tt.org, tt.space, tt.app, source))
+			assert.Equal(t, "true", source["LEGACY_FLAG"], "the source env must not be changed")
+		})
+	}
+}
+
+func TestEnv_ApplyNil(t *testing.T) {
+	var env *Env
+	source := map[string]interface{}{"KEY": "value"}
+	assert.Equal(t, source, env.Apply("org", "space", "app", source))
+}
+
+func TestLoadMissingVar(t *testing.T) {
+	_, err := Load("testdata/missing_var.yml", nil)
+	assert.ErrorContains(t, err, "invalid value for HOST")
+}
@@ -0,0 +1,103 @@
+//go:build integration
+// +build integration
+
+/*
+ *  Copyright 2022 VMware, Inc.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/app-migrator-for-cloud-foundry/pkg/storage"
+)
+
+// TestS3 runs the object store against a real S3-compatible store, e.g. a local MinIO started with
+//
+//	docker run -p 9000:9000 -e MINIO_ROOT_USER=minioadmin -e MINIO_ROOT_PASSWORD=minioadmin minio/minio server /data
+//
+// and the bucket in APP_MIGRATOR_S3_TEST_BUCKET, along with APP_MIGRATOR_S3_ENDPOINT, AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY.
+func TestS3(t *testing.T) {
+	bucket := os.Getenv("APP_MIGRATOR_S3_TEST_BUCKET")
+	if bucket == "" {
+		t.Skip("APP_MIGRATOR_S3_TEST_BUCKET is not set")
+	}
+
+	prefix := fmt.Sprintf("app-migrator-test-%d", time.Now().UnixNano())
+	s, err := storage.NewS3(fmt.Sprintf("s3://%s/%s", bucket, prefix))
+	require.NoError(t, err)
+	s.PartSize = 5 << 20
+
+	files := map[string]string{
+		"metadata.json":                       `{}`,
+		"my_org/my_space/my_app_manifest.yml": "applications: []",
+		"my_org/my_space/my_app.tgz":          strings.Repeat("0123456789", 1<<20) + "abc",
+	}
+	t.Cleanup(func() {
+		for name := range files {
+			_ = s.Delete(name)
+		}
+	})
+
+	empty, err := s.IsEmpty(".")
+	require.NoError(t, err)
+	assert.True(t, empty)
+
+	for name, content := range files {
+		w, err := s.Put(name, 0644)
+		require.NoError(t, err)
+		_, err = io.Copy(w, bytes.NewBufferString(content))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+
+	info, err := s.Stat("my_org/my_space/my_app.tgz")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(files["my_org/my_space/my_app.tgz"])), info.Size())
+
+	r, err := s.Get("my_org/my_space/my_app.tgz")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, files["my_org/my_space/my_app.tgz"], string(data))
+
+	entries, err := s.List("my_org")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "my_space", entries[0].Name())
+	assert.True(t, entries[0].IsDir())
+
+	_, err = s.Stat("missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = s.Get("missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, fstest.TestFS(storage.NewFS(s), "metadata.json", "my_org/my_space/my_app_manifest.yml", "my_org/my_space/my_app.tgz"))
+
+	require.NoError(t, s.Delete("my_org/my_space/my_app.tgz"))
+	_, err = s.Stat("my_org/my_space/my_app.tgz")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}